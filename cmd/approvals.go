@@ -0,0 +1,198 @@
+package cmd
+
+import (
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/jneo8/mcp-juju/pkg/application"
+	"github.com/spf13/cobra"
+)
+
+// approverTokenEnv holds the approver token when no token file is given.
+const approverTokenEnv = config.EnvPrefix + "_APPROVER_TOKEN"
+
+func init() {
+	approvalsCmd.PersistentFlags().String("approvals-address", config.DefaultApprovalsAddress, "Address of the approvals API")
+	approvalsCmd.PersistentFlags().String("approvals-url", "", "Base URL of the approvals API (overrides approvals-address)")
+	approvalsCmd.PersistentFlags().String("client-cert-file", "", "Client certificate identifying the approver with mutual TLS")
+	approvalsCmd.PersistentFlags().String("client-key-file", "", "Private key of the client certificate")
+	approvalsCmd.PersistentFlags().String("token-file", "", "File holding the approver token (default: $"+approverTokenEnv+")")
+	approvalsCmd.PersistentFlags().String("tls-ca-file", "", "CA bundle used to verify the approvals API certificate")
+
+	approvalsListCmd.Flags().String("status", "", "Only list requests with this status (pending, approved, denied, expired, cancelled)")
+	for _, c := range []*cobra.Command{approvalsApproveCmd, approvalsDenyCmd} {
+		c.Flags().String("reason", "", "Reason for the decision")
+	}
+
+	approvalsCmd.AddCommand(approvalsListCmd, approvalsApproveCmd, approvalsDenyCmd)
+	rootCmd.AddCommand(approvalsCmd)
+}
+
+var approvalsCmd = &cobra.Command{
+	Use:   "approvals",
+	Short: "Manage approval requests for high-risk tool calls",
+}
+
+var approvalsListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List approval requests",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		client, err := newApprovalsClient(cmd)
+		if err != nil {
+			return err
+		}
+		status, _ := cmd.Flags().GetString("status")
+		path := ""
+		if status != "" {
+			path = "?status=" + status
+		}
+		var requests []application.ApprovalRequest
+		if err := client.do(http.MethodGet, path, nil, &requests); err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(cmd.OutOrStdout(), 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tTOOL\tREQUESTER\tSTATUS\tCREATED\tEXPIRES\tARGUMENTS")
+		for _, r := range requests {
+			arguments, _ := json.Marshal(r.Arguments)
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", r.ID, r.Tool, r.Requester, r.Status,
+				r.CreatedAt.Local().Format(time.RFC3339), r.ExpiresAt.Local().Format(time.RFC3339), arguments)
+		}
+		return w.Flush()
+	},
+}
+
+var approvalsApproveCmd = &cobra.Command{
+	Use:   "approve <id>",
+	Short: "Approve a pending request",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return decideApproval(cmd, args[0], "approve")
+	},
+}
+
+var approvalsDenyCmd = &cobra.Command{
+	Use:   "deny <id>",
+	Short: "Deny a pending request",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		return decideApproval(cmd, args[0], "deny")
+	},
+}
+
+func decideApproval(cmd *cobra.Command, id string, action string) error {
+	client, err := newApprovalsClient(cmd)
+	if err != nil {
+		return err
+	}
+	reason, _ := cmd.Flags().GetString("reason")
+	var request application.ApprovalRequest
+	decision := application.ApprovalDecision{Reason: reason}
+	if err := client.do(http.MethodPost, "/"+id+"/"+action, decision, &request); err != nil {
+		return err
+	}
+	fmt.Fprintf(cmd.OutOrStdout(), "%s %s %s by %s\n", request.ID, request.Tool, request.Status, request.DecidedBy)
+	return nil
+}
+
+type approvalsClient struct {
+	baseURL string
+	token   string
+	http    *http.Client
+}
+
+func newApprovalsClient(cmd *cobra.Command) (*approvalsClient, error) {
+	baseURL, _ := cmd.Flags().GetString("approvals-url")
+	if baseURL == "" {
+		baseURL = cfg.ApprovalsURL()
+	}
+	caFile, _ := cmd.Flags().GetString("tls-ca-file")
+	tlsConfig := &tls.Config{MinVersion: tls.VersionTLS12}
+	if caFile != "" {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in CA bundle %s", caFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	// The client certificate has its own flags: the server certificate must
+	// never become the identity of an approver.
+	certFile, _ := cmd.Flags().GetString("client-cert-file")
+	keyFile, _ := cmd.Flags().GetString("client-key-file")
+	if (certFile == "") != (keyFile == "") {
+		return nil, fmt.Errorf("client-cert-file and client-key-file must be set together")
+	}
+	if certFile != "" {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	token := os.Getenv(approverTokenEnv)
+	if tokenFile, _ := cmd.Flags().GetString("token-file"); tokenFile != "" {
+		data, err := os.ReadFile(tokenFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read approver token: %w", err)
+		}
+		token = strings.TrimSpace(string(data))
+	}
+	return &approvalsClient{
+		baseURL: baseURL,
+		token:   token,
+		http: &http.Client{
+			Timeout:   30 * time.Second,
+			Transport: &http.Transport{TLSClientConfig: tlsConfig},
+		},
+	}, nil
+}
+
+func (c *approvalsClient) do(method string, path string, body any, out any) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	req, err := http.NewRequest(method, c.baseURL+path, reader)
+	if err != nil {
+		return err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return fmt.Errorf("unable to reach approvals API: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		var apiErr struct {
+			Error string `json:"error"`
+		}
+		if err := json.NewDecoder(resp.Body).Decode(&apiErr); err == nil && apiErr.Error != "" {
+			return fmt.Errorf("approvals API: %s", apiErr.Error)
+		}
+		return fmt.Errorf("approvals API returned %s", resp.Status)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
@@ -0,0 +1,182 @@
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/jneo8/mcp-juju/pkg/jujuadapter"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v3"
+)
+
+func init() {
+	rootCmd.PersistentFlags().String("config", "", "Configuration file in YAML or TOML (default $XDG_CONFIG_HOME/mcp-juju/config.yaml)")
+	rootCmd.PersistentFlags().String("profile", "", "Profile of the configuration file to apply")
+
+	configPrintCmd.Flags().String("format", "yaml", "Output format (yaml, json or toml)")
+	configCmd.AddCommand(configPrintCmd)
+	rootCmd.AddCommand(configCmd)
+}
+
+// configSource records which file and profile the configuration came from.
+type configSource struct {
+	path    string
+	profile string
+}
+
+var source configSource
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "Inspect the configuration",
+}
+
+var configPrintCmd = &cobra.Command{
+	Use:   "print",
+	Short: "Print the effective configuration merged from defaults, the config file, environment and flags",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		format, _ := cmd.Flags().GetString("format")
+		settings := cfg.Settings()
+
+		var out []byte
+		var err error
+		switch format {
+		case "json":
+			out, err = json.MarshalIndent(settings, "", "  ")
+			out = append(out, '\n')
+		case "yaml":
+			out, err = yaml.Marshal(settings)
+		case "toml":
+			out, err = toml.Marshal(settings)
+		default:
+			return fmt.Errorf("unknown format %q: must be yaml, json or toml", format)
+		}
+		if err != nil {
+			return err
+		}
+		if format != "json" {
+			fmt.Fprintf(cmd.OutOrStdout(), "# config file: %s\n# profile: %s\n", orNone(source.path), orNone(source.profile))
+		}
+		_, err = cmd.OutOrStdout().Write(out)
+		return err
+	},
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "none"
+	}
+	return s
+}
+
+// loadConfig merges defaults, the configuration file, environment variables
+// and flags, in increasing order of precedence, and validates the result.
+func loadConfig(v *viper.Viper, cmd *cobra.Command) (config.Config, configSource, error) {
+	var c config.Config
+	var src configSource
+
+	v.AutomaticEnv()
+	v.SetEnvPrefix(config.EnvPrefix)
+	v.SetEnvKeyReplacer(strings.NewReplacer("-", "_"))
+	v.SetDefault("server-type", config.ServerTypeStdio)
+	// Subcommands such as `config print` see the server flags' defaults too.
+	flagSets := []*pflag.FlagSet{cmd.Flags()}
+	if root := cmd.Root(); root != cmd {
+		flagSets = []*pflag.FlagSet{root.Flags(), cmd.Flags()}
+	}
+	for _, flags := range flagSets {
+		if err := v.BindPFlags(flags); err != nil {
+			return c, src, fmt.Errorf("unable to bind flags: %w", err)
+		}
+	}
+
+	file, err := loadConfigFile(v.GetString("config"))
+	if err != nil {
+		return c, src, err
+	}
+	src.profile = v.GetString("profile")
+	if file != nil {
+		src.path = file.Path
+		if err := file.Validate(); err != nil {
+			return c, src, fmt.Errorf("invalid config file: %w", err)
+		}
+		if src.profile == "" {
+			src.profile = file.Profile
+		}
+		settings, err := file.Merged(src.profile)
+		if err != nil {
+			return c, src, err
+		}
+		if err := v.MergeConfigMap(settings); err != nil {
+			return c, src, fmt.Errorf("unable to merge config file: %w", err)
+		}
+	} else if src.profile != "" {
+		return c, src, fmt.Errorf("profile %q requires a configuration file", src.profile)
+	}
+
+	if err := v.Unmarshal(&c); err != nil {
+		return c, src, fmt.Errorf("unable to decode config: %w", err)
+	}
+	err = c.Validate()
+	if err == nil {
+		err = jujuadapter.ValidateToolSelection(jujuadapter.NewToolSelection(c))
+	}
+	if err != nil {
+		locateErrors(err, flagSets, file, src.profile)
+		return c, src, fmt.Errorf("config validation failed: %w", err)
+	}
+	return c, src, nil
+}
+
+// loadConfigFile loads the given file, or the default one if it exists.
+func loadConfigFile(path string) (*config.File, error) {
+	if path == "" {
+		path = config.DefaultFilePath()
+		if path == "" {
+			return nil, nil
+		}
+	}
+	file, err := config.LoadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("unable to load config file: %w", err)
+	}
+	return file, nil
+}
+
+// locateErrors points every invalid setting at the source that set it, in
+// the same order viper resolves them.
+func locateErrors(err error, flagSets []*pflag.FlagSet, file *config.File, profile string) {
+	var errs config.ValidationErrors
+	if !errors.As(err, &errs) {
+		return
+	}
+	for _, e := range errs {
+		key := e.Key()
+		env := config.EnvPrefix + "_" + strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		switch {
+		case flagChanged(flagSets, key):
+			e.Location = "flag --" + key
+		case os.Getenv(env) != "":
+			e.Location = "env " + env
+		case file != nil:
+			e.Location = file.Location(profile, e.Field)
+		}
+	}
+}
+
+func flagChanged(flagSets []*pflag.FlagSet, name string) bool {
+	for _, flags := range flagSets {
+		if flag := flags.Lookup(name); flag != nil && flag.Changed {
+			return true
+		}
+	}
+	return false
+}
@@ -1,8 +1,9 @@
 package cmd
 
 import (
-	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/jneo8/mcp-juju/config"
 	"github.com/jneo8/mcp-juju/pkg/application"
@@ -18,7 +19,32 @@ func init() {
 	rootCmd.Flags().String("endpoint", "/mcp", "Endpoint path for the server")
 	rootCmd.Flags().String("server-type", "stdio", "Server type (http or stdio)")
 	rootCmd.Flags().Bool("debug", false, "Enable debug mode")
-	rootCmd.Flags().StringSlice("tool-names", []string{}, "List of tool names to register (empty means all tools)")
+	rootCmd.Flags().StringSlice("tool-names", []string{}, "Tool names or glob patterns such as show-* to register (empty with no tool groups means all tools)")
+	rootCmd.Flags().StringSlice("tool-groups", []string{}, "Groups of tools to register: "+strings.Join(jujuadapter.ToolGroupNames(), ", "))
+	rootCmd.Flags().String("tool-prefix", "", "Prefix added to every tool name, such as juju_ or juju. (resource URIs are unchanged)")
+	rootCmd.Flags().Bool("meta-tools", false, "Register only tools to search, describe and run the selected tools instead of every tool")
+	rootCmd.Flags().StringSlice("exclude-tools", []string{}, "Tool names or glob patterns to leave out (e.g. destroy-*,kill-*)")
+	rootCmd.Flags().String("prompts-dir", "", "Directory of YAML prompt templates added to, or replacing, the built-in prompts")
+	rootCmd.Flags().String("workspace-dir", "", "Directory files are uploaded to and downloaded from; local paths given to commands are confined to it")
+	rootCmd.Flags().String("artifacts-dir", "", "Directory produced files such as backups are written to when no path is given (default: a temporary directory)")
+	rootCmd.Flags().Duration("artifact-retention", jujuadapter.DefaultArtifactRetention, "How long produced files stay available as juju://artifacts resources")
+	rootCmd.Flags().Int64("artifact-max-size", jujuadapter.DefaultArtifactMaxSize, "Largest produced file, in bytes, served as a juju://artifacts resource")
+	rootCmd.Flags().String("status-snapshots-dir", "", "Directory status-diff snapshots are kept in (default: in memory)")
+	rootCmd.Flags().Duration("status-snapshot-interval", 0, "How often to snapshot the status of models with a snapshot (default: on demand only)")
+	rootCmd.Flags().String("bind-address", "", "Address to bind the http server to (empty means all interfaces)")
+	rootCmd.Flags().String("tls-cert-file", "", "TLS certificate file for the http server")
+	rootCmd.Flags().String("tls-key-file", "", "TLS private key file for the http server")
+	rootCmd.Flags().String("tls-client-ca-file", "", "CA bundle used to verify client certificates (enables mutual TLS)")
+	rootCmd.Flags().StringSlice("rate-limits", []string{}, "Token bucket rate limits as scope:key=count/period, scope is identity, tool or controller (e.g. identity:*=60/1m,tool:status=10/1m)")
+	rootCmd.Flags().StringSlice("concurrency-limits", config.DefaultConcurrencyLimits, "Maximum concurrent calls per tool as tool=max")
+	rootCmd.Flags().StringSlice("approval-tools", []string{}, "Tools (glob patterns allowed) that require approval from a second person before running")
+	rootCmd.Flags().String("approval-mode", config.ApprovalModeBlock, "How calls wait for approval: block until decided, or async to return a pending job")
+	rootCmd.Flags().Duration("approval-timeout", 15*time.Minute, "How long an approval request stays pending before it expires")
+	rootCmd.Flags().String("approval-webhook-url", "", "URL notified with a JSON POST when an approval request is created")
+	rootCmd.Flags().String("approvals-address", config.DefaultApprovalsAddress, "Address the approvals API listens on")
+	rootCmd.Flags().StringSlice("approver-tokens", []string{}, "Approver tokens as name=token, presented as bearer tokens to the approvals API")
+	rootCmd.Flags().StringSlice("approver-subjects", []string{}, "Client certificate subjects allowed to approve with mutual TLS (e.g. CN=alice,O=ops)")
+	rootCmd.Flags().String("audit-log", "", "File the audit trail is appended to as JSON lines")
 }
 
 var rootCmd = &cobra.Command{
@@ -30,11 +56,22 @@ var rootCmd = &cobra.Command{
 
 func run(cmd *cobra.Command, args []string) error {
 
-	adapter, err := jujuadapter.NewAdapter(cfg.ToolNames)
+	adapter, err := jujuadapter.NewAdapter(jujuadapter.NewToolSelection(cfg), cfg.ToolPrefix,
+		jujuadapter.WithWorkspace(cfg.WorkspaceDir),
+		jujuadapter.WithArtifacts(cfg.ArtifactsDir, cfg.ArtifactRetention, cfg.ArtifactMaxSize),
+		jujuadapter.WithStatusSnapshots(cfg.StatusSnapshotsDir, cfg.StatusSnapshotInterval),
+	)
 	if err != nil {
 		return err
 	}
-	app, err := application.NewApplication(cfg, adapter)
+	reloader := application.Reloader{
+		File: source.path,
+		Load: func() (config.Config, error) {
+			loaded, _, err := loadConfig(viper.New(), cmd)
+			return loaded, err
+		},
+	}
+	app, err := application.NewApplication(cfg, adapter, application.WithReloader(reloader))
 	if err != nil {
 		return err
 	}
@@ -45,17 +82,11 @@ func run(cmd *cobra.Command, args []string) error {
 }
 
 func persistentPreRun(cmd *cobra.Command, args []string) error {
-	viper.AutomaticEnv()
-	viper.SetEnvPrefix(config.EnvPrefix)
-	if err := viper.BindPFlags(cmd.Flags()); err != nil {
-		return fmt.Errorf("unable to bind flags: %w", err)
-	}
-	if err := viper.Unmarshal(&cfg); err != nil {
-		return fmt.Errorf("unable to decode config")
-	}
-	if err := cfg.Validate(); err != nil {
-		return fmt.Errorf("config validation failed: %w", err)
+	loaded, src, err := loadConfig(viper.GetViper(), cmd)
+	if err != nil {
+		return err
 	}
+	cfg, source = loaded, src
 	return nil
 }
 
@@ -1,7 +1,9 @@
 package cmd
 
 import (
+	"bytes"
 	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/jneo8/mcp-juju/config"
@@ -121,3 +123,97 @@ func TestConfigUnmarshal(t *testing.T) {
 		assert.Equal(t, true, testConfig.Debug)
 	})
 }
+
+func newConfigTestCmd() *cobra.Command {
+	testCmd := &cobra.Command{Use: "test"}
+	testCmd.Flags().String("port", "8080", "Port to server on")
+	testCmd.Flags().String("server-type", "stdio", "Server type (http or stdio)")
+	testCmd.Flags().StringSlice("rate-limits", []string{}, "Rate limits")
+	testCmd.Flags().StringSlice("exclude-tools", []string{}, "Tools to leave out")
+	testCmd.Flags().String("config", "", "Configuration file")
+	testCmd.Flags().String("profile", "", "Profile")
+	return testCmd
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(`port: 9000
+profiles:
+  prod-readonly:
+    tool-names: [status]
+    rate-limits: ["tool:status"]
+  staging-operator:
+    server-type: http
+    port: 9100
+`), 0o600))
+
+	t.Run("should apply the selected profile", func(t *testing.T) {
+		testCmd := newConfigTestCmd()
+		require.NoError(t, testCmd.ParseFlags([]string{"--config", path, "--profile", "staging-operator"}))
+
+		loaded, src, err := loadConfig(viper.New(), testCmd)
+
+		require.NoError(t, err)
+		assert.Equal(t, 9100, loaded.Port)
+		assert.Equal(t, config.ServerTypeHTTP, loaded.ServerType)
+		assert.Equal(t, configSource{path: path, profile: "staging-operator"}, src)
+	})
+
+	t.Run("should let env and flags override the file", func(t *testing.T) {
+		t.Setenv("MCP_JUJU_CONFIG", path)
+		t.Setenv("MCP_JUJU_SERVER_TYPE", "http")
+		testCmd := newConfigTestCmd()
+		require.NoError(t, testCmd.ParseFlags([]string{"--port", "7000"}))
+
+		loaded, _, err := loadConfig(viper.New(), testCmd)
+
+		require.NoError(t, err)
+		assert.Equal(t, 7000, loaded.Port)
+		assert.Equal(t, config.ServerTypeHTTP, loaded.ServerType)
+	})
+
+	t.Run("should report where an invalid value was set", func(t *testing.T) {
+		testCmd := newConfigTestCmd()
+		require.NoError(t, testCmd.ParseFlags([]string{"--config", path, "--profile", "prod-readonly", "--server-type", "grpc"}))
+
+		_, _, err := loadConfig(viper.New(), testCmd)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), "flag --server-type: invalid server type")
+		assert.Contains(t, err.Error(), path+":5 (profiles.prod-readonly.rate-limits[0]): invalid rate limit")
+	})
+
+	t.Run("should reject unknown tools", func(t *testing.T) {
+		testCmd := newConfigTestCmd()
+		require.NoError(t, testCmd.ParseFlags([]string{"--config", path, "--exclude-tools", "destroy-*,kil-*"}))
+
+		_, _, err := loadConfig(viper.New(), testCmd)
+
+		require.Error(t, err)
+		assert.Contains(t, err.Error(), `flag --exclude-tools: tool pattern "kil-*" matches no tool`)
+	})
+
+	t.Run("should reject a profile without a config file", func(t *testing.T) {
+		t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+		testCmd := newConfigTestCmd()
+		require.NoError(t, testCmd.ParseFlags([]string{"--profile", "prod-readonly"}))
+
+		_, _, err := loadConfig(viper.New(), testCmd)
+
+		assert.EqualError(t, err, `profile "prod-readonly" requires a configuration file`)
+	})
+}
+
+func TestConfigPrint(t *testing.T) {
+	cfg = config.Config{Port: 9000, ServerType: config.ServerTypeHTTP}
+	source = configSource{path: "/etc/mcp-juju.yaml", profile: "prod"}
+	var out bytes.Buffer
+	configPrintCmd.SetOut(&out)
+	require.NoError(t, configPrintCmd.Flags().Set("format", "yaml"))
+
+	require.NoError(t, configPrintCmd.RunE(configPrintCmd, nil))
+
+	assert.Contains(t, out.String(), "# config file: /etc/mcp-juju.yaml\n# profile: prod\n")
+	assert.Contains(t, out.String(), "port: 9000\n")
+	assert.Contains(t, out.String(), "server-type: http\n")
+}
@@ -9,4 +9,19 @@ const (
 	// Server types
 	ServerTypeHTTP  = "http"
 	ServerTypeStdio = "stdio"
+
+	// Approval modes
+	ApprovalModeBlock = "block"
+	ApprovalModeAsync = "async"
+
+	DefaultApprovalsAddress = "127.0.0.1:8765"
 )
+
+// DefaultConcurrencyLimits caps the commands that hold controller or machine
+// resources for a long time.
+var DefaultConcurrencyLimits = []string{
+	"exec=4",
+	"ssh=2",
+	"debug-log=2",
+	"bootstrap=1",
+}
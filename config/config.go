@@ -1,22 +1,135 @@
 package config
 
 import (
+	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/mark3labs/mcp-go/server"
 )
 
+// toolPrefixPattern keeps prefixed tool names within the characters MCP
+// clients accept in tool names.
+var toolPrefixPattern = regexp.MustCompile(`^[A-Za-z0-9_.-]*$`)
+
+// hostnamePattern matches host names such as localhost or mcp.example.com.
+var hostnamePattern = regexp.MustCompile(`^[A-Za-z0-9]([A-Za-z0-9-]{0,62}[A-Za-z0-9])?(\.[A-Za-z0-9]([A-Za-z0-9-]{0,62}[A-Za-z0-9])?)*\.?$`)
+
 type Config struct {
-	Port       int
-	Debug      bool
-	EndPoint   string
-	ServerType string   `mapstructure:"server-type"`
-	ToolNames  []string `mapstructure:"tool-names"`
+	Port            int      `mapstructure:"port"`
+	Debug           bool     `mapstructure:"debug"`
+	EndPoint        string   `mapstructure:"endpoint"`
+	ServerType      string   `mapstructure:"server-type"`
+	ToolNames       []string `mapstructure:"tool-names"`
+	ToolGroups      []string `mapstructure:"tool-groups"`
+	ExcludeTools    []string `mapstructure:"exclude-tools"`
+	MetaTools       bool     `mapstructure:"meta-tools"`
+	ToolPrefix      string   `mapstructure:"tool-prefix"`
+	PromptsDir      string   `mapstructure:"prompts-dir"`
+	WorkspaceDir    string   `mapstructure:"workspace-dir"`
+	BindAddress     string   `mapstructure:"bind-address"`
+	TLSCertFile     string   `mapstructure:"tls-cert-file"`
+	TLSKeyFile      string   `mapstructure:"tls-key-file"`
+	TLSClientCAFile string   `mapstructure:"tls-client-ca-file"`
+
+	RateLimits        []string `mapstructure:"rate-limits"`
+	ConcurrencyLimits []string `mapstructure:"concurrency-limits"`
+
+	ApprovalTools      []string      `mapstructure:"approval-tools"`
+	ApprovalMode       string        `mapstructure:"approval-mode"`
+	ApprovalTimeout    time.Duration `mapstructure:"approval-timeout"`
+	ApprovalWebhookURL string        `mapstructure:"approval-webhook-url"`
+	ApprovalsAddress   string        `mapstructure:"approvals-address"`
+	ApproverTokens     []string      `mapstructure:"approver-tokens"`
+	ApproverSubjects   []string      `mapstructure:"approver-subjects"`
+	AuditLog           string        `mapstructure:"audit-log"`
+
+	ArtifactsDir      string        `mapstructure:"artifacts-dir"`
+	ArtifactRetention time.Duration `mapstructure:"artifact-retention"`
+	ArtifactMaxSize   int64         `mapstructure:"artifact-max-size"`
+
+	StatusSnapshotsDir     string        `mapstructure:"status-snapshots-dir"`
+	StatusSnapshotInterval time.Duration `mapstructure:"status-snapshot-interval"`
 }
 
 func (c *Config) URL() string {
-	return fmt.Sprintf("http://localhost:%d%s", c.Port, c.EndPoint)
+	scheme := "http"
+	if c.IsTLSEnabled() {
+		scheme = "https"
+	}
+	host := "localhost"
+	if c.BindAddress != "" && !net.ParseIP(c.BindAddress).IsUnspecified() {
+		host = c.BindAddress
+	}
+	return fmt.Sprintf("%s://%s%s", scheme, net.JoinHostPort(host, strconv.Itoa(c.Port)), c.EndPoint)
+}
+
+// Addr returns the address the HTTP server listens on. An empty bind
+// address listens on all interfaces.
+func (c *Config) Addr() string {
+	return net.JoinHostPort(c.BindAddress, strconv.Itoa(c.Port))
+}
+
+// IsApprovalEnabled reports whether any tool requires a second person to
+// approve it.
+func (c *Config) IsApprovalEnabled() bool {
+	return len(c.ApprovalTools) > 0
+}
+
+// ApprovalsURL returns the base URL of the approval API.
+func (c *Config) ApprovalsURL() string {
+	scheme := "http"
+	if c.IsTLSEnabled() {
+		scheme = "https"
+	}
+	return fmt.Sprintf("%s://%s/approvals", scheme, c.ApprovalsAddress)
+}
+
+// ApproverTokenNames maps each approver token to the name of its approver.
+func (c *Config) ApproverTokenNames() (map[string]string, error) {
+	names := make(map[string]string, len(c.ApproverTokens))
+	for _, s := range c.ApproverTokens {
+		name, token, err := parseApproverToken(s)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := names[token]; ok {
+			return nil, fmt.Errorf("invalid approver token for %s: the token is already used", name)
+		}
+		names[token] = name
+	}
+	return names, nil
+}
+
+// IsApproverSubject reports whether a verified client certificate subject,
+// such as "CN=alice,O=ops", may decide approval requests. Other certificates
+// signed by the client CA, such as those of the assistants whose calls are
+// approved, may not.
+func (c *Config) IsApproverSubject(subject string) bool {
+	return c.IsMutualTLSEnabled() && slices.Contains(c.ApproverSubjects, subject)
+}
+
+// parseApproverToken parses an approver token written as name=token.
+func parseApproverToken(s string) (string, string, error) {
+	name, token, ok := strings.Cut(s, "=")
+	if !ok || name == "" || token == "" {
+		return "", "", errors.New("invalid approver token: expected name=token")
+	}
+	// These are the identities of callers without a client certificate.
+	if name == "local" || name == "anonymous" {
+		return "", "", fmt.Errorf("invalid approver token: %q cannot be an approver name", name)
+	}
+	return name, token, nil
 }
 
 func (c *Config) StreamableHTTPOptions() []server.StreamableHTTPOption {
@@ -37,9 +150,129 @@ func (c *Config) IsStdioServer() bool {
 	return c.ServerType == ServerTypeStdio
 }
 
+// IsTLSEnabled reports whether the HTTP server should serve HTTPS.
+func (c *Config) IsTLSEnabled() bool {
+	return c.TLSCertFile != "" && c.TLSKeyFile != ""
+}
+
+// IsMutualTLSEnabled reports whether clients must present a certificate
+// signed by the configured CA bundle.
+func (c *Config) IsMutualTLSEnabled() bool {
+	return c.IsTLSEnabled() && c.TLSClientCAFile != ""
+}
+
+// TLSConfig builds the server TLS configuration. The server certificate
+// itself is loaded by http.Server.ListenAndServeTLS.
+func (c *Config) TLSConfig() (*tls.Config, error) {
+	if !c.IsTLSEnabled() {
+		return nil, nil
+	}
+	tlsConfig := &tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	if c.IsMutualTLSEnabled() {
+		pem, err := os.ReadFile(c.TLSClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("unable to read client CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in client CA bundle %s", c.TLSClientCAFile)
+		}
+		tlsConfig.ClientCAs = pool
+		tlsConfig.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+	return tlsConfig, nil
+}
+
+// Validate checks every setting and returns ValidationErrors naming each
+// invalid one.
 func (c *Config) Validate() error {
+	var errs ValidationErrors
 	if c.ServerType != ServerTypeHTTP && c.ServerType != ServerTypeStdio {
-		return errors.New("invalid server type: must be 'http' or 'stdio'")
+		errs.add("server-type", errors.New("invalid server type: must be 'http' or 'stdio'"))
+	}
+	if c.TLSCertFile != "" && c.TLSKeyFile == "" {
+		errs.add("tls-cert-file", errors.New("tls-cert-file and tls-key-file must be set together"))
+	}
+	if c.TLSKeyFile != "" && c.TLSCertFile == "" {
+		errs.add("tls-key-file", errors.New("tls-cert-file and tls-key-file must be set together"))
+	}
+	if c.TLSClientCAFile != "" && !c.IsTLSEnabled() {
+		errs.add("tls-client-ca-file", errors.New("tls-client-ca-file requires tls-cert-file and tls-key-file"))
+	}
+	if !toolPrefixPattern.MatchString(c.ToolPrefix) {
+		errs.add("tool-prefix", fmt.Errorf("invalid tool prefix %q: only letters, digits, '_', '.' and '-' are allowed", c.ToolPrefix))
+	}
+	if c.ArtifactRetention < 0 {
+		errs.add("artifact-retention", errors.New("artifact-retention must not be negative"))
+	}
+	if c.ArtifactMaxSize < 0 {
+		errs.add("artifact-max-size", errors.New("artifact-max-size must not be negative"))
+	}
+	if c.StatusSnapshotInterval < 0 {
+		errs.add("status-snapshot-interval", errors.New("status-snapshot-interval must not be negative"))
+	}
+	// Only the syntax is checked, net.Listen reports addresses that cannot
+	// be bound.
+	if c.BindAddress != "" && net.ParseIP(c.BindAddress) == nil && !hostnamePattern.MatchString(c.BindAddress) {
+		errs.add("bind-address", fmt.Errorf("invalid bind address %q: must be an IP address or a host name", c.BindAddress))
+	}
+	for i, s := range c.RateLimits {
+		if _, err := ParseRateLimit(s); err != nil {
+			errs.add(fmt.Sprintf("rate-limits[%d]", i), err)
+		}
+	}
+	for i, s := range c.ConcurrencyLimits {
+		if _, _, err := parseConcurrencyLimit(s); err != nil {
+			errs.add(fmt.Sprintf("concurrency-limits[%d]", i), err)
+		}
+	}
+	if c.IsApprovalEnabled() {
+		if c.ApprovalMode != ApprovalModeBlock && c.ApprovalMode != ApprovalModeAsync {
+			errs.add("approval-mode", errors.New("invalid approval mode: must be 'block' or 'async'"))
+		}
+		if c.ApprovalTimeout <= 0 {
+			errs.add("approval-timeout", errors.New("approval-timeout must be positive"))
+		}
+		if _, _, err := net.SplitHostPort(c.ApprovalsAddress); err != nil {
+			errs.add("approvals-address", fmt.Errorf("invalid approvals address %q: %w", c.ApprovalsAddress, err))
+		}
+		// Approvers must be authenticated, either by a listed client
+		// certificate or by a token, or anyone could approve, including
+		// the requester.
+		if !(c.IsMutualTLSEnabled() && len(c.ApproverSubjects) > 0) && len(c.ApproverTokens) == 0 {
+			errs.add("approval-tools", errors.New("approval-tools requires authenticated approvers: set approver-subjects with tls-client-ca-file, or approver-tokens"))
+		}
+		for i, pattern := range c.ApprovalTools {
+			if _, err := path.Match(pattern, ""); err != nil {
+				errs.add(fmt.Sprintf("approval-tools[%d]", i), fmt.Errorf("invalid approval tool pattern %q: %w", pattern, err))
+			}
+		}
+	}
+	tokens := make(map[string]bool, len(c.ApproverTokens))
+	for i, s := range c.ApproverTokens {
+		name, token, err := parseApproverToken(s)
+		if err == nil && tokens[token] {
+			err = fmt.Errorf("invalid approver token for %s: the token is already used", name)
+		}
+		if err != nil {
+			errs.add(fmt.Sprintf("approver-tokens[%d]", i), err)
+		}
+		tokens[token] = true
+	}
+	if len(c.ApproverSubjects) > 0 && !c.IsMutualTLSEnabled() {
+		errs.add("approver-subjects", errors.New("approver-subjects requires tls-client-ca-file"))
+	}
+	for i, subject := range c.ApproverSubjects {
+		if subject == "" || subject == "local" || subject == "anonymous" {
+			errs.add(fmt.Sprintf("approver-subjects[%d]", i), fmt.Errorf("invalid approver subject %q", subject))
+		}
+	}
+	if c.ApprovalWebhookURL != "" {
+		if u, err := url.Parse(c.ApprovalWebhookURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs.add("approval-webhook-url", fmt.Errorf("invalid approval webhook url %q", c.ApprovalWebhookURL))
+		}
 	}
-	return nil
+	return errs.err()
 }
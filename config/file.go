@@ -0,0 +1,408 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/go-viper/mapstructure/v2"
+	"github.com/pelletier/go-toml/v2"
+	"github.com/pelletier/go-toml/v2/unstable"
+	"gopkg.in/yaml.v3"
+)
+
+// Keys of a configuration file that are not settings.
+const (
+	fileKeyProfile  = "profile"
+	fileKeyProfiles = "profiles"
+)
+
+// DefaultFileNames are looked up, in order, in the XDG config directory.
+var DefaultFileNames = []string{"config.yaml", "config.yml", "config.toml"}
+
+// DefaultFilePath returns the first existing configuration file in
+// $XDG_CONFIG_HOME/mcp-juju, or "" if there is none.
+func DefaultFilePath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	for _, name := range DefaultFileNames {
+		path := filepath.Join(dir, AppName, name)
+		if _, err := os.Stat(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// File is a parsed configuration file. Top level settings use the same names
+// as the command line flags and apply to every profile; the settings of the
+// selected profile override them.
+//
+//	port: 8080
+//	profile: prod-readonly
+//	profiles:
+//	  prod-readonly:
+//	    tool-names: [status, show-unit]
+//	  staging-operator:
+//	    approval-tools: ["destroy-*"]
+type File struct {
+	Path     string
+	Profile  string
+	Settings map[string]any
+	Profiles map[string]map[string]any
+
+	// lines maps key paths such as "profiles.prod.port" or "rate-limits[1]"
+	// to the line they are defined on.
+	lines map[string]int
+	// errs holds structural problems found while parsing, such as a profile
+	// that is not a table.
+	errs ValidationErrors
+}
+
+// LoadFile reads a YAML or TOML configuration file. The format is chosen by
+// the extension; anything but .toml is read as YAML.
+func LoadFile(path string) (*File, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	f := &File{Path: path, lines: make(map[string]int)}
+	var raw map[string]any
+	if strings.EqualFold(filepath.Ext(path), ".toml") {
+		raw, err = f.parseTOML(data)
+	} else {
+		raw, err = f.parseYAML(data)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("unable to parse %s: %w", path, err)
+	}
+	f.split(raw)
+	return f, nil
+}
+
+func (f *File) parseYAML(data []byte) (map[string]any, error) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	raw := map[string]any{}
+	if len(doc.Content) == 0 {
+		return raw, nil
+	}
+	if err := doc.Content[0].Decode(&raw); err != nil {
+		return nil, err
+	}
+	f.recordYAMLLines("", doc.Content[0])
+	return raw, nil
+}
+
+func (f *File) recordYAMLLines(prefix string, node *yaml.Node) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key := node.Content[i].Value
+			if prefix != "" {
+				key = prefix + "." + key
+			}
+			f.lines[key] = node.Content[i].Line
+			f.recordYAMLLines(key, node.Content[i+1])
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			key := fmt.Sprintf("%s[%d]", prefix, i)
+			f.lines[key] = item.Line
+			f.recordYAMLLines(key, item)
+		}
+	}
+}
+
+func (f *File) parseTOML(data []byte) (map[string]any, error) {
+	raw := map[string]any{}
+	if err := toml.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+
+	// go-toml does not keep positions when decoding, so walk the document
+	// again for the line of every key.
+	p := unstable.Parser{}
+	p.Reset(data)
+	var table string
+	for p.NextExpression() {
+		expr := p.Expression()
+		switch expr.Kind {
+		case unstable.Table, unstable.ArrayTable:
+			table = tomlKey(expr.Key())
+			if line := tomlLine(&p, expr.Key()); line > 0 {
+				f.lines[table] = line
+			}
+		case unstable.KeyValue:
+			key := tomlKey(expr.Key())
+			if table != "" {
+				key = table + "." + key
+			}
+			if line := tomlLine(&p, expr.Key()); line > 0 {
+				f.lines[key] = line
+			}
+		}
+	}
+	return raw, nil
+}
+
+func tomlKey(it unstable.Iterator) string {
+	var parts []string
+	for it.Next() {
+		parts = append(parts, string(it.Node().Data))
+	}
+	return strings.Join(parts, ".")
+}
+
+func tomlLine(p *unstable.Parser, it unstable.Iterator) int {
+	if !it.Next() {
+		return 0
+	}
+	return p.Shape(it.Node().Raw).Start.Line
+}
+
+// split separates the settings from the profile selection and the profiles.
+func (f *File) split(raw map[string]any) {
+	f.Settings = make(map[string]any)
+	f.Profiles = make(map[string]map[string]any)
+	for key, value := range raw {
+		switch key {
+		case fileKeyProfile:
+			profile, ok := value.(string)
+			if !ok {
+				f.errs = append(f.errs, f.fieldError("", key, errors.New("expected the name of a profile")))
+				continue
+			}
+			f.Profile = profile
+		case fileKeyProfiles:
+			profiles, ok := value.(map[string]any)
+			if !ok {
+				f.errs = append(f.errs, f.fieldError("", key, errors.New("expected a table of profiles")))
+				continue
+			}
+			for name, settings := range profiles {
+				m, ok := settings.(map[string]any)
+				if !ok {
+					f.errs = append(f.errs, &FieldError{
+						Field:    name,
+						Location: f.location(fileKeyProfiles + "." + name),
+						Err:      errors.New("expected a table of settings"),
+					})
+					continue
+				}
+				f.Profiles[name] = m
+			}
+		default:
+			f.Settings[key] = value
+		}
+	}
+}
+
+// ProfileNames returns the profiles defined in the file.
+func (f *File) ProfileNames() []string {
+	names := make([]string, 0, len(f.Profiles))
+	for name := range f.Profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Validate checks the file against the configuration schema: every key must
+// be a known setting and every value must have the setting's type. Semantic
+// checks are left to Config.Validate once all sources are merged.
+func (f *File) Validate() error {
+	errs := append(ValidationErrors{}, f.errs...)
+	fields := settingFields()
+	check := func(profile string, settings map[string]any) {
+		for _, key := range sortedSettingKeys(settings) {
+			fieldType, ok := fields[key]
+			if !ok {
+				errs = append(errs, f.fieldError(profile, key, errors.New("unknown setting")))
+				continue
+			}
+			if err := decodeSetting(key, settings[key]); err != nil {
+				errs = append(errs, f.fieldError(profile, key, fmt.Errorf("invalid value %v: expected %s", settings[key], describeType(fieldType))))
+			}
+		}
+	}
+	check("", f.Settings)
+	for _, name := range f.ProfileNames() {
+		check(name, f.Profiles[name])
+	}
+	if f.Profile != "" {
+		if _, ok := f.Profiles[f.Profile]; !ok {
+			errs = append(errs, f.fieldError("", fileKeyProfile, f.unknownProfile(f.Profile)))
+		}
+	}
+	return errs.err()
+}
+
+// Merged returns the top level settings overridden by those of the profile.
+// An empty profile selects the one named in the file, if any.
+func (f *File) Merged(profile string) (map[string]any, error) {
+	if profile == "" {
+		profile = f.Profile
+	}
+	merged := make(map[string]any, len(f.Settings))
+	for key, value := range f.Settings {
+		merged[key] = value
+	}
+	if profile == "" {
+		return merged, nil
+	}
+	settings, ok := f.Profiles[profile]
+	if !ok {
+		return nil, f.unknownProfile(profile)
+	}
+	for key, value := range settings {
+		merged[key] = value
+	}
+	return merged, nil
+}
+
+func (f *File) unknownProfile(profile string) error {
+	names := f.ProfileNames()
+	if len(names) == 0 {
+		return fmt.Errorf("unknown profile %q: %s defines no profiles", profile, f.Path)
+	}
+	return fmt.Errorf("unknown profile %q, available profiles: %s", profile, strings.Join(names, ", "))
+}
+
+// Location returns where the file sets a setting for the profile, such as
+// "config.yaml:12 (profiles.prod.port)", or "" if the file does not set it.
+// The field may carry a list index, as in FieldError.Field.
+func (f *File) Location(profile string, field string) string {
+	if profile == "" {
+		profile = f.Profile
+	}
+	key, _, _ := strings.Cut(field, "[")
+	if _, ok := f.Profiles[profile][key]; ok && profile != "" {
+		return f.location(fileKeyProfiles + "." + profile + "." + field)
+	}
+	if _, ok := f.Settings[key]; ok {
+		return f.location(field)
+	}
+	return ""
+}
+
+func (f *File) location(keyPath string) string {
+	line, ok := f.lines[keyPath]
+	if !ok {
+		// Fall back to the line of the list for list items without
+		// positions, as in TOML arrays.
+		key, _, _ := strings.Cut(keyPath, "[")
+		line, ok = f.lines[key]
+	}
+	if ok {
+		return fmt.Sprintf("%s:%d (%s)", f.Path, line, keyPath)
+	}
+	return fmt.Sprintf("%s (%s)", f.Path, keyPath)
+}
+
+func (f *File) fieldError(profile string, key string, err error) *FieldError {
+	keyPath := key
+	if profile != "" {
+		keyPath = fileKeyProfiles + "." + profile + "." + key
+	}
+	return &FieldError{Field: key, Location: f.location(keyPath), Err: err}
+}
+
+// settingFields returns the type of every setting, keyed by its name.
+func settingFields() map[string]reflect.Type {
+	fields := make(map[string]reflect.Type)
+	t := reflect.TypeOf(Config{})
+	for i := 0; i < t.NumField(); i++ {
+		fields[settingName(t.Field(i))] = t.Field(i).Type
+	}
+	return fields
+}
+
+func settingName(field reflect.StructField) string {
+	if name := field.Tag.Get("mapstructure"); name != "" {
+		return name
+	}
+	return strings.ToLower(field.Name)
+}
+
+func sortedSettingKeys(settings map[string]any) []string {
+	keys := make([]string, 0, len(settings))
+	for key := range settings {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// decodeSetting decodes a single value the way viper does when it unmarshals
+// the merged configuration.
+func decodeSetting(key string, value any) error {
+	var c Config
+	decoder, err := mapstructure.NewDecoder(&mapstructure.DecoderConfig{
+		Result:           &c,
+		WeaklyTypedInput: true,
+		DecodeHook: mapstructure.ComposeDecodeHookFunc(
+			mapstructure.StringToTimeDurationHookFunc(),
+			mapstructure.StringToSliceHookFunc(","),
+		),
+	})
+	if err != nil {
+		return err
+	}
+	return decoder.Decode(map[string]any{key: value})
+}
+
+func describeType(t reflect.Type) string {
+	if t == reflect.TypeOf(time.Duration(0)) {
+		return "a duration such as 30s or 15m"
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return "true or false"
+	case reflect.Int:
+		return "an integer"
+	case reflect.String:
+		return "a string"
+	case reflect.Slice:
+		return "a list of strings"
+	default:
+		return t.String()
+	}
+}
+
+// Settings returns the configuration keyed by setting name, in a form that
+// marshals back into a configuration file.
+func (c *Config) Settings() map[string]any {
+	settings := make(map[string]any)
+	v := reflect.ValueOf(*c)
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		value := v.Field(i).Interface()
+		switch x := value.(type) {
+		case time.Duration:
+			value = x.String()
+		case []string:
+			if x == nil {
+				value = []string{}
+			}
+		}
+		settings[settingName(t.Field(i))] = value
+	}
+	// Only the names of the approvers are shown, never their tokens.
+	redacted := make([]string, len(c.ApproverTokens))
+	for i, s := range c.ApproverTokens {
+		name, _, _ := strings.Cut(s, "=")
+		redacted[i] = name + "=********"
+	}
+	settings["approver-tokens"] = redacted
+	return settings
+}
@@ -0,0 +1,201 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const testYAML = `port: 9000
+tool-names: [status]
+profile: prod-readonly
+profiles:
+  prod-readonly:
+    tool-names: [status, show-unit]
+    rate-limits:
+      - identity:*=60/1m
+  staging-operator:
+    approval-tools: ["destroy-*"]
+    approval-timeout: 5m
+`
+
+func writeFile(t *testing.T, name string, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	require.NoError(t, os.WriteFile(path, []byte(content), 0o600))
+	return path
+}
+
+func TestLoadFile_YAMLProfiles(t *testing.T) {
+	// Arrange
+	path := writeFile(t, "config.yaml", testYAML)
+
+	// Act
+	f, err := LoadFile(path)
+	require.NoError(t, err)
+	defaults, err := f.Merged("")
+	require.NoError(t, err)
+	staging, err := f.Merged("staging-operator")
+	require.NoError(t, err)
+
+	// Assert
+	require.NoError(t, f.Validate())
+	assert.Equal(t, []string{"prod-readonly", "staging-operator"}, f.ProfileNames())
+	assert.Equal(t, "prod-readonly", f.Profile)
+	assert.Equal(t, []any{"status", "show-unit"}, defaults["tool-names"])
+	assert.Equal(t, 9000, defaults["port"])
+	assert.Equal(t, []any{"status"}, staging["tool-names"])
+	assert.Equal(t, "5m", staging["approval-timeout"])
+	assert.Equal(t, path+":8 (profiles.prod-readonly.rate-limits[0])", f.Location("", "rate-limits[0]"))
+	assert.Equal(t, path+":1 (port)", f.Location("staging-operator", "port"))
+	assert.Empty(t, f.Location("", "debug"))
+}
+
+func TestLoadFile_UnknownProfile(t *testing.T) {
+	f, err := LoadFile(writeFile(t, "config.yaml", testYAML))
+	require.NoError(t, err)
+
+	_, err = f.Merged("prod-operator")
+
+	assert.EqualError(t, err, `unknown profile "prod-operator", available profiles: prod-readonly, staging-operator`)
+}
+
+func TestFileValidate_Locations(t *testing.T) {
+	// Arrange
+	path := writeFile(t, "config.yaml", `port: eighty
+profiles:
+  prod:
+    tool-name: [status]
+    approval-timeout: soon
+`)
+	f, err := LoadFile(path)
+	require.NoError(t, err)
+
+	// Act
+	err = f.Validate()
+
+	// Assert
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 3)
+	assert.Equal(t, path+":1 (port): invalid value eighty: expected an integer", errs[0].Error())
+	assert.Equal(t, path+":5 (profiles.prod.approval-timeout): invalid value soon: expected a duration such as 30s or 15m", errs[1].Error())
+	assert.Equal(t, path+":4 (profiles.prod.tool-name): unknown setting", errs[2].Error())
+}
+
+func TestLoadFile_TOML(t *testing.T) {
+	// Arrange
+	path := writeFile(t, "config.toml", `port = 9000
+
+[profiles.prod]
+server-type = "http"
+rate-limits = ["tool:status"]
+`)
+
+	// Act
+	f, err := LoadFile(path)
+	require.NoError(t, err)
+	merged, err := f.Merged("prod")
+	require.NoError(t, err)
+
+	// Assert
+	require.NoError(t, f.Validate())
+	assert.Equal(t, "http", merged["server-type"])
+	assert.Equal(t, path+":4 (profiles.prod.server-type)", f.Location("prod", "server-type"))
+	assert.Equal(t, path+":5 (profiles.prod.rate-limits[0])", f.Location("prod", "rate-limits[0]"))
+}
+
+func TestConfigValidate_ReportsEveryField(t *testing.T) {
+	c := Config{
+		ServerType: "grpc",
+		RateLimits: []string{"identity:*=60/1m", "tool:status"},
+		TLSKeyFile: "server.key",
+		ToolPrefix: "juju/",
+	}
+
+	err := c.Validate()
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	fields := make([]string, len(errs))
+	for i, e := range errs {
+		fields[i] = e.Field
+	}
+	assert.Equal(t, []string{"server-type", "tls-key-file", "tool-prefix", "rate-limits[1]"}, fields)
+	assert.Equal(t, "rate-limits", errs[3].Key())
+}
+
+func TestConfigValidate_BindAddress(t *testing.T) {
+	for _, address := range []string{"0.0.0.0", "::1", "localhost", "mcp.unresolvable.invalid"} {
+		c := Config{ServerType: "http", BindAddress: address}
+		assert.NoError(t, c.Validate(), address)
+	}
+
+	c := Config{ServerType: "http", BindAddress: "not a host"}
+	err := c.Validate()
+
+	var errs ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	assert.Equal(t, "bind-address", errs[0].Field)
+}
+
+func TestConfigValidate_ApproversAuthenticated(t *testing.T) {
+	approvals := Config{
+		ServerType:       "stdio",
+		ApprovalTools:    []string{"destroy-*"},
+		ApprovalMode:     ApprovalModeBlock,
+		ApprovalTimeout:  time.Minute,
+		ApprovalsAddress: DefaultApprovalsAddress,
+	}
+	unauthenticated := approvals
+	tokens := approvals
+	tokens.ApproverTokens = []string{"alice=s3cret", "local=other", "bob=s3cret"}
+
+	var errs ValidationErrors
+	require.True(t, errors.As(unauthenticated.Validate(), &errs))
+	assert.Equal(t, "approval-tools", errs[0].Field)
+
+	require.True(t, errors.As(tokens.Validate(), &errs))
+	fields := make([]string, len(errs))
+	for i, e := range errs {
+		fields[i] = e.Field
+	}
+	assert.Equal(t, []string{"approver-tokens[1]", "approver-tokens[2]"}, fields)
+
+	tokens.ApproverTokens = tokens.ApproverTokens[:1]
+	assert.NoError(t, tokens.Validate())
+	assert.Equal(t, []string{"alice=********"}, tokens.Settings()["approver-tokens"])
+
+	mutualTLS := approvals
+	mutualTLS.TLSCertFile, mutualTLS.TLSKeyFile, mutualTLS.TLSClientCAFile = "server.crt", "server.key", "ca.pem"
+	require.True(t, errors.As(mutualTLS.Validate(), &errs), "any certificate signed by the CA is not enough")
+	assert.Equal(t, "approval-tools", errs[0].Field)
+	mutualTLS.ApproverSubjects = []string{"CN=alice,O=ops"}
+	assert.NoError(t, mutualTLS.Validate())
+	assert.True(t, mutualTLS.IsApproverSubject("CN=alice,O=ops"))
+	assert.False(t, mutualTLS.IsApproverSubject("CN=assistant,O=ops"))
+
+	subjects := approvals
+	subjects.ApproverSubjects = []string{"CN=alice,O=ops"}
+	require.True(t, errors.As(subjects.Validate(), &errs))
+	fields = fields[:0]
+	for _, e := range errs {
+		fields = append(fields, e.Field)
+	}
+	assert.Equal(t, []string{"approval-tools", "approver-subjects"}, fields)
+}
+
+func TestConfigSettings(t *testing.T) {
+	c := Config{Port: 8080, ApprovalTimeout: 15 * time.Minute}
+
+	settings := c.Settings()
+
+	assert.Equal(t, 8080, settings["port"])
+	assert.Equal(t, "15m0s", settings["approval-timeout"])
+	assert.Equal(t, []string{}, settings["tool-names"])
+	assert.Contains(t, settings, "endpoint")
+}
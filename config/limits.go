@@ -0,0 +1,118 @@
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Rate limit scopes
+const (
+	RateLimitScopeIdentity   = "identity"
+	RateLimitScopeTool       = "tool"
+	RateLimitScopeController = "controller"
+
+	// RateLimitAnyKey applies a rule to every identity, tool or controller,
+	// each one getting its own bucket.
+	RateLimitAnyKey = "*"
+)
+
+// RateLimit is a token bucket rule. Count calls are allowed per Period and
+// the bucket holds at most Count tokens.
+type RateLimit struct {
+	Scope  string
+	Key    string
+	Count  int
+	Period time.Duration
+}
+
+func (r RateLimit) String() string {
+	return fmt.Sprintf("%s:%s=%d/%s", r.Scope, r.Key, r.Count, r.Period)
+}
+
+// ParseRateLimit parses a rule of the form "scope:key=count/period", for
+// example "identity:*=60/1m" or "tool:status=10/30s".
+func ParseRateLimit(s string) (RateLimit, error) {
+	target, limit, ok := strings.Cut(s, "=")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected scope:key=count/period", s)
+	}
+	scope, key, ok := strings.Cut(target, ":")
+	if !ok || key == "" {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected scope:key=count/period", s)
+	}
+	switch scope {
+	case RateLimitScopeIdentity, RateLimitScopeTool, RateLimitScopeController:
+	default:
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: unknown scope %q", s, scope)
+	}
+	countStr, periodStr, ok := strings.Cut(limit, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: expected count/period", s)
+	}
+	count, err := strconv.Atoi(countStr)
+	if err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: count must be a positive integer", s)
+	}
+	period, err := parsePeriod(periodStr)
+	if err != nil {
+		return RateLimit{}, fmt.Errorf("invalid rate limit %q: %w", s, err)
+	}
+	return RateLimit{Scope: scope, Key: key, Count: count, Period: period}, nil
+}
+
+// parsePeriod accepts Go durations as well as a bare unit such as "s" or "m".
+func parsePeriod(s string) (time.Duration, error) {
+	switch s {
+	case "s", "m", "h":
+		s = "1" + s
+	}
+	period, err := time.ParseDuration(s)
+	if err != nil {
+		return 0, err
+	}
+	if period <= 0 {
+		return 0, fmt.Errorf("period must be positive")
+	}
+	return period, nil
+}
+
+// RateLimitRules returns the parsed rate limit rules.
+func (c *Config) RateLimitRules() ([]RateLimit, error) {
+	rules := make([]RateLimit, 0, len(c.RateLimits))
+	for _, s := range c.RateLimits {
+		rule, err := ParseRateLimit(s)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// ConcurrencyCaps returns the maximum number of in-flight calls per tool,
+// parsed from entries of the form "tool=max".
+func (c *Config) ConcurrencyCaps() (map[string]int, error) {
+	caps := make(map[string]int, len(c.ConcurrencyLimits))
+	for _, s := range c.ConcurrencyLimits {
+		tool, max, err := parseConcurrencyLimit(s)
+		if err != nil {
+			return nil, err
+		}
+		caps[tool] = max
+	}
+	return caps, nil
+}
+
+func parseConcurrencyLimit(s string) (string, int, error) {
+	tool, maxStr, ok := strings.Cut(s, "=")
+	if !ok || tool == "" {
+		return "", 0, fmt.Errorf("invalid concurrency limit %q: expected tool=max", s)
+	}
+	max, err := strconv.Atoi(maxStr)
+	if err != nil || max <= 0 {
+		return "", 0, fmt.Errorf("invalid concurrency limit %q: max must be a positive integer", s)
+	}
+	return tool, max, nil
+}
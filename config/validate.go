@@ -0,0 +1,57 @@
+package config
+
+import (
+	"strings"
+)
+
+// FieldError is a validation failure of a single setting. Field is the
+// setting name, with an index for list settings such as "rate-limits[1]".
+// Location tells where the value came from, for example
+// "config.yaml:12 (profiles.prod.port)" or "flag --port", once the loader has
+// worked it out.
+type FieldError struct {
+	Field    string
+	Location string
+	Err      error
+}
+
+func (e *FieldError) Error() string {
+	if e.Location != "" {
+		return e.Location + ": " + e.Err.Error()
+	}
+	return e.Field + ": " + e.Err.Error()
+}
+
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// Key returns the setting name without the list index.
+func (e *FieldError) Key() string {
+	key, _, _ := strings.Cut(e.Field, "[")
+	return key
+}
+
+// ValidationErrors collects every invalid setting rather than stopping at the
+// first one.
+type ValidationErrors []*FieldError
+
+func (e ValidationErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e *ValidationErrors) add(field string, err error) {
+	*e = append(*e, &FieldError{Field: field, Err: err})
+}
+
+// err returns nil when there are no errors, avoiding a typed nil error.
+func (e ValidationErrors) err() error {
+	if len(e) == 0 {
+		return nil
+	}
+	return e
+}
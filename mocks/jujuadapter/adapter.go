@@ -5,6 +5,9 @@
 package mockjujuadapter
 
 import (
+	"context"
+
+	"github.com/jneo8/mcp-juju/pkg/jujuadapter"
 	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	mock "github.com/stretchr/testify/mock"
@@ -37,6 +40,309 @@ func (_m *MockAdapter) EXPECT() *MockAdapter_Expecter {
 	return &MockAdapter_Expecter{mock: &_m.Mock}
 }
 
+// AnnotateBlockedTools provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) AnnotateBlockedTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	ret := _mock.Called(ctx, tools)
+
+	if len(ret) == 0 {
+		panic("no return value specified for AnnotateBlockedTools")
+	}
+
+	var r0 []mcp.Tool
+	if returnFunc, ok := ret.Get(0).(func(context.Context, []mcp.Tool) []mcp.Tool); ok {
+		r0 = returnFunc(ctx, tools)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]mcp.Tool)
+		}
+	}
+	return r0
+}
+
+// MockAdapter_AnnotateBlockedTools_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'AnnotateBlockedTools'
+type MockAdapter_AnnotateBlockedTools_Call struct {
+	*mock.Call
+}
+
+// AnnotateBlockedTools is a helper method to define mock.On call
+//   - ctx context.Context
+//   - tools []mcp.Tool
+func (_e *MockAdapter_Expecter) AnnotateBlockedTools(ctx interface{}, tools interface{}) *MockAdapter_AnnotateBlockedTools_Call {
+	return &MockAdapter_AnnotateBlockedTools_Call{Call: _e.mock.On("AnnotateBlockedTools", ctx, tools)}
+}
+
+func (_c *MockAdapter_AnnotateBlockedTools_Call) Run(run func(ctx context.Context, tools []mcp.Tool)) *MockAdapter_AnnotateBlockedTools_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		var arg1 []mcp.Tool
+		if args[1] != nil {
+			arg1 = args[1].([]mcp.Tool)
+		}
+		run(
+			arg0,
+			arg1,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAdapter_AnnotateBlockedTools_Call) Return(tools []mcp.Tool) *MockAdapter_AnnotateBlockedTools_Call {
+	_c.Call.Return(tools)
+	return _c
+}
+
+func (_c *MockAdapter_AnnotateBlockedTools_Call) RunAndReturn(run func(ctx context.Context, tools []mcp.Tool) []mcp.Tool) *MockAdapter_AnnotateBlockedTools_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CommandName provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) CommandName(toolName string) string {
+	ret := _mock.Called(toolName)
+
+	if len(ret) == 0 {
+		panic("no return value specified for CommandName")
+	}
+
+	var r0 string
+	if returnFunc, ok := ret.Get(0).(func(string) string); ok {
+		r0 = returnFunc(toolName)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	return r0
+}
+
+// MockAdapter_CommandName_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CommandName'
+type MockAdapter_CommandName_Call struct {
+	*mock.Call
+}
+
+// CommandName is a helper method to define mock.On call
+//   - toolName string
+func (_e *MockAdapter_Expecter) CommandName(toolName interface{}) *MockAdapter_CommandName_Call {
+	return &MockAdapter_CommandName_Call{Call: _e.mock.On("CommandName", toolName)}
+}
+
+func (_c *MockAdapter_CommandName_Call) Run(run func(toolName string)) *MockAdapter_CommandName_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAdapter_CommandName_Call) Return(s string) *MockAdapter_CommandName_Call {
+	_c.Call.Return(s)
+	return _c
+}
+
+func (_c *MockAdapter_CommandName_Call) RunAndReturn(run func(toolName string) string) *MockAdapter_CommandName_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// CurrentController provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) CurrentController() (string, error) {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for CurrentController")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func() (string, error)); ok {
+		return returnFunc()
+	}
+	if returnFunc, ok := ret.Get(0).(func() string); ok {
+		r0 = returnFunc()
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func() error); ok {
+		r1 = returnFunc()
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAdapter_CurrentController_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'CurrentController'
+type MockAdapter_CurrentController_Call struct {
+	*mock.Call
+}
+
+// CurrentController is a helper method to define mock.On call
+func (_e *MockAdapter_Expecter) CurrentController() *MockAdapter_CurrentController_Call {
+	return &MockAdapter_CurrentController_Call{Call: _e.mock.On("CurrentController")}
+}
+
+func (_c *MockAdapter_CurrentController_Call) Run(run func()) *MockAdapter_CurrentController_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockAdapter_CurrentController_Call) Return(s string, err error) *MockAdapter_CurrentController_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockAdapter_CurrentController_Call) RunAndReturn(run func() (string, error)) *MockAdapter_CurrentController_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetResource provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) GetResource(name string) (*mcp.Resource, server.ResourceHandlerFunc, error) {
+	ret := _mock.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetResource")
+	}
+
+	var r0 *mcp.Resource
+	var r1 server.ResourceHandlerFunc
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(string) (*mcp.Resource, server.ResourceHandlerFunc, error)); ok {
+		return returnFunc(name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) *mcp.Resource); ok {
+		r0 = returnFunc(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mcp.Resource)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) server.ResourceHandlerFunc); ok {
+		r1 = returnFunc(name)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(server.ResourceHandlerFunc)
+		}
+	}
+	if returnFunc, ok := ret.Get(2).(func(string) error); ok {
+		r2 = returnFunc(name)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockAdapter_GetResource_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetResource'
+type MockAdapter_GetResource_Call struct {
+	*mock.Call
+}
+
+// GetResource is a helper method to define mock.On call
+//   - name string
+func (_e *MockAdapter_Expecter) GetResource(name interface{}) *MockAdapter_GetResource_Call {
+	return &MockAdapter_GetResource_Call{Call: _e.mock.On("GetResource", name)}
+}
+
+func (_c *MockAdapter_GetResource_Call) Run(run func(name string)) *MockAdapter_GetResource_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAdapter_GetResource_Call) Return(resource *mcp.Resource, resourceHandlerFunc server.ResourceHandlerFunc, err error) *MockAdapter_GetResource_Call {
+	_c.Call.Return(resource, resourceHandlerFunc, err)
+	return _c
+}
+
+func (_c *MockAdapter_GetResource_Call) RunAndReturn(run func(name string) (*mcp.Resource, server.ResourceHandlerFunc, error)) *MockAdapter_GetResource_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// GetResourceTemplate provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) GetResourceTemplate(name string) (*mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc, error) {
+	ret := _mock.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetResourceTemplate")
+	}
+
+	var r0 *mcp.ResourceTemplate
+	var r1 server.ResourceTemplateHandlerFunc
+	var r2 error
+	if returnFunc, ok := ret.Get(0).(func(string) (*mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc, error)); ok {
+		return returnFunc(name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) *mcp.ResourceTemplate); ok {
+		r0 = returnFunc(name)
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).(*mcp.ResourceTemplate)
+		}
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) server.ResourceTemplateHandlerFunc); ok {
+		r1 = returnFunc(name)
+	} else {
+		if ret.Get(1) != nil {
+			r1 = ret.Get(1).(server.ResourceTemplateHandlerFunc)
+		}
+	}
+	if returnFunc, ok := ret.Get(2).(func(string) error); ok {
+		r2 = returnFunc(name)
+	} else {
+		r2 = ret.Error(2)
+	}
+	return r0, r1, r2
+}
+
+// MockAdapter_GetResourceTemplate_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetResourceTemplate'
+type MockAdapter_GetResourceTemplate_Call struct {
+	*mock.Call
+}
+
+// GetResourceTemplate is a helper method to define mock.On call
+//   - name string
+func (_e *MockAdapter_Expecter) GetResourceTemplate(name interface{}) *MockAdapter_GetResourceTemplate_Call {
+	return &MockAdapter_GetResourceTemplate_Call{Call: _e.mock.On("GetResourceTemplate", name)}
+}
+
+func (_c *MockAdapter_GetResourceTemplate_Call) Run(run func(name string)) *MockAdapter_GetResourceTemplate_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAdapter_GetResourceTemplate_Call) Return(resourceTemplate *mcp.ResourceTemplate, resourceTemplateHandlerFunc server.ResourceTemplateHandlerFunc, err error) *MockAdapter_GetResourceTemplate_Call {
+	_c.Call.Return(resourceTemplate, resourceTemplateHandlerFunc, err)
+	return _c
+}
+
+func (_c *MockAdapter_GetResourceTemplate_Call) RunAndReturn(run func(name string) (*mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc, error)) *MockAdapter_GetResourceTemplate_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // GetTool provides a mock function for the type MockAdapter
 func (_mock *MockAdapter) GetTool(name string) (*mcp.Tool, server.ToolHandlerFunc, error) {
 	ret := _mock.Called(name)
@@ -107,6 +413,295 @@ func (_c *MockAdapter_GetTool_Call) RunAndReturn(run func(name string) (*mcp.Too
 	return _c
 }
 
+// GetToolDoc provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) GetToolDoc(name string) (string, error) {
+	ret := _mock.Called(name)
+
+	if len(ret) == 0 {
+		panic("no return value specified for GetToolDoc")
+	}
+
+	var r0 string
+	var r1 error
+	if returnFunc, ok := ret.Get(0).(func(string) (string, error)); ok {
+		return returnFunc(name)
+	}
+	if returnFunc, ok := ret.Get(0).(func(string) string); ok {
+		r0 = returnFunc(name)
+	} else {
+		r0 = ret.Get(0).(string)
+	}
+	if returnFunc, ok := ret.Get(1).(func(string) error); ok {
+		r1 = returnFunc(name)
+	} else {
+		r1 = ret.Error(1)
+	}
+	return r0, r1
+}
+
+// MockAdapter_GetToolDoc_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'GetToolDoc'
+type MockAdapter_GetToolDoc_Call struct {
+	*mock.Call
+}
+
+// GetToolDoc is a helper method to define mock.On call
+//   - name string
+func (_e *MockAdapter_Expecter) GetToolDoc(name interface{}) *MockAdapter_GetToolDoc_Call {
+	return &MockAdapter_GetToolDoc_Call{Call: _e.mock.On("GetToolDoc", name)}
+}
+
+func (_c *MockAdapter_GetToolDoc_Call) Run(run func(name string)) *MockAdapter_GetToolDoc_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 string
+		if args[0] != nil {
+			arg0 = args[0].(string)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAdapter_GetToolDoc_Call) Return(s string, err error) *MockAdapter_GetToolDoc_Call {
+	_c.Call.Return(s, err)
+	return _c
+}
+
+func (_c *MockAdapter_GetToolDoc_Call) RunAndReturn(run func(name string) (string, error)) *MockAdapter_GetToolDoc_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResourceNames provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) ResourceNames() []string {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResourceNames")
+	}
+
+	var r0 []string
+	if returnFunc, ok := ret.Get(0).(func() []string); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+	return r0
+}
+
+// MockAdapter_ResourceNames_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResourceNames'
+type MockAdapter_ResourceNames_Call struct {
+	*mock.Call
+}
+
+// ResourceNames is a helper method to define mock.On call
+func (_e *MockAdapter_Expecter) ResourceNames() *MockAdapter_ResourceNames_Call {
+	return &MockAdapter_ResourceNames_Call{Call: _e.mock.On("ResourceNames")}
+}
+
+func (_c *MockAdapter_ResourceNames_Call) Run(run func()) *MockAdapter_ResourceNames_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockAdapter_ResourceNames_Call) Return(strings []string) *MockAdapter_ResourceNames_Call {
+	_c.Call.Return(strings)
+	return _c
+}
+
+func (_c *MockAdapter_ResourceNames_Call) RunAndReturn(run func() []string) *MockAdapter_ResourceNames_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// ResourceTemplateNames provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) ResourceTemplateNames() []string {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ResourceTemplateNames")
+	}
+
+	var r0 []string
+	if returnFunc, ok := ret.Get(0).(func() []string); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+	return r0
+}
+
+// MockAdapter_ResourceTemplateNames_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ResourceTemplateNames'
+type MockAdapter_ResourceTemplateNames_Call struct {
+	*mock.Call
+}
+
+// ResourceTemplateNames is a helper method to define mock.On call
+func (_e *MockAdapter_Expecter) ResourceTemplateNames() *MockAdapter_ResourceTemplateNames_Call {
+	return &MockAdapter_ResourceTemplateNames_Call{Call: _e.mock.On("ResourceTemplateNames")}
+}
+
+func (_c *MockAdapter_ResourceTemplateNames_Call) Run(run func()) *MockAdapter_ResourceTemplateNames_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockAdapter_ResourceTemplateNames_Call) Return(strings []string) *MockAdapter_ResourceTemplateNames_Call {
+	_c.Call.Return(strings)
+	return _c
+}
+
+func (_c *MockAdapter_ResourceTemplateNames_Call) RunAndReturn(run func() []string) *MockAdapter_ResourceTemplateNames_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// SetToolSelection provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) SetToolSelection(selection jujuadapter.ToolSelection) error {
+	ret := _mock.Called(selection)
+
+	if len(ret) == 0 {
+		panic("no return value specified for SetToolSelection")
+	}
+
+	var r0 error
+	if returnFunc, ok := ret.Get(0).(func(jujuadapter.ToolSelection) error); ok {
+		r0 = returnFunc(selection)
+	} else {
+		r0 = ret.Error(0)
+	}
+	return r0
+}
+
+// MockAdapter_SetToolSelection_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'SetToolSelection'
+type MockAdapter_SetToolSelection_Call struct {
+	*mock.Call
+}
+
+// SetToolSelection is a helper method to define mock.On call
+//   - selection jujuadapter.ToolSelection
+func (_e *MockAdapter_Expecter) SetToolSelection(selection interface{}) *MockAdapter_SetToolSelection_Call {
+	return &MockAdapter_SetToolSelection_Call{Call: _e.mock.On("SetToolSelection", selection)}
+}
+
+func (_c *MockAdapter_SetToolSelection_Call) Run(run func(selection jujuadapter.ToolSelection)) *MockAdapter_SetToolSelection_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 jujuadapter.ToolSelection
+		if args[0] != nil {
+			arg0 = args[0].(jujuadapter.ToolSelection)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAdapter_SetToolSelection_Call) Return(err error) *MockAdapter_SetToolSelection_Call {
+	_c.Call.Return(err)
+	return _c
+}
+
+func (_c *MockAdapter_SetToolSelection_Call) RunAndReturn(run func(selection jujuadapter.ToolSelection) error) *MockAdapter_SetToolSelection_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
+// Start provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) Start(ctx context.Context) {
+	_mock.Called(ctx)
+	return
+}
+
+// MockAdapter_Start_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'Start'
+type MockAdapter_Start_Call struct {
+	*mock.Call
+}
+
+// Start is a helper method to define mock.On call
+//   - ctx context.Context
+func (_e *MockAdapter_Expecter) Start(ctx interface{}) *MockAdapter_Start_Call {
+	return &MockAdapter_Start_Call{Call: _e.mock.On("Start", ctx)}
+}
+
+func (_c *MockAdapter_Start_Call) Run(run func(ctx context.Context)) *MockAdapter_Start_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		var arg0 context.Context
+		if args[0] != nil {
+			arg0 = args[0].(context.Context)
+		}
+		run(
+			arg0,
+		)
+	})
+	return _c
+}
+
+func (_c *MockAdapter_Start_Call) Return() *MockAdapter_Start_Call {
+	_c.Call.Return()
+	return _c
+}
+
+func (_c *MockAdapter_Start_Call) RunAndReturn(run func(ctx context.Context)) *MockAdapter_Start_Call {
+	_c.Run(run)
+	return _c
+}
+
+// ToolDocResourceNames provides a mock function for the type MockAdapter
+func (_mock *MockAdapter) ToolDocResourceNames() []string {
+	ret := _mock.Called()
+
+	if len(ret) == 0 {
+		panic("no return value specified for ToolDocResourceNames")
+	}
+
+	var r0 []string
+	if returnFunc, ok := ret.Get(0).(func() []string); ok {
+		r0 = returnFunc()
+	} else {
+		if ret.Get(0) != nil {
+			r0 = ret.Get(0).([]string)
+		}
+	}
+	return r0
+}
+
+// MockAdapter_ToolDocResourceNames_Call is a *mock.Call that shadows Run/Return methods with type explicit version for method 'ToolDocResourceNames'
+type MockAdapter_ToolDocResourceNames_Call struct {
+	*mock.Call
+}
+
+// ToolDocResourceNames is a helper method to define mock.On call
+func (_e *MockAdapter_Expecter) ToolDocResourceNames() *MockAdapter_ToolDocResourceNames_Call {
+	return &MockAdapter_ToolDocResourceNames_Call{Call: _e.mock.On("ToolDocResourceNames")}
+}
+
+func (_c *MockAdapter_ToolDocResourceNames_Call) Run(run func()) *MockAdapter_ToolDocResourceNames_Call {
+	_c.Call.Run(func(args mock.Arguments) {
+		run()
+	})
+	return _c
+}
+
+func (_c *MockAdapter_ToolDocResourceNames_Call) Return(strings []string) *MockAdapter_ToolDocResourceNames_Call {
+	_c.Call.Return(strings)
+	return _c
+}
+
+func (_c *MockAdapter_ToolDocResourceNames_Call) RunAndReturn(run func() []string) *MockAdapter_ToolDocResourceNames_Call {
+	_c.Call.Return(run)
+	return _c
+}
+
 // ToolNames provides a mock function for the type MockAdapter
 func (_mock *MockAdapter) ToolNames() []string {
 	ret := _mock.Called()
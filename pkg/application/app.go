@@ -1,8 +1,12 @@
 package application
 
 import (
+	"context"
+	"sync"
+
 	"github.com/jneo8/mcp-juju/config"
 	"github.com/jneo8/mcp-juju/pkg/jujuadapter"
+	"github.com/mark3labs/mcp-go/mcp"
 	"github.com/mark3labs/mcp-go/server"
 	"github.com/rs/zerolog/log"
 )
@@ -13,68 +17,315 @@ type Application interface {
 
 type application struct {
 	mcpServer *server.MCPServer
-	config    config.Config
+	limiter   *rateLimiter
+	audit     *auditLog
+	approvals *approvalManager
 	adapter   jujuadapter.Adapter
+	reloader  *Reloader
+	// policies wrap every tool call, including those made through
+	// juju-run-command.
+	policies []server.ToolHandlerMiddleware
+	// stepPolicies wrap the commands run by tools made of several
+	// commands, such as deploy-and-wait. Approvals always block there.
+	stepPolicies []server.ToolHandlerMiddleware
+
+	reloadMu      sync.Mutex
+	serving       bool
+	approvalsOnce sync.Once
+
+	mu         sync.RWMutex
+	config     config.Config
+	registered registration
+}
+
+// Option configures optional behaviour of the application.
+type Option func(*application)
+
+// WithReloader enables reloading the configuration on SIGHUP and, if the
+// reloader names a file, whenever that file changes.
+func WithReloader(r Reloader) Option {
+	return func(a *application) {
+		a.reloader = &r
+	}
 }
 
-func NewApplication(cfg config.Config, adapter jujuadapter.Adapter) (Application, error) {
+func NewApplication(cfg config.Config, adapter jujuadapter.Adapter, opts ...Option) (Application, error) {
+	limiter, err := newRateLimiter(cfg, adapter.CurrentController)
+	if err != nil {
+		return nil, err
+	}
+	audit, err := newAuditLog(cfg.AuditLog)
+	if err != nil {
+		return nil, err
+	}
+
 	app := &application{
-		mcpServer: server.NewMCPServer(
-			config.MCPServerName,
-			config.Version,
-			server.WithResourceCapabilities(true, false),
-			server.WithLogging(),
-		),
-		config:  cfg,
-		adapter: adapter,
+		config:    cfg,
+		adapter:   adapter,
+		limiter:   limiter,
+		audit:     audit,
+		approvals: newApprovalManager(cfg, audit),
+	}
+	for _, opt := range opts {
+		opt(app)
+	}
+
+	// Policies run in order: audit, rate limits, then approvals, so
+	// limited calls never create approval requests.
+	if cfg.AuditLog != "" {
+		app.policies = append(app.policies, audit.middleware)
+		app.stepPolicies = append(app.stepPolicies, audit.middleware)
 	}
+	app.policies = append(app.policies, limiter.middleware, app.approvals.middleware)
+	app.stepPolicies = append(app.stepPolicies, limiter.middleware, app.approvals.blockingMiddleware)
+	serverOptions := []server.ServerOption{
+		// mcp-go does not handle resources/subscribe, so subscriptions
+		// are not advertised.
+		server.WithResourceCapabilities(false, true),
+		server.WithPromptCapabilities(true),
+		server.WithLogging(),
+		server.WithToolHandlerMiddleware(app.policyMiddleware),
+		server.WithToolFilter(adapter.AnnotateBlockedTools),
+	}
+	app.mcpServer = server.NewMCPServer(
+		config.MCPServerName,
+		config.Version,
+		serverOptions...,
+	)
+
 	if err := app.init(); err != nil {
 		return nil, err
 	}
 	return app, nil
 }
 
+// policyMiddleware applies the policies to direct tool calls. The
+// meta-tools are let through: juju-run-command applies them to the command
+// it runs, so they are not counted twice.
+func (a *application) policyMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	wrapped := a.withPolicies(next)
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if isMetaTool(req.Params.Name) {
+			return next(ctx, req)
+		}
+		// Policies are configured with command names, without the tool
+		// name prefix.
+		req.Params.Name = a.adapter.CommandName(req.Params.Name)
+		return wrapped(a.withStepPolicies(ctx), req)
+	}
+}
+
+func (a *application) withPolicies(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return chain(a.policies, handler)
+}
+
+// withStepPolicies makes the commands run by the tool called go through the
+// policies as well, so that calling deploy-and-wait does not bypass the
+// policies of deploy.
+func (a *application) withStepPolicies(ctx context.Context) context.Context {
+	return jujuadapter.WithPolicy(ctx, func(handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+		return chain(a.stepPolicies, handler)
+	})
+}
+
+func chain(policies []server.ToolHandlerMiddleware, handler server.ToolHandlerFunc) server.ToolHandlerFunc {
+	for i := len(policies) - 1; i >= 0; i-- {
+		handler = policies[i](handler)
+	}
+	return handler
+}
+
 func (a *application) RunServer() error {
-	if a.config.IsStdioServer() {
+	a.reloadMu.Lock()
+	a.serving = true
+	cfg := a.config
+	if cfg.IsApprovalEnabled() {
+		a.startApprovalsServer(cfg)
+	}
+	a.reloadMu.Unlock()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a.adapter.Start(ctx)
+	if a.reloader != nil {
+		go a.watchReload(ctx)
+	}
+	if cfg.IsStdioServer() {
 		return runStdioServer(a.mcpServer)
 	}
-	streamableHTTPServer := newStreamableHTTPServer(a.mcpServer, a.config)
-	return runStreamableHTTPServer(streamableHTTPServer, a.config)
+	streamableHTTPServer := newStreamableHTTPServer(a.mcpServer, cfg)
+	return runStreamableHTTPServer(streamableHTTPServer, cfg)
+}
+
+// serveApprovals runs the approvals API; tests replace it.
+var serveApprovals = runApprovalsServer
+
+// startApprovalsServer starts the approvals API once, at start up or when a
+// reload first enables approvals. It keeps the listener settings of cfg.
+func (a *application) startApprovalsServer(cfg config.Config) {
+	a.approvalsOnce.Do(func() {
+		go func() {
+			if err := serveApprovals(a.approvals, cfg); err != nil {
+				log.Error().Err(err).Msg("Approvals API stopped")
+			}
+		}()
+	})
 }
 
 func (a *application) init() error {
+	reg, err := a.collect(a.adapter, a.config)
+	if err != nil {
+		return err
+	}
+	a.apply(reg)
+	return nil
+}
+
+// resourceTemplate pairs a resource template with its handler; mcp-go has no
+// such type for templates.
+type resourceTemplate struct {
+	template mcp.ResourceTemplate
+	handler  server.ResourceTemplateHandlerFunc
+}
+
+// registration is everything registered with the MCP server for one
+// configuration.
+type registration struct {
+	tools     []server.ServerTool
+	resources []server.ServerResource
+	templates []resourceTemplate
+	prompts   []server.ServerPrompt
+	// catalog holds the selected tools in meta-tools mode, where they are
+	// reached through the meta-tools instead of being registered.
+	catalog map[string]catalogEntry
+}
+
+// collect gathers the tools and resources of the adapter without touching
+// the MCP server, so a failure leaves the current registration in place.
+func (a *application) collect(adapter jujuadapter.Adapter, cfg config.Config) (registration, error) {
+	var reg registration
+
 	// Register tools
-	toolNames := a.adapter.ToolNames()
+	toolNames := adapter.ToolNames()
 	for _, toolName := range toolNames {
 		log.Debug().Msgf("Register mcp tool %s", toolName)
-		tool, handlerFunc, err := a.adapter.GetTool(toolName)
+		tool, handlerFunc, err := adapter.GetTool(toolName)
+		if err != nil {
+			return reg, err
+		}
+		reg.tools = append(reg.tools, server.ServerTool{Tool: *tool, Handler: handlerFunc})
+	}
+	if cfg.MetaTools {
+		catalog, err := collectCatalog(adapter, reg.tools)
 		if err != nil {
-			return err
+			return reg, err
 		}
-		a.mcpServer.AddTool(*tool, handlerFunc)
+		reg.catalog = catalog
+		reg.tools = a.metaTools()
+	}
+	if cfg.IsApprovalEnabled() {
+		tool, handlerFunc := a.approvals.statusTool()
+		reg.tools = append(reg.tools, server.ServerTool{Tool: tool, Handler: handlerFunc})
 	}
 
-	// Register documentation resources
-	docResourceNames := a.adapter.ToolDocResourceNames()
-	for _, resourceName := range docResourceNames {
+	// Register documentation resources, then the other static resources
+	// such as the change journal. In meta-tools mode juju-describe-command
+	// serves the documentation instead.
+	var resourceNames []string
+	if !cfg.MetaTools {
+		resourceNames = adapter.ToolDocResourceNames()
+	}
+	resourceNames = append(resourceNames, adapter.ResourceNames()...)
+	for _, resourceName := range resourceNames {
 		log.Debug().Msgf("Register mcp resource %s", resourceName)
-		resource, handlerFunc, err := a.adapter.GetResource(resourceName)
+		resource, handlerFunc, err := adapter.GetResource(resourceName)
 		if err != nil {
-			return err
+			return reg, err
 		}
-		a.mcpServer.AddResource(*resource, handlerFunc)
+		reg.resources = append(reg.resources, server.ServerResource{Resource: *resource, Handler: a.limiter.resourceMiddleware(handlerFunc)})
 	}
 
 	// Register resource templates
-	resourceTemplateNames := a.adapter.ResourceTemplateNames()
+	resourceTemplateNames := adapter.ResourceTemplateNames()
 	for _, templateName := range resourceTemplateNames {
 		log.Debug().Msgf("Register mcp resource template %s", templateName)
-		template, handlerFunc, err := a.adapter.GetResourceTemplate(templateName)
+		template, handlerFunc, err := adapter.GetResourceTemplate(templateName)
 		if err != nil {
-			return err
+			return reg, err
 		}
-		a.mcpServer.AddResourceTemplate(*template, handlerFunc)
+		handler := a.limiter.resourceMiddleware(server.ResourceHandlerFunc(handlerFunc))
+		reg.templates = append(reg.templates, resourceTemplate{template: *template, handler: server.ResourceTemplateHandlerFunc(handler)})
 	}
 
-	return nil
+	// Register prompts
+	prompts, err := a.collectPrompts(cfg.PromptsDir)
+	if err != nil {
+		return reg, err
+	}
+	reg.prompts = prompts
+
+	return reg, nil
+}
+
+// apply replaces the current registration. mcp-go notifies clients with
+// tools/list_changed and resources/list_changed.
+func (a *application) apply(reg registration) {
+	a.mu.Lock()
+	previous := a.registered
+	a.registered = reg
+	a.mu.Unlock()
+
+	keepTools := make(map[string]bool, len(reg.tools))
+	for _, t := range reg.tools {
+		keepTools[t.Tool.Name] = true
+	}
+	var removedTools []string
+	for _, t := range previous.tools {
+		if !keepTools[t.Tool.Name] {
+			removedTools = append(removedTools, t.Tool.Name)
+		}
+	}
+	if len(removedTools) > 0 {
+		a.mcpServer.DeleteTools(removedTools...)
+	}
+	if len(reg.tools) > 0 {
+		a.mcpServer.AddTools(reg.tools...)
+	}
+
+	keepResources := make(map[string]bool, len(reg.resources))
+	for _, r := range reg.resources {
+		keepResources[r.Resource.URI] = true
+	}
+	for _, r := range previous.resources {
+		if !keepResources[r.Resource.URI] {
+			a.mcpServer.RemoveResource(r.Resource.URI)
+		}
+	}
+	if len(reg.resources) > 0 {
+		a.mcpServer.AddResources(reg.resources...)
+	}
+
+	// mcp-go cannot remove resource templates; they only depend on the
+	// adapter, not on the tool selection, so re-adding them is enough.
+	for _, t := range reg.templates {
+		a.mcpServer.AddResourceTemplate(t.template, t.handler)
+	}
+
+	keepPrompts := make(map[string]bool, len(reg.prompts))
+	for _, p := range reg.prompts {
+		keepPrompts[p.Prompt.Name] = true
+	}
+	var removedPrompts []string
+	for _, p := range previous.prompts {
+		if !keepPrompts[p.Prompt.Name] {
+			removedPrompts = append(removedPrompts, p.Prompt.Name)
+		}
+	}
+	if len(removedPrompts) > 0 {
+		a.mcpServer.DeletePrompts(removedPrompts...)
+	}
+	if len(reg.prompts) > 0 {
+		a.mcpServer.AddPrompts(reg.prompts...)
+	}
 }
+
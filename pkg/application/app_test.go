@@ -33,6 +33,10 @@ func TestNewApplication_Success(t *testing.T) {
 		mockAdapter.EXPECT().GetTool(toolName).Return(&tool, handlerFunc, nil)
 	}
 
+	mockAdapter.EXPECT().ToolDocResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{})
+
 	// Act
 	app, err := NewApplication(cfg, mockAdapter)
 
@@ -83,6 +87,9 @@ func TestNewApplication_EmptyToolNames(t *testing.T) {
 
 	mockAdapter := mockjujuadapter.NewMockAdapter(t)
 	mockAdapter.EXPECT().ToolNames().Return([]string{})
+	mockAdapter.EXPECT().ToolDocResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{})
 
 	// Act
 	app, err := NewApplication(cfg, mockAdapter)
@@ -102,6 +109,9 @@ func TestApplication_RunServer_InterfaceExists(t *testing.T) {
 
 	mockAdapter := mockjujuadapter.NewMockAdapter(t)
 	mockAdapter.EXPECT().ToolNames().Return([]string{})
+	mockAdapter.EXPECT().ToolDocResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{})
 
 	app, err := NewApplication(cfg, mockAdapter)
 	require.NoError(t, err)
@@ -149,6 +159,10 @@ func TestApplication_InitWithManyTools(t *testing.T) {
 		mockAdapter.EXPECT().GetTool(toolName).Return(&tool, handlerFunc, nil)
 	}
 
+	mockAdapter.EXPECT().ToolDocResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{})
+
 	// Act
 	app, err := NewApplication(cfg, mockAdapter)
 
@@ -176,6 +190,10 @@ func TestApplication_ConfigValues(t *testing.T) {
 	}
 	mockAdapter.EXPECT().GetTool("version").Return(&tool, handlerFunc, nil)
 
+	mockAdapter.EXPECT().ToolDocResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{})
+
 	// Act
 	app, err := NewApplication(cfg, mockAdapter)
 
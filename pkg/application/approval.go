@@ -0,0 +1,423 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	// approvalStatusToolName is the tool used to follow up on approval
+	// requests in async mode.
+	approvalStatusToolName = "approval-status"
+
+	webhookTimeout = 10 * time.Second
+)
+
+// approvalRetention is how long decided requests, and the results of the
+// calls they ran, are kept for approval-status and the approvals API.
+var approvalRetention = 24 * time.Hour
+
+// ApprovalStatus is the state of an approval request.
+type ApprovalStatus string
+
+const (
+	ApprovalPending   ApprovalStatus = "pending"
+	ApprovalApproved  ApprovalStatus = "approved"
+	ApprovalDenied    ApprovalStatus = "denied"
+	ApprovalExpired   ApprovalStatus = "expired"
+	ApprovalCancelled ApprovalStatus = "cancelled"
+)
+
+// ApprovalRequest records a high-risk tool call waiting for a second person.
+type ApprovalRequest struct {
+	ID        string              `json:"id"`
+	Tool      string              `json:"tool"`
+	Arguments map[string]any      `json:"arguments,omitempty"`
+	Requester string              `json:"requester"`
+	Status    ApprovalStatus      `json:"status"`
+	CreatedAt time.Time           `json:"created_at"`
+	ExpiresAt time.Time           `json:"expires_at"`
+	DecidedBy string              `json:"decided_by,omitempty"`
+	DecidedAt *time.Time          `json:"decided_at,omitempty"`
+	Reason    string              `json:"reason,omitempty"`
+	Result    *mcp.CallToolResult `json:"result,omitempty"`
+
+	decided chan struct{}
+}
+
+// ApprovalDecision is the body of approve and deny calls. The approver is
+// the authenticated caller.
+type ApprovalDecision struct {
+	Reason string `json:"reason,omitempty"`
+}
+
+var (
+	errApprovalNotFound    = errors.New("approval request not found")
+	errApprovalNotPending  = errors.New("approval request is no longer pending")
+	errApprovalSelfApprove = errors.New("approval requests cannot be decided by the requester")
+	// errApprovalUnauthenticated is returned to approvers identified
+	// neither by an approver client certificate nor by an approver token.
+	errApprovalUnauthenticated = errors.New("approvers must authenticate with an approver client certificate or an approver token")
+)
+
+// approvalManager holds approval requests and gates the tools matched by the
+// approval policy.
+type approvalManager struct {
+	mu       sync.Mutex
+	requests map[string]*ApprovalRequest
+	cfg      config.Config
+	audit    *auditLog
+	client   *http.Client
+	now      func() time.Time
+}
+
+func newApprovalManager(cfg config.Config, audit *auditLog) *approvalManager {
+	return &approvalManager{
+		requests: make(map[string]*ApprovalRequest),
+		cfg:      cfg,
+		audit:    audit,
+		client:   &http.Client{Timeout: webhookTimeout},
+		now:      time.Now,
+	}
+}
+
+// update swaps the approval policy. Pending requests keep their expiry.
+func (m *approvalManager) update(cfg config.Config) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.cfg = cfg
+}
+
+func (m *approvalManager) enabled() bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.cfg.IsApprovalEnabled()
+}
+
+func (m *approvalManager) requiresApproval(tool string) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, pattern := range m.cfg.ApprovalTools {
+		if ok, _ := path.Match(pattern, tool); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (m *approvalManager) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return m.gate(next, false)
+}
+
+// blockingMiddleware always waits for the decision, whatever the mode. It
+// gates the commands run by tools made of several commands, which need the
+// outcome of each command before running the next one. The commands of a
+// tool call that was itself approved are not submitted again.
+func (m *approvalManager) blockingMiddleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return m.gate(next, true)
+}
+
+func (m *approvalManager) gate(next server.ToolHandlerFunc, block bool) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if !m.requiresApproval(req.Params.Name) {
+			return next(ctx, req)
+		}
+		if _, approved := ctx.Value(approvedContextKey{}).(string); approved && block {
+			return next(ctx, req)
+		}
+		request, err := m.create(CallerIdentity(ctx), req)
+		if err != nil {
+			return nil, err
+		}
+		go m.notify(request)
+
+		m.mu.Lock()
+		mode := m.cfg.ApprovalMode
+		m.mu.Unlock()
+		if mode == config.ApprovalModeAsync && !block {
+			go m.runWhenApproved(context.WithoutCancel(ctx), request, next, req)
+			return pendingResult(request), nil
+		}
+		return m.waitAndRun(ctx, request, next, req)
+	}
+}
+
+func (m *approvalManager) create(requester string, req mcp.CallToolRequest) (*ApprovalRequest, error) {
+	id, err := newApprovalID()
+	if err != nil {
+		return nil, err
+	}
+	m.mu.Lock()
+	now := m.now().UTC()
+	request := &ApprovalRequest{
+		ID:        id,
+		Tool:      req.Params.Name,
+		Arguments: redactArguments(req.Params.Name, req.GetArguments()),
+		Requester: requester,
+		Status:    ApprovalPending,
+		CreatedAt: now,
+		ExpiresAt: now.Add(m.cfg.ApprovalTimeout),
+		decided:   make(chan struct{}),
+	}
+	m.requests[id] = request
+	timeout := m.cfg.ApprovalTimeout
+	m.mu.Unlock()
+
+	time.AfterFunc(timeout, func() { m.expire(id) })
+	m.audit.record(auditEvent{
+		Event:      "approval.requested",
+		Identity:   requester,
+		Tool:       request.Tool,
+		Arguments:  request.Arguments,
+		ApprovalID: id,
+	})
+	log.Info().Str("id", id).Str("tool", request.Tool).Str("requester", requester).Msg("Approval requested")
+	return request, nil
+}
+
+// waitAndRun blocks the tool call until the request is decided.
+func (m *approvalManager) waitAndRun(ctx context.Context, request *ApprovalRequest, next server.ToolHandlerFunc, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	select {
+	case <-request.decided:
+	case <-ctx.Done():
+		m.finish(request.ID, ApprovalCancelled, "", "caller went away")
+		return nil, ctx.Err()
+	}
+	snapshot := m.snapshot(request)
+	if snapshot.Status != ApprovalApproved {
+		return notApprovedResult(snapshot), nil
+	}
+	return next(withApproval(ctx, request.ID), req)
+}
+
+type approvedContextKey struct{}
+
+// withApproval records that the call was approved by the given request.
+func withApproval(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, approvedContextKey{}, id)
+}
+
+// runWhenApproved runs the call in the background once approved and keeps
+// the result for approval-status.
+func (m *approvalManager) runWhenApproved(ctx context.Context, request *ApprovalRequest, next server.ToolHandlerFunc, req mcp.CallToolRequest) {
+	<-request.decided
+	snapshot := m.snapshot(request)
+	var result *mcp.CallToolResult
+	if snapshot.Status == ApprovalApproved {
+		var err error
+		result, err = next(withApproval(ctx, request.ID), req)
+		if err != nil {
+			result = mcp.NewToolResultError(err.Error())
+		}
+	} else {
+		result = notApprovedResult(snapshot)
+	}
+	m.mu.Lock()
+	request.Result = result
+	m.mu.Unlock()
+}
+
+// decide approves or denies a pending request on behalf of the approver.
+func (m *approvalManager) decide(id string, approve bool, approver string, reason string) (ApprovalRequest, error) {
+	if !isApprover(approver) {
+		return ApprovalRequest{}, errApprovalUnauthenticated
+	}
+	m.mu.Lock()
+	request, ok := m.requests[id]
+	if !ok {
+		m.mu.Unlock()
+		return ApprovalRequest{}, errApprovalNotFound
+	}
+	if request.Requester == approver {
+		m.mu.Unlock()
+		return ApprovalRequest{}, errApprovalSelfApprove
+	}
+	m.mu.Unlock()
+
+	status := ApprovalDenied
+	if approve {
+		status = ApprovalApproved
+	}
+	if !m.finish(id, status, approver, reason) {
+		return m.snapshot(request), errApprovalNotPending
+	}
+	return m.snapshot(request), nil
+}
+
+func (m *approvalManager) expire(id string) {
+	m.finish(id, ApprovalExpired, "", "no decision before expiry")
+}
+
+// finish moves a pending request to its final state. It reports false when
+// the request was already decided.
+func (m *approvalManager) finish(id string, status ApprovalStatus, by string, reason string) bool {
+	m.mu.Lock()
+	request, ok := m.requests[id]
+	if !ok || request.Status != ApprovalPending {
+		m.mu.Unlock()
+		return false
+	}
+	now := m.now().UTC()
+	request.Status = status
+	request.DecidedBy = by
+	request.DecidedAt = &now
+	request.Reason = reason
+	close(request.decided)
+	m.mu.Unlock()
+	time.AfterFunc(approvalRetention, func() { m.forget(id) })
+
+	m.audit.record(auditEvent{
+		Event:      "approval." + string(status),
+		Identity:   by,
+		Tool:       request.Tool,
+		ApprovalID: id,
+		Detail:     reason,
+	})
+	log.Info().Str("id", id).Str("status", string(status)).Str("by", by).Msg("Approval decided")
+	return true
+}
+
+// forget drops a decided request once its retention has passed.
+func (m *approvalManager) forget(id string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.requests, id)
+}
+
+func (m *approvalManager) snapshot(request *ApprovalRequest) ApprovalRequest {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return *request
+}
+
+func (m *approvalManager) get(id string) (ApprovalRequest, bool) {
+	m.mu.Lock()
+	request, ok := m.requests[id]
+	m.mu.Unlock()
+	if !ok {
+		return ApprovalRequest{}, false
+	}
+	return m.snapshot(request), true
+}
+
+// list returns the requests, newest first, optionally filtered by status.
+func (m *approvalManager) list(status ApprovalStatus) []ApprovalRequest {
+	m.mu.Lock()
+	requests := make([]ApprovalRequest, 0, len(m.requests))
+	for _, request := range m.requests {
+		if status == "" || request.Status == status {
+			requests = append(requests, *request)
+		}
+	}
+	m.mu.Unlock()
+	sort.Slice(requests, func(i, j int) bool {
+		return requests[i].CreatedAt.After(requests[j].CreatedAt)
+	})
+	return requests
+}
+
+// notify posts the new request to the approval webhook, if configured.
+func (m *approvalManager) notify(request *ApprovalRequest) {
+	m.mu.Lock()
+	webhookURL := m.cfg.ApprovalWebhookURL
+	approvalsURL := m.cfg.ApprovalsURL()
+	m.mu.Unlock()
+	if webhookURL == "" {
+		return
+	}
+	body, err := json.Marshal(map[string]any{
+		"event":       "approval.requested",
+		"approval":    m.snapshot(request),
+		"approve_url": fmt.Sprintf("%s/%s/approve", approvalsURL, request.ID),
+		"deny_url":    fmt.Sprintf("%s/%s/deny", approvalsURL, request.ID),
+	})
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode approval webhook")
+		return
+	}
+	resp, err := m.client.Post(webhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		log.Error().Err(err).Str("id", request.ID).Msg("Failed to notify approval webhook")
+		return
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 300 {
+		log.Error().Int("status", resp.StatusCode).Str("id", request.ID).Msg("Approval webhook rejected notification")
+	}
+}
+
+// statusTool returns the approval-status tool used in async mode.
+func (m *approvalManager) statusTool() (mcp.Tool, server.ToolHandlerFunc) {
+	tool := mcp.NewTool(approvalStatusToolName,
+		mcp.WithDescription("Show the state of an approval request created for one of your high-risk tool calls and, once it has been approved and run, its result. "+
+			fmt.Sprintf("Decided requests are kept for %s.", approvalRetention)),
+		mcp.WithString("id", mcp.Required(), mcp.Description("Approval request ID")),
+		mcp.WithReadOnlyHintAnnotation(true),
+	)
+	handler := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		id, err := req.RequireString("id")
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		// Other callers' requests are reported as not found, so that their
+		// arguments and results do not leak.
+		request, ok := m.get(id)
+		if !ok || request.Requester != CallerIdentity(ctx) {
+			return mcp.NewToolResultError(errApprovalNotFound.Error()), nil
+		}
+		if request.Result != nil {
+			return request.Result, nil
+		}
+		body, err := json.MarshalIndent(request, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		return mcp.NewToolResultText(string(body)), nil
+	}
+	return tool, handler
+}
+
+func pendingResult(request *ApprovalRequest) *mcp.CallToolResult {
+	body, _ := json.Marshal(map[string]any{
+		"status":      ApprovalPending,
+		"approval_id": request.ID,
+		"expires_at":  request.ExpiresAt,
+		"message": fmt.Sprintf("%s requires approval from a second person; call %s with id %q to follow up",
+			request.Tool, approvalStatusToolName, request.ID),
+	})
+	return mcp.NewToolResultText(string(body))
+}
+
+func notApprovedResult(request ApprovalRequest) *mcp.CallToolResult {
+	msg := fmt.Sprintf("%s was not run: approval request %s was %s", request.Tool, request.ID, request.Status)
+	if request.DecidedBy != "" {
+		msg += " by " + request.DecidedBy
+	}
+	if request.Reason != "" {
+		msg += ": " + request.Reason
+	}
+	return mcp.NewToolResultError(msg)
+}
+
+func newApprovalID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate approval id: %w", err)
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,148 @@
+package application
+
+import (
+	"context"
+	"crypto/subtle"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/rs/zerolog/log"
+)
+
+// registerRoutes mounts the approval API used by the approvals CLI and by
+// approve/deny links sent to the webhook.
+// Every route requires an authenticated approver, since requests hold the
+// arguments of the calls.
+func (m *approvalManager) registerRoutes(mux *http.ServeMux) {
+	mux.HandleFunc("GET /approvals", m.authenticated(m.handleList))
+	mux.HandleFunc("GET /approvals/{id}", m.authenticated(m.handleGet))
+	mux.HandleFunc("POST /approvals/{id}/approve", m.authenticated(m.handleDecision(true)))
+	mux.HandleFunc("POST /approvals/{id}/deny", m.authenticated(m.handleDecision(false)))
+}
+
+// authenticated rejects callers that are neither identified by a client
+// certificate nor by an approver token.
+func (m *approvalManager) authenticated(next http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		approver := m.approverIdentity(r)
+		if !isApprover(approver) {
+			writeJSONError(w, http.StatusUnauthorized, errApprovalUnauthenticated)
+			return
+		}
+		next(w, r.WithContext(context.WithValue(r.Context(), identityContextKey{}, approver)))
+	}
+}
+
+func (m *approvalManager) handleList(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, m.list(ApprovalStatus(r.URL.Query().Get("status"))))
+}
+
+func (m *approvalManager) handleGet(w http.ResponseWriter, r *http.Request) {
+	request, ok := m.get(r.PathValue("id"))
+	if !ok {
+		writeJSONError(w, http.StatusNotFound, errApprovalNotFound)
+		return
+	}
+	writeJSON(w, http.StatusOK, request)
+}
+
+func (m *approvalManager) handleDecision(approve bool) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		var decision ApprovalDecision
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&decision); err != nil {
+				writeJSONError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+		request, err := m.decide(r.PathValue("id"), approve, CallerIdentity(r.Context()), decision.Reason)
+		switch {
+		case errors.Is(err, errApprovalUnauthenticated):
+			writeJSONError(w, http.StatusUnauthorized, err)
+		case errors.Is(err, errApprovalNotFound):
+			writeJSONError(w, http.StatusNotFound, err)
+		case errors.Is(err, errApprovalSelfApprove):
+			writeJSONError(w, http.StatusForbidden, err)
+		case errors.Is(err, errApprovalNotPending):
+			writeJSONError(w, http.StatusConflict, err)
+		case err != nil:
+			writeJSONError(w, http.StatusInternalServerError, err)
+		default:
+			writeJSON(w, http.StatusOK, request)
+		}
+	}
+}
+
+// approverIdentity is the verified client certificate subject, if it is one
+// of the approver subjects, or else the name of the approver token given as a
+// bearer token. It is never taken from the request body.
+func (m *approvalManager) approverIdentity(r *http.Request) string {
+	m.mu.Lock()
+	cfg := m.cfg
+	m.mu.Unlock()
+	if identity := CallerIdentity(withCallerIdentity(r.Context(), r)); cfg.IsApproverSubject(identity) {
+		return identity
+	}
+	given, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	if !ok || given == "" {
+		return AnonymousIdentity
+	}
+	names, err := cfg.ApproverTokenNames()
+	if err != nil {
+		log.Error().Err(err).Msg("Invalid approver tokens")
+		return AnonymousIdentity
+	}
+	for token, name := range names {
+		if subtle.ConstantTimeCompare([]byte(token), []byte(given)) == 1 {
+			return name
+		}
+	}
+	return AnonymousIdentity
+}
+
+// isApprover reports whether an identity is authenticated well enough to
+// decide approval requests.
+func isApprover(identity string) bool {
+	return identity != "" && identity != LocalIdentity && identity != AnonymousIdentity
+}
+
+func newApprovalsServer(m *approvalManager, cfg config.Config) (*http.Server, error) {
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	m.registerRoutes(mux)
+	return &http.Server{
+		Addr:      cfg.ApprovalsAddress,
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}, nil
+}
+
+func runApprovalsServer(m *approvalManager, cfg config.Config) error {
+	httpServer, err := newApprovalsServer(m, cfg)
+	if err != nil {
+		return err
+	}
+	log.Debug().Msgf("Run approvals API at %s", cfg.ApprovalsURL())
+	if cfg.IsTLSEnabled() {
+		return httpServer.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return httpServer.ListenAndServe()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		log.Error().Err(err).Msg("Failed to write response")
+	}
+}
+
+func writeJSONError(w http.ResponseWriter, status int, err error) {
+	writeJSON(w, status, map[string]string{"error": err.Error()})
+}
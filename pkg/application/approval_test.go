@@ -0,0 +1,370 @@
+package application
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestApprovalManager(t *testing.T, mode string, timeout time.Duration) (*approvalManager, string) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLog(auditPath)
+	require.NoError(t, err)
+	cfg := config.Config{
+		ApprovalTools:    []string{"destroy-*", "remove-application"},
+		ApprovalMode:     mode,
+		ApprovalTimeout:  timeout,
+		ApprovalsAddress: config.DefaultApprovalsAddress,
+		ApproverTokens:   []string{"bob=bob-token", "carol=carol-token"},
+	}
+	return newApprovalManager(cfg, audit), auditPath
+}
+
+// waitForPending returns the first pending request once the middleware has
+// created it.
+func waitForPending(t *testing.T, m *approvalManager) ApprovalRequest {
+	var pending []ApprovalRequest
+	require.Eventually(t, func() bool {
+		pending = m.list(ApprovalPending)
+		return len(pending) == 1
+	}, time.Second, 5*time.Millisecond)
+	return pending[0]
+}
+
+func TestApprovalManager_RequiresApproval(t *testing.T) {
+	m, _ := newTestApprovalManager(t, config.ApprovalModeBlock, time.Minute)
+
+	assert.True(t, m.requiresApproval("destroy-model"))
+	assert.True(t, m.requiresApproval("remove-application"))
+	assert.False(t, m.requiresApproval("remove-unit"))
+	assert.False(t, m.requiresApproval("status"))
+}
+
+func TestApprovalManager_BlockModeApproved(t *testing.T) {
+	// Arrange
+	m, auditPath := newTestApprovalManager(t, config.ApprovalModeBlock, time.Minute)
+	handler := m.middleware(okHandler)
+	done := make(chan *mcp.CallToolResult)
+
+	// Act
+	go func() {
+		result, _ := handler(context.Background(), newTestRequest("destroy-model", map[string]any{"args": []any{"prod"}}))
+		done <- result
+	}()
+	pending := waitForPending(t, m)
+	_, err := m.decide(pending.ID, true, "bob", "change ticket 42")
+	require.NoError(t, err)
+	result := <-done
+
+	// Assert
+	assert.False(t, result.IsError)
+	assert.Equal(t, "destroy-model", pending.Tool)
+	assert.Equal(t, LocalIdentity, pending.Requester)
+	decided, ok := m.get(pending.ID)
+	require.True(t, ok)
+	assert.Equal(t, ApprovalApproved, decided.Status)
+	assert.Equal(t, "bob", decided.DecidedBy)
+
+	audit, err := os.ReadFile(auditPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(audit), `"event":"approval.requested"`)
+	assert.Contains(t, string(audit), `"event":"approval.approved"`)
+}
+
+func TestApprovalManager_BlockModeDenied(t *testing.T) {
+	// Arrange
+	m, _ := newTestApprovalManager(t, config.ApprovalModeBlock, time.Minute)
+	called := false
+	handler := m.middleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		called = true
+		return mcp.NewToolResultText("ok"), nil
+	})
+	done := make(chan *mcp.CallToolResult)
+
+	// Act
+	go func() {
+		result, _ := handler(context.Background(), newTestRequest("remove-application", nil))
+		done <- result
+	}()
+	pending := waitForPending(t, m)
+	_, err := m.decide(pending.ID, false, "bob", "not during business hours")
+	require.NoError(t, err)
+	result := <-done
+
+	// Assert
+	assert.False(t, called)
+	require.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "denied by bob: not during business hours")
+}
+
+func TestApprovalManager_RequesterCannotApprove(t *testing.T) {
+	// Arrange
+	m, _ := newTestApprovalManager(t, config.ApprovalModeAsync, time.Minute)
+	handler := m.middleware(okHandler)
+	_, err := handler(context.Background(), newTestRequest("destroy-model", nil))
+	require.NoError(t, err)
+	pending := waitForPending(t, m)
+
+	// Act
+	_, local := m.decide(pending.ID, true, LocalIdentity, "")
+	_, anonymous := m.decide(pending.ID, true, AnonymousIdentity, "")
+
+	// Assert
+	assert.ErrorIs(t, local, errApprovalUnauthenticated)
+	assert.ErrorIs(t, anonymous, errApprovalUnauthenticated)
+	request, _ := m.get(pending.ID)
+	assert.Equal(t, ApprovalPending, request.Status)
+}
+
+func TestApprovalManager_RedactsArguments(t *testing.T) {
+	// Arrange
+	m, _ := newTestApprovalManager(t, config.ApprovalModeAsync, time.Minute)
+	var ran map[string]any
+	handler := m.middleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		ran = req.GetArguments()
+		return mcp.NewToolResultText("ok"), nil
+	})
+
+	// Act
+	_, err := handler(context.Background(), newTestRequest("destroy-model", map[string]any{"args": []any{"prod"}, "password": "s3cret"}))
+	require.NoError(t, err)
+	pending := waitForPending(t, m)
+	_, err = m.decide(pending.ID, true, "bob", "")
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "[redacted]", pending.Arguments["password"])
+	assert.Equal(t, []any{"prod"}, pending.Arguments["args"])
+	require.Eventually(t, func() bool {
+		request, _ := m.get(pending.ID)
+		return request.Result != nil
+	}, time.Second, 5*time.Millisecond)
+	assert.Equal(t, "s3cret", ran["password"], "the call itself keeps its arguments")
+}
+
+func TestApprovalManager_Expires(t *testing.T) {
+	// Arrange
+	m, _ := newTestApprovalManager(t, config.ApprovalModeBlock, 20*time.Millisecond)
+	handler := m.middleware(okHandler)
+
+	// Act
+	result, err := handler(context.Background(), newTestRequest("destroy-model", nil))
+
+	// Assert
+	require.NoError(t, err)
+	require.True(t, result.IsError)
+	assert.Contains(t, result.Content[0].(mcp.TextContent).Text, "expired")
+}
+
+func TestApprovalManager_AsyncMode(t *testing.T) {
+	// Arrange
+	m, _ := newTestApprovalManager(t, config.ApprovalModeAsync, time.Minute)
+	handler := m.middleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("model destroyed"), nil
+	})
+	_, statusHandler := m.statusTool()
+
+	// Act
+	result, err := handler(context.Background(), newTestRequest("destroy-model", nil))
+	require.NoError(t, err)
+	var pending map[string]any
+	require.NoError(t, json.Unmarshal([]byte(result.Content[0].(mcp.TextContent).Text), &pending))
+	id := pending["approval_id"].(string)
+
+	_, err = m.decide(id, true, "bob", "")
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "pending", pending["status"])
+	require.Eventually(t, func() bool {
+		status, _ := statusHandler(context.Background(), newTestRequest(approvalStatusToolName, map[string]any{"id": id}))
+		return status.Content[0].(mcp.TextContent).Text == "model destroyed"
+	}, time.Second, 5*time.Millisecond)
+	other := context.WithValue(context.Background(), identityContextKey{}, "CN=mallory")
+	status, err := statusHandler(other, newTestRequest(approvalStatusToolName, map[string]any{"id": id}))
+	require.NoError(t, err)
+	assert.True(t, status.IsError)
+	assert.Equal(t, errApprovalNotFound.Error(), status.Content[0].(mcp.TextContent).Text)
+}
+
+func TestApprovalManager_ForgetsDecidedRequests(t *testing.T) {
+	retention := approvalRetention
+	approvalRetention = 20 * time.Millisecond
+	t.Cleanup(func() { approvalRetention = retention })
+	m, _ := newTestApprovalManager(t, config.ApprovalModeAsync, time.Minute)
+	handler := m.middleware(okHandler)
+
+	_, err := handler(context.Background(), newTestRequest("destroy-model", nil))
+	require.NoError(t, err)
+	pending := waitForPending(t, m)
+	_, err = m.decide(pending.ID, false, "bob", "")
+	require.NoError(t, err)
+
+	require.Eventually(t, func() bool {
+		_, ok := m.get(pending.ID)
+		return !ok
+	}, time.Second, 5*time.Millisecond)
+	assert.Empty(t, m.list(""))
+}
+
+func TestApprovalManager_StepsBlockInAsyncMode(t *testing.T) {
+	// Arrange
+	m, _ := newTestApprovalManager(t, config.ApprovalModeAsync, time.Minute)
+	handler := m.blockingMiddleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("model destroyed"), nil
+	})
+	done := make(chan *mcp.CallToolResult)
+
+	// Act
+	go func() {
+		result, _ := handler(context.Background(), newTestRequest("destroy-model", nil))
+		done <- result
+	}()
+	pending := waitForPending(t, m)
+	_, err := m.decide(pending.ID, true, "bob", "")
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, "model destroyed", (<-done).Content[0].(mcp.TextContent).Text)
+}
+
+func TestApprovalManager_ApprovedCallStepsSkipApproval(t *testing.T) {
+	for _, mode := range []string{config.ApprovalModeBlock, config.ApprovalModeAsync} {
+		t.Run(mode, func(t *testing.T) {
+			// Arrange
+			m, _ := newTestApprovalManager(t, mode, time.Minute)
+			step := m.blockingMiddleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return mcp.NewToolResultText("application removed"), nil
+			})
+			handler := m.middleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+				return step(ctx, newTestRequest("remove-application", nil))
+			})
+			done := make(chan *mcp.CallToolResult, 1)
+
+			// Act
+			go func() {
+				result, _ := handler(context.Background(), newTestRequest("destroy-model", nil))
+				done <- result
+			}()
+			pending := waitForPending(t, m)
+			_, err := m.decide(pending.ID, true, "bob", "")
+			require.NoError(t, err)
+
+			// Assert
+			if mode == config.ApprovalModeBlock {
+				assert.Equal(t, "application removed", (<-done).Content[0].(mcp.TextContent).Text)
+			} else {
+				<-done
+				require.Eventually(t, func() bool {
+					request, ok := m.get(pending.ID)
+					return ok && request.Result != nil
+				}, time.Second, 5*time.Millisecond)
+				request, _ := m.get(pending.ID)
+				assert.Equal(t, "application removed", request.Result.Content[0].(mcp.TextContent).Text)
+			}
+			assert.Empty(t, m.list(ApprovalPending), "the step of the approved call is not submitted")
+			assert.Len(t, m.list(""), 1)
+		})
+	}
+}
+
+func TestApprovalAPI(t *testing.T) {
+	// Arrange
+	m, _ := newTestApprovalManager(t, config.ApprovalModeAsync, time.Minute)
+	handler := m.middleware(okHandler)
+	_, err := handler(context.Background(), newTestRequest("destroy-model", nil))
+	require.NoError(t, err)
+	pending := waitForPending(t, m)
+
+	mux := http.NewServeMux()
+	m.registerRoutes(mux)
+	server := httptest.NewServer(mux)
+	defer server.Close()
+
+	post := func(path, token, body string) *http.Response {
+		req, err := http.NewRequest(http.MethodPost, server.URL+path, strings.NewReader(body))
+		require.NoError(t, err)
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		require.NoError(t, err)
+		return resp
+	}
+
+	// Act
+	unauthenticated, err := http.Get(server.URL + "/approvals?status=pending")
+	require.NoError(t, err)
+	unauthenticated.Body.Close()
+
+	req, err := http.NewRequest(http.MethodGet, server.URL+"/approvals?status=pending", nil)
+	require.NoError(t, err)
+	req.Header.Set("Authorization", "Bearer bob-token")
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	var listed []ApprovalRequest
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&listed))
+	resp.Body.Close()
+
+	claimed := post("/approvals/"+pending.ID+"/approve", "", `{"approver":"bob"}`)
+	claimed.Body.Close()
+	wrongToken := post("/approvals/"+pending.ID+"/approve", "guess", "")
+	wrongToken.Body.Close()
+
+	resp = post("/approvals/"+pending.ID+"/deny", "bob-token", `{"approver":"carol","reason":"looks fine"}`)
+	var decided ApprovalRequest
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&decided))
+	resp.Body.Close()
+
+	conflict := post("/approvals/"+pending.ID+"/approve", "carol-token", "")
+	conflict.Body.Close()
+	missing := post("/approvals/unknown/approve", "carol-token", "")
+	missing.Body.Close()
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, unauthenticated.StatusCode)
+	assert.Equal(t, http.StatusUnauthorized, claimed.StatusCode, "the approver named in the body is ignored")
+	assert.Equal(t, http.StatusUnauthorized, wrongToken.StatusCode)
+	require.Len(t, listed, 1)
+	assert.Equal(t, pending.ID, listed[0].ID)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, ApprovalDenied, decided.Status)
+	assert.Equal(t, "bob", decided.DecidedBy)
+	assert.Equal(t, "looks fine", decided.Reason)
+	assert.Equal(t, http.StatusConflict, conflict.StatusCode)
+	assert.Equal(t, http.StatusNotFound, missing.StatusCode)
+}
+
+func TestApprovalManager_ApproverSubjects(t *testing.T) {
+	m, _ := newTestApprovalManager(t, config.ApprovalModeBlock, time.Minute)
+	m.cfg.TLSCertFile, m.cfg.TLSKeyFile, m.cfg.TLSClientCAFile = "server.crt", "server.key", "ca.pem"
+	m.cfg.ApproverSubjects = []string{"CN=alice,O=ops"}
+	request := func(subject, token string) *http.Request {
+		r := httptest.NewRequest("GET", "/approvals", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: subject, Organization: []string{"ops"}}}
+		r.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		if token != "" {
+			r.Header.Set("Authorization", "Bearer "+token)
+		}
+		return r
+	}
+
+	assert.Equal(t, "CN=alice,O=ops", m.approverIdentity(request("alice", "")))
+	assert.Equal(t, AnonymousIdentity, m.approverIdentity(request("assistant", "")),
+		"certificates signed by the client CA cannot approve unless listed")
+	assert.Equal(t, "bob", m.approverIdentity(request("assistant", "bob-token")))
+}
@@ -0,0 +1,159 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+// auditEvent is one entry of the audit trail.
+type auditEvent struct {
+	Time       time.Time      `json:"time"`
+	Event      string         `json:"event"`
+	Identity   string         `json:"identity,omitempty"`
+	Tool       string         `json:"tool,omitempty"`
+	Arguments  map[string]any `json:"arguments,omitempty"`
+	ApprovalID string         `json:"approval_id,omitempty"`
+	Detail     string         `json:"detail,omitempty"`
+}
+
+// auditLog appends events as JSON lines to a file, or to the application log
+// when no audit file is configured.
+type auditLog struct {
+	mu sync.Mutex
+	w  io.Writer
+}
+
+func newAuditLog(path string) (*auditLog, error) {
+	if path == "" {
+		return &auditLog{}, nil
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open audit log: %w", err)
+	}
+	return &auditLog{w: f}, nil
+}
+
+func (a *auditLog) record(event auditEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now().UTC()
+	}
+	event.Arguments = redactArguments(event.Tool, event.Arguments)
+	if a.w == nil {
+		log.Info().
+			Str("event", event.Event).
+			Str("identity", event.Identity).
+			Str("tool", event.Tool).
+			Str("approval_id", event.ApprovalID).
+			Str("detail", event.Detail).
+			Msg("audit")
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		log.Error().Err(err).Msg("Failed to encode audit event")
+		return
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if _, err := a.w.Write(append(line, '\n')); err != nil {
+		log.Error().Err(err).Msg("Failed to write audit event")
+	}
+}
+
+// redactedArguments are the tool arguments never written to the audit
+// trail nor shown in approval requests, such as the password of login.
+var redactedArguments = []string{"password"}
+
+// secretCommands are the commands whose positional arguments carry secrets:
+// the key=value content of secrets, secret backend configuration and
+// credential attributes, which keep their key, and the registration string
+// of register, which is redacted whole.
+var secretCommands = map[string]bool{
+	"add-secret":            true,
+	"update-secret":         true,
+	"add-secret-backend":    true,
+	"update-secret-backend": true,
+	"add-credential":        true,
+	"update-credential":     true,
+	"register":              true,
+}
+
+const redacted = "[redacted]"
+
+// redactArguments returns a copy of the arguments of a tool call with the
+// secret ones replaced.
+func redactArguments(tool string, arguments map[string]any) map[string]any {
+	var out map[string]any
+	replace := func(name string, value any) {
+		if out == nil {
+			out = make(map[string]any, len(arguments))
+			for k, v := range arguments {
+				out[k] = v
+			}
+		}
+		out[name] = value
+	}
+	for _, name := range redactedArguments {
+		if _, ok := arguments[name]; ok {
+			replace(name, redacted)
+		}
+	}
+	if args, ok := arguments["args"].([]any); ok && secretCommands[tool] {
+		replace("args", redactPositional(tool, args))
+	}
+	if out == nil {
+		return arguments
+	}
+	return out
+}
+
+// redactPositional redacts the positional arguments of a secret command.
+func redactPositional(tool string, args []any) []any {
+	out := make([]any, len(args))
+	for i, arg := range args {
+		s, ok := arg.(string)
+		switch {
+		case tool == "register" || !ok:
+			out[i] = redacted
+		case strings.Contains(s, "="):
+			key, _, _ := strings.Cut(s, "=")
+			out[i] = key + "=" + redacted
+		default:
+			out[i] = s
+		}
+	}
+	return out
+}
+
+// middleware records every tool call and whether it failed.
+func (a *auditLog) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		result, err := next(ctx, req)
+		outcome := "ok"
+		switch {
+		case err != nil:
+			outcome = "error: " + err.Error()
+		case result != nil && result.IsError:
+			outcome = "tool error"
+		}
+		a.record(auditEvent{
+			Event:     "tool.call",
+			Identity:  CallerIdentity(ctx),
+			Tool:      req.Params.Name,
+			Arguments: req.GetArguments(),
+			Detail:    outcome,
+		})
+		return result, err
+	}
+}
@@ -0,0 +1,91 @@
+package application
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuditLog_RedactsPasswords(t *testing.T) {
+	auditPath := filepath.Join(t.TempDir(), "audit.log")
+	audit, err := newAuditLog(auditPath)
+	require.NoError(t, err)
+	handler := audit.middleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		return mcp.NewToolResultText("ok"), nil
+	})
+	req := mcp.CallToolRequest{}
+	req.Params.Name = "login"
+	arguments := map[string]any{"user": "admin", "password": "hunter2"}
+	req.Params.Arguments = arguments
+
+	_, err = handler(context.Background(), req)
+	require.NoError(t, err)
+
+	line, err := os.ReadFile(auditPath)
+	require.NoError(t, err)
+	assert.Contains(t, string(line), `"arguments":{"password":"[redacted]","user":"admin"}`)
+	assert.NotContains(t, string(line), "hunter2")
+	assert.Equal(t, "hunter2", arguments["password"], "the call keeps its arguments")
+}
+
+func TestRedactArguments(t *testing.T) {
+	tests := []struct {
+		name      string
+		tool      string
+		arguments map[string]any
+		want      map[string]any
+	}{
+		{
+			name:      "password flag",
+			tool:      "login",
+			arguments: map[string]any{"user": "admin", "password": "hunter2"},
+			want:      map[string]any{"user": "admin", "password": "[redacted]"},
+		},
+		{
+			name:      "secret content",
+			tool:      "add-secret",
+			arguments: map[string]any{"args": []any{"db-pass", "password=hunter2", "user=admin"}, "info": "database"},
+			want:      map[string]any{"args": []any{"db-pass", "password=[redacted]", "user=[redacted]"}, "info": "database"},
+		},
+		{
+			name:      "secret update",
+			tool:      "update-secret",
+			arguments: map[string]any{"args": []any{"secret:9m4e2mr0ui3e8a215n4g", "token=abc"}},
+			want:      map[string]any{"args": []any{"secret:9m4e2mr0ui3e8a215n4g", "token=[redacted]"}},
+		},
+		{
+			name:      "secret backend configuration",
+			tool:      "add-secret-backend",
+			arguments: map[string]any{"args": []any{"myvault", "vault", "endpoint=https://vault:8200", "token=s.abc"}},
+			want:      map[string]any{"args": []any{"myvault", "vault", "endpoint=[redacted]", "token=[redacted]"}},
+		},
+		{
+			name:      "credential attributes",
+			tool:      "update-credential",
+			arguments: map[string]any{"args": []any{"aws", "ops", "secret-key=abc"}},
+			want:      map[string]any{"args": []any{"aws", "ops", "secret-key=[redacted]"}},
+		},
+		{
+			name:      "registration string",
+			tool:      "register",
+			arguments: map[string]any{"args": []any{"MFATA3JvZDAyMBYTFDEwLjEzOC4zOS4xNTk6MTcwNzA="}},
+			want:      map[string]any{"args": []any{"[redacted]"}},
+		},
+		{
+			name:      "other commands",
+			tool:      "config",
+			arguments: map[string]any{"args": []any{"mysql", "profile=testing"}},
+			want:      map[string]any{"args": []any{"mysql", "profile=testing"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, redactArguments(tt.tool, tt.arguments))
+		})
+	}
+}
@@ -0,0 +1,36 @@
+package application
+
+import (
+	"context"
+	"net/http"
+)
+
+const (
+	// LocalIdentity identifies callers on the stdio transport, which is
+	// always the user that started the server process.
+	LocalIdentity = "local"
+	// AnonymousIdentity identifies HTTP callers that did not present a
+	// client certificate.
+	AnonymousIdentity = "anonymous"
+)
+
+type identityContextKey struct{}
+
+// withCallerIdentity is an HTTPContextFunc that records who is calling. With
+// mutual TLS the verified client certificate subject is used, otherwise the
+// caller is anonymous.
+func withCallerIdentity(ctx context.Context, r *http.Request) context.Context {
+	identity := AnonymousIdentity
+	if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+		identity = r.TLS.VerifiedChains[0][0].Subject.String()
+	}
+	return context.WithValue(ctx, identityContextKey{}, identity)
+}
+
+// CallerIdentity returns the identity of the MCP client making the request.
+func CallerIdentity(ctx context.Context) string {
+	if identity, ok := ctx.Value(identityContextKey{}).(string); ok {
+		return identity
+	}
+	return LocalIdentity
+}
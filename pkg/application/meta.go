@@ -0,0 +1,210 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/jneo8/mcp-juju/pkg/jujuadapter"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+)
+
+// Meta-tools replace the selected tools when meta-tools mode is enabled, so
+// clients list three small schemas instead of one per Juju command.
+const (
+	searchCommandsToolName  = "juju-search-commands"
+	describeCommandToolName = "juju-describe-command"
+	runCommandToolName      = "juju-run-command"
+
+	defaultSearchLimit = 10
+)
+
+func isMetaTool(name string) bool {
+	switch name {
+	case searchCommandsToolName, describeCommandToolName, runCommandToolName:
+		return true
+	}
+	return false
+}
+
+// catalogEntry is a selected tool that is reachable through the meta-tools
+// rather than registered directly.
+type catalogEntry struct {
+	tool server.ServerTool
+	doc  string
+}
+
+// collectCatalog gathers the selected tools and their documentation for the
+// meta-tools to search, describe and run.
+func collectCatalog(adapter jujuadapter.Adapter, tools []server.ServerTool) (map[string]catalogEntry, error) {
+	catalog := make(map[string]catalogEntry, len(tools))
+	for _, tool := range tools {
+		doc, err := adapter.GetToolDoc(tool.Tool.Name)
+		if err != nil {
+			return nil, err
+		}
+		catalog[tool.Tool.Name] = catalogEntry{tool: tool, doc: doc}
+	}
+	return catalog, nil
+}
+
+func (a *application) metaTools() []server.ServerTool {
+	return []server.ServerTool{
+		{
+			Tool: mcp.NewTool(searchCommandsToolName,
+				mcp.WithDescription("Search the available Juju commands by what you want to do, for example \"scale an application\" or \"show unit logs\". "+
+					"Returns the best matching command names with a short description; use "+describeCommandToolName+" for the arguments of one."),
+				mcp.WithString("query", mcp.Required(), mcp.Description("Words describing the task")),
+				mcp.WithNumber("limit", mcp.Description("Maximum number of commands to return"), mcp.DefaultNumber(defaultSearchLimit)),
+				mcp.WithReadOnlyHintAnnotation(true),
+			),
+			Handler: a.handleSearchCommands,
+		},
+		{
+			Tool: mcp.NewTool(describeCommandToolName,
+				mcp.WithDescription("Show the input schema and documentation of a Juju command, to call it with "+runCommandToolName+"."),
+				mcp.WithString("command", mcp.Required(), mcp.Description("Command name, as returned by "+searchCommandsToolName)),
+				mcp.WithReadOnlyHintAnnotation(true),
+			),
+			Handler: a.handleDescribeCommand,
+		},
+		{
+			Tool: mcp.NewTool(runCommandToolName,
+				mcp.WithDescription("Run a Juju command with arguments matching the input schema from "+describeCommandToolName+". "+
+					"The call is subject to the same rate limits, approvals and audit as calling the command directly."),
+				mcp.WithString("command", mcp.Required(), mcp.Description("Command name")),
+				mcp.WithObject("arguments", mcp.Description("Arguments of the command, such as {\"args\": [\"mysql\"], \"model\": \"prod\"}")),
+				mcp.WithDestructiveHintAnnotation(true),
+			),
+			Handler: a.handleRunCommand,
+		},
+	}
+}
+
+// lookupCommand returns a selected tool. Tools left out of the selection are
+// unknown here just as they are to direct calls.
+func (a *application) lookupCommand(name string) (catalogEntry, bool) {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	entry, ok := a.registered.catalog[name]
+	return entry, ok
+}
+
+func unknownCommandResult(name string) *mcp.CallToolResult {
+	return mcp.NewToolResultError(fmt.Sprintf("unknown command %q: use %s to find the available commands", name, searchCommandsToolName))
+}
+
+// commandMatch is a search result.
+type commandMatch struct {
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	score       int
+}
+
+func (a *application) handleSearchCommands(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	query, err := req.RequireString("query")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	limit := req.GetInt("limit", defaultSearchLimit)
+
+	a.mu.RLock()
+	matches := searchCatalog(a.registered.catalog, query)
+	a.mu.RUnlock()
+	if len(matches) == 0 {
+		return mcp.NewToolResultText(fmt.Sprintf("No commands match %q; try other words.", query)), nil
+	}
+	if limit > 0 && len(matches) > limit {
+		matches = matches[:limit]
+	}
+	body, err := json.Marshal(map[string]any{"commands": matches})
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// searchCatalog ranks the tools by how many query words appear in their
+// name, description and documentation, the name counting most.
+func searchCatalog(catalog map[string]catalogEntry, query string) []commandMatch {
+	terms := strings.FieldsFunc(strings.ToLower(query), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var matches []commandMatch
+	for name, entry := range catalog {
+		description := strings.ToLower(entry.tool.Tool.Description)
+		doc := strings.ToLower(entry.doc)
+		score := 0
+		for _, term := range terms {
+			switch {
+			case strings.Contains(name, term):
+				score += 5
+			case strings.Contains(description, term):
+				score += 2
+			case strings.Contains(doc, term):
+				score++
+			}
+		}
+		if score > 0 {
+			summary, _, _ := strings.Cut(entry.tool.Tool.Description, "\n")
+			matches = append(matches, commandMatch{Name: name, Description: summary, score: score})
+		}
+	}
+	sort.Slice(matches, func(i, j int) bool {
+		if matches[i].score != matches[j].score {
+			return matches[i].score > matches[j].score
+		}
+		return matches[i].Name < matches[j].Name
+	})
+	return matches
+}
+
+func (a *application) handleDescribeCommand(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := req.RequireString("command")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	entry, ok := a.lookupCommand(name)
+	if !ok {
+		return unknownCommandResult(name), nil
+	}
+
+	// Show the same blocked annotations a direct tools/list would.
+	tool := a.adapter.AnnotateBlockedTools(ctx, []mcp.Tool{entry.tool.Tool})[0]
+	body, err := json.Marshal(struct {
+		Tool          mcp.Tool `json:"tool"`
+		Documentation string   `json:"documentation"`
+	}{tool, entry.doc})
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(body)), nil
+}
+
+// handleRunCommand calls the selected tool as if it had been called
+// directly, through the audit, rate limit and approval policies.
+func (a *application) handleRunCommand(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	name, err := req.RequireString("command")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	entry, ok := a.lookupCommand(name)
+	if !ok {
+		return unknownCommandResult(name), nil
+	}
+	arguments := map[string]any{}
+	if raw, ok := req.GetArguments()["arguments"]; ok && raw != nil {
+		if arguments, ok = raw.(map[string]any); !ok {
+			return mcp.NewToolResultError("arguments must be an object"), nil
+		}
+	}
+
+	call := req
+	call.Params.Name = a.adapter.CommandName(name)
+	call.Params.Arguments = arguments
+	return a.withPolicies(entry.tool.Handler)(a.withStepPolicies(ctx), call)
+}
@@ -0,0 +1,151 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/jneo8/mcp-juju/config"
+	mockjujuadapter "github.com/jneo8/mcp-juju/mocks/jujuadapter"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+var metaTestTools = map[string]string{
+	"status":            "Report the status of the model, its machines, applications and units.",
+	"scale-application": "Set the desired number of k8s application units.",
+	"destroy-model":     "Terminate all machines and resources of a model.",
+}
+
+// newMetaApplication returns an application in meta-tools mode whose tools
+// echo the arguments they were called with.
+func newMetaApplication(t *testing.T, cfg config.Config) *application {
+	names := []string{"status", "scale-application", "destroy-model"}
+	mockAdapter := mockjujuadapter.NewMockAdapter(t)
+	mockAdapter.EXPECT().ToolNames().Return(names)
+	mockAdapter.EXPECT().GetTool(mock.Anything).RunAndReturn(func(name string) (*mcp.Tool, server.ToolHandlerFunc, error) {
+		tool := mcp.NewTool(name, mcp.WithDescription(metaTestTools[name]), mcp.WithString("model"))
+		handler := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultText(fmt.Sprintf("%s %v", req.Params.Name, req.GetArguments())), nil
+		}
+		return &tool, handler, nil
+	})
+	mockAdapter.EXPECT().GetToolDoc(mock.Anything).RunAndReturn(func(name string) (string, error) {
+		return "# " + name + "\n\n" + metaTestTools[name], nil
+	})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{})
+	mockAdapter.EXPECT().CommandName(mock.Anything).RunAndReturn(func(name string) string { return name }).Maybe()
+	mockAdapter.EXPECT().AnnotateBlockedTools(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, tools []mcp.Tool) []mcp.Tool { return tools }).Maybe()
+
+	cfg.ServerType = config.ServerTypeStdio
+	cfg.MetaTools = true
+	app, err := NewApplication(cfg, mockAdapter)
+	require.NoError(t, err)
+	return app.(*application)
+}
+
+func callServerTool(t *testing.T, s *server.MCPServer, name string, args map[string]any) *mcp.CallToolResult {
+	message, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "tools/call",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	require.NoError(t, err)
+	resp, ok := s.HandleMessage(context.Background(), message).(mcp.JSONRPCResponse)
+	require.True(t, ok, "tools/call failed")
+	result, ok := resp.Result.(mcp.CallToolResult)
+	require.True(t, ok)
+	return &result
+}
+
+func resultText(t *testing.T, result *mcp.CallToolResult) string {
+	require.Len(t, result.Content, 1)
+	text, ok := result.Content[0].(mcp.TextContent)
+	require.True(t, ok)
+	return text.Text
+}
+
+func TestMetaTools_ReplaceSelectedTools(t *testing.T) {
+	a := newMetaApplication(t, config.Config{})
+
+	assert.ElementsMatch(t, []string{searchCommandsToolName, describeCommandToolName, runCommandToolName}, listToolNames(t, a.mcpServer))
+}
+
+func TestMetaTools_Search(t *testing.T) {
+	a := newMetaApplication(t, config.Config{})
+
+	result := callServerTool(t, a.mcpServer, searchCommandsToolName, map[string]any{"query": "scale units"})
+
+	var found struct {
+		Commands []commandMatch `json:"commands"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &found))
+	require.Len(t, found.Commands, 2)
+	assert.Equal(t, "scale-application", found.Commands[0].Name, "matching the name ranks first")
+	assert.Equal(t, "status", found.Commands[1].Name)
+}
+
+func TestMetaTools_Describe(t *testing.T) {
+	a := newMetaApplication(t, config.Config{})
+
+	result := callServerTool(t, a.mcpServer, describeCommandToolName, map[string]any{"command": "status"})
+
+	var described struct {
+		Tool          mcp.Tool `json:"tool"`
+		Documentation string   `json:"documentation"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(t, result)), &described))
+	assert.Equal(t, "status", described.Tool.Name)
+	assert.Contains(t, described.Tool.InputSchema.Properties, "model")
+	assert.Contains(t, described.Documentation, "# status")
+}
+
+func TestMetaTools_RejectUnselectedCommands(t *testing.T) {
+	a := newMetaApplication(t, config.Config{})
+
+	for _, tool := range []string{describeCommandToolName, runCommandToolName} {
+		result := callServerTool(t, a.mcpServer, tool, map[string]any{"command": "kill-controller"})
+
+		assert.True(t, result.IsError)
+		assert.Contains(t, resultText(t, result), `unknown command "kill-controller"`)
+	}
+}
+
+func TestMetaTools_RunAppliesPolicies(t *testing.T) {
+	// Arrange
+	a := newMetaApplication(t, config.Config{
+		RateLimits:       []string{"tool:status=1/1m"},
+		ApprovalTools:    []string{"destroy-*"},
+		ApprovalMode:     config.ApprovalModeAsync,
+		ApprovalTimeout:  time.Minute,
+		ApprovalsAddress: config.DefaultApprovalsAddress,
+	})
+	run := func(command string) *mcp.CallToolResult {
+		return callServerTool(t, a.mcpServer, runCommandToolName, map[string]any{
+			"command":   command,
+			"arguments": map[string]any{"model": "prod"},
+		})
+	}
+
+	// Act
+	first := run("status")
+	second := run("status")
+	destroy := run("destroy-model")
+
+	// Assert
+	assert.Equal(t, "status map[model:prod]", resultText(t, first))
+	assert.True(t, second.IsError)
+	assert.Contains(t, resultText(t, second), "rate_limited")
+	assert.Contains(t, resultText(t, destroy), `"status":"pending"`)
+	pending := a.approvals.list(ApprovalPending)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "destroy-model", pending[0].Tool)
+}
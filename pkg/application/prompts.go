@@ -0,0 +1,225 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/yosida95/uritemplate/v3"
+	"gopkg.in/yaml.v3"
+)
+
+// promptTemplate is a parameterized prompt. Built-in prompts and those
+// loaded from the prompts directory share this format:
+//
+//	name: restart-unit
+//	description: Restart a unit safely
+//	arguments:
+//	  - name: unit
+//	    required: true
+//	  - name: model
+//	    required: true
+//	resources:
+//	  - juju://status/{model}
+//	template: |
+//	  Restart {{.unit}} in model {{.model}} ...
+//
+// The template is a Go text/template over the arguments. Resources are URI
+// templates expanded with the same arguments; their current contents are
+// embedded in the prompt.
+type promptTemplate struct {
+	Name        string           `yaml:"name"`
+	Description string           `yaml:"description"`
+	Arguments   []promptArgument `yaml:"arguments"`
+	Resources   []string         `yaml:"resources"`
+	Template    string           `yaml:"template"`
+
+	text      *template.Template
+	resources []*uritemplate.Template
+}
+
+type promptArgument struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Required    bool   `yaml:"required"`
+}
+
+// compile parses the text and resource templates.
+func (p *promptTemplate) compile() error {
+	if p.Name == "" {
+		return errors.New("prompt has no name")
+	}
+	if strings.TrimSpace(p.Template) == "" {
+		return fmt.Errorf("prompt %s has no template", p.Name)
+	}
+	text, err := template.New(p.Name).Option("missingkey=zero").Parse(p.Template)
+	if err != nil {
+		return fmt.Errorf("prompt %s: %w", p.Name, err)
+	}
+	p.text = text
+	p.resources = nil
+	for _, raw := range p.Resources {
+		resource, err := uritemplate.New(raw)
+		if err != nil {
+			return fmt.Errorf("prompt %s: invalid resource %q: %w", p.Name, raw, err)
+		}
+		p.resources = append(p.resources, resource)
+	}
+	return nil
+}
+
+func (p *promptTemplate) prompt() mcp.Prompt {
+	opts := []mcp.PromptOption{mcp.WithPromptDescription(p.Description)}
+	for _, arg := range p.Arguments {
+		argOpts := []mcp.ArgumentOption{mcp.ArgumentDescription(arg.Description)}
+		if arg.Required {
+			argOpts = append(argOpts, mcp.RequiredArgument())
+		}
+		opts = append(opts, mcp.WithArgument(arg.Name, argOpts...))
+	}
+	return mcp.NewPrompt(p.Name, opts...)
+}
+
+// collectPrompts returns the built-in prompts overridden or extended by
+// those of the prompts directory.
+func (a *application) collectPrompts(dir string) ([]server.ServerPrompt, error) {
+	templates := builtinPrompts()
+	if dir != "" {
+		loaded, err := loadPrompts(dir)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, loaded...)
+	}
+
+	byName := make(map[string]*promptTemplate, len(templates))
+	for _, p := range templates {
+		if err := p.compile(); err != nil {
+			return nil, err
+		}
+		byName[p.Name] = p
+	}
+	names := make([]string, 0, len(byName))
+	for name := range byName {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	prompts := make([]server.ServerPrompt, 0, len(names))
+	for _, name := range names {
+		p := byName[name]
+		prompts = append(prompts, server.ServerPrompt{
+			Prompt: p.prompt(),
+			Handler: func(ctx context.Context, req mcp.GetPromptRequest) (*mcp.GetPromptResult, error) {
+				return a.renderPrompt(ctx, p, req.Params.Arguments)
+			},
+		})
+	}
+	return prompts, nil
+}
+
+// loadPrompts reads every .yaml and .yml file of the directory as a prompt.
+func loadPrompts(dir string) ([]*promptTemplate, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read prompts directory: %w", err)
+	}
+	var prompts []*promptTemplate
+	for _, entry := range entries {
+		ext := filepath.Ext(entry.Name())
+		if entry.IsDir() || (ext != ".yaml" && ext != ".yml") {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		p := &promptTemplate{}
+		if err := yaml.Unmarshal(data, p); err != nil {
+			return nil, fmt.Errorf("unable to parse prompt %s: %w", path, err)
+		}
+		if p.Name == "" {
+			p.Name = strings.TrimSuffix(entry.Name(), ext)
+		}
+		prompts = append(prompts, p)
+	}
+	return prompts, nil
+}
+
+func (a *application) renderPrompt(ctx context.Context, p *promptTemplate, args map[string]string) (*mcp.GetPromptResult, error) {
+	values := uritemplate.Values{}
+	for _, arg := range p.Arguments {
+		value := args[arg.Name]
+		if value == "" && arg.Required {
+			return nil, fmt.Errorf("prompt %s requires the %s argument", p.Name, arg.Name)
+		}
+		if value != "" {
+			values.Set(arg.Name, uritemplate.String(value))
+		}
+	}
+
+	var text strings.Builder
+	if err := p.text.Execute(&text, args); err != nil {
+		return nil, fmt.Errorf("unable to render prompt %s: %w", p.Name, err)
+	}
+	messages := []mcp.PromptMessage{
+		mcp.NewPromptMessage(mcp.RoleUser, mcp.NewTextContent(strings.TrimSpace(text.String()))),
+	}
+	for _, resource := range p.resources {
+		uri, err := resource.Expand(values)
+		if err != nil {
+			return nil, fmt.Errorf("unable to expand resource %s: %w", resource.Raw(), err)
+		}
+		messages = append(messages, mcp.NewPromptMessage(mcp.RoleUser, a.embedResource(ctx, uri)))
+	}
+	return mcp.NewGetPromptResult(p.Description, messages), nil
+}
+
+// embedResource reads a resource the way a client would. A resource that
+// cannot be read, for example because the controller is unreachable, is
+// replaced by a note so the rest of the prompt is still usable.
+func (a *application) embedResource(ctx context.Context, uri string) mcp.Content {
+	contents, err := a.readResource(ctx, uri)
+	if err != nil {
+		return mcp.NewTextContent(fmt.Sprintf("Resource %s could not be read: %s", uri, err))
+	}
+	if len(contents) == 0 {
+		return mcp.NewTextContent(fmt.Sprintf("Resource %s is empty.", uri))
+	}
+	return mcp.NewEmbeddedResource(contents[0])
+}
+
+// readResource reads a registered resource or resource template through the
+// MCP server, so prompts see exactly what clients do.
+func (a *application) readResource(ctx context.Context, uri string) ([]mcp.ResourceContents, error) {
+	message, err := json.Marshal(map[string]any{
+		"jsonrpc": mcp.JSONRPC_VERSION,
+		"id":      0,
+		"method":  string(mcp.MethodResourcesRead),
+		"params":  map[string]any{"uri": uri},
+	})
+	if err != nil {
+		return nil, err
+	}
+	switch resp := a.mcpServer.HandleMessage(ctx, message).(type) {
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.ReadResourceResult)
+		if !ok {
+			return nil, fmt.Errorf("unexpected result %T", resp.Result)
+		}
+		return result.Contents, nil
+	case mcp.JSONRPCError:
+		return nil, errors.New(resp.Error.Message)
+	default:
+		return nil, fmt.Errorf("unexpected response %T", resp)
+	}
+}
@@ -0,0 +1,133 @@
+package application
+
+// builtinPrompts returns the prompts for common operational workflows. A
+// prompt of the same name in the prompts directory replaces the built-in one.
+func builtinPrompts() []*promptTemplate {
+	model := promptArgument{Name: "model", Description: "Model name, such as admin/prod", Required: true}
+	return []*promptTemplate{
+		{
+			Name:        "troubleshoot-application",
+			Description: "Troubleshoot an application that is not healthy",
+			Arguments: []promptArgument{
+				{Name: "application", Description: "Application name", Required: true},
+				model,
+			},
+			Resources: []string{
+				"juju://status/{model}/{application}",
+				"juju://debug-log/{model}/{application}",
+				"juju://config/{application}{?model}",
+			},
+			Template: `Troubleshoot the application {{.application}} in the model {{.model}}.
+The current status, the latest debug log lines and the configuration of the
+application are attached.
+
+1. Summarise the workload and agent status of every unit and flag those that
+   are not active and idle.
+2. Look for errors and tracebacks in the debug log and relate them to the
+   units they come from.
+3. Check the configuration for values that could explain the problem.
+4. Propose a fix, starting with the least disruptive option. Prefer read-only
+   commands (status-history, show-unit, show-application) to gather more
+   detail before suggesting changes, and explain what each change does before
+   making it.`,
+		},
+		{
+			Name:        "deploy-bundle",
+			Description: "Deploy a bundle safely, previewing the changes first",
+			Arguments: []promptArgument{
+				{Name: "bundle", Description: "Bundle name in Charmhub or path of a bundle file", Required: true},
+				model,
+			},
+			Resources: []string{"juju://status/{model}"},
+			Template: `Deploy the bundle {{.bundle}} to the model {{.model}} safely.
+The current status of the model is attached.
+
+1. Run diff-bundle against the model and summarise what would be added,
+   changed or removed. Stop and ask for confirmation if anything would be
+   removed or an existing application reconfigured.
+2. Check disabled-commands for blocks on the model.
+3. Run deploy with --dry-run and confirm the plan matches the diff.
+4. Deploy the bundle, then use wait-for to wait until every application of
+   the bundle is active, and report any unit that does not settle.`,
+		},
+		{
+			Name:        "upgrade-model",
+			Description: "Upgrade the agents of a model, checking its health before and after",
+			Arguments: []promptArgument{
+				model,
+				{Name: "version", Description: "Target agent version, the latest available if empty"},
+			},
+			Resources: []string{"juju://status/{model}"},
+			Template: `Upgrade the model {{.model}}{{if .version}} to agent version {{.version}}{{end}}.
+The current status of the model is attached.
+
+1. Check that every application and unit is healthy; do not upgrade a model
+   with units in error, and list them instead.
+2. Compare the model and controller versions and confirm the target version
+   is supported by the controller.
+3. Run upgrade-model with --dry-run{{if .version}} --agent-version {{.version}}{{end}} and show the result.
+4. After confirmation, run the upgrade, then wait for the agents to report
+   the new version and for the units to settle. Report anything that did not
+   come back.`,
+		},
+		{
+			Name:        "investigate-unit-error",
+			Description: "Find out why a unit is in an error state",
+			Arguments: []promptArgument{
+				{Name: "unit", Description: "Unit name, such as mysql/0", Required: true},
+				model,
+			},
+			Resources: []string{
+				"juju://status/{model}",
+				"juju://debug-log/{model}/{unit}",
+			},
+			Template: `Investigate why the unit {{.unit}} in the model {{.model}} is in error.
+The status of the model and the latest debug log lines of the unit are
+attached.
+
+1. Identify the failing hook or action and the error message.
+2. Use show-unit and status-history for the unit to see when the problem
+   started and what changed around it.
+3. Look for the root cause in the log, such as a missing relation, bad
+   configuration or a failing workload.
+4. Suggest a fix. Only suggest resolved once the cause is addressed, and say
+   whether the hook should be retried.`,
+		},
+		{
+			Name:        "review-cross-model-relations",
+			Description: "Review the offers, consumed applications and cross-model relations of a model",
+			Arguments:   []promptArgument{model},
+			Resources:   []string{"juju://status/{model}"},
+			Template: `Review the cross-model relations of the model {{.model}}.
+The status of the model, including its offers, remote applications and
+relations, is attached.
+
+1. List the offers of the model with their endpoints and connections, using
+   list-endpoints for details.
+2. List the remote applications the model consumes and the relations to
+   them.
+3. Flag relations that are not active, suspended relations and offers that
+   nobody consumes.
+4. Recommend clean-ups and access changes, explaining the impact of each
+   before making it.`,
+		},
+		{
+			Name:        "rotate-secret",
+			Description: "Rotate a user secret and check that its consumers pick up the new revision",
+			Arguments: []promptArgument{
+				{Name: "id", Description: "Secret ID or name", Required: true},
+				model,
+			},
+			Resources: []string{"juju://status/{model}"},
+			Template: `Rotate the secret {{.id}} in the model {{.model}}.
+The status of the model is attached.
+
+1. Use show-secret without revealing the content to find the current
+   revision, its owner and the applications it is granted to.
+2. Ask for the new content, or how to generate it; never print secret values.
+3. Update the secret with update-secret and confirm a new revision exists.
+4. Check that the applications the secret is granted to settle, and report
+   any that end up in error.`,
+		},
+	}
+}
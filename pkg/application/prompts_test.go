@@ -0,0 +1,170 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jneo8/mcp-juju/config"
+	mockjujuadapter "github.com/jneo8/mcp-juju/mocks/jujuadapter"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newPromptApplication returns an application with a status resource
+// template that echoes the URI it was read with.
+func newPromptApplication(t *testing.T, cfg config.Config) *application {
+	mockAdapter := mockjujuadapter.NewMockAdapter(t)
+	mockAdapter.EXPECT().ToolNames().Return([]string{})
+	mockAdapter.EXPECT().ToolDocResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{"juju-status-template"})
+	mockAdapter.EXPECT().GetResourceTemplate("juju-status-template").RunAndReturn(func(string) (*mcp.ResourceTemplate, server.ResourceTemplateHandlerFunc, error) {
+		template := mcp.NewResourceTemplate("juju://status/{model}{/application}", "juju-status-template")
+		handler := func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+			return []mcp.ResourceContents{mcp.TextResourceContents{URI: req.Params.URI, MIMEType: "application/json", Text: `{"model":"prod"}`}}, nil
+		}
+		return &template, handler, nil
+	})
+	mockAdapter.EXPECT().AnnotateBlockedTools(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, tools []mcp.Tool) []mcp.Tool { return tools }).Maybe()
+
+	cfg.ServerType = config.ServerTypeStdio
+	app, err := NewApplication(cfg, mockAdapter)
+	require.NoError(t, err)
+	return app.(*application)
+}
+
+func getPrompt(t *testing.T, s *server.MCPServer, name string, args map[string]string) (*mcp.GetPromptResult, *mcp.JSONRPCError) {
+	message, err := json.Marshal(map[string]any{
+		"jsonrpc": "2.0",
+		"id":      1,
+		"method":  "prompts/get",
+		"params":  map[string]any{"name": name, "arguments": args},
+	})
+	require.NoError(t, err)
+	switch resp := s.HandleMessage(context.Background(), message).(type) {
+	case mcp.JSONRPCResponse:
+		result, ok := resp.Result.(mcp.GetPromptResult)
+		require.True(t, ok, "unexpected result %T", resp.Result)
+		return &result, nil
+	case mcp.JSONRPCError:
+		return nil, &resp
+	default:
+		t.Fatalf("unexpected response %T", resp)
+		return nil, nil
+	}
+}
+
+func listPromptNames(t *testing.T, s *server.MCPServer) []string {
+	resp, ok := s.HandleMessage(context.Background(), []byte(`{"jsonrpc":"2.0","id":1,"method":"prompts/list"}`)).(mcp.JSONRPCResponse)
+	require.True(t, ok, "prompts/list failed")
+	result, ok := resp.Result.(mcp.ListPromptsResult)
+	require.True(t, ok, "unexpected result %T", resp.Result)
+	var names []string
+	for _, p := range result.Prompts {
+		names = append(names, p.Name)
+	}
+	return names
+}
+
+func TestPrompts_ListBuiltins(t *testing.T) {
+	a := newPromptApplication(t, config.Config{})
+
+	names := listPromptNames(t, a.mcpServer)
+
+	assert.Equal(t, []string{
+		"deploy-bundle",
+		"investigate-unit-error",
+		"review-cross-model-relations",
+		"rotate-secret",
+		"troubleshoot-application",
+		"upgrade-model",
+	}, names)
+}
+
+func TestPrompts_RenderEmbedsResources(t *testing.T) {
+	a := newPromptApplication(t, config.Config{})
+
+	result, rpcErr := getPrompt(t, a.mcpServer, "upgrade-model", map[string]string{"model": "admin/prod", "version": "3.6.8"})
+
+	require.Nil(t, rpcErr)
+	require.Len(t, result.Messages, 2)
+	text, ok := result.Messages[0].Content.(mcp.TextContent)
+	require.True(t, ok)
+	assert.Contains(t, text.Text, "Upgrade the model admin/prod to agent version 3.6.8.")
+	assert.Contains(t, text.Text, "--agent-version 3.6.8")
+	embedded, ok := result.Messages[1].Content.(mcp.EmbeddedResource)
+	require.True(t, ok)
+	contents, ok := embedded.Resource.(mcp.TextResourceContents)
+	require.True(t, ok)
+	assert.Equal(t, "juju://status/admin%2Fprod", contents.URI)
+	assert.Equal(t, `{"model":"prod"}`, contents.Text)
+}
+
+func TestPrompts_UnreadableResourceBecomesNote(t *testing.T) {
+	a := newPromptApplication(t, config.Config{})
+
+	result, rpcErr := getPrompt(t, a.mcpServer, "troubleshoot-application", map[string]string{"model": "prod", "application": "mysql"})
+
+	require.Nil(t, rpcErr)
+	require.Len(t, result.Messages, 4)
+	note, ok := result.Messages[2].Content.(mcp.TextContent)
+	require.True(t, ok, "the debug-log template is not registered")
+	assert.Contains(t, note.Text, "Resource juju://debug-log/prod/mysql could not be read")
+}
+
+func TestPrompts_RequireArguments(t *testing.T) {
+	a := newPromptApplication(t, config.Config{})
+
+	_, rpcErr := getPrompt(t, a.mcpServer, "investigate-unit-error", map[string]string{"model": "prod"})
+
+	require.NotNil(t, rpcErr)
+	assert.Contains(t, rpcErr.Error.Message, "prompt investigate-unit-error requires the unit argument")
+}
+
+func TestPrompts_LoadDirectory(t *testing.T) {
+	// Arrange
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "restart-unit.yaml"), []byte(`
+description: Restart a unit
+arguments:
+  - name: unit
+    required: true
+template: Restart {{.unit}}.
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "rotate.yml"), []byte(`
+name: rotate-secret
+description: Rotate a secret the local way
+template: Use the runbook.
+`), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("not a prompt"), 0o600))
+
+	// Act
+	a := newPromptApplication(t, config.Config{PromptsDir: dir})
+	restart, restartErr := getPrompt(t, a.mcpServer, "restart-unit", map[string]string{"unit": "mysql/0"})
+	rotate, rotateErr := getPrompt(t, a.mcpServer, "rotate-secret", nil)
+
+	// Assert
+	assert.Contains(t, listPromptNames(t, a.mcpServer), "restart-unit")
+	require.Nil(t, restartErr)
+	assert.Equal(t, mcp.TextContent{Type: "text", Text: "Restart mysql/0."}, restart.Messages[0].Content)
+	require.Nil(t, rotateErr)
+	assert.Equal(t, "Rotate a secret the local way", rotate.Description)
+	assert.Len(t, rotate.Messages, 1)
+}
+
+func TestPrompts_RejectInvalidTemplate(t *testing.T) {
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "broken.yaml"), []byte("template: \"{{.unit\"\n"), 0o600))
+	a := &application{}
+
+	_, err := a.collectPrompts(dir)
+
+	assert.ErrorContains(t, err, "prompt broken")
+}
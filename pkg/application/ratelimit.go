@@ -0,0 +1,296 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/jneo8/mcp-juju/pkg/jujuadapter"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/time/rate"
+)
+
+// concurrencyRetryAfter is suggested to callers rejected by a concurrency
+// cap, since there is no way to know when an in-flight call finishes.
+const concurrencyRetryAfter = 5 * time.Second
+
+// rateLimiter enforces token bucket rate limits and concurrency caps on tool
+// calls before they reach the adapter.
+type rateLimiter struct {
+	mu       sync.Mutex
+	rules    []config.RateLimit
+	caps     map[string]int
+	buckets  map[string]*rate.Limiter
+	inFlight map[string]int
+
+	currentController func() (string, error)
+	now               func() time.Time
+}
+
+func newRateLimiter(cfg config.Config, currentController func() (string, error)) (*rateLimiter, error) {
+	l := &rateLimiter{
+		currentController: currentController,
+		now:               time.Now,
+	}
+	if err := l.update(cfg); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// update replaces the rules and caps. Existing buckets are dropped so the new
+// limits apply immediately; in-flight counters are kept.
+func (l *rateLimiter) update(cfg config.Config) error {
+	rules, err := cfg.RateLimitRules()
+	if err != nil {
+		return err
+	}
+	caps, err := cfg.ConcurrencyCaps()
+	if err != nil {
+		return err
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.rules = rules
+	l.caps = caps
+	l.buckets = make(map[string]*rate.Limiter)
+	if l.inFlight == nil {
+		l.inFlight = make(map[string]int)
+	}
+	return nil
+}
+
+func (l *rateLimiter) middleware(next server.ToolHandlerFunc) server.ToolHandlerFunc {
+	return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		tool := req.Params.Name
+		release, ok := l.acquire(tool)
+		if !ok {
+			log.Warn().Str("tool", tool).Msg("Concurrency limit reached")
+			return rateLimitedResult("concurrency:"+tool, concurrencyRetryAfter), nil
+		}
+		defer release()
+
+		identity := CallerIdentity(ctx)
+		if rule, retryAfter, limited := l.reserve(identity, tool, l.controllerFor(req.GetArguments())); limited {
+			log.Warn().Str("tool", tool).Str("identity", identity).Str("rule", rule).Msg("Rate limit reached")
+			return rateLimitedResult(rule, retryAfter), nil
+		}
+		return next(ctx, req)
+	}
+}
+
+// resourceTools are the tools whose limits apply to reading resources under
+// juju://<name>, since both hit the controller the same way. Resources such
+// as the documentation or the workspace are served locally and not limited.
+var resourceTools = map[string]string{
+	"status":      string(jujuadapter.CmdStatus),
+	"config":      string(jujuadapter.CmdConfig),
+	"debug-log":   string(jujuadapter.CmdDebugLog),
+	"health":      jujuadapter.ModelHealthToolName,
+	"logs":        jujuadapter.LogsQueryToolName,
+	"status-diff": jujuadapter.StatusDiffToolName,
+	"topology":    jujuadapter.RelationGraphToolName,
+}
+
+// resourceTool returns the tool whose limits apply to reading a resource,
+// or "" if it is not limited.
+func resourceTool(uri string) string {
+	name, found := strings.CutPrefix(uri, "juju://")
+	if !found {
+		return ""
+	}
+	name, _, _ = strings.Cut(name, "/")
+	name, _, _ = strings.Cut(name, "?")
+	return resourceTools[name]
+}
+
+// resourceMiddleware applies the limits of the matching tool to resource
+// and resource template reads.
+func (l *rateLimiter) resourceMiddleware(next server.ResourceHandlerFunc) server.ResourceHandlerFunc {
+	return func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		tool := resourceTool(req.Params.URI)
+		if tool == "" {
+			return next(ctx, req)
+		}
+		release, ok := l.acquire(tool)
+		if !ok {
+			log.Warn().Str("resource", req.Params.URI).Msg("Concurrency limit reached")
+			return nil, rateLimitedErr("concurrency:"+tool, concurrencyRetryAfter)
+		}
+		defer release()
+
+		identity := CallerIdentity(ctx)
+		if rule, retryAfter, limited := l.reserve(identity, tool, l.controllerFor(req.Params.Arguments)); limited {
+			log.Warn().Str("resource", req.Params.URI).Str("identity", identity).Str("rule", rule).Msg("Rate limit reached")
+			return nil, rateLimitedErr(rule, retryAfter)
+		}
+		return next(ctx, req)
+	}
+}
+
+// acquire takes a concurrency slot for the tool, if it is capped.
+func (l *rateLimiter) acquire(tool string) (func(), bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	max, capped := l.caps[tool]
+	if !capped {
+		return func() {}, true
+	}
+	if l.inFlight[tool] >= max {
+		return nil, false
+	}
+	l.inFlight[tool]++
+	return func() {
+		l.mu.Lock()
+		defer l.mu.Unlock()
+		l.inFlight[tool]--
+	}, true
+}
+
+// reserve takes one token from every bucket that applies to the call. If any
+// bucket is empty, nothing is consumed and the longest wait is returned.
+func (l *rateLimiter) reserve(identity, tool, controller string) (string, time.Duration, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if len(l.rules) == 0 {
+		return "", 0, false
+	}
+
+	now := l.now()
+	keys := map[string]string{
+		config.RateLimitScopeIdentity:   identity,
+		config.RateLimitScopeTool:       tool,
+		config.RateLimitScopeController: controller,
+	}
+	var reservations []*rate.Reservation
+	var limitedBy string
+	var retryAfter time.Duration
+	for _, rule := range l.matchingRules(keys) {
+		bucketKey := rule.String() + "|" + keys[rule.Scope]
+		bucket, ok := l.buckets[bucketKey]
+		if !ok {
+			bucket = rate.NewLimiter(rate.Every(rule.Period/time.Duration(rule.Count)), rule.Count)
+			l.buckets[bucketKey] = bucket
+		}
+		r := bucket.ReserveN(now, 1)
+		reservations = append(reservations, r)
+		if delay := r.DelayFrom(now); delay > retryAfter {
+			retryAfter = delay
+			limitedBy = rule.String()
+		}
+	}
+	if retryAfter == 0 {
+		return "", 0, false
+	}
+	for _, r := range reservations {
+		r.CancelAt(now)
+	}
+	return limitedBy, retryAfter, true
+}
+
+// matchingRules returns the rules for each scope. Rules naming the key
+// explicitly take precedence over wildcard rules for the same scope.
+func (l *rateLimiter) matchingRules(keys map[string]string) []config.RateLimit {
+	var matched []config.RateLimit
+	for _, scope := range []string{config.RateLimitScopeIdentity, config.RateLimitScopeTool, config.RateLimitScopeController} {
+		var specific, wildcard []config.RateLimit
+		for _, rule := range l.rules {
+			if rule.Scope != scope {
+				continue
+			}
+			switch rule.Key {
+			case keys[scope]:
+				specific = append(specific, rule)
+			case config.RateLimitAnyKey:
+				wildcard = append(wildcard, rule)
+			}
+		}
+		if len(specific) > 0 {
+			matched = append(matched, specific...)
+		} else {
+			matched = append(matched, wildcard...)
+		}
+	}
+	return matched
+}
+
+// controllerFor works out which controller a call targets from its
+// "controller" or "controller:model" style "model" argument, falling back to
+// the current controller. It is only resolved when controller rules exist.
+func (l *rateLimiter) controllerFor(args map[string]any) string {
+	l.mu.Lock()
+	hasControllerRules := false
+	for _, rule := range l.rules {
+		if rule.Scope == config.RateLimitScopeController {
+			hasControllerRules = true
+			break
+		}
+	}
+	l.mu.Unlock()
+	if !hasControllerRules {
+		return ""
+	}
+
+	if controller, ok := args["controller"].(string); ok && controller != "" {
+		return controller
+	}
+	if model, ok := args["model"].(string); ok {
+		if controller, _, found := strings.Cut(model, ":"); found && controller != "" {
+			return controller
+		}
+	}
+	if l.currentController == nil {
+		return ""
+	}
+	controller, err := l.currentController()
+	if err != nil {
+		log.Debug().Err(err).Msg("Unable to determine current controller")
+		return ""
+	}
+	return controller
+}
+
+type rateLimitedError struct {
+	Error             string `json:"error"`
+	Message           string `json:"message"`
+	Limit             string `json:"limit"`
+	RetryAfterSeconds int    `json:"retry_after_seconds"`
+}
+
+// retryAfterSeconds rounds a wait up to whole seconds, at least one.
+func retryAfterSeconds(retryAfter time.Duration) int {
+	seconds := int(math.Ceil(retryAfter.Seconds()))
+	if seconds < 1 {
+		seconds = 1
+	}
+	return seconds
+}
+
+// rateLimitedErr is the error returned to limited resource reads, which
+// cannot return a tool result.
+func rateLimitedErr(limit string, retryAfter time.Duration) error {
+	return fmt.Errorf("rate limited by %s, retry after %d seconds", limit, retryAfterSeconds(retryAfter))
+}
+
+// rateLimitedResult builds the tool error returned to limited callers.
+func rateLimitedResult(limit string, retryAfter time.Duration) *mcp.CallToolResult {
+	seconds := retryAfterSeconds(retryAfter)
+	message := fmt.Sprintf("rate limited, retry after %d seconds", seconds)
+	body, err := json.Marshal(rateLimitedError{
+		Error:             "rate_limited",
+		Message:           message,
+		Limit:             limit,
+		RetryAfterSeconds: seconds,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(message)
+	}
+	return mcp.NewToolResultError(string(body))
+}
@@ -0,0 +1,187 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestRequest(name string, args map[string]any) mcp.CallToolRequest {
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = args
+	return req
+}
+
+func okHandler(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	return mcp.NewToolResultText("ok"), nil
+}
+
+func TestRateLimiter_ToolRule(t *testing.T) {
+	// Arrange
+	cfg := config.Config{RateLimits: []string{"tool:status=2/1m"}}
+	limiter, err := newRateLimiter(cfg, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	handler := limiter.middleware(okHandler)
+
+	// Act
+	first, _ := handler(context.Background(), newTestRequest("status", nil))
+	second, _ := handler(context.Background(), newTestRequest("status", nil))
+	third, _ := handler(context.Background(), newTestRequest("status", nil))
+	other, _ := handler(context.Background(), newTestRequest("version", nil))
+
+	// Assert
+	assert.False(t, first.IsError)
+	assert.False(t, second.IsError)
+	require.True(t, third.IsError)
+	assert.False(t, other.IsError)
+
+	var body rateLimitedError
+	require.NoError(t, json.Unmarshal([]byte(third.Content[0].(mcp.TextContent).Text), &body))
+	assert.Equal(t, "rate_limited", body.Error)
+	assert.Equal(t, 30, body.RetryAfterSeconds)
+	assert.Equal(t, "rate limited, retry after 30 seconds", body.Message)
+}
+
+func TestRateLimiter_ResourceReads(t *testing.T) {
+	// Arrange
+	cfg := config.Config{RateLimits: []string{"tool:status=2/1m"}}
+	limiter, err := newRateLimiter(cfg, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	tool := limiter.middleware(okHandler)
+	resource := limiter.resourceMiddleware(func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		return nil, nil
+	})
+	read := func(uri string) error {
+		req := mcp.ReadResourceRequest{}
+		req.Params.URI = uri
+		_, err := resource(context.Background(), req)
+		return err
+	}
+
+	// Act
+	first := read("juju://status/prod")
+	called, _ := tool(context.Background(), newTestRequest("status", nil))
+	third := read("juju://status/prod/mysql")
+	doc := read("juju://status-doc")
+
+	// Assert
+	assert.NoError(t, first)
+	assert.False(t, called.IsError)
+	assert.EqualError(t, third, "rate limited by tool:status=2/1m0s, retry after 30 seconds")
+	assert.NoError(t, doc)
+}
+
+func TestRateLimiter_RefillsOverTime(t *testing.T) {
+	// Arrange
+	cfg := config.Config{RateLimits: []string{"identity:*=1/10s"}}
+	limiter, err := newRateLimiter(cfg, nil)
+	require.NoError(t, err)
+	now := time.Now()
+	limiter.now = func() time.Time { return now }
+	handler := limiter.middleware(okHandler)
+
+	// Act & Assert
+	result, _ := handler(context.Background(), newTestRequest("status", nil))
+	assert.False(t, result.IsError)
+	result, _ = handler(context.Background(), newTestRequest("status", nil))
+	assert.True(t, result.IsError)
+
+	now = now.Add(10 * time.Second)
+	result, _ = handler(context.Background(), newTestRequest("status", nil))
+	assert.False(t, result.IsError)
+}
+
+func TestRateLimiter_SpecificRuleOverridesWildcard(t *testing.T) {
+	// Arrange
+	cfg := config.Config{RateLimits: []string{"tool:*=1/1m", "tool:status=3/1m"}}
+	limiter, err := newRateLimiter(cfg, nil)
+	require.NoError(t, err)
+	handler := limiter.middleware(okHandler)
+
+	// Act & Assert
+	for i := 0; i < 3; i++ {
+		result, _ := handler(context.Background(), newTestRequest("status", nil))
+		assert.False(t, result.IsError)
+	}
+	result, _ := handler(context.Background(), newTestRequest("deploy", nil))
+	assert.False(t, result.IsError)
+	result, _ = handler(context.Background(), newTestRequest("deploy", nil))
+	assert.True(t, result.IsError)
+}
+
+func TestRateLimiter_ControllerRule(t *testing.T) {
+	// Arrange
+	cfg := config.Config{RateLimits: []string{"controller:prod=1/1m"}}
+	limiter, err := newRateLimiter(cfg, func() (string, error) { return "dev", nil })
+	require.NoError(t, err)
+	handler := limiter.middleware(okHandler)
+
+	// Act & Assert
+	result, _ := handler(context.Background(), newTestRequest("status", map[string]any{"model": "prod:default"}))
+	assert.False(t, result.IsError)
+	result, _ = handler(context.Background(), newTestRequest("status", map[string]any{"model": "prod:other"}))
+	assert.True(t, result.IsError)
+	result, _ = handler(context.Background(), newTestRequest("status", nil))
+	assert.False(t, result.IsError, "current controller is dev, which is not limited")
+}
+
+func TestRateLimiter_ConcurrencyCap(t *testing.T) {
+	// Arrange
+	cfg := config.Config{ConcurrencyLimits: []string{"exec=1"}}
+	limiter, err := newRateLimiter(cfg, nil)
+	require.NoError(t, err)
+
+	started := make(chan struct{})
+	finish := make(chan struct{})
+	blocking := limiter.middleware(func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		close(started)
+		<-finish
+		return mcp.NewToolResultText("ok"), nil
+	})
+	handler := limiter.middleware(okHandler)
+
+	// Act
+	done := make(chan *mcp.CallToolResult)
+	go func() {
+		result, _ := blocking(context.Background(), newTestRequest("exec", nil))
+		done <- result
+	}()
+	<-started
+	limited, _ := handler(context.Background(), newTestRequest("exec", nil))
+	close(finish)
+	first := <-done
+	after, _ := handler(context.Background(), newTestRequest("exec", nil))
+
+	// Assert
+	assert.False(t, first.IsError)
+	assert.True(t, limited.IsError)
+	assert.Contains(t, limited.Content[0].(mcp.TextContent).Text, "concurrency:exec")
+	assert.False(t, after.IsError)
+}
+
+func TestNewRateLimiter_InvalidRule(t *testing.T) {
+	testCases := []string{
+		"status=1/1m",
+		"user:alice=1/1m",
+		"tool:status=0/1m",
+		"tool:status=1",
+		"tool:status=1/soon",
+	}
+	for _, rule := range testCases {
+		t.Run(rule, func(t *testing.T) {
+			_, err := newRateLimiter(config.Config{RateLimits: []string{rule}}, nil)
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package application
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"syscall"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/jneo8/mcp-juju/pkg/jujuadapter"
+	"github.com/rs/zerolog/log"
+)
+
+// reloadDebounce groups the bursts of events editors produce when saving.
+const reloadDebounce = 250 * time.Millisecond
+
+// restartSettings configure listeners or files opened at start up, so
+// changing them only takes effect after a restart.
+var restartSettings = []string{
+	"server-type",
+	"tool-prefix",
+	"port",
+	"endpoint",
+	"bind-address",
+	"tls-cert-file",
+	"tls-key-file",
+	"tls-client-ca-file",
+	"approvals-address",
+	"audit-log",
+	"workspace-dir",
+	"artifacts-dir",
+	"artifact-retention",
+	"artifact-max-size",
+	"status-snapshots-dir",
+	"status-snapshot-interval",
+	"debug",
+}
+
+// Reloader loads a new configuration on SIGHUP and, when File is set,
+// whenever the file changes.
+type Reloader struct {
+	// Load returns the new configuration, or an error if it is invalid.
+	Load func() (config.Config, error)
+	File string
+}
+
+// reload applies a new configuration. An invalid configuration is rejected
+// and the current tools and policies stay in place.
+func (a *application) reload() error {
+	a.reloadMu.Lock()
+	defer a.reloadMu.Unlock()
+
+	cfg, err := a.reloader.Load()
+	if err != nil {
+		return fmt.Errorf("rejected new configuration: %w", err)
+	}
+	// Check the policies before changing anything.
+	if _, err := newRateLimiter(cfg, a.adapter.CurrentController); err != nil {
+		return fmt.Errorf("rejected new configuration: %w", err)
+	}
+
+	a.mu.RLock()
+	previous := a.config
+	a.mu.RUnlock()
+
+	if err := a.adapter.SetToolSelection(jujuadapter.NewToolSelection(cfg)); err != nil {
+		return fmt.Errorf("rejected new configuration: %w", err)
+	}
+	// The previous selection was valid, so restoring it cannot fail.
+	restore := func() { _ = a.adapter.SetToolSelection(jujuadapter.NewToolSelection(previous)) }
+	reg, err := a.collect(a.adapter, cfg)
+	if err != nil {
+		restore()
+		return fmt.Errorf("rejected new configuration: %w", err)
+	}
+
+	if err := a.limiter.update(cfg); err != nil {
+		restore()
+		return fmt.Errorf("rejected new configuration: %w", err)
+	}
+	a.approvals.update(cfg)
+	a.mu.Lock()
+	a.config = cfg
+	a.mu.Unlock()
+	a.apply(reg)
+	if a.serving && cfg.IsApprovalEnabled() {
+		a.startApprovalsServer(cfg)
+	}
+
+	if changed := changedRestartSettings(previous, cfg); len(changed) > 0 {
+		log.Warn().Strs("settings", changed).Msg("Changed settings take effect after a restart")
+	}
+	log.Info().Int("tools", len(reg.tools)).Int("resources", len(reg.resources)).Msg("Configuration reloaded")
+	return nil
+}
+
+func changedRestartSettings(previous, current config.Config) []string {
+	before, after := previous.Settings(), current.Settings()
+	var changed []string
+	for _, name := range restartSettings {
+		if !reflect.DeepEqual(before[name], after[name]) {
+			changed = append(changed, name)
+		}
+	}
+	return changed
+}
+
+// watchReload reloads on SIGHUP and on changes to the configuration file
+// until the context is done.
+func (a *application) watchReload(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var changes <-chan struct{}
+	if a.reloader.File != "" {
+		ch, err := watchFile(ctx, a.reloader.File)
+		if err != nil {
+			log.Error().Err(err).Str("file", a.reloader.File).Msg("Unable to watch the configuration file, reload with SIGHUP instead")
+		} else {
+			changes = ch
+		}
+	}
+
+	for {
+		var trigger string
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			trigger = "SIGHUP"
+		case <-changes:
+			trigger = "file change"
+		}
+		if err := a.reload(); err != nil {
+			log.Error().Err(err).Str("trigger", trigger).Msg("Configuration reload failed, keeping the current configuration")
+		}
+	}
+}
+
+// watchFile signals when the file is written, created or replaced. The
+// directory is watched because editors often save by renaming a new file
+// over the old one.
+func watchFile(ctx context.Context, file string) (<-chan struct{}, error) {
+	file = filepath.Clean(file)
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return nil, err
+	}
+	if err := watcher.Add(filepath.Dir(file)); err != nil {
+		watcher.Close()
+		return nil, err
+	}
+
+	changes := make(chan struct{}, 1)
+	go func() {
+		defer watcher.Close()
+		var debounce <-chan time.Time
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case event, ok := <-watcher.Events:
+				if !ok {
+					return
+				}
+				if filepath.Clean(event.Name) == file && event.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+					debounce = time.After(reloadDebounce)
+				}
+			case err, ok := <-watcher.Errors:
+				if !ok {
+					return
+				}
+				log.Warn().Err(err).Msg("Configuration file watcher error")
+			case <-debounce:
+				debounce = nil
+				select {
+				case changes <- struct{}{}:
+				default:
+				}
+			}
+		}
+	}()
+	return changes, nil
+}
@@ -0,0 +1,182 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/jneo8/mcp-juju/config"
+	mockjujuadapter "github.com/jneo8/mcp-juju/mocks/jujuadapter"
+	"github.com/jneo8/mcp-juju/pkg/jujuadapter"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+)
+
+// newReloadableAdapter returns a mock adapter whose tool list follows
+// SetToolSelection. Selecting "bogus" is rejected and the tool "broken"
+// cannot be built.
+func newReloadableAdapter(t *testing.T, names []string) *mockjujuadapter.MockAdapter {
+	mockAdapter := mockjujuadapter.NewMockAdapter(t)
+	mockAdapter.EXPECT().ToolNames().RunAndReturn(func() []string { return names })
+	mockAdapter.EXPECT().SetToolSelection(mock.Anything).RunAndReturn(func(selection jujuadapter.ToolSelection) error {
+		if slices.Contains(selection.Names, "bogus") {
+			return errors.New(`tool-names[1]: unknown tool "bogus"`)
+		}
+		names = selection.Names
+		return nil
+	}).Maybe()
+	mockAdapter.EXPECT().GetTool(mock.Anything).RunAndReturn(func(name string) (*mcp.Tool, server.ToolHandlerFunc, error) {
+		if name == "broken" {
+			return nil, nil, fmt.Errorf("unknown command: %s", name)
+		}
+		tool := mcp.NewTool(name, mcp.WithDescription("Test tool"))
+		return &tool, okHandler, nil
+	})
+	mockAdapter.EXPECT().ToolDocResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceNames().Return([]string{})
+	mockAdapter.EXPECT().ResourceTemplateNames().Return([]string{})
+	mockAdapter.EXPECT().AnnotateBlockedTools(mock.Anything, mock.Anything).
+		RunAndReturn(func(_ context.Context, tools []mcp.Tool) []mcp.Tool { return tools }).Maybe()
+	return mockAdapter
+}
+
+func listToolNames(t *testing.T, s *server.MCPServer) []string {
+	resp := s.HandleMessage(context.Background(), json.RawMessage(`{"jsonrpc":"2.0","id":1,"method":"tools/list"}`))
+	encoded, err := json.Marshal(resp)
+	require.NoError(t, err)
+	var decoded struct {
+		Result mcp.ListToolsResult `json:"result"`
+	}
+	require.NoError(t, json.Unmarshal(encoded, &decoded))
+	names := make([]string, len(decoded.Result.Tools))
+	for i, tool := range decoded.Result.Tools {
+		names[i] = tool.Name
+	}
+	return names
+}
+
+func TestReload_SwapsToolsAndPolicies(t *testing.T) {
+	// Arrange
+	cfg := config.Config{ServerType: config.ServerTypeStdio, ToolNames: []string{"status", "deploy"}}
+	next := config.Config{
+		ServerType:    config.ServerTypeStdio,
+		ToolNames:     []string{"status", "show-unit"},
+		RateLimits:    []string{"tool:status=1/1m"},
+		ApprovalTools: []string{"destroy-*"},
+		ApprovalMode:  config.ApprovalModeAsync,
+	}
+	mockAdapter := newReloadableAdapter(t, cfg.ToolNames)
+	app, err := NewApplication(cfg, mockAdapter, WithReloader(Reloader{
+		Load: func() (config.Config, error) { return next, nil },
+	}))
+	require.NoError(t, err)
+	a := app.(*application)
+
+	// Act
+	err = a.reload()
+
+	// Assert
+	require.NoError(t, err)
+	assert.ElementsMatch(t, []string{"status", "show-unit", approvalStatusToolName}, listToolNames(t, a.mcpServer))
+	assert.True(t, a.approvals.requiresApproval("destroy-model"))
+	assert.Equal(t, next, a.config)
+	_, _, limited := a.limiter.reserve(LocalIdentity, "status", "")
+	assert.False(t, limited)
+	_, _, limited = a.limiter.reserve(LocalIdentity, "status", "")
+	assert.True(t, limited, "the new rate limit applies")
+}
+
+func TestReload_StartsApprovalsServerWithNewConfig(t *testing.T) {
+	// Arrange
+	started := make(chan config.Config, 1)
+	serve := serveApprovals
+	serveApprovals = func(_ *approvalManager, cfg config.Config) error {
+		started <- cfg
+		return nil
+	}
+	t.Cleanup(func() { serveApprovals = serve })
+	cfg := config.Config{ServerType: config.ServerTypeStdio, ToolNames: []string{"status"}}
+	next := cfg
+	next.ApprovalTools = []string{"destroy-*"}
+	next.ApprovalsAddress = "127.0.0.1:8090"
+	next.ApproverTokens = []string{"alice=s3cret"}
+	mockAdapter := newReloadableAdapter(t, cfg.ToolNames)
+	app, err := NewApplication(cfg, mockAdapter, WithReloader(Reloader{
+		Load: func() (config.Config, error) { return next, nil },
+	}))
+	require.NoError(t, err)
+	a := app.(*application)
+	a.serving = true
+
+	// Act
+	err = a.reload()
+
+	// Assert
+	require.NoError(t, err)
+	select {
+	case got := <-started:
+		assert.Equal(t, "127.0.0.1:8090", got.ApprovalsAddress)
+		assert.Equal(t, []string{"alice=s3cret"}, got.ApproverTokens)
+	case <-time.After(time.Second):
+		t.Fatal("the approvals API was not started")
+	}
+}
+
+func TestReload_RejectsInvalidConfig(t *testing.T) {
+	cfg := config.Config{ServerType: config.ServerTypeStdio, ToolNames: []string{"status", "deploy"}}
+
+	tests := []struct {
+		name string
+		load func() (config.Config, error)
+	}{
+		{
+			name: "validation error",
+			load: func() (config.Config, error) { return config.Config{}, errors.New("config validation failed") },
+		},
+		{
+			name: "unknown tool",
+			load: func() (config.Config, error) {
+				return config.Config{ServerType: config.ServerTypeStdio, ToolNames: []string{"status", "bogus"}}, nil
+			},
+		},
+		{
+			name: "tool fails to build",
+			load: func() (config.Config, error) {
+				return config.Config{ServerType: config.ServerTypeStdio, ToolNames: []string{"status", "broken"}}, nil
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			mockAdapter := newReloadableAdapter(t, cfg.ToolNames)
+			app, err := NewApplication(cfg, mockAdapter, WithReloader(Reloader{Load: tt.load}))
+			require.NoError(t, err)
+			a := app.(*application)
+
+			// Act
+			err = a.reload()
+
+			// Assert
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), "rejected new configuration")
+			assert.ElementsMatch(t, []string{"status", "deploy"}, listToolNames(t, a.mcpServer))
+			assert.Equal(t, []string{"status", "deploy"}, mockAdapter.ToolNames())
+			assert.Equal(t, cfg, a.config)
+		})
+	}
+}
+
+func TestChangedRestartSettings(t *testing.T) {
+	previous := config.Config{Port: 8080, ToolNames: []string{"status"}}
+	current := config.Config{Port: 9090, ToolNames: []string{"deploy"}, TLSCertFile: "server.crt"}
+
+	assert.Equal(t, []string{"port", "tls-cert-file"}, changedRestartSettings(previous, current))
+}
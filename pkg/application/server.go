@@ -1,7 +1,7 @@
 package application
 
 import (
-	"fmt"
+	"net/http"
 
 	"github.com/jneo8/mcp-juju/config"
 	"github.com/mark3labs/mcp-go/server"
@@ -9,14 +9,37 @@ import (
 )
 
 func newStreamableHTTPServer(mcpServer *server.MCPServer, cfg config.Config) *server.StreamableHTTPServer {
+	opts := append(cfg.StreamableHTTPOptions(), server.WithHTTPContextFunc(withCallerIdentity))
 	return server.NewStreamableHTTPServer(
-		mcpServer, cfg.StreamableHTTPOptions()...,
+		mcpServer, opts...,
 	)
 }
 
+func newHTTPServer(handler http.Handler, cfg config.Config) (*http.Server, error) {
+	tlsConfig, err := cfg.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	mux := http.NewServeMux()
+	mux.Handle(cfg.EndPoint, handler)
+	return &http.Server{
+		Addr:      cfg.Addr(),
+		Handler:   mux,
+		TLSConfig: tlsConfig,
+	}, nil
+}
+
 func runStreamableHTTPServer(server *server.StreamableHTTPServer, cfg config.Config) error {
+	httpServer, err := newHTTPServer(server, cfg)
+	if err != nil {
+		return err
+	}
 	log.Debug().Msgf("Run Streamable HTTP Server at %s", cfg.URL())
-	return server.Start(fmt.Sprintf(":%d", cfg.Port))
+	if cfg.IsTLSEnabled() {
+		log.Debug().Bool("mtls", cfg.IsMutualTLSEnabled()).Msg("TLS enabled")
+		return httpServer.ListenAndServeTLS(cfg.TLSCertFile, cfg.TLSKeyFile)
+	}
+	return httpServer.ListenAndServe()
 }
 
 func runStdioServer(mcpServer *server.MCPServer) error {
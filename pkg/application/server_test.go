@@ -1,7 +1,13 @@
 package application
 
 import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/jneo8/mcp-juju/config"
@@ -183,6 +189,34 @@ func TestConfig_URL(t *testing.T) {
 			},
 			expectedURL: "http://localhost:8080",
 		},
+		{
+			name: "bind address",
+			config: config.Config{
+				Port:        8080,
+				EndPoint:    "/mcp",
+				BindAddress: "127.0.0.1",
+			},
+			expectedURL: "http://127.0.0.1:8080/mcp",
+		},
+		{
+			name: "unspecified bind address",
+			config: config.Config{
+				Port:        8080,
+				EndPoint:    "/mcp",
+				BindAddress: "0.0.0.0",
+			},
+			expectedURL: "http://localhost:8080/mcp",
+		},
+		{
+			name: "tls enabled",
+			config: config.Config{
+				Port:        8443,
+				EndPoint:    "/mcp",
+				TLSCertFile: "server.crt",
+				TLSKeyFile:  "server.key",
+			},
+			expectedURL: "https://localhost:8443/mcp",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -245,4 +279,78 @@ func TestConfig_StreamableHTTPOptions_DifferentEndpoints(t *testing.T) {
 			assert.Len(t, options, 1)
 		})
 	}
-}
\ No newline at end of file
+}
+
+func TestNewHTTPServer(t *testing.T) {
+	testCases := []struct {
+		name         string
+		config       config.Config
+		expectedAddr string
+	}{
+		{
+			name:         "all interfaces",
+			config:       config.Config{Port: 8080, EndPoint: "/mcp"},
+			expectedAddr: ":8080",
+		},
+		{
+			name:         "bind address",
+			config:       config.Config{Port: 8080, EndPoint: "/mcp", BindAddress: "127.0.0.1"},
+			expectedAddr: "127.0.0.1:8080",
+		},
+		{
+			name:         "ipv6 bind address",
+			config:       config.Config{Port: 8080, EndPoint: "/mcp", BindAddress: "::1"},
+			expectedAddr: "[::1]:8080",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Act
+			httpServer, err := newHTTPServer(http.NotFoundHandler(), tc.config)
+
+			// Assert
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedAddr, httpServer.Addr)
+			assert.Nil(t, httpServer.TLSConfig)
+		})
+	}
+}
+
+func TestNewHTTPServer_MissingClientCA(t *testing.T) {
+	// Arrange
+	cfg := config.Config{
+		Port:            8443,
+		EndPoint:        "/mcp",
+		TLSCertFile:     "server.crt",
+		TLSKeyFile:      "server.key",
+		TLSClientCAFile: "/nonexistent/ca.pem",
+	}
+
+	// Act
+	_, err := newHTTPServer(nil, cfg)
+
+	// Assert
+	require.Error(t, err)
+	assert.Contains(t, err.Error(), "client CA bundle")
+}
+
+func TestCallerIdentity(t *testing.T) {
+	t.Run("stdio callers are local", func(t *testing.T) {
+		assert.Equal(t, LocalIdentity, CallerIdentity(context.Background()))
+	})
+
+	t.Run("http callers without certificate are anonymous", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/mcp", nil)
+		ctx := withCallerIdentity(context.Background(), req)
+		assert.Equal(t, AnonymousIdentity, CallerIdentity(ctx))
+	})
+
+	t.Run("verified client certificate subject is the identity", func(t *testing.T) {
+		req := httptest.NewRequest("POST", "/mcp", nil)
+		cert := &x509.Certificate{Subject: pkix.Name{CommonName: "alice", Organization: []string{"ops"}}}
+		req.TLS = &tls.ConnectionState{VerifiedChains: [][]*x509.Certificate{{cert}}}
+		ctx := withCallerIdentity(context.Background(), req)
+		assert.Equal(t, "CN=alice,O=ops", CallerIdentity(ctx))
+	})
+}
@@ -6,9 +6,12 @@ import (
 	"reflect"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/juju/gnuflag"
 	"github.com/juju/juju/juju"
+	"github.com/juju/juju/jujuclient"
 	"github.com/mark3labs/mcp-go/mcp"
 	mcpserver "github.com/mark3labs/mcp-go/server"
 	"github.com/rs/zerolog/log"
@@ -16,54 +19,186 @@ import (
 
 type Adapter interface {
 	ToolNames() []string
+	CommandName(toolName string) string
+	SetToolSelection(selection ToolSelection) error
 	GetTool(name string) (*mcp.Tool, mcpserver.ToolHandlerFunc, error)
+	GetToolDoc(name string) (string, error)
 	ToolDocResourceNames() []string
+	ResourceNames() []string
 	GetResource(name string) (*mcp.Resource, mcpserver.ResourceHandlerFunc, error)
 	ResourceTemplateNames() []string
 	GetResourceTemplate(name string) (*mcp.ResourceTemplate, mcpserver.ResourceTemplateHandlerFunc, error)
+	CurrentController() (string, error)
+	AnnotateBlockedTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool
+	Start(ctx context.Context)
 }
 
-func NewAdapter(toolNames []string) (Adapter, error) {
-	a := &adapter{
-		factory:   &commandFactory{},
-		toolNames: toolNames,
+// Option configures an adapter.
+type Option func(a *adapter) error
+
+// WithWorkspace sets the directory files are uploaded to and downloaded
+// from. Local paths given to commands are confined to it.
+func WithWorkspace(dir string) Option {
+	return func(a *adapter) error {
+		if dir == "" {
+			return nil
+		}
+		w, err := newWorkspace(dir)
+		if err != nil {
+			return err
+		}
+		a.workspace = w
+		return nil
+	}
+}
+
+// WithArtifacts keeps the files produced by commands, such as backups, as
+// artifact resources for the retention period. Files over maxSize bytes are
+// not served. An empty dir uses a temporary directory.
+func WithArtifacts(dir string, retention time.Duration, maxSize int64) Option {
+	return func(a *adapter) error {
+		store, err := newArtifactStore(dir, retention, maxSize)
+		if err != nil {
+			return err
+		}
+		a.artifacts = store
+		return nil
+	}
+}
+
+// WithStatusSnapshots keeps the status snapshots compared by status-diff in
+// dir, rather than in memory. Once started, the adapter snapshots the models
+// that have one at every interval. A zero interval only takes snapshots on
+// demand.
+func WithStatusSnapshots(dir string, interval time.Duration) Option {
+	return func(a *adapter) error {
+		store, err := newSnapshotStore(dir, maxStatusSnapshots)
+		if err != nil {
+			return err
+		}
+		a.snapshots = store
+		a.snapshotInterval = interval
+		return nil
+	}
+}
+
+// NewAdapter returns an adapter serving the selected tools. The prefix, such
+// as "juju_" or "juju.", is prepended to every tool name.
+func NewAdapter(selection ToolSelection, prefix string, opts ...Option) (Adapter, error) {
+	a := newAdapter(&commandFactory{}, prefix)
+	for _, opt := range opts {
+		if err := opt(a); err != nil {
+			return nil, err
+		}
+	}
+	a.registerBuiltins()
+	if err := a.SetToolSelection(selection); err != nil {
+		return nil, err
 	}
 	a.init()
 	return a, nil
 }
 
+// newAdapter returns an adapter running commands from the factory, with
+// in-memory stores. Options may replace them before the built-in tools are
+// registered.
+func newAdapter(factory CommandFactory, prefix string) *adapter {
+	a := &adapter{
+		factory:   factory,
+		prefix:    prefix,
+		journal:   newChangeJournal(),
+		logs:      newLogStreams(),
+		baselines: newBaselineStore(),
+	}
+	a.snapshots, _ = newSnapshotStore("", maxStatusSnapshots)
+	a.blocks = newBlockCache(a.fetchBlocks)
+	return a
+}
+
 type adapter struct {
 	factory   CommandFactory
+	prefix    string
+	mu        sync.RWMutex
 	toolNames []string
+	blocks    *blockCache
+	journal   *changeJournal
+	builtins  *builtins
+	workspace *workspace
+	artifacts *artifactStore
+	logs      *logStreams
+	snapshots *snapshotStore
+	baselines *baselineStore
+
+	snapshotInterval time.Duration
 }
 
 func (a *adapter) ToolNames() []string {
-	// If specific tool names are configured, use those
-	if len(a.toolNames) > 0 {
-		return a.toolNames
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	names := make([]string, len(a.toolNames))
+	for i, name := range a.toolNames {
+		names[i] = a.toolName(name)
 	}
+	return names
+}
+
+// toolName returns the name a command or built-in tool is registered under.
+func (a *adapter) toolName(command string) string {
+	return a.prefix + command
+}
+
+// CommandName reverses the tool name prefix. Names without the prefix, such
+// as those of tools added by the application, are returned unchanged.
+func (a *adapter) CommandName(toolName string) string {
+	return strings.TrimPrefix(toolName, a.prefix)
+}
+
+// lookupName reverses the tool name prefix of a tool or -doc resource name,
+// failing if the prefix is missing.
+func (a *adapter) lookupName(name string) (string, bool) {
+	return strings.CutPrefix(name, a.prefix)
+}
 
-	// Otherwise, return all available command IDs
-	ids := GetAllCommandIDs()
-	names := make([]string, len(ids))
-	for i, id := range ids {
-		names[i] = string(id)
+// SetToolSelection changes the registered tools, for example when the
+// configuration is reloaded. An invalid selection leaves them unchanged.
+func (a *adapter) SetToolSelection(selection ToolSelection) error {
+	names, err := selectTools(selection, a.availableToolNames())
+	if err != nil {
+		return err
 	}
-	return names
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.toolNames = names
+	return nil
 }
 
 func (a *adapter) ToolDocResourceNames() []string {
 	// Create documentation resources for each tool (1-to-1 mapping)
-	toolNames := a.ToolNames()
-	resourceNames := make([]string, len(toolNames))
-	for i, toolName := range toolNames {
-		resourceNames[i] = toolName + "-doc"
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	resourceNames := make([]string, 0, len(a.toolNames))
+	for _, command := range a.toolNames {
+		if a.builtins.isTool(command) {
+			continue
+		}
+		resourceNames = append(resourceNames, a.toolName(command)+"-doc")
 	}
 	return resourceNames
 }
 
+// ResourceNames returns the static resources that are not tool documentation.
+func (a *adapter) ResourceNames() []string {
+	return a.builtins.resourceNames()
+}
+
 func (a *adapter) ResourceTemplateNames() []string {
-	return a.factory.GetResourceTemplateNames()
+	return append(a.factory.GetResourceTemplateNames(), a.builtins.templateNames()...)
+}
+
+// CurrentController returns the controller commands run against when no
+// controller or model is given explicitly.
+func (a *adapter) CurrentController() (string, error) {
+	return jujuclient.NewFileClientStore().CurrentController()
 }
 
 func (a *adapter) init() {
@@ -129,7 +264,17 @@ func (a *adapter) buildDocumentationContent(cmd Command) string {
 }
 
 func (a *adapter) GetTool(name string) (*mcp.Tool, mcpserver.ToolHandlerFunc, error) {
-	cmd, err := a.factory.GetCommandByName(name)
+	command, ok := a.lookupName(name)
+	if !ok {
+		return nil, nil, fmt.Errorf("tool '%s' not found", name)
+	}
+	if builtin, ok := a.builtins.tools[command]; ok {
+		tool := builtin.tool
+		tool.Name = name
+		return &tool, builtin.handler, nil
+	}
+
+	cmd, err := a.factory.GetCommandByName(command)
 	if err != nil {
 		return nil, nil, err
 	}
@@ -140,11 +285,28 @@ func (a *adapter) GetTool(name string) (*mcp.Tool, mcpserver.ToolHandlerFunc, er
 	}
 	allOptions := []mcp.ToolOption{mcp.WithDescription(a.buildEnhancedDescription(cmd))}
 	allOptions = append(allOptions, toolOptions...)
-	tool := mcp.NewTool(cmd.Name(), allOptions...)
+	tool := mcp.NewTool(a.toolName(cmd.Name()), allOptions...)
 	handlerFunc := a.getHandlerFunc(cmd)
 	return &tool, handlerFunc, nil
 }
 
+// GetToolDoc returns the markdown documentation of a tool, the same as its
+// -doc resource.
+func (a *adapter) GetToolDoc(name string) (string, error) {
+	command, ok := a.lookupName(name)
+	if !ok {
+		return "", fmt.Errorf("tool '%s' not found", name)
+	}
+	if builtin, ok := a.builtins.tools[command]; ok {
+		return fmt.Sprintf("# %s\n\n%s\n", name, builtin.tool.Description), nil
+	}
+	cmd, err := a.factory.GetCommandByName(command)
+	if err != nil {
+		return "", err
+	}
+	return a.buildDocumentationContent(cmd), nil
+}
+
 func (a *adapter) flagSetToToolOptions(cmd Command) ([]mcp.ToolOption, error) {
 	flagSet := gnuflag.NewFlagSet(cmd.Name(), gnuflag.ContinueOnError)
 	cmd.SetFlags(flagSet)
@@ -218,6 +380,16 @@ func (a *adapter) executeCommand(ctx context.Context, config CommandExecutionCon
 		return "", fmt.Errorf("failed to get command '%s': %w", config.CommandName, err)
 	}
 
+	// Write produced files where they can be served as artifacts
+	outputFlag, produces := a.defaultOutput(&config)
+
+	// Keep local file paths inside the workspace
+	if a.workspace != nil {
+		if err := a.workspace.confine(&config); err != nil {
+			return "", fmt.Errorf("command '%s': %w", config.CommandName, err)
+		}
+	}
+
 	// Set up the command flags
 	flagSet := gnuflag.NewFlagSet(config.CommandName, gnuflag.ContinueOnError)
 	cmd.SetFlags(flagSet)
@@ -240,26 +412,19 @@ func (a *adapter) executeCommand(ctx context.Context, config CommandExecutionCon
 			continue
 		}
 
-		// Convert the value to string and set it
-		var stringValue string
-		switch v := value.(type) {
-		case string:
-			stringValue = v
-		case bool:
-			if v {
-				stringValue = "true"
-			} else {
-				stringValue = "false"
+		// Repeatable flags, such as deploy --config, take one value per call
+		if values, ok := value.([]string); ok {
+			for _, v := range values {
+				if err := flag.Value.Set(v); err != nil {
+					return "", fmt.Errorf("failed to set flag '%s': %w", key, err)
+				}
 			}
-		case float64:
-			stringValue = strconv.FormatFloat(v, 'f', -1, 64)
-		case int:
-			stringValue = strconv.Itoa(v)
-		default:
-			// For other types, convert to string
-			stringValue = fmt.Sprintf("%v", v)
+			continue
 		}
 
+		// Convert the value to string and set it
+		stringValue := formatFlagValue(value)
+
 		// Skip empty string values for flags
 		if stringValue == "" {
 			continue
@@ -280,6 +445,9 @@ func (a *adapter) executeCommand(ctx context.Context, config CommandExecutionCon
 		return "", fmt.Errorf("failed to initialize command '%s': %w", config.CommandName, err)
 	}
 
+	// Record what a mutating command is about to change so it can be reverted
+	change := a.captureChange(ctx, config)
+
 	// Execute the command
 	stdout, stderr, err := cmd.RunWithOutput(ctx)
 	if err != nil {
@@ -295,9 +463,46 @@ func (a *adapter) executeCommand(ctx context.Context, config CommandExecutionCon
 		output += stderr
 	}
 
+	if produces {
+		if note := a.recordArtifact(config, outputFlag); note != "" {
+			if output != "" {
+				output += "\n"
+			}
+			output += note
+		}
+	}
+
+	if change != nil {
+		a.journal.add(change)
+		if output != "" {
+			output += "\n"
+		}
+		output += a.journalNote(change)
+	}
+
 	return output, nil
 }
 
+// formatFlagValue converts a tool argument to the string form gnuflag expects.
+func formatFlagValue(value interface{}) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case bool:
+		if v {
+			return "true"
+		}
+		return "false"
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case int:
+		return strconv.Itoa(v)
+	default:
+		// For other types, convert to string
+		return fmt.Sprintf("%v", v)
+	}
+}
+
 // CommandExecutionConfig holds all the configuration needed to execute a command
 type CommandExecutionConfig struct {
 	CommandName string
@@ -344,6 +549,11 @@ func (a *adapter) run(name string, ctx context.Context, req mcp.CallToolRequest)
 		}
 	}
 
+	// Refuse commands disabled on the target model before touching Juju.
+	if msg, blocked := a.checkBlocked(ctx, JujuCommandID(name), targetModel(flagValues)); blocked {
+		return mcp.NewToolResultError(msg), nil
+	}
+
 	config := CommandExecutionConfig{
 		CommandName: name,
 		Arguments:   positionalArgs,
@@ -355,6 +565,12 @@ func (a *adapter) run(name string, ctx context.Context, req mcp.CallToolRequest)
 		return nil, err
 	}
 
+	// Changing the blocks makes the cached ones stale.
+	switch JujuCommandID(name) {
+	case CmdDisableCommand, CmdEnableCommand:
+		a.blocks.invalidate()
+	}
+
 	return &mcp.CallToolResult{
 		Content: []mcp.Content{
 			mcp.TextContent{
@@ -366,16 +582,26 @@ func (a *adapter) run(name string, ctx context.Context, req mcp.CallToolRequest)
 }
 
 func (a *adapter) GetResource(name string) (*mcp.Resource, mcpserver.ResourceHandlerFunc, error) {
+	if builtin, ok := a.builtins.resources[name]; ok {
+		resource := builtin.resource
+		return &resource, builtin.handler, nil
+	}
+
 	// Check if this is a documentation resource (ends with -doc)
 	if strings.HasSuffix(name, "-doc") {
 		toolName := strings.TrimSuffix(name, "-doc")
-		cmd, err := a.factory.GetCommandByName(toolName)
+		command, ok := a.lookupName(toolName)
+		if !ok {
+			return nil, nil, fmt.Errorf("resource '%s' not found", name)
+		}
+		cmd, err := a.factory.GetCommandByName(command)
 		if err != nil {
 			return nil, nil, err
 		}
 
-		// Create documentation resource
-		uri := fmt.Sprintf("juju://%s", name)
+		// Create documentation resource. The URI does not depend on the
+		// tool name prefix, so it stays the same whatever the prefix.
+		uri := fmt.Sprintf("juju://%s-doc", command)
 		resource := mcp.NewResource(
 			uri,
 			name,
@@ -401,6 +627,11 @@ func (a *adapter) GetResource(name string) (*mcp.Resource, mcpserver.ResourceHan
 }
 
 func (a *adapter) GetResourceTemplate(name string) (*mcp.ResourceTemplate, mcpserver.ResourceTemplateHandlerFunc, error) {
+	if builtin, ok := a.builtins.templates[name]; ok {
+		template := builtin.template
+		return &template, builtin.handler, nil
+	}
+
 	configs := a.factory.GetResourceTemplateConfigs()
 	config, exists := configs[name]
 	if !exists {
@@ -0,0 +1,51 @@
+package jujuadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolPrefix(t *testing.T) {
+	// Arrange
+	a, _ := newFakeAdapter(func(call fakeCall) string { return "" })
+	a.prefix = "juju_"
+	require.NoError(t, a.SetToolSelection(ToolSelection{Names: []string{"status", RevertChangeToolName}}))
+
+	// Act
+	tool, _, err := a.GetTool("juju_status")
+	require.NoError(t, err)
+	builtin, _, err := a.GetTool("juju_revert-change")
+	require.NoError(t, err)
+	_, _, unprefixedErr := a.GetTool("status")
+	resource, handler, err := a.GetResource("juju_status-doc")
+	require.NoError(t, err)
+	contents, err := handler(context.Background(), mcp.ReadResourceRequest{Params: mcp.ReadResourceParams{URI: resource.URI}})
+	require.NoError(t, err)
+
+	// Assert
+	assert.Equal(t, []string{"juju_status", "juju_revert-change"}, a.ToolNames())
+	assert.Equal(t, "juju_status", tool.Name)
+	assert.Equal(t, "juju_revert-change", builtin.Name)
+	assert.Error(t, unprefixedErr)
+	assert.Equal(t, []string{"juju_status-doc"}, a.ToolDocResourceNames())
+	assert.Equal(t, "juju_status-doc", resource.Name)
+	assert.Equal(t, "juju://status-doc", resource.URI, "URIs do not depend on the prefix")
+	assert.Equal(t, "juju://status-doc", contents[0].(mcp.TextResourceContents).URI)
+	assert.Equal(t, "status", a.CommandName("juju_status"))
+	assert.Equal(t, "approval-status", a.CommandName("approval-status"))
+}
+
+func TestToolPrefix_JournalNote(t *testing.T) {
+	a, _ := newFakeAdapter(func(call fakeCall) string {
+		return `{"applications":{"web":{"exposed":false}}}`
+	})
+	a.prefix = "juju."
+
+	result := callTool(t, a, "juju.expose", map[string]any{"args": []any{"web"}})
+
+	assert.Contains(t, resultText(result), "Undo it with the juju.revert-change tool.")
+}
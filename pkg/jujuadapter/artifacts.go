@@ -0,0 +1,331 @@
+package jujuadapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	artifactsTemplateName = "artifact"
+	artifactsResourceName = "artifacts"
+	artifactsURI          = "juju://artifacts"
+
+	DefaultArtifactRetention = 24 * time.Hour
+	DefaultArtifactMaxSize   = 256 << 20
+)
+
+// artifactOutputs are the commands that write a file, with the flag naming
+// it.
+var artifactOutputs = map[JujuCommandID]string{
+	CmdCreateBackup:   "filename",
+	CmdDownloadBackup: "filename",
+	CmdDownload:       "filepath",
+}
+
+// artifact is a file produced by a command, served as a blob resource.
+type artifact struct {
+	ID       string    `json:"id"`
+	URI      string    `json:"uri"`
+	Name     string    `json:"name"`
+	Command  string    `json:"command"`
+	MIMEType string    `json:"mime_type"`
+	Size     int64     `json:"size"`
+	SHA256   string    `json:"sha256"`
+	Created  time.Time `json:"created"`
+	Expires  time.Time `json:"expires"`
+	path     string
+	owned    bool
+}
+
+// artifactStore keeps track of the artifacts until their retention passes.
+// Files the store named, in its own directory or in the workspace, are
+// deleted with them.
+type artifactStore struct {
+	mu        sync.Mutex
+	dir       string
+	retention time.Duration
+	maxSize   int64
+	items     map[string]*artifact
+	outputs   map[string]bool
+	now       func() time.Time
+}
+
+// newArtifactStore creates a store in dir, or in a temporary directory if dir
+// is empty. A zero retention or size limit takes the default.
+func newArtifactStore(dir string, retention time.Duration, maxSize int64) (*artifactStore, error) {
+	if retention == 0 {
+		retention = DefaultArtifactRetention
+	}
+	if maxSize == 0 {
+		maxSize = DefaultArtifactMaxSize
+	}
+	if dir == "" {
+		var err error
+		if dir, err = os.MkdirTemp("", "mcp-juju-artifacts-"); err != nil {
+			return nil, fmt.Errorf("unable to create the artifacts directory: %w", err)
+		}
+	} else if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create the artifacts directory: %w", err)
+	}
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid artifacts directory: %w", err)
+	}
+	return &artifactStore{
+		dir:       dir,
+		retention: retention,
+		maxSize:   maxSize,
+		items:     make(map[string]*artifact),
+		outputs:   make(map[string]bool),
+		now:       time.Now,
+	}, nil
+}
+
+// add records a file produced by a command. Files over the size limit are
+// not served and are reported as an error.
+func (s *artifactStore) add(file string, command string) (*artifact, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return nil, err
+	}
+	if info.Size() > s.maxSize {
+		return nil, fmt.Errorf("%s is %d bytes, over the %d bytes limit of artifacts", file, info.Size(), s.maxSize)
+	}
+	hash := sha256.New()
+	if _, err := io.Copy(hash, f); err != nil {
+		return nil, err
+	}
+	sum := hex.EncodeToString(hash.Sum(nil))
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	// The artifacts of earlier content of the path are gone with it, and
+	// must not delete the new content when they expire.
+	for id, a := range s.items {
+		if a.path == file {
+			delete(s.items, id)
+		}
+	}
+	s.pruneLocked()
+	now := s.now().UTC()
+	// The same content from the same path is the same artifact.
+	id := artifactID(file, sum)
+	a := &artifact{
+		ID:       id,
+		URI:      artifactsURI + "/" + id,
+		Name:     filepath.Base(file),
+		Command:  command,
+		MIMEType: mimeType(file),
+		Size:     info.Size(),
+		SHA256:   sum,
+		Created:  now,
+		Expires:  now.Add(s.retention),
+		path:     file,
+		owned:    s.outputs[file] || filepath.Dir(file) == s.dir,
+	}
+	s.items[id] = a
+	return a, nil
+}
+
+// artifactID identifies the content of a file at a path.
+func artifactID(file, sum string) string {
+	id := sha256.Sum256([]byte(file + "\x00" + sum))
+	return hex.EncodeToString(id[:8])
+}
+
+// output returns the path of a file the store names in dir, to be deleted
+// when its artifact expires.
+func (s *artifactStore) output(dir, name string) string {
+	file := filepath.Join(dir, name)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.outputs[file] = true
+	return file
+}
+
+// get returns an artifact that has not expired.
+func (s *artifactStore) get(id string) (*artifact, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	a, ok := s.items[id]
+	return a, ok
+}
+
+// list returns the artifacts, the newest first.
+func (s *artifactStore) list() []artifact {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.pruneLocked()
+	items := make([]artifact, 0, len(s.items))
+	for _, a := range s.items {
+		items = append(items, *a)
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if !items[i].Created.Equal(items[j].Created) {
+			return items[i].Created.After(items[j].Created)
+		}
+		return items[i].ID < items[j].ID
+	})
+	return items
+}
+
+func (s *artifactStore) pruneLocked() {
+	now := s.now()
+	for id, a := range s.items {
+		if now.Before(a.Expires) {
+			continue
+		}
+		delete(s.items, id)
+		if a.owned {
+			delete(s.outputs, a.path)
+			if err := os.Remove(a.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				log.Warn().Err(err).Str("artifact", id).Msg("Failed to delete an expired artifact")
+			}
+		}
+	}
+}
+
+// read returns the content of an artifact, checking that the file did not
+// change since it was produced.
+func (s *artifactStore) read(a *artifact) (mcp.BlobResourceContents, error) {
+	data, err := os.ReadFile(a.path)
+	if err != nil {
+		return mcp.BlobResourceContents{}, fmt.Errorf("unable to read artifact %s: %w", a.ID, err)
+	}
+	if int64(len(data)) > s.maxSize {
+		return mcp.BlobResourceContents{}, fmt.Errorf("artifact %s is over the %d bytes limit", a.ID, s.maxSize)
+	}
+	if sum := sha256.Sum256(data); hex.EncodeToString(sum[:]) != a.SHA256 {
+		return mcp.BlobResourceContents{}, fmt.Errorf("artifact %s changed since it was produced", a.ID)
+	}
+	return mcp.BlobResourceContents{
+		URI:      a.URI,
+		MIMEType: a.MIMEType,
+		Blob:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// defaultOutput makes a command that writes a file write it to the workspace,
+// or to the artifacts directory, rather than the server's working directory,
+// and returns the flag naming the file.
+func (a *adapter) defaultOutput(config *CommandExecutionConfig) (string, bool) {
+	flag, ok := artifactOutputs[JujuCommandID(config.CommandName)]
+	if !ok || a.artifacts == nil {
+		return "", false
+	}
+	if value, _ := config.FlagValues[flag].(string); value != "" {
+		return flag, true
+	}
+	if config.FixedFlags[flag] != "" {
+		return flag, true
+	}
+	if noDownload, _ := config.FlagValues["no-download"].(bool); noDownload {
+		return "", false
+	}
+
+	var name string
+	switch JujuCommandID(config.CommandName) {
+	case CmdCreateBackup:
+		name = "juju-backup-" + a.artifacts.now().UTC().Format("20060102-150405") + ".tar.gz"
+	case CmdDownloadBackup:
+		name = "backup.tar.gz"
+		if len(config.Arguments) > 0 {
+			name = path.Base(config.Arguments[0])
+		}
+	case CmdDownload:
+		name = "charm"
+		if len(config.Arguments) > 0 {
+			name = path.Base(config.Arguments[0])
+		}
+		name += ".charm"
+	}
+	dir := a.artifacts.dir
+	if a.workspace != nil {
+		dir = a.workspace.root
+	}
+	fixed := make(map[string]string, len(config.FixedFlags)+1)
+	for k, v := range config.FixedFlags {
+		fixed[k] = v
+	}
+	fixed[flag] = a.artifacts.output(dir, name)
+	config.FixedFlags = fixed
+	return flag, true
+}
+
+// recordArtifact registers the file written by a command and returns a note
+// telling the client where to fetch it.
+func (a *adapter) recordArtifact(config CommandExecutionConfig, flag string) string {
+	file := config.FixedFlags[flag]
+	if value, _ := config.FlagValues[flag].(string); value != "" {
+		file = value
+	}
+	if file == "" {
+		return ""
+	}
+	artifact, err := a.artifacts.add(file, config.CommandName)
+	if errors.Is(err, os.ErrNotExist) {
+		return ""
+	}
+	if err != nil {
+		return fmt.Sprintf("The file is not available as an artifact: %v", err)
+	}
+	return fmt.Sprintf("Artifact: %s (%s, %d bytes, sha256 %s)", artifact.URI, artifact.MIMEType, artifact.Size, artifact.SHA256)
+}
+
+// registerArtifacts adds the resources serving the artifacts.
+func (a *adapter) registerArtifacts() {
+	a.builtins.addResource(artifactsResourceName, mcp.NewResource(
+		artifactsURI,
+		artifactsResourceName,
+		mcp.WithResourceDescription("Files produced by create-backup, download-backup and download, with their MIME type, size and SHA-256 checksum"),
+		mcp.WithMIMEType("application/json"),
+	), func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		items := []artifact{}
+		if a.artifacts != nil {
+			items = a.artifacts.list()
+		}
+		return jsonResourceContents(req.Params.URI, items)
+	})
+
+	a.builtins.addTemplate(artifactsTemplateName, mcp.NewResourceTemplate(
+		artifactsURI+"/{id}",
+		artifactsTemplateName,
+		mcp.WithTemplateDescription("A file produced by a command, such as a backup or a charm archive, as a base64 blob. "+
+			"juju://artifacts lists them with their MIME type, size and checksum."),
+	), func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		id := strings.TrimPrefix(req.Params.URI, artifactsURI+"/")
+		if a.artifacts == nil {
+			return nil, fmt.Errorf("artifact %s not found", id)
+		}
+		artifact, ok := a.artifacts.get(id)
+		if !ok {
+			return nil, fmt.Errorf("artifact %s not found or expired", id)
+		}
+		contents, err := a.artifacts.read(artifact)
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{contents}, nil
+	})
+}
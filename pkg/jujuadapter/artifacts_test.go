@@ -0,0 +1,181 @@
+package jujuadapter
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestArtifacts(t *testing.T, maxSize int64) *artifactStore {
+	s, err := newArtifactStore(t.TempDir(), time.Hour, maxSize)
+	require.NoError(t, err)
+	s.now = func() time.Time { return time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC) }
+	return s
+}
+
+func readArtifact(a *adapter, uri string) ([]mcp.ResourceContents, error) {
+	_, handler, err := a.GetResourceTemplate(artifactsTemplateName)
+	if err != nil {
+		return nil, err
+	}
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = uri
+	return handler(context.Background(), req)
+}
+
+func TestArtifacts_DefaultOutput(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+	a.artifacts = newTestArtifacts(t, DefaultArtifactMaxSize)
+
+	backup := CommandExecutionConfig{CommandName: string(CmdDownloadBackup), Arguments: []string{"/var/lib/juju/backups/juju-backup-1.tar.gz"}}
+	flag, ok := a.defaultOutput(&backup)
+	require.True(t, ok)
+	assert.Equal(t, "filename", flag)
+	assert.Equal(t, filepath.Join(a.artifacts.dir, "juju-backup-1.tar.gz"), backup.FixedFlags["filename"])
+
+	a.workspace = newTestWorkspace(t)
+	charm := CommandExecutionConfig{CommandName: string(CmdDownload), Arguments: []string{"mysql"}}
+	_, ok = a.defaultOutput(&charm)
+	require.True(t, ok)
+	assert.Equal(t, filepath.Join(a.workspace.root, "mysql.charm"), charm.FixedFlags["filepath"])
+
+	kept := CommandExecutionConfig{CommandName: string(CmdCreateBackup), FlagValues: map[string]any{"no-download": true}}
+	_, ok = a.defaultOutput(&kept)
+	assert.False(t, ok)
+	assert.Empty(t, kept.FixedFlags)
+}
+
+func TestArtifacts_ServesProducedFiles(t *testing.T) {
+	// Arrange
+	content := []byte("backup archive")
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		if err := os.WriteFile(call.flags["filename"], content, 0o600); err != nil {
+			return err.Error()
+		}
+		return "Downloaded to " + call.flags["filename"]
+	})
+	factory.flags = []string{"filename"}
+	a.artifacts = newTestArtifacts(t, DefaultArtifactMaxSize)
+	sum := sha256.Sum256(content)
+	id := artifactID(filepath.Join(a.artifacts.dir, "juju-backup-20250701-120000.tar.gz"), hex.EncodeToString(sum[:]))
+
+	// Act
+	output, err := a.executeCommand(context.Background(), CommandExecutionConfig{CommandName: string(CmdCreateBackup)})
+	require.NoError(t, err)
+	_, listHandler, err := a.GetResource(artifactsResourceName)
+	require.NoError(t, err)
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = artifactsURI
+	list, err := listHandler(context.Background(), req)
+	require.NoError(t, err)
+	contents, err := readArtifact(a, artifactsURI+"/"+id)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, filepath.Join(a.artifacts.dir, "juju-backup-20250701-120000.tar.gz"), factory.calls[0].flags["filename"])
+	assert.Contains(t, output, "Artifact: juju://artifacts/"+id+" (application/gzip, 14 bytes, sha256 "+hex.EncodeToString(sum[:])+")")
+
+	var items []artifact
+	require.NoError(t, json.Unmarshal([]byte(list[0].(mcp.TextResourceContents).Text), &items))
+	require.Len(t, items, 1)
+	assert.Equal(t, "create-backup", items[0].Command)
+	assert.Equal(t, time.Date(2025, 7, 1, 13, 0, 0, 0, time.UTC), items[0].Expires)
+
+	blob := contents[0].(mcp.BlobResourceContents)
+	assert.Equal(t, "application/gzip", blob.MIMEType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(content), blob.Blob)
+}
+
+func TestArtifacts_ChangedExpiredAndOversized(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+	a.artifacts = newTestArtifacts(t, 8)
+	file := filepath.Join(a.artifacts.dir, "small.txt")
+	require.NoError(t, os.WriteFile(file, []byte("small"), 0o600))
+	item, err := a.artifacts.add(file, "download")
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(file, []byte("other"), 0o600))
+	_, err = readArtifact(a, item.URI)
+	assert.EqualError(t, err, "artifact "+item.ID+" changed since it was produced")
+
+	a.artifacts.now = func() time.Time { return item.Expires }
+	_, err = readArtifact(a, item.URI)
+	assert.EqualError(t, err, "artifact "+item.ID+" not found or expired")
+	assert.NoFileExists(t, file)
+
+	large := filepath.Join(a.artifacts.dir, "large.txt")
+	require.NoError(t, os.WriteFile(large, []byte("too large"), 0o600))
+	_, err = a.artifacts.add(large, "download")
+	assert.EqualError(t, err, large+" is 9 bytes, over the 8 bytes limit of artifacts")
+}
+
+func TestArtifacts_PrunesWorkspaceOutputs(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		if err := os.WriteFile(call.flags["filepath"], []byte(call.args[0]), 0o600); err != nil {
+			return err.Error()
+		}
+		return "Fetched " + call.args[0]
+	})
+	factory.flags = []string{"filepath"}
+	a.artifacts = newTestArtifacts(t, DefaultArtifactMaxSize)
+	a.workspace = newTestWorkspace(t)
+	named := filepath.Join(a.workspace.root, "mysql.charm")
+	given := filepath.Join(a.workspace.root, "kept.charm")
+	same := filepath.Join(a.workspace.root, "same.charm")
+	require.NoError(t, os.WriteFile(same, []byte("mysql"), 0o600))
+
+	// Act
+	_, err := a.executeCommand(context.Background(), CommandExecutionConfig{CommandName: string(CmdDownload), Arguments: []string{"mysql"}})
+	require.NoError(t, err)
+	_, err = a.executeCommand(context.Background(), CommandExecutionConfig{
+		CommandName: string(CmdDownload),
+		Arguments:   []string{"mysql"},
+		FlagValues:  map[string]any{"filepath": "kept.charm"},
+	})
+	require.NoError(t, err)
+	copied, err := a.artifacts.add(same, "download")
+	require.NoError(t, err)
+	itemsBefore := a.artifacts.list()
+	a.artifacts.now = func() time.Time { return time.Date(2025, 7, 2, 12, 0, 0, 0, time.UTC) }
+	itemsAfter := a.artifacts.list()
+
+	// Assert
+	require.Len(t, itemsBefore, 3)
+	assert.Empty(t, itemsAfter)
+	assert.NotEqual(t, artifactID(named, copied.SHA256), copied.ID, "the same content at another path is another artifact")
+	assert.NoFileExists(t, named)
+	assert.FileExists(t, given)
+	assert.FileExists(t, same)
+}
+
+func TestArtifacts_RewrittenPathKeepsNewContent(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+	a.artifacts = newTestArtifacts(t, DefaultArtifactMaxSize)
+	file := a.artifacts.output(t.TempDir(), "backup.tar.gz")
+	require.NoError(t, os.WriteFile(file, []byte("first"), 0o600))
+	first, err := a.artifacts.add(file, "create-backup")
+	require.NoError(t, err)
+
+	a.artifacts.now = func() time.Time { return first.Expires }
+	require.NoError(t, os.WriteFile(file, []byte("second"), 0o600))
+	second, err := a.artifacts.add(file, "create-backup")
+	require.NoError(t, err)
+
+	assert.NotEqual(t, first.ID, second.ID)
+	assert.FileExists(t, file)
+	_, ok := a.artifacts.get(first.ID)
+	assert.False(t, ok)
+	_, err = readArtifact(a, second.URI)
+	assert.NoError(t, err)
+}
@@ -0,0 +1,298 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+// blockCacheTTL is how long the disabled command sets of a model are reused
+// before they are fetched again.
+const blockCacheTTL = 30 * time.Second
+
+// blockFetchTimeout bounds the background refresh of the blocks.
+const blockFetchTimeout = 10 * time.Second
+
+// Command sets that can be disabled with `juju disable-command`.
+const (
+	BlockDestroyModel = "destroy-model"
+	BlockRemoveObject = "remove-object"
+	BlockAll          = "all"
+)
+
+// destroyCommands are prevented by every command set.
+var destroyCommands = []JujuCommandID{
+	CmdDestroyController,
+	CmdDestroyModel,
+}
+
+// removeCommands are prevented by the remove-object and all command sets.
+var removeCommands = []JujuCommandID{
+	CmdDetachStorage,
+	CmdRemoveApplication,
+	CmdRemoveMachine,
+	CmdRemoveRelation,
+	CmdRemoveSaas,
+	CmdRemoveStorage,
+	CmdRemoveUnit,
+}
+
+// changeCommands are prevented by the all command set only. The list follows
+// `juju help disable-command`.
+var changeCommands = []JujuCommandID{
+	CmdAddMachine,
+	CmdAddRelation,
+	CmdAddUnit,
+	CmdAddSshKey,
+	CmdAddUser,
+	CmdAttachResource,
+	CmdAttachStorage,
+	CmdChangePassword,
+	CmdConfig,
+	CmdConsume,
+	CmdDeploy,
+	CmdDisableUser,
+	CmdEnableHa,
+	CmdEnableUser,
+	CmdExpose,
+	CmdImportFilesystem,
+	CmdImportSshKey,
+	CmdModelDefaults,
+	CmdModelConfig,
+	CmdReloadSpaces,
+	CmdRemoveSshKey,
+	CmdRemoveUser,
+	CmdResolved,
+	CmdRetryProvisioning,
+	CmdRun,
+	CmdScaleApplication,
+	CmdModelCredential,
+	CmdSetConstraints,
+	CmdSyncAgentBinary,
+	CmdUnexpose,
+	CmdRefresh,
+	CmdUpgradeModel,
+}
+
+// blockingCommandSets returns the command sets that prevent the command from
+// running, or nil if the command cannot be disabled.
+func blockingCommandSets(id JujuCommandID) []string {
+	for _, cmd := range destroyCommands {
+		if cmd == id {
+			return []string{BlockDestroyModel, BlockRemoveObject, BlockAll}
+		}
+	}
+	for _, cmd := range removeCommands {
+		if cmd == id {
+			return []string{BlockRemoveObject, BlockAll}
+		}
+	}
+	for _, cmd := range changeCommands {
+		if cmd == id {
+			return []string{BlockAll}
+		}
+	}
+	return nil
+}
+
+// activeBlock returns the first active block that prevents the command.
+func activeBlock(id JujuCommandID, blocks []block.BlockInfo) (block.BlockInfo, bool) {
+	for _, set := range blockingCommandSets(id) {
+		for _, b := range blocks {
+			if b.Commands == set {
+				return b, true
+			}
+		}
+	}
+	return block.BlockInfo{}, false
+}
+
+func blockedMessage(id JujuCommandID, model string, b block.BlockInfo) string {
+	target := "the current model"
+	if model != "" {
+		target = fmt.Sprintf("model %q", model)
+	}
+	msg := fmt.Sprintf("the %q command is disabled on %s by `juju disable-command %s`", id, target, b.Commands)
+	if b.Message != "" {
+		msg += ": " + b.Message
+	}
+	return msg
+}
+
+type blockCacheEntry struct {
+	blocks []block.BlockInfo
+	// err is set when the lookup failed, so the blocks are unknown.
+	err     error
+	fetched time.Time
+}
+
+// blockCache keeps the disabled command sets per model, keyed by the model
+// argument as given ("" for the current model).
+type blockCache struct {
+	mu         sync.Mutex
+	entries    map[string]blockCacheEntry
+	refreshing map[string]bool
+	// generation changes on invalidate, so refreshes started before are
+	// not stored.
+	generation int
+	fetch      func(ctx context.Context, model string) ([]block.BlockInfo, error)
+	now        func() time.Time
+}
+
+func newBlockCache(fetch func(ctx context.Context, model string) ([]block.BlockInfo, error)) *blockCache {
+	return &blockCache{
+		entries:    make(map[string]blockCacheEntry),
+		refreshing: make(map[string]bool),
+		fetch:      fetch,
+		now:        time.Now,
+	}
+}
+
+// get returns the blocks for the model, fetching them if the cached ones are
+// stale. Failures are cached too, with their error, so an unreachable
+// controller is not queried on every call.
+func (c *blockCache) get(ctx context.Context, model string) ([]block.BlockInfo, error) {
+	c.mu.Lock()
+	entry, ok := c.entries[model]
+	generation := c.generation
+	c.mu.Unlock()
+	if ok && c.now().Sub(entry.fetched) < blockCacheTTL {
+		return entry.blocks, entry.err
+	}
+	entry = c.load(ctx, model, generation)
+	return entry.blocks, entry.err
+}
+
+// peek returns the cached blocks for the model without waiting for the
+// controller, and refreshes them in the background when they are stale or
+// missing. It returns false until the first lookup completes.
+func (c *blockCache) peek(model string) (blockCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[model]
+	if (!ok || c.now().Sub(entry.fetched) >= blockCacheTTL) && !c.refreshing[model] {
+		c.refreshing[model] = true
+		generation := c.generation
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), blockFetchTimeout)
+			defer cancel()
+			c.load(ctx, model, generation)
+			c.mu.Lock()
+			delete(c.refreshing, model)
+			c.mu.Unlock()
+		}()
+	}
+	return entry, ok
+}
+
+// load fetches the blocks for the model and caches them, unless the cache
+// was invalidated in the meantime.
+func (c *blockCache) load(ctx context.Context, model string, generation int) blockCacheEntry {
+	blocks, err := c.fetch(ctx, model)
+	if err != nil {
+		log.Debug().Err(err).Str("model", model).Msg("Unable to fetch disabled commands")
+	}
+	entry := blockCacheEntry{blocks: blocks, err: err, fetched: c.now()}
+	c.mu.Lock()
+	if c.generation == generation {
+		c.entries[model] = entry
+	}
+	c.mu.Unlock()
+	return entry
+}
+
+// invalidate drops cached blocks, for example after disable-command runs.
+func (c *blockCache) invalidate() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.entries = make(map[string]blockCacheEntry)
+	c.generation++
+}
+
+// fetchBlocks lists the blocks of a model the same way `juju disabled-commands`
+// does.
+func (a *adapter) fetchBlocks(ctx context.Context, model string) ([]block.BlockInfo, error) {
+	flagValues := map[string]interface{}{}
+	if model != "" {
+		flagValues["model"] = model
+	}
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdDisabledCommands),
+		FixedFlags:  map[string]string{"format": "json"},
+		FlagValues:  flagValues,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var blocks []block.BlockInfo
+	if err := json.Unmarshal([]byte(strings.TrimSpace(output)), &blocks); err != nil {
+		return nil, fmt.Errorf("failed to parse disabled commands: %w", err)
+	}
+	return blocks, nil
+}
+
+// checkBlocked returns the block message if the command is disabled on the
+// model it targets. When the blocks are unknown the command is let through:
+// Juju still enforces its blocks server side.
+func (a *adapter) checkBlocked(ctx context.Context, id JujuCommandID, model string) (string, bool) {
+	if blockingCommandSets(id) == nil {
+		return "", false
+	}
+	blocks, err := a.blocks.get(ctx, model)
+	if err != nil {
+		return "", false
+	}
+	b, blocked := activeBlock(id, blocks)
+	if !blocked {
+		return "", false
+	}
+	return blockedMessage(id, model, b), true
+}
+
+// AnnotateBlockedTools marks tools that are disabled on the current model. It
+// is used as a tool filter so the listing reflects blocks added after start up.
+// It never waits for the controller: the listing uses the cached blocks,
+// which are refreshed in the background, and marks the tools that can be
+// disabled when the last lookup failed.
+func (a *adapter) AnnotateBlockedTools(ctx context.Context, tools []mcp.Tool) []mcp.Tool {
+	entry, found := a.blocks.peek("")
+	if !found || (entry.err == nil && len(entry.blocks) == 0) {
+		return tools
+	}
+	for i := range tools {
+		command := JujuCommandID(a.CommandName(tools[i].Name))
+		if entry.err != nil {
+			if blockingCommandSets(command) != nil {
+				tools[i].Description = fmt.Sprintf("MAY BE DISABLED: unable to check the disabled commands of the current model: %v.\n\n%s",
+					entry.err, tools[i].Description)
+				tools[i].Annotations.Title = fmt.Sprintf("%s (disabled: unknown)", tools[i].Name)
+			}
+			continue
+		}
+		b, blocked := activeBlock(command, entry.blocks)
+		if !blocked {
+			continue
+		}
+		tools[i].Description = fmt.Sprintf("DISABLED: %s.\n\n%s", blockedMessage(command, "", b), tools[i].Description)
+		tools[i].Annotations.Title = fmt.Sprintf("%s (disabled: %s)", tools[i].Name, b.Commands)
+	}
+	return tools
+}
+
+// targetModel returns the model a tool call runs against, "" meaning the
+// current model.
+func targetModel(flagValues map[string]interface{}) string {
+	for _, key := range []string{"model", "m"} {
+		if model, ok := flagValues[key].(string); ok && model != "" {
+			return model
+		}
+	}
+	return ""
+}
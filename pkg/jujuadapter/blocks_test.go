@@ -0,0 +1,117 @@
+package jujuadapter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestActiveBlock(t *testing.T) {
+	blocks := []block.BlockInfo{{Commands: BlockRemoveObject, Message: "production"}}
+
+	b, blocked := activeBlock(CmdRemoveApplication, blocks)
+	assert.True(t, blocked)
+	assert.Equal(t, "production", b.Message)
+
+	_, blocked = activeBlock(CmdDestroyModel, blocks)
+	assert.True(t, blocked, "remove-object also prevents destroy-model")
+
+	_, blocked = activeBlock(CmdDeploy, blocks)
+	assert.False(t, blocked, "remove-object does not prevent changes")
+
+	_, blocked = activeBlock(CmdStatus, []block.BlockInfo{{Commands: BlockAll}})
+	assert.False(t, blocked, "read only commands are never blocked")
+}
+
+func TestBlockCache(t *testing.T) {
+	calls := 0
+	cache := newBlockCache(func(ctx context.Context, model string) ([]block.BlockInfo, error) {
+		calls++
+		if model == "unreachable" {
+			return nil, errors.New("connection refused")
+		}
+		return []block.BlockInfo{{Commands: BlockAll}}, nil
+	})
+	now := time.Now()
+	cache.now = func() time.Time { return now }
+
+	blocks, err := cache.get(context.Background(), "prod")
+	assert.NoError(t, err)
+	assert.Len(t, blocks, 1)
+	cache.get(context.Background(), "prod")
+	assert.Equal(t, 1, calls)
+
+	_, err = cache.get(context.Background(), "unreachable")
+	assert.EqualError(t, err, "connection refused")
+	_, err = cache.get(context.Background(), "unreachable")
+	assert.EqualError(t, err, "connection refused", "failures are cached as unknown")
+	assert.Equal(t, 2, calls)
+
+	now = now.Add(blockCacheTTL)
+	cache.get(context.Background(), "prod")
+	assert.Equal(t, 3, calls)
+
+	cache.invalidate()
+	cache.get(context.Background(), "prod")
+	assert.Equal(t, 4, calls)
+}
+
+func TestBlockCachePeek(t *testing.T) {
+	release := make(chan struct{})
+	cache := newBlockCache(func(ctx context.Context, model string) ([]block.BlockInfo, error) {
+		<-release
+		return []block.BlockInfo{{Commands: BlockAll}}, nil
+	})
+
+	_, found := cache.peek("")
+	assert.False(t, found, "peek does not wait for the controller")
+	close(release)
+
+	assert.Eventually(t, func() bool {
+		entry, found := cache.peek("")
+		return found && len(entry.blocks) == 1
+	}, time.Second, 10*time.Millisecond)
+}
+
+func TestAnnotateBlockedTools(t *testing.T) {
+	a := &adapter{}
+	a.blocks = newBlockCache(func(ctx context.Context, model string) ([]block.BlockInfo, error) {
+		return []block.BlockInfo{{Commands: BlockDestroyModel, Message: "do not destroy"}}, nil
+	})
+	a.blocks.get(context.Background(), "")
+	tools := []mcp.Tool{
+		mcp.NewTool("destroy-model", mcp.WithDescription("Terminate all machines")),
+		mcp.NewTool("status", mcp.WithDescription("Report status")),
+	}
+
+	tools = a.AnnotateBlockedTools(context.Background(), tools)
+
+	assert.Contains(t, tools[0].Description, "DISABLED")
+	assert.Contains(t, tools[0].Description, "do not destroy")
+	assert.Equal(t, "destroy-model (disabled: destroy-model)", tools[0].Annotations.Title)
+	assert.Equal(t, "Report status", tools[1].Description)
+}
+
+func TestAnnotateBlockedToolsUnknown(t *testing.T) {
+	a := &adapter{}
+	a.blocks = newBlockCache(func(ctx context.Context, model string) ([]block.BlockInfo, error) {
+		return nil, errors.New("connection refused")
+	})
+	a.blocks.get(context.Background(), "")
+	tools := []mcp.Tool{
+		mcp.NewTool("deploy", mcp.WithDescription("Deploy a charm")),
+		mcp.NewTool("status", mcp.WithDescription("Report status")),
+	}
+
+	tools = a.AnnotateBlockedTools(context.Background(), tools)
+
+	assert.Contains(t, tools[0].Description, "MAY BE DISABLED")
+	assert.Contains(t, tools[0].Description, "connection refused")
+	assert.Equal(t, "deploy (disabled: unknown)", tools[0].Annotations.Title)
+	assert.Equal(t, "Report status", tools[1].Description)
+}
@@ -0,0 +1,102 @@
+package jujuadapter
+
+import (
+	"sort"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+// builtinTool is a tool implemented by the adapter itself rather than by a
+// single Juju command.
+type builtinTool struct {
+	tool    mcp.Tool
+	handler mcpserver.ToolHandlerFunc
+}
+
+// builtinResource is a static resource served by the adapter.
+type builtinResource struct {
+	resource mcp.Resource
+	handler  mcpserver.ResourceHandlerFunc
+}
+
+// builtinResourceTemplate is a resource template served by the adapter
+// rather than by a Juju command.
+type builtinResourceTemplate struct {
+	template mcp.ResourceTemplate
+	handler  mcpserver.ResourceTemplateHandlerFunc
+}
+
+// builtins holds the tools and resources the adapter provides on top of the
+// Juju commands, keyed by name.
+type builtins struct {
+	tools     map[string]builtinTool
+	resources map[string]builtinResource
+	templates map[string]builtinResourceTemplate
+}
+
+func newBuiltins() *builtins {
+	return &builtins{
+		tools:     make(map[string]builtinTool),
+		resources: make(map[string]builtinResource),
+		templates: make(map[string]builtinResourceTemplate),
+	}
+}
+
+func (b *builtins) addTool(tool mcp.Tool, handler mcpserver.ToolHandlerFunc) {
+	b.tools[tool.Name] = builtinTool{tool: tool, handler: handler}
+}
+
+func (b *builtins) addResource(name string, resource mcp.Resource, handler mcpserver.ResourceHandlerFunc) {
+	b.resources[name] = builtinResource{resource: resource, handler: handler}
+}
+
+func (b *builtins) addTemplate(name string, template mcp.ResourceTemplate, handler mcpserver.ResourceTemplateHandlerFunc) {
+	b.templates[name] = builtinResourceTemplate{template: template, handler: handler}
+}
+
+func (b *builtins) isTool(name string) bool {
+	_, ok := b.tools[name]
+	return ok
+}
+
+func (b *builtins) toolNames() []string {
+	return sortedKeys(b.tools)
+}
+
+func (b *builtins) resourceNames() []string {
+	return sortedKeys(b.resources)
+}
+
+func (b *builtins) templateNames() []string {
+	return sortedKeys(b.templates)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// registerBuiltins adds the adapter's own tools and resources.
+func (a *adapter) registerBuiltins() {
+	a.builtins = newBuiltins()
+	a.registerJournal()
+	a.registerDiagnose()
+	a.registerHealth()
+	a.registerDeploy()
+	a.registerWaitFor()
+	a.registerInteractive()
+	a.registerWorkspace()
+	a.registerArtifacts()
+	a.registerLogs()
+	a.registerLogSummary()
+	a.registerStatusDiff()
+	a.registerTopology()
+	a.registerConfigDrift()
+	a.registerBundles()
+	a.registerUpgradePlan()
+}
@@ -0,0 +1,482 @@
+package jujuadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/juju/charm/v12"
+	"github.com/juju/juju/core/constraints"
+	"github.com/juju/juju/core/devices"
+	"github.com/juju/juju/storage"
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	BundleValidateToolName = "bundle-validate"
+	BundleGenerateToolName = "bundle-generate"
+)
+
+// Checks reported by bundle-validate, in the order they are listed.
+const (
+	checkSyntax      = "syntax"
+	checkSchema      = "schema"
+	checkOverlay     = "overlay"
+	checkCharm       = "charm"
+	checkChannel     = "channel"
+	checkBase        = "base"
+	checkRelations   = "relations"
+	checkPlacement   = "placement"
+	checkConstraints = "constraints"
+	checkStorage     = "storage"
+	checkBundle      = "bundle"
+)
+
+// bundleIssue is an error or a warning found in a bundle.
+type bundleIssue struct {
+	Check   string `json:"check"`
+	Message string `json:"message"`
+}
+
+// bundleValidation is the result of bundle-validate.
+type bundleValidation struct {
+	Valid        bool          `json:"valid"`
+	Summary      string        `json:"summary"`
+	Documents    int           `json:"documents"`
+	Applications []string      `json:"applications,omitempty"`
+	Machines     int           `json:"machines,omitempty"`
+	Relations    int           `json:"relations,omitempty"`
+	Errors       []bundleIssue `json:"errors"`
+	Warnings     []bundleIssue `json:"warnings,omitempty"`
+}
+
+func (v *bundleValidation) fail(check string, err error) {
+	v.Errors = append(v.Errors, bundleIssue{Check: check, Message: err.Error()})
+}
+
+// verificationCheck tells which check a verification error of the charm
+// library belongs to, from its message.
+func verificationCheck(message string) string {
+	switch {
+	case strings.Contains(message, "constraints"):
+		return checkConstraints
+	case strings.Contains(message, "storage"), strings.Contains(message, "device"):
+		return checkStorage
+	case strings.Contains(message, "relation"), strings.Contains(message, "endpoint"):
+		return checkRelations
+	case strings.Contains(message, "placement"), strings.Contains(message, "machine"):
+		return checkPlacement
+	case strings.Contains(message, "base"), strings.Contains(message, "series"):
+		return checkBase
+	case strings.Contains(message, "charm"), strings.Contains(message, "revision"):
+		return checkCharm
+	}
+	return checkBundle
+}
+
+// validateBundle checks a bundle offline, the way deploy does before talking
+// to the controller: each document is parsed strictly, the overlays, the
+// documents after the first and the overlay files, are merged into the base
+// bundle, and the result is verified. Charms and their endpoints are not
+// fetched, so only the syntax of the relations is checked.
+func validateBundle(data []byte, basePath string, overlays ...[]byte) bundleValidation {
+	v := bundleValidation{Errors: []bundleIssue{}}
+	var sources []charm.BundleDataSource
+	for i, doc := range append([][]byte{data}, overlays...) {
+		source, err := charm.StreamBundleDataSource(bytes.NewReader(doc), basePath)
+		if err != nil {
+			if i > 0 {
+				err = fmt.Errorf("overlay %d: %w", i, err)
+			}
+			v.fail(checkSyntax, err)
+			continue
+		}
+		for _, part := range source.Parts() {
+			if part.UnmarshallError != nil {
+				v.fail(checkSchema, part.UnmarshallError)
+			}
+		}
+		v.Documents += len(source.Parts())
+		sources = append(sources, source)
+	}
+	if len(v.Errors) > 0 && len(sources) == 0 {
+		v.Summary = describeBundleValidation(v)
+		return v
+	}
+
+	verifyConstraints := func(s string) error {
+		_, err := constraints.Parse(s)
+		return err
+	}
+	verifyStorage := func(s string) error {
+		_, err := storage.ParseConstraints(s)
+		return err
+	}
+	verifyDevices := func(s string) error {
+		_, err := devices.ParseConstraints(s)
+		return err
+	}
+
+	// image-id is only allowed in overlays.
+	if parts := sources[0].Parts(); len(parts) > 0 && parts[0].Data != nil {
+		for name, app := range parts[0].Data.Applications {
+			if app == nil || app.Constraints == "" {
+				continue
+			}
+			if cons, err := constraints.Parse(app.Constraints); err == nil && cons.HasImageID() {
+				v.fail(checkConstraints, fmt.Errorf("application %q: the image-id constraint is only supported in overlays", name))
+			}
+		}
+	}
+
+	merged, err := charm.ReadAndMergeBundleData(sources...)
+	if err != nil {
+		v.fail(checkOverlay, err)
+		v.Summary = describeBundleValidation(v)
+		return v
+	}
+
+	for _, name := range sortedKeys(merged.Applications) {
+		app := merged.Applications[name]
+		if app == nil || app.Channel == "" {
+			continue
+		}
+		if _, err := charm.ParseChannelNormalize(app.Channel); err != nil {
+			v.fail(checkChannel, fmt.Errorf("application %q declares an invalid channel %q: %v", name, app.Channel, err))
+		}
+	}
+	if merged.Series != "" || hasSeries(merged) {
+		v.Warnings = append(v.Warnings, bundleIssue{Check: checkBase, Message: "series are deprecated in favour of bases, such as ubuntu@22.04"})
+	}
+
+	if basePath == "" {
+		err = merged.Verify(verifyConstraints, verifyStorage, verifyDevices)
+	} else {
+		err = merged.VerifyLocal(basePath, verifyConstraints, verifyStorage, verifyDevices)
+	}
+	var verr *charm.VerificationError
+	switch {
+	case errors.As(err, &verr):
+		messages := make([]string, 0, len(verr.Errors))
+		for _, e := range verr.Errors {
+			messages = append(messages, e.Error())
+		}
+		// The verifier walks maps, sort its errors for stable results.
+		for _, message := range sortedUnique(messages) {
+			v.Errors = append(v.Errors, bundleIssue{Check: verificationCheck(message), Message: message})
+		}
+	case err != nil:
+		v.fail(checkBundle, err)
+	}
+
+	v.Applications = sortedKeys(merged.Applications)
+	v.Machines = len(merged.Machines)
+	v.Relations = len(merged.Relations)
+	v.Valid = len(v.Errors) == 0
+	v.Summary = describeBundleValidation(v)
+	return v
+}
+
+func hasSeries(bd *charm.BundleData) bool {
+	for _, app := range bd.Applications {
+		if app != nil && app.Series != "" {
+			return true
+		}
+	}
+	for _, m := range bd.Machines {
+		if m != nil && m.Series != "" {
+			return true
+		}
+	}
+	return false
+}
+
+func sortedUnique(values []string) []string {
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		set[value] = true
+	}
+	return sortedKeys(set)
+}
+
+func describeBundleValidation(v bundleValidation) string {
+	if v.Valid {
+		text := fmt.Sprintf("The bundle is valid: %s, %s, %s.", plural(len(v.Applications), "application"),
+			plural(v.Machines, "machine"), plural(v.Relations, "relation"))
+		if len(v.Warnings) > 0 {
+			text += fmt.Sprintf(" %s.", plural(len(v.Warnings), "warning"))
+		}
+		return text
+	}
+	counts := map[string]int{}
+	for _, issue := range v.Errors {
+		counts[issue.Check]++
+	}
+	var parts []string
+	for _, check := range []string{checkSyntax, checkSchema, checkOverlay, checkCharm, checkChannel, checkBase, checkRelations,
+		checkPlacement, checkConstraints, checkStorage, checkBundle} {
+		if counts[check] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", counts[check], check))
+		}
+	}
+	return fmt.Sprintf("The bundle is not valid, %s (%s). First: %s", plural(len(v.Errors), "error"),
+		strings.Join(parts, ", "), v.Errors[0].Message)
+}
+
+// generatedApplication is an application given to bundle-generate.
+type generatedApplication struct {
+	Name        string            `json:"name"`
+	Charm       string            `json:"charm"`
+	Channel     string            `json:"channel"`
+	Revision    *int              `json:"revision"`
+	Base        string            `json:"base"`
+	NumUnits    int               `json:"num_units"`
+	To          []string          `json:"to"`
+	Options     map[string]any    `json:"options"`
+	Constraints string            `json:"constraints"`
+	Storage     map[string]string `json:"storage"`
+	Bindings    map[string]string `json:"bindings"`
+	Expose      bool              `json:"expose"`
+	Trust       bool              `json:"trust"`
+}
+
+// generatedMachine is a machine given to bundle-generate.
+type generatedMachine struct {
+	Base        string `json:"base"`
+	Constraints string `json:"constraints"`
+}
+
+// bundleRequest is the input of bundle-generate.
+type bundleRequest struct {
+	Description  string                      `json:"description"`
+	Type         string                      `json:"type"`
+	DefaultBase  string                      `json:"default_base"`
+	Applications []generatedApplication      `json:"applications"`
+	Machines     map[string]generatedMachine `json:"machines"`
+	Relations    [][]string                  `json:"relations"`
+	Saas         map[string]string           `json:"saas"`
+}
+
+// bundleData builds the bundle described by a bundle-generate request.
+func (r bundleRequest) bundleData() (*charm.BundleData, error) {
+	bd := &charm.BundleData{
+		Type:         r.Type,
+		Description:  r.Description,
+		DefaultBase:  r.DefaultBase,
+		Applications: make(map[string]*charm.ApplicationSpec, len(r.Applications)),
+		Relations:    r.Relations,
+	}
+	for _, app := range r.Applications {
+		if app.Name == "" {
+			return nil, errors.New("every application needs a name")
+		}
+		if _, ok := bd.Applications[app.Name]; ok {
+			return nil, fmt.Errorf("application %q is given more than once", app.Name)
+		}
+		bd.Applications[app.Name] = &charm.ApplicationSpec{
+			Charm:            app.Charm,
+			Channel:          app.Channel,
+			Revision:         app.Revision,
+			Base:             app.Base,
+			NumUnits:         app.NumUnits,
+			To:               app.To,
+			Options:          app.Options,
+			Constraints:      app.Constraints,
+			Storage:          app.Storage,
+			EndpointBindings: app.Bindings,
+			Expose:           app.Expose,
+			RequiresTrust:    app.Trust,
+		}
+	}
+	if len(r.Machines) > 0 {
+		bd.Machines = make(map[string]*charm.MachineSpec, len(r.Machines))
+		for id, m := range r.Machines {
+			bd.Machines[id] = &charm.MachineSpec{Base: m.Base, Constraints: m.Constraints}
+		}
+	}
+	if len(r.Saas) > 0 {
+		bd.Saas = make(map[string]*charm.SaasSpec, len(r.Saas))
+		for name, url := range r.Saas {
+			bd.Saas[name] = &charm.SaasSpec{URL: url}
+		}
+	}
+	return bd, nil
+}
+
+// marshalBundle writes a bundle the way bundles are usually written, with a
+// two space indentation.
+func marshalBundle(bd *charm.BundleData) ([]byte, error) {
+	var b bytes.Buffer
+	encoder := yaml.NewEncoder(&b)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(bd); err != nil {
+		return nil, fmt.Errorf("failed to encode the bundle: %w", err)
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, fmt.Errorf("failed to encode the bundle: %w", err)
+	}
+	return b.Bytes(), nil
+}
+
+// registerBundles adds the bundle-validate and bundle-generate tools.
+func (a *adapter) registerBundles() {
+	a.builtins.addTool(mcp.NewTool(BundleValidateToolName,
+		mcp.WithDescription("Validate a bundle offline before deploying it: YAML syntax and schema, charm, channel and base "+
+			"syntax, relation endpoint format, machine placement references, overlay merging, and constraints, storage and "+
+			"device directives. Charms are not fetched, so endpoint names are not checked against them. Returns the errors "+
+			"and warnings found, each with the check it failed."),
+		mcp.WithString("bundle",
+			mcp.Description("Bundle file to validate, in the workspace when there is one"),
+		),
+		mcp.WithString("content",
+			mcp.Description("Bundle YAML to validate instead of a file; overlays may follow as further YAML documents"),
+		),
+		mcp.WithArray("overlays",
+			mcp.Description("Overlay files to merge into the bundle, in order"),
+			mcp.WithStringItems(),
+		),
+	), a.handleBundleValidate)
+
+	a.builtins.addTool(mcp.NewTool(BundleGenerateToolName,
+		mcp.WithDescription("Generate bundle YAML from a structured description of its applications, machines, relations and "+
+			"SAAS applications, validate it like bundle-validate, and optionally write it to the workspace. Deploy the "+
+			"result with deploy once it is valid."),
+		mcp.WithArray("applications",
+			mcp.Required(),
+			mcp.Description("Applications of the bundle, such as [{\"name\": \"mysql\", \"charm\": \"mysql\", \"channel\": \"8.0/stable\", "+
+				"\"num_units\": 3, \"options\": {\"profile\": \"production\"}, \"constraints\": \"mem=4G\", \"storage\": "+
+				"{\"database\": \"ebs,100G\"}, \"bindings\": {\"\": \"alpha\"}, \"to\": [\"0\"]}]; revision, base, expose and "+
+				"trust may be set too"),
+			mcp.Items(map[string]any{"type": "object"}),
+		),
+		mcp.WithArray("relations",
+			mcp.Description("Relations as pairs of endpoints, such as [[\"wordpress:db\", \"mysql:database\"]]"),
+			mcp.Items(map[string]any{"type": "array", "items": map[string]any{"type": "string"}}),
+		),
+		mcp.WithObject("machines",
+			mcp.Description("Machines by ID, such as {\"0\": {\"base\": \"ubuntu@22.04\", \"constraints\": \"cores=4\"}}"),
+		),
+		mcp.WithObject("saas",
+			mcp.Description("SAAS applications by name with their offer URL, such as {\"loki\": \"cos:admin/cos.loki\"}"),
+		),
+		mcp.WithString("default_base",
+			mcp.Description("Base of the applications that do not set one, such as ubuntu@24.04"),
+		),
+		mcp.WithString("type",
+			mcp.Description("Bundle type, kubernetes for Kubernetes models"),
+			mcp.Enum("kubernetes"),
+		),
+		mcp.WithString("description",
+			mcp.Description("Description of the bundle"),
+		),
+		mcp.WithString("filename",
+			mcp.Description("Workspace file to write the bundle to"),
+		),
+	), a.handleBundleGenerate)
+}
+
+func (a *adapter) handleBundleValidate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	file := req.GetString("bundle", "")
+	content := req.GetString("content", "")
+	if (file == "") == (content == "") {
+		return mcp.NewToolResultError("exactly one of bundle or content is required"), nil
+	}
+
+	var data []byte
+	var basePath string
+	if a.workspace != nil {
+		basePath = a.workspace.root
+	}
+	if file != "" {
+		path, err := a.localPath(file)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if data, err = os.ReadFile(path); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("unable to read the bundle: %v", err)), nil
+		}
+		// Local charms and included files are relative to the bundle.
+		basePath = filepath.Dir(path)
+	} else {
+		data = []byte(content)
+	}
+
+	var overlays [][]byte
+	for _, overlay := range req.GetStringSlice("overlays", nil) {
+		path, err := a.localPath(overlay)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("unable to read overlay %s: %v", overlay, err)), nil
+		}
+		overlays = append(overlays, data)
+	}
+	return jsonToolResult(validateBundle(data, basePath, overlays...))
+}
+
+func (a *adapter) handleBundleGenerate(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	encoded, err := json.Marshal(req.GetArguments())
+	if err != nil {
+		return nil, err
+	}
+	var request bundleRequest
+	if err := json.Unmarshal(encoded, &request); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid bundle description: %v", err)), nil
+	}
+	if len(request.Applications) == 0 {
+		return mcp.NewToolResultError("at least one application is required"), nil
+	}
+	bd, err := request.bundleData()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	data, err := marshalBundle(bd)
+	if err != nil {
+		return nil, err
+	}
+
+	var basePath string
+	if a.workspace != nil {
+		basePath = a.workspace.root
+	}
+	result := struct {
+		Bundle     string           `json:"bundle"`
+		File       string           `json:"file,omitempty"`
+		Validation bundleValidation `json:"validation"`
+	}{Bundle: string(data), Validation: validateBundle(data, basePath)}
+
+	if filename := req.GetString("filename", ""); filename != "" {
+		if a.workspace == nil {
+			return mcp.NewToolResultError(errNoWorkspace.Error()), nil
+		}
+		full, err := a.workspace.resolve(filename)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		if err := os.MkdirAll(filepath.Dir(full), 0o700); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("unable to write the bundle: %v", err)), nil
+		}
+		if err := os.WriteFile(full, data, 0o600); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("unable to write the bundle: %v", err)), nil
+		}
+		result.File = a.workspace.rel(full)
+	}
+	return jsonToolResult(result)
+}
+
+// localPath returns the path of a file given to a tool: inside the
+// workspace when there is one, as given otherwise.
+func (a *adapter) localPath(name string) (string, error) {
+	if a.workspace == nil {
+		return name, nil
+	}
+	return a.workspace.resolve(name)
+}
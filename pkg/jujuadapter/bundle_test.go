@@ -0,0 +1,160 @@
+package jujuadapter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const validBundle = `default-base: ubuntu@22.04
+machines:
+  "0":
+    constraints: cores=4
+applications:
+  mysql:
+    charm: mysql
+    channel: 8.0/stable
+    num_units: 1
+    to: ["0"]
+    storage:
+      database: rootfs,10G
+  wordpress:
+    charm: wordpress
+    num_units: 1
+relations:
+- [wordpress:db, mysql:database]
+`
+
+const brokenBundle = `default-base: ubuntu@22.04
+applications:
+  mysql:
+    charm: mysql
+    channel: 8.0/superstable
+    num_units: 1
+    to: ["3"]
+    constraints: mem=lots
+    storage:
+      database: rootfs,ten
+    colour: blue
+  wordpress:
+    charm: wordpress
+    num_units: 1
+relations:
+- [wordpress:db, mysql:database, loki]
+`
+
+func TestValidateBundle(t *testing.T) {
+	valid := validateBundle([]byte(validBundle), "")
+
+	assert.True(t, valid.Valid, "%v", valid.Errors)
+	assert.Equal(t, []string{"mysql", "wordpress"}, valid.Applications)
+	assert.Equal(t, "The bundle is valid: 2 applications, 1 machine, 1 relation.", valid.Summary)
+
+	broken := validateBundle([]byte(brokenBundle), "")
+
+	assert.False(t, broken.Valid)
+	checks := map[string]bool{}
+	for _, issue := range broken.Errors {
+		checks[issue.Check] = true
+	}
+	assert.Equal(t, map[string]bool{
+		checkSchema: true, checkChannel: true, checkPlacement: true, checkConstraints: true, checkStorage: true, checkRelations: true,
+	}, checks, "%v", broken.Errors)
+	assert.Contains(t, broken.Errors, bundleIssue{Check: checkChannel,
+		Message: `application "mysql" declares an invalid channel "8.0/superstable": risk in channel "8.0/superstable" not valid`})
+
+	syntax := validateBundle([]byte("applications: [mysql"), "")
+	assert.False(t, syntax.Valid)
+	assert.Equal(t, checkSyntax, syntax.Errors[0].Check)
+}
+
+func TestBundleValidateOverlays(t *testing.T) {
+	// Arrange
+	a, _ := newFakeAdapter(func(call fakeCall) string { return "" })
+	w, err := newWorkspace(t.TempDir())
+	require.NoError(t, err)
+	a.workspace = w
+	require.NoError(t, os.WriteFile(filepath.Join(w.root, "bundle.yaml"), []byte(validBundle), 0o600))
+	require.NoError(t, os.WriteFile(filepath.Join(w.root, "overlay.yaml"), []byte(`applications:
+  wordpress:
+    num_units: 2
+    to: ["lxd:9"]
+`), 0o600))
+
+	// Act
+	base := callTool(t, a, BundleValidateToolName, map[string]any{"bundle": "bundle.yaml"})
+	overlaid := callTool(t, a, BundleValidateToolName, map[string]any{"bundle": "bundle.yaml", "overlays": []any{"overlay.yaml"}})
+	outside := callTool(t, a, BundleValidateToolName, map[string]any{"bundle": "../bundle.yaml"})
+
+	// Assert
+	var result bundleValidation
+	require.NoError(t, json.Unmarshal([]byte(resultText(base)), &result))
+	assert.True(t, result.Valid, "%v", result.Errors)
+	require.NoError(t, json.Unmarshal([]byte(resultText(overlaid)), &result))
+	assert.False(t, result.Valid)
+	assert.Equal(t, 2, result.Documents)
+	assert.Equal(t, []bundleIssue{{Check: checkPlacement, Message: `placement "lxd:9" refers to a machine not defined in this bundle`}}, result.Errors)
+	assert.True(t, outside.IsError)
+}
+
+func TestBundleGenerate(t *testing.T) {
+	// Arrange
+	a, _ := newFakeAdapter(func(call fakeCall) string { return "" })
+	w, err := newWorkspace(t.TempDir())
+	require.NoError(t, err)
+	a.workspace = w
+
+	// Act
+	result := callTool(t, a, BundleGenerateToolName, map[string]any{
+		"default_base": "ubuntu@22.04",
+		"applications": []any{
+			map[string]any{"name": "mysql", "charm": "mysql", "channel": "8.0/stable", "num_units": 3,
+				"options": map[string]any{"profile": "production"}, "constraints": "mem=4G"},
+			map[string]any{"name": "wordpress", "charm": "wordpress", "num_units": 1, "expose": true},
+		},
+		"relations": []any{[]any{"wordpress:db", "mysql:database"}},
+		"filename":  "generated/bundle.yaml",
+	})
+	invalid := callTool(t, a, BundleGenerateToolName, map[string]any{
+		"applications": []any{map[string]any{"name": "mysql", "charm": "mysql", "to": []any{"0"}}},
+	})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	var generated struct {
+		Bundle     string           `json:"bundle"`
+		File       string           `json:"file"`
+		Validation bundleValidation `json:"validation"`
+	}
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &generated))
+	assert.Equal(t, `applications:
+  mysql:
+    charm: mysql
+    channel: 8.0/stable
+    num_units: 3
+    options:
+      profile: production
+    constraints: mem=4G
+  wordpress:
+    charm: wordpress
+    num_units: 1
+    expose: true
+default-base: ubuntu@22.04
+relations:
+  - - wordpress:db
+    - mysql:database
+`, generated.Bundle)
+	assert.True(t, generated.Validation.Valid, "%v", generated.Validation.Errors)
+	assert.Equal(t, "generated/bundle.yaml", generated.File)
+	written, err := os.ReadFile(filepath.Join(w.root, "generated", "bundle.yaml"))
+	require.NoError(t, err)
+	assert.Equal(t, generated.Bundle, string(written))
+
+	require.NoError(t, json.Unmarshal([]byte(resultText(invalid)), &generated))
+	assert.False(t, generated.Validation.Valid)
+	assert.Equal(t, checkPlacement, generated.Validation.Errors[0].Check)
+}
@@ -3,6 +3,8 @@ package jujuadapter
 import (
 	"bytes"
 	"context"
+	"io"
+	"strings"
 	"time"
 
 	"github.com/juju/cmd/v3"
@@ -42,12 +44,45 @@ func (c *command) Info() *cmd.Info {
 }
 
 
+type stdinKey struct{}
+
+// withStdin returns a context whose commands read their standard input from
+// input, such as the answers to the prompts of register.
+func withStdin(ctx context.Context, input string) context.Context {
+	return context.WithValue(ctx, stdinKey{}, input)
+}
+
+// stdin returns the standard input of the commands run with ctx. It is empty
+// unless set with withStdin: a command must never read the server's own
+// standard input, which carries the MCP messages over stdio.
+func stdin(ctx context.Context) io.Reader {
+	input, _ := ctx.Value(stdinKey{}).(string)
+	return strings.NewReader(input)
+}
+
+type stdoutKey struct{}
+
+// withStdout returns a context whose commands write their standard output to
+// w as it is produced, rather than returning it once they finish, such as a
+// debug-log that follows the log.
+func withStdout(ctx context.Context, w io.Writer) context.Context {
+	return context.WithValue(ctx, stdoutKey{}, w)
+}
+
+// streamedStdout returns the writer set with withStdout, or nil.
+func streamedStdout(ctx context.Context) io.Writer {
+	w, _ := ctx.Value(stdoutKey{}).(io.Writer)
+	return w
+}
+
 func (c *command) getContext(ctx context.Context) (*cmd.Context, error) {
 	cmdCtx, err := cmd.DefaultContext()
 	if err != nil {
 		return nil, err
 	}
-	// Note: cmd/v3 Context might not have Context field
+	// Commands watching the context, such as debug-log, stop once it is done
+	cmdCtx.Context = ctx
+	cmdCtx.Stdin = stdin(ctx)
 	return cmdCtx, nil
 }
 
@@ -56,12 +91,17 @@ func (c *command) getContextWithOutput(ctx context.Context) (*cmd.Context, *byte
 	if err != nil {
 		return nil, nil, nil, err
 	}
-	// Note: cmd/v3 Context might not have Context field
+	// Commands watching the context, such as debug-log, stop once it is done
+	cmdCtx.Context = ctx
 
 	stdout := &bytes.Buffer{}
 	stderr := &bytes.Buffer{}
+	cmdCtx.Stdin = stdin(ctx)
 	cmdCtx.Stdout = stdout
 	cmdCtx.Stderr = stderr
+	if w := streamedStdout(ctx); w != nil {
+		cmdCtx.Stdout = w
+	}
 
 	return cmdCtx, stdout, stderr, nil
 }
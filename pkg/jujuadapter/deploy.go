@@ -0,0 +1,374 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	DeployAndWaitToolName = "deploy-and-wait"
+
+	defaultDeployTimeout = 10 * time.Minute
+)
+
+// deployPollInterval is how often deploy-and-wait checks the status of the
+// deployed units.
+var deployPollInterval = 5 * time.Second
+
+// Outcomes of deploy-and-wait.
+const (
+	deploySettled  = "settled"
+	deployPartial  = "partial"
+	deployTimedOut = "timed_out"
+	deployError    = "error"
+	deployFailed   = "failed"
+)
+
+var deployedPattern = regexp.MustCompile(`Deployed "([^"]+)"`)
+
+// deployStep is a command run by deploy-and-wait.
+type deployStep struct {
+	Step    string `json:"step"`
+	Command string `json:"command"`
+	OK      bool   `json:"ok"`
+	Output  string `json:"output,omitempty"`
+	Error   string `json:"error,omitempty"`
+}
+
+// deployUnit is the last status seen of a deployed unit.
+type deployUnit struct {
+	Workload string `json:"workload"`
+	Agent    string `json:"agent"`
+	Message  string `json:"message,omitempty"`
+}
+
+// deployReport is the result of deploy-and-wait.
+type deployReport struct {
+	Application string                `json:"application"`
+	Model       string                `json:"model,omitempty"`
+	Outcome     string                `json:"outcome"`
+	Summary     string                `json:"summary"`
+	Steps       []deployStep          `json:"steps"`
+	Units       map[string]deployUnit `json:"units,omitempty"`
+	Waited      string                `json:"waited,omitempty"`
+	Progress    []string              `json:"progress,omitempty"`
+}
+
+// registerDeploy adds the deploy-and-wait tool.
+func (a *adapter) registerDeploy() {
+	a.builtins.addTool(mcp.NewTool(DeployAndWaitToolName,
+		mcp.WithDescription("Deploy a charm, add its relations, optionally expose it, then wait until its units are active and idle "+
+			"or the timeout passes. Sends progress notifications while waiting and returns a final report of every step and unit; "+
+			"steps that failed after the deploy are reported without undoing the deploy."),
+		mcp.WithString("charm",
+			mcp.Required(),
+			mcp.Description("Charm to deploy, such as mysql or ./mysql.charm"),
+		),
+		mcp.WithString("application",
+			mcp.Description("Application name, the charm name if empty"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Model to deploy to, the current model if empty"),
+		),
+		mcp.WithString("channel",
+			mcp.Description("Charmhub channel, such as 8.0/stable"),
+		),
+		mcp.WithNumber("revision",
+			mcp.Description("Charm revision to deploy"),
+		),
+		mcp.WithNumber("num_units",
+			mcp.Description("Number of units to deploy"),
+			mcp.DefaultNumber(1),
+		),
+		mcp.WithObject("config",
+			mcp.Description("Application configuration, such as {\"profile\": \"testing\"}"),
+		),
+		mcp.WithString("constraints",
+			mcp.Description("Application constraints, such as \"cores=2 mem=4G\""),
+		),
+		mcp.WithString("base",
+			mcp.Description("Base to deploy on, such as ubuntu@22.04"),
+		),
+		mcp.WithBoolean("trust",
+			mcp.Description("Allow the charm to use the credentials of the model"),
+		),
+		mcp.WithArray("relations",
+			mcp.Description("Endpoints to relate the application to, such as [\"mysql-router\", \"traefik:ingress\"]"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithBoolean("expose",
+			mcp.Description("Expose the application once deployed"),
+		),
+		mcp.WithString("expose_endpoints",
+			mcp.Description("Comma separated endpoints to expose, all of them if empty"),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("How long to wait for the units to settle"),
+			mcp.DefaultString(defaultDeployTimeout.String()),
+		),
+	), a.handleDeployAndWait)
+}
+
+func (a *adapter) handleDeployAndWait(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	charm, err := req.RequireString("charm")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	timeout, err := time.ParseDuration(req.GetString("timeout", defaultDeployTimeout.String()))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid timeout: %v", err)), nil
+	}
+	model := req.GetString("model", "")
+	numUnits := req.GetInt("num_units", 1)
+	arguments := req.GetArguments()
+
+	deployFlags := map[string]any{
+		"model":       model,
+		"channel":     req.GetString("channel", ""),
+		"constraints": req.GetString("constraints", ""),
+		"base":        req.GetString("base", ""),
+		"n":           numUnits,
+	}
+	if _, ok := arguments["revision"]; ok {
+		deployFlags["revision"] = req.GetInt("revision", -1)
+	}
+	if req.GetBool("trust", false) {
+		deployFlags["trust"] = true
+	}
+	if raw, ok := arguments["config"]; ok && raw != nil {
+		settings, ok := raw.(map[string]any)
+		if !ok {
+			return mcp.NewToolResultError("config must be an object"), nil
+		}
+		var config []string
+		for _, key := range sortedKeys(settings) {
+			config = append(config, key+"="+formatSettingValue(settings[key]))
+		}
+		deployFlags["config"] = config
+	}
+
+	for _, id := range []JujuCommandID{CmdDeploy, CmdAddRelation, CmdExpose} {
+		if msg, blocked := a.checkBlocked(ctx, id, model); blocked {
+			return mcp.NewToolResultError(msg), nil
+		}
+	}
+
+	progress := newProgressReporter(ctx, req)
+	report := &deployReport{Application: req.GetString("application", ""), Model: model}
+	finish := func(outcome, summary string) (*mcp.CallToolResult, error) {
+		report.Outcome = outcome
+		report.Summary = summary
+		report.Progress = progress.messages
+		encoded, err := json.MarshalIndent(report, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		if outcome == deployFailed {
+			return mcp.NewToolResultError(string(encoded)), nil
+		}
+		return mcp.NewToolResultText(string(encoded)), nil
+	}
+	// Each command goes through the same checks and policies as a direct
+	// call to it, so approvals and limits on deploy also apply here.
+	step := func(name string, config CommandExecutionConfig) bool {
+		output, err := a.runStep(ctx, config)
+		s := deployStep{Step: name, Command: commandLine(config), OK: err == nil, Output: strings.TrimSpace(output)}
+		if err != nil {
+			s.Error = err.Error()
+			progress.report(fmt.Sprintf("%s failed: %s", name, firstLine(s.Error)))
+		} else {
+			progress.report(name + " done")
+		}
+		report.Steps = append(report.Steps, s)
+		return err == nil
+	}
+
+	// Deploy
+	deployArgs := []string{charm}
+	if report.Application != "" {
+		deployArgs = append(deployArgs, report.Application)
+	}
+	if !step("deploy", CommandExecutionConfig{CommandName: string(CmdDeploy), Arguments: deployArgs, FlagValues: deployFlags}) {
+		return finish(deployFailed, fmt.Sprintf("Deploying %s failed; nothing else was done.", charm))
+	}
+	if report.Application == "" {
+		report.Application = deployedApplication(charm, report.Steps[0].Output)
+	}
+	app := report.Application
+
+	// Relations and expose
+	var failed []string
+	for _, endpoint := range req.GetStringSlice("relations", nil) {
+		if !step("add-relation "+endpoint, CommandExecutionConfig{
+			CommandName: string(CmdAddRelation),
+			Arguments:   []string{app, endpoint},
+			FixedFlags:  withModel(nil, model),
+		}) {
+			failed = append(failed, "add-relation "+endpoint)
+		}
+	}
+	if req.GetBool("expose", false) || req.GetString("expose_endpoints", "") != "" {
+		flags := withModel(nil, model)
+		if endpoints := req.GetString("expose_endpoints", ""); endpoints != "" {
+			flags = withModel(map[string]string{"endpoints": endpoints}, model)
+		}
+		if !step("expose", CommandExecutionConfig{CommandName: string(CmdExpose), Arguments: []string{app}, FixedFlags: flags}) {
+			failed = append(failed, "expose")
+		}
+	}
+
+	// Wait for the units
+	started := time.Now()
+	outcome, err := a.waitForUnits(ctx, report, numUnits, timeout, progress)
+	report.Waited = time.Since(started).Round(time.Second).String()
+	var summary string
+	switch {
+	case err != nil:
+		outcome = deployError
+		summary = fmt.Sprintf("%s was deployed but waiting for it failed: %v.", app, err)
+	case outcome == deployError:
+		summary = fmt.Sprintf("%s was deployed but some of its units are in error.", app)
+	case outcome == deployTimedOut:
+		summary = fmt.Sprintf("%s was deployed but its units did not settle within %s.", app, timeout)
+	case len(failed) > 0:
+		outcome = deployPartial
+		summary = fmt.Sprintf("%s is deployed and its units are active and idle.", app)
+	default:
+		summary = fmt.Sprintf("%s is deployed and its units are active and idle.", app)
+	}
+	if len(failed) > 0 {
+		summary += fmt.Sprintf(" These steps failed: %s.", strings.Join(failed, ", "))
+	}
+	return finish(outcome, summary)
+}
+
+// waitForUnits polls the status until the units of the application are
+// active and idle, one of them is in error or the timeout passes. A call
+// cancelled by the client is an error rather than a timeout.
+func (a *adapter) waitForUnits(ctx context.Context, report *deployReport, numUnits int, timeout time.Duration, progress *progressReporter) (string, error) {
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(deployPollInterval)
+	defer ticker.Stop()
+
+	last := ""
+	for {
+		units, err := a.applicationUnits(waitCtx, report.Application, report.Model)
+		switch {
+		case ctx.Err() != nil:
+			return "", context.Cause(ctx)
+		case waitCtx.Err() != nil:
+			return deployTimedOut, nil
+		case err != nil:
+			return "", err
+		}
+		report.Units = units
+
+		settled, inError := 0, 0
+		for _, unit := range units {
+			switch {
+			case unit.Workload == "error" || unit.Agent == "error" || unit.Agent == "lost":
+				inError++
+			case unit.Workload == "active" && unit.Agent == "idle":
+				settled++
+			}
+		}
+		if summary := fmt.Sprintf("%d of %d units of %s active and idle", settled, max(numUnits, len(units)), report.Application); summary != last {
+			progress.report(summary)
+			last = summary
+		}
+		switch {
+		case inError > 0:
+			return deployError, nil
+		case len(units) > 0 && settled == len(units) && len(units) >= numUnits:
+			return deploySettled, nil
+		}
+
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return "", context.Cause(ctx)
+			}
+			return deployTimedOut, nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// applicationUnits returns the units of an application, including the
+// subordinate units of a subordinate application.
+func (a *adapter) applicationUnits(ctx context.Context, app string, model string) (map[string]deployUnit, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var status modelStatus
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status: %w", err)
+	}
+
+	units := make(map[string]deployUnit)
+	add := func(name string, unit modelStatusUnit) {
+		if strings.HasPrefix(name, app+"/") {
+			units[name] = deployUnit{
+				Workload: unit.WorkloadStatus.Current,
+				Agent:    unit.JujuStatus.Current,
+				Message:  unit.WorkloadStatus.Message,
+			}
+		}
+	}
+	for _, application := range status.Applications {
+		for name, unit := range application.Units {
+			add(name, unit)
+			for sub, subUnit := range unit.Subordinates {
+				add(sub, subUnit)
+			}
+		}
+	}
+	return units, nil
+}
+
+// deployedApplication returns the application name from the deploy output,
+// or derives it from the charm.
+func deployedApplication(charm string, output string) string {
+	if match := deployedPattern.FindStringSubmatch(output); match != nil {
+		return match[1]
+	}
+	name := path.Base(strings.TrimPrefix(strings.TrimPrefix(charm, "ch:"), "local:"))
+	return strings.TrimSuffix(name, ".charm")
+}
+
+// commandLine renders a call as the equivalent juju command line.
+func commandLine(config CommandExecutionConfig) string {
+	flags := make(map[string]string)
+	var repeated []string
+	for _, name := range sortedKeys(config.FlagValues) {
+		if values, ok := config.FlagValues[name].([]string); ok {
+			for _, value := range values {
+				repeated = append(repeated, fmt.Sprintf("--%s=%s", name, value))
+			}
+			continue
+		}
+		if value := formatFlagValue(config.FlagValues[name]); value != "" {
+			flags[name] = value
+		}
+	}
+	for name, value := range config.FixedFlags {
+		flags[name] = value
+	}
+	line := revertStep{Command: config.CommandName, Arguments: config.Arguments, Flags: flags}.String()
+	if len(repeated) > 0 {
+		line += " " + strings.Join(repeated, " ")
+	}
+	return line
+}
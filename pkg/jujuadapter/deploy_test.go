@@ -0,0 +1,190 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// deployingStatus returns the status of mysql, whose units settle on the
+// second poll.
+func deployingStatus() func(call fakeCall) string {
+	var polls atomic.Int32
+	return func(call fakeCall) string {
+		switch call.name {
+		case "deploy":
+			return `Deployed "mysql" from charm-hub charm "mysql", revision 151 in channel 8.0/stable on ubuntu@22.04/stable`
+		case "status":
+			if len(call.args) > 0 {
+				return `{"applications":{"mysql":{"exposed":false}}}`
+			}
+			if polls.Add(1) == 1 {
+				return `{"applications":{"mysql":{"units":{
+					"mysql/0":{"workload-status":{"current":"waiting","message":"agent initialising"},"juju-status":{"current":"allocating"}}}}}}`
+			}
+			return `{"applications":{"mysql":{"units":{
+				"mysql/0":{"workload-status":{"current":"active"},"juju-status":{"current":"idle"},
+					"subordinates":{"telegraf/0":{"workload-status":{"current":"active"},"juju-status":{"current":"idle"}}}},
+				"mysql/1":{"workload-status":{"current":"active"},"juju-status":{"current":"idle"}}}}}}`
+		}
+		return ""
+	}
+}
+
+// fastPolling makes deploy-and-wait poll the status every millisecond.
+func fastPolling(t *testing.T) {
+	interval := deployPollInterval
+	deployPollInterval = time.Millisecond
+	t.Cleanup(func() { deployPollInterval = interval })
+}
+
+func decodeDeployReport(t *testing.T, text string) deployReport {
+	var report deployReport
+	require.NoError(t, json.Unmarshal([]byte(text), &report))
+	return report
+}
+
+func TestDeployAndWait(t *testing.T) {
+	// Arrange
+	fastPolling(t)
+	a, factory := newFakeAdapter(deployingStatus())
+	factory.repeatable = []string{"config"}
+
+	// Act
+	result := callTool(t, a, DeployAndWaitToolName, map[string]any{
+		"charm":     "mysql",
+		"model":     "prod",
+		"channel":   "8.0/stable",
+		"num_units": float64(2),
+		"config":    map[string]any{"profile": "testing", "max-connections": float64(100)},
+		"relations": []any{"mysql-router"},
+		"expose":    true,
+	})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	report := decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deploySettled, report.Outcome)
+	assert.Equal(t, "mysql is deployed and its units are active and idle.", report.Summary)
+	require.Len(t, report.Steps, 3)
+	assert.Equal(t, "juju deploy mysql --channel=8.0/stable --model=prod --n=2 --config=max-connections=100 --config=profile=testing", report.Steps[0].Command)
+	assert.Equal(t, "juju add-relation mysql mysql-router --model=prod", report.Steps[1].Command)
+	assert.Equal(t, "juju expose mysql --model=prod", report.Steps[2].Command)
+	assert.Equal(t, map[string]deployUnit{
+		"mysql/0": {Workload: "active", Agent: "idle"},
+		"mysql/1": {Workload: "active", Agent: "idle"},
+	}, report.Units)
+	assert.Contains(t, report.Progress, "0 of 2 units of mysql active and idle")
+	assert.Contains(t, report.Progress, "2 of 2 units of mysql active and idle")
+	assert.Equal(t, "max-connections=100,profile=testing", factory.calls[0].flags["config"])
+}
+
+func TestDeployAndWait_ReportsPartialFailures(t *testing.T) {
+	// Arrange
+	fastPolling(t)
+	a, factory := newFakeAdapter(deployingStatus())
+	factory.fail = func(call fakeCall) error {
+		if call.name == "add-relation" && call.args[1] == "postgresql" {
+			return errors.New(`no relations found`)
+		}
+		return nil
+	}
+
+	// Act
+	result := callTool(t, a, DeployAndWaitToolName, map[string]any{
+		"charm":     "ch:mysql",
+		"relations": []any{"postgresql", "mysql-router"},
+	})
+
+	// Assert
+	require.False(t, result.IsError)
+	report := decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deployPartial, report.Outcome)
+	assert.Equal(t, "mysql is deployed and its units are active and idle. These steps failed: add-relation postgresql.", report.Summary)
+	assert.False(t, report.Steps[1].OK)
+	assert.Contains(t, report.Steps[1].Error, "no relations found")
+	assert.True(t, report.Steps[2].OK)
+}
+
+func TestDeployAndWait_TimesOut(t *testing.T) {
+	fastPolling(t)
+	a, _ := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "status" {
+			return `{"applications":{"mysql":{"units":{"mysql/0":{"workload-status":{"current":"maintenance"},"juju-status":{"current":"executing"}}}}}}`
+		}
+		return ""
+	})
+
+	result := callTool(t, a, DeployAndWaitToolName, map[string]any{"charm": "mysql", "timeout": "20ms"})
+
+	report := decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deployTimedOut, report.Outcome)
+	assert.Equal(t, "mysql", report.Application, "derived from the charm without a deploy message")
+	assert.Equal(t, "maintenance", report.Units["mysql/0"].Workload)
+}
+
+func TestDeployAndWait_Cancelled(t *testing.T) {
+	fastPolling(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	a, _ := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "status" {
+			cancel()
+			return `{"applications":{"mysql":{"units":{"mysql/0":{"workload-status":{"current":"maintenance"},"juju-status":{"current":"executing"}}}}}}`
+		}
+		return ""
+	})
+
+	result := callToolContext(t, ctx, a, DeployAndWaitToolName, map[string]any{"charm": "mysql", "timeout": "1m"})
+
+	report := decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deployError, report.Outcome, "a cancelled call did not time out")
+	assert.True(t, strings.HasPrefix(report.Summary, "mysql was deployed but waiting for it failed: context canceled."), report.Summary)
+}
+
+func TestDeployAndWait_StopsAtUnitErrors(t *testing.T) {
+	fastPolling(t)
+	a, _ := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "status" {
+			return `{"applications":{"mysql":{"units":{"mysql/0":{"workload-status":{"current":"error","message":"hook failed: \"install\""},"juju-status":{"current":"idle"}}}}}}`
+		}
+		return ""
+	})
+
+	result := callTool(t, a, DeployAndWaitToolName, map[string]any{"charm": "mysql", "application": "db", "timeout": "20ms"})
+
+	report := decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deployTimedOut, report.Outcome, "mysql/0 is not a unit of db")
+
+	a, _ = newFakeAdapter(func(call fakeCall) string {
+		if call.name == "status" {
+			return `{"applications":{"db":{"units":{"db/0":{"workload-status":{"current":"error","message":"hook failed: \"install\""},"juju-status":{"current":"idle"}}}}}}`
+		}
+		return ""
+	})
+	result = callTool(t, a, DeployAndWaitToolName, map[string]any{"charm": "mysql", "application": "db", "timeout": "1m"})
+
+	report = decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deployError, report.Outcome)
+	assert.True(t, strings.HasPrefix(report.Summary, "db was deployed but some of its units are in error."))
+}
+
+func TestDeployAndWait_DeployFailure(t *testing.T) {
+	a, factory := newFakeAdapter(nil)
+	factory.fail = func(call fakeCall) error { return errors.New("charm not found") }
+
+	result := callTool(t, a, DeployAndWaitToolName, map[string]any{"charm": "mysqll", "relations": []any{"mysql-router"}})
+
+	assert.True(t, result.IsError)
+	report := decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deployFailed, report.Outcome)
+	assert.Len(t, report.Steps, 1)
+	assert.Len(t, factory.calls, 1)
+}
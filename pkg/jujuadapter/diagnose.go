@@ -0,0 +1,405 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/names/v5"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	DiagnoseUnitToolName = "diagnose-unit"
+
+	defaultDiagnoseLogLines = 50
+	defaultDiagnoseHistory  = 20
+)
+
+// Severities of the issues a diagnosis reports, most severe first.
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+	SeverityInfo    = "info"
+)
+
+var severityRank = map[string]int{SeverityError: 0, SeverityWarning: 1, SeverityInfo: 2}
+
+// issue is a problem detected in the output of one or more commands.
+type issue struct {
+	Severity string `json:"severity"`
+	Source   string `json:"source"`
+	Message  string `json:"message"`
+}
+
+// sortIssues orders issues by severity, keeping the detection order within
+// a severity.
+func sortIssues(issues []issue) {
+	sort.SliceStable(issues, func(i, j int) bool {
+		return severityRank[issues[i].Severity] < severityRank[issues[j].Severity]
+	})
+}
+
+// unitDiagnosis is the report of the diagnose-unit tool. Sections whose
+// command failed are left empty and the failure is listed in Errors.
+type unitDiagnosis struct {
+	Unit          string            `json:"unit"`
+	Model         string            `json:"model,omitempty"`
+	Summary       string            `json:"summary"`
+	Issues        []issue           `json:"issues"`
+	Details       json.RawMessage   `json:"details,omitempty"`
+	StatusHistory []statusEntry     `json:"status_history,omitempty"`
+	Logs          []string          `json:"logs,omitempty"`
+	Machine       json.RawMessage   `json:"machine,omitempty"`
+	Operations    json.RawMessage   `json:"operations,omitempty"`
+	Errors        map[string]string `json:"errors,omitempty"`
+}
+
+// statusEntry is a line of `juju status-history --format json`.
+type statusEntry struct {
+	Status  string     `json:"status"`
+	Message string     `json:"message,omitempty"`
+	Since   *time.Time `json:"since,omitempty"`
+	Kind    string     `json:"type,omitempty"`
+}
+
+// unitDetails is the part of `juju show-unit --format json` the diagnosis
+// looks at.
+type unitDetails struct {
+	Machine      string `json:"machine"`
+	Life         string `json:"life"`
+	RelationInfo []struct {
+		Endpoint        string `json:"endpoint"`
+		RelatedEndpoint string `json:"related-endpoint"`
+		RelatedUnits    map[string]struct {
+			InScope bool `json:"in-scope"`
+		} `json:"related-units"`
+	} `json:"relation-info"`
+}
+
+type machineDetails struct {
+	Machines map[string]struct {
+		JujuStatus struct {
+			Current string `json:"current"`
+			Message string `json:"message"`
+		} `json:"juju-status"`
+		MachineStatus struct {
+			Current string `json:"current"`
+			Message string `json:"message"`
+		} `json:"machine-status"`
+	} `json:"machines"`
+}
+
+type operationSummary struct {
+	Summary string `json:"summary"`
+	Status  string `json:"status"`
+}
+
+// registerDiagnose adds the diagnose-unit tool.
+func (a *adapter) registerDiagnose() {
+	a.builtins.addTool(mcp.NewTool(DiagnoseUnitToolName,
+		mcp.WithDescription("Diagnose a unit in one call. Runs show-unit, status-history, debug-log, show-machine and operations in parallel "+
+			"and returns the unit's status history, latest log lines, relation data, machine state and pending operations "+
+			"together with a summary of the issues detected."),
+		mcp.WithString("unit",
+			mcp.Required(),
+			mcp.Description("Unit name, such as mysql/0"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Model of the unit, the current model if empty"),
+		),
+		mcp.WithNumber("log_lines",
+			mcp.Description("Number of recent debug log lines to include"),
+			mcp.DefaultNumber(defaultDiagnoseLogLines),
+		),
+		mcp.WithNumber("history",
+			mcp.Description("Number of status history entries to include"),
+			mcp.DefaultNumber(defaultDiagnoseHistory),
+		),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), a.handleDiagnoseUnit)
+}
+
+func (a *adapter) handleDiagnoseUnit(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	unit, err := req.RequireString("unit")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if !names.IsValidUnit(unit) {
+		return mcp.NewToolResultError(fmt.Sprintf("%q is not a valid unit name", unit)), nil
+	}
+	model := req.GetString("model", "")
+	logLines := req.GetInt("log_lines", defaultDiagnoseLogLines)
+	history := req.GetInt("history", defaultDiagnoseHistory)
+
+	report := a.diagnoseUnit(ctx, unit, model, logLines, history)
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(encoded)), nil
+}
+
+// diagnoseUnit gathers the report. The commands run in parallel, except
+// show-machine which needs the machine from show-unit.
+func (a *adapter) diagnoseUnit(ctx context.Context, unit, model string, logLines, history int) *unitDiagnosis {
+	report := &unitDiagnosis{Unit: unit, Model: model, Errors: make(map[string]string)}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	run := func(section string, config CommandExecutionConfig, collect func(output string) error) {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			output, err := a.executeCommand(ctx, config)
+			mu.Lock()
+			defer mu.Unlock()
+			if err == nil {
+				err = collect(output)
+			}
+			if err != nil {
+				report.Errors[section] = err.Error()
+			}
+		}()
+	}
+
+	var details unitDetails
+	run("details", CommandExecutionConfig{
+		CommandName: string(CmdShowUnit),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+		Arguments:   []string{unit},
+	}, func(output string) error {
+		var units map[string]json.RawMessage
+		if err := json.Unmarshal([]byte(output), &units); err != nil {
+			return fmt.Errorf("failed to parse show-unit output: %w", err)
+		}
+		raw, ok := units[unit]
+		if !ok {
+			return fmt.Errorf("unit %q not found", unit)
+		}
+		if err := json.Unmarshal(raw, &details); err != nil {
+			return fmt.Errorf("failed to parse show-unit output: %w", err)
+		}
+		report.Details = raw
+		if details.Machine != "" {
+			run("machine", CommandExecutionConfig{
+				CommandName: string(CmdShowMachine),
+				FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+				Arguments:   []string{details.Machine},
+			}, func(output string) error {
+				report.Machine = json.RawMessage(output)
+				return nil
+			})
+		}
+		return nil
+	})
+	run("status_history", CommandExecutionConfig{
+		CommandName: string(CmdStatusHistory),
+		FixedFlags:  withModel(map[string]string{"format": "json", "type": "unit", "n": strconv.Itoa(history)}, model),
+		Arguments:   []string{unit},
+	}, func(output string) error {
+		if err := json.Unmarshal([]byte(output), &report.StatusHistory); err != nil {
+			return fmt.Errorf("failed to parse status-history output: %w", err)
+		}
+		return nil
+	})
+	run("logs", CommandExecutionConfig{
+		CommandName: string(CmdDebugLog),
+		FixedFlags:  withModel(map[string]string{"include": unit, "limit": strconv.Itoa(logLines)}, model),
+	}, func(output string) error {
+		report.Logs = nonEmptyLines(output)
+		return nil
+	})
+	run("operations", CommandExecutionConfig{
+		CommandName: string(CmdOperations),
+		FixedFlags:  withModel(map[string]string{"format": "json", "units": unit, "status": "pending,running"}, model),
+	}, func(output string) error {
+		// Without matching operations the command only prints a notice.
+		if output = strings.TrimSpace(output); strings.HasPrefix(output, "{") {
+			report.Operations = json.RawMessage(output)
+		}
+		return nil
+	})
+	wg.Wait()
+
+	if len(report.Errors) == 0 {
+		report.Errors = nil
+	}
+	report.Issues = detectUnitIssues(report, details)
+	report.Summary = summarizeIssues(unit, report.Issues)
+	return report
+}
+
+// detectUnitIssues looks for the usual reasons a unit is unhealthy in the
+// gathered sections.
+func detectUnitIssues(report *unitDiagnosis, details unitDetails) []issue {
+	issues := []issue{}
+	add := func(severity, source, format string, args ...any) {
+		issues = append(issues, issue{Severity: severity, Source: source, Message: fmt.Sprintf(format, args...)})
+	}
+
+	for _, section := range sortedKeys(report.Errors) {
+		add(SeverityWarning, section, "unable to gather %s: %s", section, firstLine(report.Errors[section]))
+	}
+
+	if details.Life != "" && details.Life != "alive" {
+		add(SeverityWarning, "details", "unit is %s", details.Life)
+	}
+	for _, relation := range details.RelationInfo {
+		inScope := 0
+		for _, related := range relation.RelatedUnits {
+			if related.InScope {
+				inScope++
+			}
+		}
+		if inScope == 0 {
+			add(SeverityInfo, "details", "relation %s has no related units in scope", relationName(relation.Endpoint, relation.RelatedEndpoint))
+		}
+	}
+
+	workload, agent := latestStatuses(report.StatusHistory)
+	switch workload.Status {
+	case "error":
+		add(SeverityError, "status_history", "workload is in error: %s", workload.Message)
+	case "blocked":
+		add(SeverityError, "status_history", "workload is blocked: %s", workload.Message)
+	case "waiting":
+		add(SeverityWarning, "status_history", "workload is waiting: %s", workload.Message)
+	}
+	switch agent.Status {
+	case "error", "failed", "lost":
+		add(SeverityError, "status_history", "agent is %s: %s", agent.Status, agent.Message)
+	}
+	if errors := countStatus(report.StatusHistory, "error"); errors > 1 {
+		add(SeverityWarning, "status_history", "unit entered the error state %d times in the last %d status changes", errors, len(report.StatusHistory))
+	}
+
+	var logErrors []string
+	for _, line := range report.Logs {
+		if isErrorLogLine(line) {
+			logErrors = append(logErrors, line)
+		}
+	}
+	if len(logErrors) > 0 {
+		add(SeverityWarning, "logs", "%s in the debug log, the last one: %s", plural(len(logErrors), "error line"), logErrors[len(logErrors)-1])
+	}
+
+	var machine machineDetails
+	if len(report.Machine) > 0 && json.Unmarshal(report.Machine, &machine) == nil {
+		for id, m := range machine.Machines {
+			if m.JujuStatus.Current != "" && m.JujuStatus.Current != "started" {
+				add(SeverityError, "machine", "machine %s agent is %s %s", id, m.JujuStatus.Current, m.JujuStatus.Message)
+			}
+			if m.MachineStatus.Current == "error" || m.MachineStatus.Current == "provisioning error" {
+				add(SeverityError, "machine", "machine %s is in %s: %s", id, m.MachineStatus.Current, m.MachineStatus.Message)
+			}
+		}
+	}
+
+	var operations map[string]operationSummary
+	if len(report.Operations) > 0 && json.Unmarshal(report.Operations, &operations) == nil && len(operations) > 0 {
+		add(SeverityInfo, "operations", "%s pending or running", plural(len(operations), "operation"))
+	}
+
+	for i := range issues {
+		issues[i].Message = strings.TrimSpace(issues[i].Message)
+	}
+	sortIssues(issues)
+	return issues
+}
+
+// latestStatuses returns the most recent workload and agent statuses.
+func latestStatuses(history []statusEntry) (workload, agent statusEntry) {
+	for _, entry := range history {
+		var latest *statusEntry
+		switch entry.Kind {
+		case "workload":
+			latest = &workload
+		case "juju-unit":
+			latest = &agent
+		default:
+			continue
+		}
+		if latest.Since == nil || (entry.Since != nil && !entry.Since.Before(*latest.Since)) {
+			*latest = entry
+		}
+	}
+	return workload, agent
+}
+
+func countStatus(history []statusEntry, status string) int {
+	count := 0
+	for _, entry := range history {
+		if entry.Status == status {
+			count++
+		}
+	}
+	return count
+}
+
+// isErrorLogLine reports whether a debug-log line, such as
+// "unit-mysql-0: 12:00:00 ERROR juju.worker.uniter hook failed", is an error.
+func isErrorLogLine(line string) bool {
+	for _, field := range strings.Fields(line) {
+		switch field {
+		case "ERROR", "CRITICAL":
+			return true
+		}
+	}
+	return strings.Contains(line, "Traceback (most recent call last)")
+}
+
+func relationName(endpoint, related string) string {
+	if related == "" {
+		return endpoint
+	}
+	return endpoint + " - " + related
+}
+
+func summarizeIssues(subject string, issues []issue) string {
+	if len(issues) == 0 {
+		return fmt.Sprintf("No issues detected for %s.", subject)
+	}
+	counts := make(map[string]int)
+	for _, i := range issues {
+		counts[i.Severity]++
+	}
+	var parts []string
+	for _, severity := range []string{SeverityError, SeverityWarning, SeverityInfo} {
+		if n := counts[severity]; n > 0 {
+			if severity == SeverityInfo {
+				parts = append(parts, fmt.Sprintf("%d %s", n, severity))
+			} else {
+				parts = append(parts, plural(n, severity))
+			}
+		}
+	}
+	return fmt.Sprintf("%s: %s. Most severe: %s", subject, strings.Join(parts, ", "), issues[0].Message)
+}
+
+// plural returns the count followed by the word, pluralized with an "s".
+func plural(n int, word string) string {
+	if n == 1 {
+		return fmt.Sprintf("%d %s", n, word)
+	}
+	return fmt.Sprintf("%d %ss", n, word)
+}
+
+func nonEmptyLines(output string) []string {
+	var lines []string
+	for _, line := range strings.Split(output, "\n") {
+		if line = strings.TrimRight(line, "\r "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return lines
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(s, "\n")
+	return line
+}
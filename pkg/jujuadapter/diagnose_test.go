@@ -0,0 +1,172 @@
+package jujuadapter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// unitInError answers the diagnose-unit commands for mysql/0, whose
+// install hook keeps failing.
+func unitInError(call fakeCall) string {
+	switch call.name {
+	case "show-unit":
+		return `{"mysql/0":{"machine":"0","life":"alive","relation-info":[
+			{"endpoint":"db","related-endpoint":"db","related-units":{"wordpress/0":{"in-scope":true}}},
+			{"endpoint":"cluster","related-endpoint":"cluster"}]}}`
+	case "show-machine":
+		return `{"machines":{"0":{"juju-status":{"current":"started"},"machine-status":{"current":"running"}}}}`
+	case "status-history":
+		return `[
+			{"status":"error","message":"hook failed: \"install\"","since":"2026-10-19T10:00:00Z","type":"workload"},
+			{"status":"maintenance","message":"installing","since":"2026-10-19T10:05:00Z","type":"workload"},
+			{"status":"executing","message":"running install hook","since":"2026-10-19T10:05:00Z","type":"juju-unit"},
+			{"status":"error","message":"hook failed: \"install\"","since":"2026-10-19T10:06:00Z","type":"workload"},
+			{"status":"idle","since":"2026-10-19T10:06:00Z","type":"juju-unit"}]`
+	case "debug-log":
+		return "unit-mysql-0: 10:05:59 INFO unit.mysql/0.juju-log installing packages\n" +
+			"unit-mysql-0: 10:06:00 ERROR unit.mysql/0.juju-log apt-get failed\n"
+	case "operations":
+		return "no matching operations"
+	}
+	return ""
+}
+
+func TestDiagnoseUnit(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(unitInError)
+	factory.flags = []string{"include", "limit", "status"}
+
+	// Act
+	result := callTool(t, a, DiagnoseUnitToolName, map[string]any{"unit": "mysql/0", "model": "prod", "log_lines": float64(10)})
+
+	// Assert
+	require.False(t, result.IsError)
+	var report unitDiagnosis
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &report))
+	assert.Equal(t, `mysql/0: 1 error, 2 warnings, 1 info. Most severe: workload is in error: hook failed: "install"`, report.Summary)
+	assert.Len(t, report.Logs, 2)
+	assert.NotEmpty(t, report.Machine)
+	assert.Empty(t, report.Operations)
+	assert.Empty(t, report.Errors)
+
+	byName := make(map[string]fakeCall)
+	for _, call := range factory.calls {
+		byName[call.name] = call
+	}
+	assert.Equal(t, map[string]string{"include": "mysql/0", "limit": "10", "model": "prod"}, byName["debug-log"].flags)
+	assert.Equal(t, []string{"0"}, byName["show-machine"].args)
+	assert.Equal(t, "pending,running", byName["operations"].flags["status"])
+}
+
+func TestDetectUnitIssues(t *testing.T) {
+	tests := []struct {
+		name    string
+		details string
+		report  unitDiagnosis
+		want    []issue
+	}{
+		{
+			name:    "healthy",
+			details: `{"life": "alive", "relation-info": [{"endpoint": "db", "related-endpoint": "db", "related-units": {"wordpress/0": {"in-scope": true}}}]}`,
+			report: unitDiagnosis{
+				StatusHistory: []statusEntry{{Status: "active", Kind: "workload"}, {Status: "idle", Kind: "juju-unit"}},
+				Logs:          []string{"unit-mysql-0: 10:05:59 INFO unit.mysql/0.juju-log started"},
+				Machine:       json.RawMessage(`{"machines": {"0": {"juju-status": {"current": "started"}}}}`),
+			},
+			want: []issue{},
+		},
+		{
+			name: "failing hook",
+			report: unitDiagnosis{
+				StatusHistory: []statusEntry{
+					{Status: "error", Message: `hook failed: "install"`, Kind: "workload"},
+					{Status: "maintenance", Message: "installing", Kind: "workload"},
+					{Status: "error", Message: `hook failed: "install"`, Kind: "workload"},
+				},
+				Logs: []string{"unit-mysql-0: 10:06:00 ERROR unit.mysql/0.juju-log apt-get failed"},
+			},
+			want: []issue{
+				{Severity: SeverityError, Source: "status_history", Message: `workload is in error: hook failed: "install"`},
+				{Severity: SeverityWarning, Source: "status_history", Message: "unit entered the error state 2 times in the last 3 status changes"},
+				{Severity: SeverityWarning, Source: "logs", Message: "1 error line in the debug log, the last one: unit-mysql-0: 10:06:00 ERROR unit.mysql/0.juju-log apt-get failed"},
+			},
+		},
+		{
+			name: "blocked with a lost agent",
+			report: unitDiagnosis{
+				StatusHistory: []statusEntry{{Status: "blocked", Message: "missing relation", Kind: "workload"}, {Status: "lost", Message: "agent lost", Kind: "juju-unit"}},
+			},
+			want: []issue{
+				{Severity: SeverityError, Source: "status_history", Message: "workload is blocked: missing relation"},
+				{Severity: SeverityError, Source: "status_history", Message: "agent is lost: agent lost"},
+			},
+		},
+		{
+			name:    "dying unit without related units",
+			details: `{"life": "dying", "relation-info": [{"endpoint": "cluster", "related-endpoint": "cluster"}]}`,
+			want: []issue{
+				{Severity: SeverityWarning, Source: "details", Message: "unit is dying"},
+				{Severity: SeverityInfo, Source: "details", Message: "relation cluster - cluster has no related units in scope"},
+			},
+		},
+		{
+			name: "machine down and pending operations",
+			report: unitDiagnosis{
+				Machine:    json.RawMessage(`{"machines": {"0": {"juju-status": {"current": "down", "message": "agent is not communicating"}}}}`),
+				Operations: json.RawMessage(`{"1": {"summary": "backup", "status": "pending"}}`),
+			},
+			want: []issue{
+				{Severity: SeverityError, Source: "machine", Message: "machine 0 agent is down agent is not communicating"},
+				{Severity: SeverityInfo, Source: "operations", Message: "1 operation pending or running"},
+			},
+		},
+		{
+			name:   "sections not gathered",
+			report: unitDiagnosis{Errors: map[string]string{"logs": "command 'debug-log' failed\nStderr: permission denied"}},
+			want:   []issue{{Severity: SeverityWarning, Source: "logs", Message: "unable to gather logs: command 'debug-log' failed"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var details unitDetails
+			if tt.details != "" {
+				require.NoError(t, json.Unmarshal([]byte(tt.details), &details))
+			}
+
+			assert.Equal(t, tt.want, detectUnitIssues(&tt.report, details))
+		})
+	}
+}
+
+func TestDiagnoseUnit_ReportsFailedSections(t *testing.T) {
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "show-unit" {
+			return "ERROR unit \"mysql/0\" not found"
+		}
+		return unitInError(call)
+	})
+
+	result := callTool(t, a, DiagnoseUnitToolName, map[string]any{"unit": "mysql/0"})
+
+	var report unitDiagnosis
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &report))
+	assert.Contains(t, report.Errors["details"], "failed to parse show-unit output")
+	assert.Contains(t, report.Issues, issue{Severity: SeverityWarning, Source: "details", Message: "unable to gather details: " + firstLine(report.Errors["details"])})
+	assert.NotEmpty(t, report.StatusHistory)
+	for _, call := range factory.calls {
+		assert.NotEqual(t, "show-machine", call.name, "the machine is unknown")
+	}
+}
+
+func TestDiagnoseUnit_RejectsInvalidUnit(t *testing.T) {
+	a, factory := newFakeAdapter(nil)
+
+	result := callTool(t, a, DiagnoseUnitToolName, map[string]any{"unit": "mysql"})
+
+	assert.True(t, result.IsError)
+	assert.Equal(t, `"mysql" is not a valid unit name`, resultText(result))
+	assert.Empty(t, factory.calls)
+}
@@ -0,0 +1,621 @@
+package jujuadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	ConfigBaselineToolName = "config-baseline"
+	ConfigDriftToolName    = "config-drift"
+
+	// baselinesDir is the workspace directory stored baselines are written to.
+	baselinesDir = "baselines"
+)
+
+// Kinds of drift.
+const (
+	driftConfig      = "config"
+	driftConstraints = "constraints"
+	driftBinding     = "binding"
+	driftScale       = "scale"
+	driftCharm       = "charm"
+	driftChannel     = "channel"
+	driftModelConfig = "model-config"
+)
+
+// Origins of the expected and actual values of a drift. The expected value
+// comes from the bundle or the baseline, or is the default when they do not
+// set the key; the actual value is set by a user or is the default.
+const (
+	originBundle   = "bundle"
+	originBaseline = "baseline"
+	originDefault  = "default"
+	originUser     = "user"
+)
+
+// bundleApplication is the part of a bundle application config-drift
+// compares.
+type bundleApplication struct {
+	Charm       string            `yaml:"charm,omitempty"`
+	Channel     string            `yaml:"channel,omitempty"`
+	NumUnits    int               `yaml:"num_units,omitempty"`
+	Scale       int               `yaml:"scale,omitempty"`
+	Options     map[string]any    `yaml:"options,omitempty"`
+	Constraints string            `yaml:"constraints,omitempty"`
+	Bindings    map[string]string `yaml:"bindings,omitempty"`
+}
+
+func (app *bundleApplication) units() int {
+	return max(app.NumUnits, app.Scale)
+}
+
+// bundleSpec is the part of a bundle config-drift compares.
+type bundleSpec struct {
+	Applications map[string]*bundleApplication `yaml:"applications"`
+}
+
+// parseBundle reads a bundle and its overlays, the documents after the
+// first, which add applications, override their fields and merge their
+// options and bindings. An overlay removes an application by giving it no
+// value.
+func parseBundle(data []byte) (bundleSpec, error) {
+	bundle := bundleSpec{Applications: map[string]*bundleApplication{}}
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for i := 0; ; i++ {
+		var doc struct {
+			Applications map[string]*bundleApplication `yaml:"applications"`
+			// Services is the name of applications in older bundles.
+			Services map[string]*bundleApplication `yaml:"services"`
+		}
+		if err := decoder.Decode(&doc); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return bundleSpec{}, fmt.Errorf("failed to parse bundle document %d: %w", i+1, err)
+		}
+		if doc.Applications == nil {
+			doc.Applications = doc.Services
+		}
+		for name, app := range doc.Applications {
+			base, ok := bundle.Applications[name]
+			switch {
+			case i > 0 && app == nil:
+				delete(bundle.Applications, name)
+			case !ok || app == nil:
+				if app == nil {
+					app = &bundleApplication{}
+				}
+				bundle.Applications[name] = app
+			default:
+				mergeBundleApplication(base, app)
+			}
+		}
+	}
+	return bundle, nil
+}
+
+func mergeBundleApplication(base, overlay *bundleApplication) {
+	if overlay.Charm != "" {
+		base.Charm = overlay.Charm
+	}
+	if overlay.Channel != "" {
+		base.Channel = overlay.Channel
+	}
+	if overlay.NumUnits != 0 {
+		base.NumUnits = overlay.NumUnits
+	}
+	if overlay.Scale != 0 {
+		base.Scale = overlay.Scale
+	}
+	if overlay.Constraints != "" {
+		base.Constraints = overlay.Constraints
+	}
+	for key, value := range overlay.Options {
+		if base.Options == nil {
+			base.Options = map[string]any{}
+		}
+		base.Options[key] = value
+	}
+	for endpoint, space := range overlay.Bindings {
+		if base.Bindings == nil {
+			base.Bindings = map[string]string{}
+		}
+		base.Bindings[endpoint] = space
+	}
+}
+
+// configBaseline is the declared state of a model stored by
+// config-baseline: its exported bundle and its model config set by users.
+type configBaseline struct {
+	Model       string         `yaml:"model"`
+	Taken       time.Time      `yaml:"taken"`
+	ModelConfig map[string]any `yaml:"model-config,omitempty"`
+	Bundle      bundleSpec     `yaml:"bundle"`
+}
+
+// baselineStore keeps the baselines of the models in memory. They are also
+// written to the workspace, when there is one, to survive restarts and to be
+// edited by hand.
+type baselineStore struct {
+	mu        sync.Mutex
+	baselines map[string]configBaseline
+}
+
+func newBaselineStore() *baselineStore {
+	return &baselineStore{baselines: make(map[string]configBaseline)}
+}
+
+func baselinePath(model string) string {
+	return filepath.Join(baselinesDir, url.PathEscape(model)+".yaml")
+}
+
+func (a *adapter) saveBaseline(baseline configBaseline) (string, error) {
+	a.baselines.mu.Lock()
+	a.baselines.baselines[baseline.Model] = baseline
+	a.baselines.mu.Unlock()
+	if a.workspace == nil {
+		return "", nil
+	}
+
+	data, err := yaml.Marshal(baseline)
+	if err != nil {
+		return "", fmt.Errorf("failed to encode the baseline: %w", err)
+	}
+	full, err := a.workspace.resolve(baselinePath(baseline.Model))
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(full), 0o700); err != nil {
+		return "", fmt.Errorf("unable to write the baseline: %w", err)
+	}
+	if err := os.WriteFile(full, data, 0o600); err != nil {
+		return "", fmt.Errorf("unable to write the baseline: %w", err)
+	}
+	return a.workspace.rel(full), nil
+}
+
+// loadBaseline returns the baseline of a model, from the workspace when it
+// has one, as it may have been edited, or else from memory.
+func (a *adapter) loadBaseline(model string) (configBaseline, error) {
+	if a.workspace != nil {
+		full, err := a.workspace.resolve(baselinePath(model))
+		if err != nil {
+			return configBaseline{}, err
+		}
+		data, err := os.ReadFile(full)
+		if err == nil {
+			var baseline configBaseline
+			if err := yaml.Unmarshal(data, &baseline); err != nil {
+				return configBaseline{}, fmt.Errorf("failed to parse baseline %s: %w", a.workspace.rel(full), err)
+			}
+			return baseline, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return configBaseline{}, fmt.Errorf("unable to read the baseline: %w", err)
+		}
+	}
+
+	a.baselines.mu.Lock()
+	defer a.baselines.mu.Unlock()
+	baseline, ok := a.baselines.baselines[model]
+	if !ok {
+		return configBaseline{}, fmt.Errorf("no baseline stored for model %s, store one with %s", model, ConfigBaselineToolName)
+	}
+	return baseline, nil
+}
+
+// driftItem is a difference between the declared and the live value of a
+// setting.
+type driftItem struct {
+	Application    string `json:"application,omitempty"`
+	Kind           string `json:"kind"`
+	Key            string `json:"key,omitempty"`
+	Expected       any    `json:"expected"`
+	ExpectedOrigin string `json:"expected_origin,omitempty"`
+	Actual         any    `json:"actual"`
+	ActualOrigin   string `json:"actual_origin,omitempty"`
+}
+
+// configDrift is the result of config-drift.
+type configDrift struct {
+	Model                  string      `json:"model,omitempty"`
+	Source                 string      `json:"source"`
+	Summary                string      `json:"summary"`
+	Note                   string      `json:"note,omitempty"`
+	MissingApplications    []string    `json:"missing_applications,omitempty"`
+	UnexpectedApplications []string    `json:"unexpected_applications,omitempty"`
+	Drift                  []driftItem `json:"drift"`
+}
+
+// liveSetting is a setting of `juju config --format json`.
+type liveSetting struct {
+	Value   any    `json:"value"`
+	Default any    `json:"default"`
+	Source  string `json:"source"`
+}
+
+// sameValue compares a value read from YAML with one read from JSON, where
+// all numbers are floats.
+func sameValue(a, b any) bool {
+	return formatValue(a) == formatValue(b)
+}
+
+func formatValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return ""
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1e15 {
+			return strconv.FormatInt(int64(v), 10)
+		}
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// parseConstraints splits a constraints string, such as "cores=2 mem=4G",
+// into its values.
+func parseConstraints(constraints string) map[string]string {
+	values := make(map[string]string)
+	for _, field := range strings.Fields(constraints) {
+		key, value, _ := strings.Cut(field, "=")
+		values[key] = value
+	}
+	return values
+}
+
+// charmName returns the name of the charm of a bundle, without its
+// charmhub prefix and revision.
+func charmName(charm string) string {
+	charm = strings.TrimPrefix(strings.TrimPrefix(charm, "ch:"), "local:")
+	if i := strings.LastIndex(charm, "-"); i > 0 && isRevision(charm[i+1:]) {
+		charm = charm[:i]
+	}
+	return charm
+}
+
+// diffApplication compares the declared settings of an application with the
+// live ones. config holds the output of `juju config`, nil when it was not
+// read.
+func diffApplication(name string, expected, actual *bundleApplication, config map[string]liveSetting, origin string) []driftItem {
+	var drift []driftItem
+	add := func(kind, key string, want any, wantOrigin string, got any, gotOrigin string) {
+		drift = append(drift, driftItem{Application: name, Kind: kind, Key: key, Expected: want, ExpectedOrigin: wantOrigin, Actual: got, ActualOrigin: gotOrigin})
+	}
+
+	// Local charms are deployed from a path and cannot be compared.
+	if expected.Charm != "" && !strings.HasPrefix(expected.Charm, ".") && !strings.HasPrefix(expected.Charm, "/") &&
+		charmName(expected.Charm) != charmName(actual.Charm) {
+		add(driftCharm, "", charmName(expected.Charm), origin, charmName(actual.Charm), "")
+	}
+	if expected.Channel != "" && expected.Channel != actual.Channel {
+		add(driftChannel, "", expected.Channel, origin, actual.Channel, "")
+	}
+	if expected.units() != actual.units() {
+		add(driftScale, "", expected.units(), origin, actual.units(), "")
+	}
+
+	if config != nil {
+		for _, key := range sortedKeys(expected.Options) {
+			want := expected.Options[key]
+			setting, ok := config[key]
+			if !ok {
+				add(driftConfig, key, want, origin, nil, "")
+				continue
+			}
+			if !sameValue(want, setting.Value) {
+				add(driftConfig, key, want, origin, setting.Value, liveOrigin(setting.Source))
+			}
+		}
+		for _, key := range sortedKeys(config) {
+			setting := config[key]
+			if _, ok := expected.Options[key]; ok || setting.Source != originUser {
+				continue
+			}
+			if !sameValue(setting.Default, setting.Value) {
+				add(driftConfig, key, setting.Default, originDefault, setting.Value, originUser)
+			}
+		}
+	}
+
+	want, got := parseConstraints(expected.Constraints), parseConstraints(actual.Constraints)
+	for _, key := range sortedKeys(mergeKeys(want, got)) {
+		// Juju adds the architecture it deployed to the constraints.
+		if _, declared := want[key]; !declared && key == "arch" {
+			continue
+		}
+		if want[key] != got[key] {
+			add(driftConstraints, key, want[key], origin, got[key], "")
+		}
+	}
+
+	for _, endpoint := range sortedKeys(expected.Bindings) {
+		space, ok := actual.Bindings[endpoint]
+		if !ok {
+			space = actual.Bindings[""]
+		}
+		if expected.Bindings[endpoint] != space {
+			add(driftBinding, endpoint, expected.Bindings[endpoint], origin, space, "")
+		}
+	}
+	return drift
+}
+
+// liveOrigin maps the source of a live setting to its origin.
+func liveOrigin(source string) string {
+	if source == originUser || source == "model" {
+		return originUser
+	}
+	return originDefault
+}
+
+// mergeKeys returns the keys of two maps.
+func mergeKeys[A, B any](a map[string]A, b map[string]B) map[string]bool {
+	keys := make(map[string]bool, len(a)+len(b))
+	for key := range a {
+		keys[key] = true
+	}
+	for key := range b {
+		keys[key] = true
+	}
+	return keys
+}
+
+func describeConfigDrift(d configDrift) string {
+	if len(d.Drift)+len(d.MissingApplications)+len(d.UnexpectedApplications) == 0 {
+		return fmt.Sprintf("No drift from %s.", d.Source)
+	}
+	kinds := map[string]int{}
+	applications := map[string]bool{}
+	for _, item := range d.Drift {
+		kinds[item.Kind]++
+		if item.Application != "" {
+			applications[item.Application] = true
+		}
+	}
+	var parts []string
+	for _, kind := range []string{driftConfig, driftConstraints, driftBinding, driftScale, driftCharm, driftChannel, driftModelConfig} {
+		if kinds[kind] > 0 {
+			parts = append(parts, fmt.Sprintf("%d %s", kinds[kind], kind))
+		}
+	}
+	text := fmt.Sprintf("%s from %s", plural(len(d.Drift), "difference"), d.Source)
+	if len(parts) > 0 {
+		text += fmt.Sprintf(" (%s) in %s", strings.Join(parts, ", "), plural(len(applications), "application"))
+	}
+	text += "."
+	if len(d.MissingApplications) > 0 {
+		text += " Missing from the model: " + strings.Join(d.MissingApplications, ", ") + "."
+	}
+	if len(d.UnexpectedApplications) > 0 {
+		text += " Not declared: " + strings.Join(d.UnexpectedApplications, ", ") + "."
+	}
+	return text
+}
+
+// registerConfigDrift adds the config-baseline and config-drift tools.
+func (a *adapter) registerConfigDrift() {
+	a.builtins.addTool(mcp.NewTool(ConfigBaselineToolName,
+		mcp.WithDescription("Store the current declared state of a model as its baseline for config-drift: the bundle exported "+
+			"with export-bundle, with the options, constraints, bindings and scale of its applications, and the model config "+
+			"set by users. With a workspace, the baseline is written to baselines/<model>.yaml, where it can be edited."),
+		mcp.WithString("model",
+			mcp.Required(),
+			mcp.Description("Model to store the baseline of"),
+		),
+	), a.handleConfigBaseline)
+
+	a.builtins.addTool(mcp.NewTool(ConfigDriftToolName,
+		mcp.WithDescription("Compare the live configuration of a model with a bundle file or its stored baseline, like diff-bundle, "+
+			"and report every difference per key: application config, constraints, endpoint bindings, scale, charm and "+
+			"channel, and model config for baselines. Each difference gives the expected value and its origin (bundle, "+
+			"baseline or default) and the actual value and its origin (user or default). Applications declared but missing "+
+			"and deployed but not declared are listed."),
+		mcp.WithString("model",
+			mcp.Description("Model to check, the current model if empty; required to compare with a baseline"),
+		),
+		mcp.WithString("bundle",
+			mcp.Description("Bundle file to compare with, with its overlays as further YAML documents; in the workspace when there is one. "+
+				"The stored baseline of the model is used when empty"),
+		),
+		mcp.WithString("application",
+			mcp.Description("Only compare this application"),
+		),
+	), a.handleConfigDrift)
+}
+
+func (a *adapter) handleConfigBaseline(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	model, err := req.RequireString("model")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	bundle, err := a.exportBundle(ctx, model)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	modelConfig, err := a.liveModelConfig(ctx, model)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	baseline := configBaseline{Model: model, Taken: time.Now().UTC(), Bundle: bundle, ModelConfig: map[string]any{}}
+	for key, setting := range modelConfig {
+		if setting.Source == "model" {
+			baseline.ModelConfig[key] = setting.Value
+		}
+	}
+	file, err := a.saveBaseline(baseline)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonToolResult(struct {
+		Model        string    `json:"model"`
+		Taken        time.Time `json:"taken"`
+		Applications []string  `json:"applications"`
+		ModelConfig  []string  `json:"model_config"`
+		File         string    `json:"file,omitempty"`
+	}{model, baseline.Taken, sortedKeys(bundle.Applications), sortedKeys(baseline.ModelConfig), file})
+}
+
+func (a *adapter) handleConfigDrift(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	model := req.GetString("model", "")
+	bundleFile := req.GetString("bundle", "")
+	application := req.GetString("application", "")
+
+	drift := configDrift{Model: model, Drift: []driftItem{}}
+	var expected bundleSpec
+	var expectedModelConfig map[string]any
+	origin := originBundle
+	if bundleFile != "" {
+		path, err := a.localPath(bundleFile)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("unable to read the bundle: %v", err)), nil
+		}
+		if expected, err = parseBundle(data); err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		drift.Source = "bundle " + bundleFile
+		drift.Note = "Bundles do not declare model config, store a baseline with " + ConfigBaselineToolName + " to compare it."
+	} else {
+		if model == "" {
+			return mcp.NewToolResultError("either a bundle or a model with a stored baseline is required"), nil
+		}
+		baseline, err := a.loadBaseline(model)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		expected, expectedModelConfig, origin = baseline.Bundle, baseline.ModelConfig, originBaseline
+		drift.Source = fmt.Sprintf("the baseline of %s taken at %s", model, baseline.Taken.Format(time.RFC3339))
+	}
+
+	actual, err := a.exportBundle(ctx, model)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if application != "" {
+		if _, ok := expected.Applications[application]; !ok {
+			if _, ok := actual.Applications[application]; !ok {
+				return mcp.NewToolResultError(fmt.Sprintf("application %q is neither declared nor deployed", application)), nil
+			}
+		}
+	}
+
+	for _, name := range sortedKeys(mergeKeys(expected.Applications, actual.Applications)) {
+		if application != "" && name != application {
+			continue
+		}
+		want, declared := expected.Applications[name]
+		got, deployed := actual.Applications[name]
+		switch {
+		case !deployed:
+			drift.MissingApplications = append(drift.MissingApplications, name)
+			continue
+		case !declared:
+			drift.UnexpectedApplications = append(drift.UnexpectedApplications, name)
+			continue
+		}
+		config, err := a.liveApplicationConfig(ctx, name, model)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		drift.Drift = append(drift.Drift, diffApplication(name, want, got, config, origin)...)
+	}
+
+	if expectedModelConfig != nil && application == "" {
+		modelConfig, err := a.liveModelConfig(ctx, model)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		for _, key := range sortedKeys(mergeKeys(expectedModelConfig, modelConfig)) {
+			want, declared := expectedModelConfig[key]
+			setting := modelConfig[key]
+			switch {
+			case declared && !sameValue(want, setting.Value):
+				drift.Drift = append(drift.Drift, driftItem{Kind: driftModelConfig, Key: key, Expected: want, ExpectedOrigin: origin,
+					Actual: setting.Value, ActualOrigin: liveOrigin(setting.Source)})
+			case !declared && setting.Source == "model":
+				drift.Drift = append(drift.Drift, driftItem{Kind: driftModelConfig, Key: key, Expected: nil, ExpectedOrigin: originDefault,
+					Actual: setting.Value, ActualOrigin: originUser})
+			}
+		}
+	}
+
+	drift.Summary = describeConfigDrift(drift)
+	return jsonToolResult(drift)
+}
+
+// exportBundle reads the live state of a model with export-bundle.
+func (a *adapter) exportBundle(ctx context.Context, model string) (bundleSpec, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdExportBundle),
+		FixedFlags:  withModel(nil, model),
+	})
+	if err != nil {
+		return bundleSpec{}, err
+	}
+	bundle, err := parseBundle([]byte(output))
+	if err != nil {
+		return bundleSpec{}, fmt.Errorf("failed to parse the exported bundle: %w", err)
+	}
+	return bundle, nil
+}
+
+func (a *adapter) liveApplicationConfig(ctx context.Context, application, model string) (map[string]liveSetting, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdConfig),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+		Arguments:   []string{application},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var config struct {
+		Settings map[string]liveSetting `json:"settings"`
+	}
+	if err := json.Unmarshal([]byte(output), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse the config of %s: %w", application, err)
+	}
+	return config.Settings, nil
+}
+
+func (a *adapter) liveModelConfig(ctx context.Context, model string) (map[string]liveSetting, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdModelConfig),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var config map[string]struct {
+		Value  any    `json:"Value"`
+		Source string `json:"Source"`
+	}
+	if err := json.Unmarshal([]byte(output), &config); err != nil {
+		return nil, fmt.Errorf("failed to parse model config: %w", err)
+	}
+	settings := make(map[string]liveSetting, len(config))
+	for key, setting := range config {
+		settings[key] = liveSetting{Value: setting.Value, Source: setting.Source}
+	}
+	return settings, nil
+}
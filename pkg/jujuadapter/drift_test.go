@@ -0,0 +1,239 @@
+package jujuadapter
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gopkg.in/yaml.v3"
+)
+
+const declaredBundle = `default-base: ubuntu@22.04
+applications:
+  mysql:
+    charm: mysql
+    channel: 8.0/stable
+    num_units: 3
+    constraints: cores=2 mem=4G
+    options:
+      max-connections: 500
+      profile: production
+    bindings:
+      "": alpha
+      database: internal
+  wordpress:
+    charm: ch:wordpress
+    num_units: 1
+  redis:
+    charm: redis-k8s
+    num_units: 1
+---
+applications:
+  mysql:
+    options:
+      profile: testing
+  redis:
+`
+
+const exportedBundle = `default-base: ubuntu@22.04
+applications:
+  mysql:
+    charm: mysql
+    channel: 8.0/candidate
+    num_units: 2
+    constraints: arch=amd64 cores=2 mem=8G
+    options:
+      max-connections: 800
+      tuning-level: fast
+    bindings:
+      "": alpha
+  wordpress:
+    charm: wordpress
+    num_units: 1
+  grafana-agent:
+    charm: grafana-agent
+`
+
+const mysqlConfig = `{"application": "mysql", "charm": "mysql", "settings": {
+	"max-connections": {"default": 100, "source": "user", "value": 800},
+	"profile": {"default": "production", "source": "default", "value": "production"},
+	"tuning-level": {"default": "safe", "source": "user", "value": "fast"}
+}}`
+
+const driftModelConfigOutput = `{
+	"update-status-hook-interval": {"Value": "5m", "Source": "model"},
+	"logging-config": {"Value": "<root>=INFO", "Source": "default"},
+	"automatically-retry-hooks": {"Value": false, "Source": "model"}
+}`
+
+func driftOutput(call fakeCall) string {
+	switch JujuCommandID(call.name) {
+	case CmdExportBundle:
+		return exportedBundle
+	case CmdConfig:
+		if call.args[0] == "mysql" {
+			return mysqlConfig
+		}
+		return `{"settings": {}}`
+	case CmdModelConfig:
+		return driftModelConfigOutput
+	}
+	return ""
+}
+
+func TestParseBundleOverlays(t *testing.T) {
+	bundle, err := parseBundle([]byte(declaredBundle))
+
+	require.NoError(t, err)
+	assert.Equal(t, []string{"mysql", "wordpress"}, sortedKeys(bundle.Applications))
+	assert.Equal(t, map[string]any{"max-connections": 500, "profile": "testing"}, bundle.Applications["mysql"].Options)
+	assert.Equal(t, 3, bundle.Applications["mysql"].units())
+}
+
+func TestDiffApplication(t *testing.T) {
+	tests := []struct {
+		name     string
+		expected bundleApplication
+		actual   bundleApplication
+		config   map[string]liveSetting
+		want     []driftItem
+	}{
+		{
+			name:     "same",
+			expected: bundleApplication{Charm: "ch:mysql", Channel: "8.0/stable", NumUnits: 1, Options: map[string]any{"profile": "testing"}},
+			actual:   bundleApplication{Charm: "mysql", Channel: "8.0/stable", NumUnits: 1},
+			config:   map[string]liveSetting{"profile": {Value: "testing", Default: "production", Source: originUser}},
+		},
+		{
+			name:     "charm and scale",
+			expected: bundleApplication{Charm: "mysql", NumUnits: 3},
+			actual:   bundleApplication{Charm: "percona-cluster", Scale: 2},
+			want: []driftItem{
+				{Application: "db", Kind: driftCharm, Expected: "mysql", ExpectedOrigin: originBundle, Actual: "percona-cluster"},
+				{Application: "db", Kind: driftScale, Expected: 3, ExpectedOrigin: originBundle, Actual: 2},
+			},
+		},
+		{
+			name:     "local charm",
+			expected: bundleApplication{Charm: "./mysql.charm"},
+			actual:   bundleApplication{Charm: "local:mysql-0"},
+		},
+		{
+			name:     "config",
+			expected: bundleApplication{Options: map[string]any{"max-connections": 500, "removed": true}},
+			config: map[string]liveSetting{
+				"max-connections": {Value: float64(800), Default: float64(100), Source: originUser},
+				"tuning-level":    {Value: "fast", Default: "safe", Source: originUser},
+				"profile":         {Value: "production", Default: "production", Source: originDefault},
+			},
+			want: []driftItem{
+				{Application: "db", Kind: driftConfig, Key: "max-connections", Expected: 500, ExpectedOrigin: originBundle, Actual: float64(800), ActualOrigin: originUser},
+				{Application: "db", Kind: driftConfig, Key: "removed", Expected: true, ExpectedOrigin: originBundle},
+				{Application: "db", Kind: driftConfig, Key: "tuning-level", Expected: "safe", ExpectedOrigin: originDefault, Actual: "fast", ActualOrigin: originUser},
+			},
+		},
+		{
+			name:     "config not read",
+			expected: bundleApplication{Options: map[string]any{"max-connections": 500}},
+		},
+		{
+			name:     "constraints and bindings",
+			expected: bundleApplication{Constraints: "cores=2 mem=4G", Bindings: map[string]string{"": "alpha", "database": "internal"}},
+			actual:   bundleApplication{Constraints: "arch=amd64 cores=2 mem=8G", Bindings: map[string]string{"": "alpha"}},
+			want: []driftItem{
+				{Application: "db", Kind: driftConstraints, Key: "mem", Expected: "4G", ExpectedOrigin: originBundle, Actual: "8G"},
+				{Application: "db", Kind: driftBinding, Key: "database", Expected: "internal", ExpectedOrigin: originBundle, Actual: "alpha"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			assert.Equal(t, tt.want, diffApplication("db", &tt.expected, &tt.actual, tt.config, originBundle))
+		})
+	}
+}
+
+func TestConfigDriftBundle(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(driftOutput)
+	w, err := newWorkspace(t.TempDir())
+	require.NoError(t, err)
+	a.workspace = w
+	require.NoError(t, os.WriteFile(filepath.Join(w.root, "bundle.yaml"), []byte(declaredBundle), 0o600))
+
+	// Act
+	result := callTool(t, a, ConfigDriftToolName, map[string]any{"model": "prod", "bundle": "bundle.yaml"})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	var drift configDrift
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &drift))
+	assert.Equal(t, []string{"grafana-agent"}, drift.UnexpectedApplications)
+	assert.Empty(t, drift.MissingApplications)
+	assert.Equal(t, []driftItem{
+		{Application: "mysql", Kind: driftChannel, Expected: "8.0/stable", ExpectedOrigin: originBundle, Actual: "8.0/candidate"},
+		{Application: "mysql", Kind: driftScale, Expected: float64(3), ExpectedOrigin: originBundle, Actual: float64(2)},
+		{Application: "mysql", Kind: driftConfig, Key: "max-connections", Expected: float64(500), ExpectedOrigin: originBundle, Actual: float64(800), ActualOrigin: originUser},
+		{Application: "mysql", Kind: driftConfig, Key: "profile", Expected: "testing", ExpectedOrigin: originBundle, Actual: "production", ActualOrigin: originDefault},
+		{Application: "mysql", Kind: driftConfig, Key: "tuning-level", Expected: "safe", ExpectedOrigin: originDefault, Actual: "fast", ActualOrigin: originUser},
+		{Application: "mysql", Kind: driftConstraints, Key: "mem", Expected: "4G", ExpectedOrigin: originBundle, Actual: "8G"},
+		{Application: "mysql", Kind: driftBinding, Key: "database", Expected: "internal", ExpectedOrigin: originBundle, Actual: "alpha"},
+	}, drift.Drift)
+	assert.Equal(t, "7 differences from bundle bundle.yaml (3 config, 1 constraints, 1 binding, 1 scale, 1 channel) in 1 application. "+
+		"Not declared: grafana-agent.", drift.Summary)
+	assert.NotEmpty(t, drift.Note)
+	for _, call := range factory.calls {
+		assert.NotEqual(t, string(CmdModelConfig), call.name)
+	}
+}
+
+func TestConfigDriftBaseline(t *testing.T) {
+	// Arrange
+	a, _ := newFakeAdapter(driftOutput)
+	w, err := newWorkspace(t.TempDir())
+	require.NoError(t, err)
+	a.workspace = w
+
+	// Act
+	stored := callTool(t, a, ConfigBaselineToolName, map[string]any{"model": "admin/prod"})
+	unchanged := callTool(t, a, ConfigDriftToolName, map[string]any{"model": "admin/prod"})
+	file := filepath.Join(w.root, "baselines", "admin%2Fprod.yaml")
+	data, err := os.ReadFile(file)
+	require.NoError(t, err)
+	var baseline configBaseline
+	require.NoError(t, yaml.Unmarshal(data, &baseline))
+	baseline.ModelConfig["update-status-hook-interval"] = "1m"
+	delete(baseline.ModelConfig, "automatically-retry-hooks")
+	baseline.Bundle.Applications["mysql"].NumUnits = 3
+	data, err = yaml.Marshal(baseline)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, data, 0o600))
+	edited := callTool(t, a, ConfigDriftToolName, map[string]any{"model": "admin/prod"})
+
+	// Assert
+	require.False(t, stored.IsError, resultText(stored))
+	assert.Contains(t, resultText(stored), `"file": "baselines/admin%2Fprod.yaml"`)
+	var drift configDrift
+	require.NoError(t, json.Unmarshal([]byte(resultText(unchanged)), &drift))
+	assert.Empty(t, drift.Drift)
+	assert.Regexp(t, `^No drift from the baseline of admin/prod taken at `, drift.Summary)
+
+	require.NoError(t, json.Unmarshal([]byte(resultText(edited)), &drift))
+	assert.Equal(t, []driftItem{
+		{Application: "mysql", Kind: driftScale, Expected: float64(3), ExpectedOrigin: originBaseline, Actual: float64(2)},
+		{Kind: driftModelConfig, Key: "automatically-retry-hooks", Expected: nil, ExpectedOrigin: originDefault, Actual: false, ActualOrigin: originUser},
+		{Kind: driftModelConfig, Key: "update-status-hook-interval", Expected: "1m", ExpectedOrigin: originBaseline, Actual: "5m", ActualOrigin: originUser},
+	}, drift.Drift)
+}
+
+func TestConfigDriftWithoutBaseline(t *testing.T) {
+	a, _ := newFakeAdapter(driftOutput)
+
+	result := callTool(t, a, ConfigDriftToolName, map[string]any{"model": "prod"})
+
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), "no baseline stored for model prod")
+}
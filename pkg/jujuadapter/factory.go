@@ -457,12 +457,30 @@ func (c *commandFactory) GetResourceTemplateConfigs() map[string]ResourceTemplat
 	return map[string]ResourceTemplateConfig{
 		"juju-config-template": {
 			CommandName: "config",
-			URITemplate: "juju://config/{application}{/config_name*}",
+			URITemplate: "juju://config/{application}{/config_name*}{?model}",
 			Name:        "Juju Application Configuration",
 			Description: "Get configuration for any Juju application or specific config key in JSON format",
 			FixedFlags:  map[string]string{"format": "json"},
 			URIToArgs:   map[string]string{"application": "0", "config_name": "1"}, // Map to positional args
-			URIToFlags:  map[string]string{},                                       // No URI variables map to flags for this template
+			URIToFlags:  map[string]string{"model": "model"},
+		},
+		"juju-status-template": {
+			CommandName: "status",
+			URITemplate: "juju://status/{model}{/application}",
+			Name:        "Juju Model Status",
+			Description: "Get the status of a model, or of one of its applications, in JSON format",
+			FixedFlags:  map[string]string{"format": "json"},
+			URIToArgs:   map[string]string{"application": "0"},
+			URIToFlags:  map[string]string{"model": "model"},
+		},
+		"juju-debug-log-template": {
+			CommandName: "debug-log",
+			URITemplate: "juju://debug-log/{model}{/entity}",
+			Name:        "Juju Debug Log Tail",
+			Description: "Get the most recent debug log lines of a model, or of one application, unit or machine such as mysql/0",
+			FixedFlags:  map[string]string{"limit": "100"},
+			URIToArgs:   map[string]string{},
+			URIToFlags:  map[string]string{"model": "model", "entity": "include"},
 		},
 	}
 }
@@ -0,0 +1,165 @@
+package jujuadapter
+
+import (
+	"context"
+	"io"
+	"sync"
+	"testing"
+
+	"github.com/juju/cmd/v3"
+	"github.com/juju/gnuflag"
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/require"
+)
+
+// fakeCall is a command line run through a fakeFactory.
+type fakeCall struct {
+	name  string
+	args  []string
+	flags map[string]string
+}
+
+// fakeFactory returns commands that record their calls and answer with the
+// output function instead of talking to Juju.
+type fakeFactory struct {
+	mu     sync.Mutex
+	calls  []fakeCall
+	output func(call fakeCall) string
+	// fail, when set, makes the calls it returns an error for fail.
+	fail func(call fakeCall) error
+	// stdin, when set, receives the standard input of every call.
+	stdin func(call fakeCall, stdin io.Reader)
+	// flags are the string flags commands accept besides model and format.
+	// Other flags are skipped, like the flags a command does not have.
+	flags []string
+	// repeatable are the flags that can be given several times, recorded
+	// as their comma separated values.
+	repeatable []string
+	// follow makes commands streaming their output, such as debug-log,
+	// run until their context is done after writing it.
+	follow bool
+}
+
+func (f *fakeFactory) GetCommand(id JujuCommandID) (Command, error) {
+	return &fakeCommand{factory: f, name: string(id), flags: make(map[string]*string)}, nil
+}
+
+func (f *fakeFactory) GetCommandByName(name string) (Command, error) {
+	return f.GetCommand(JujuCommandID(name))
+}
+
+func (f *fakeFactory) GetResourceTemplateConfigs() map[string]ResourceTemplateConfig {
+	return nil
+}
+
+func (f *fakeFactory) GetResourceTemplateNames() []string {
+	return nil
+}
+
+type fakeCommand struct {
+	factory *fakeFactory
+	name    string
+	args    []string
+	flags   map[string]*string
+}
+
+func (c *fakeCommand) SetFlags(f *gnuflag.FlagSet) {
+	for _, name := range append([]string{"model", "format"}, c.factory.flags...) {
+		c.flags[name] = f.String(name, "", "")
+	}
+	for _, name := range c.factory.repeatable {
+		value := ""
+		c.flags[name] = &value
+		f.Var((*appendValue)(&value), name, "")
+	}
+}
+
+// appendValue is a repeatable flag recorded as its comma separated values.
+type appendValue string
+
+func (v *appendValue) Set(s string) error {
+	if *v != "" {
+		s = string(*v) + "," + s
+	}
+	*v = appendValue(s)
+	return nil
+}
+
+func (v *appendValue) String() string { return string(*v) }
+
+func (c *fakeCommand) Init(args []string) error {
+	c.args = args
+	return nil
+}
+
+func (c *fakeCommand) Name() string            { return c.name }
+func (c *fakeCommand) ToolDescription() string { return c.name }
+func (c *fakeCommand) Info() *cmd.Info         { return &cmd.Info{Name: c.name} }
+func (c *fakeCommand) Run(ctx context.Context) error {
+	_, _, err := c.RunWithOutput(ctx)
+	return err
+}
+
+func (c *fakeCommand) RunWithOutput(ctx context.Context) (string, string, error) {
+	call := fakeCall{name: c.name, args: c.args, flags: make(map[string]string)}
+	for name, value := range c.flags {
+		if *value != "" {
+			call.flags[name] = *value
+		}
+	}
+	c.factory.mu.Lock()
+	c.factory.calls = append(c.factory.calls, call)
+	c.factory.mu.Unlock()
+	if c.factory.stdin != nil {
+		c.factory.stdin(call, stdin(ctx))
+	}
+	if c.factory.fail != nil {
+		if err := c.factory.fail(call); err != nil {
+			return "", err.Error(), err
+		}
+	}
+	if c.factory.output == nil {
+		return "", "", nil
+	}
+	output := c.factory.output(call)
+	if w := streamedStdout(ctx); w != nil {
+		_, err := io.WriteString(w, output)
+		if err == nil && c.factory.follow {
+			<-ctx.Done()
+		}
+		return "", "", err
+	}
+	return output, "", nil
+}
+
+// newFakeAdapter returns an adapter with its built-in tools running
+// commands through a fakeFactory, on models without disabled commands.
+// Tests set the flags, workspace or stores their feature needs on the
+// returned adapter and factory.
+func newFakeAdapter(output func(call fakeCall) string) (*adapter, *fakeFactory) {
+	factory := &fakeFactory{output: output}
+	a := newAdapter(factory, "")
+	a.blocks = newBlockCache(func(ctx context.Context, model string) ([]block.BlockInfo, error) { return nil, nil })
+	a.registerBuiltins()
+	return a, factory
+}
+
+func callTool(t *testing.T, a *adapter, name string, arguments map[string]any) *mcp.CallToolResult {
+	return callToolContext(t, context.Background(), a, name, arguments)
+}
+
+func callToolContext(t *testing.T, ctx context.Context, a *adapter, name string, arguments map[string]any) *mcp.CallToolResult {
+	_, handler, err := a.GetTool(name)
+	require.NoError(t, err)
+	req := mcp.CallToolRequest{}
+	req.Params.Name = name
+	req.Params.Arguments = arguments
+	result, err := handler(ctx, req)
+	require.NoError(t, err)
+	return result
+}
+
+func resultText(result *mcp.CallToolResult) string {
+	return result.Content[0].(mcp.TextContent).Text
+}
@@ -0,0 +1,389 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	ModelHealthToolName = "model-health"
+	healthTemplateName  = "model-health"
+	healthURI           = "juju://health"
+
+	defaultStaleAfter = time.Hour
+)
+
+// Kinds of health findings, with their severity scores. The score orders
+// the findings from the most to the least urgent.
+const (
+	findingUnitError         = "unit-error"
+	findingAgentLost         = "agent-lost"
+	findingMachineDown       = "machine-down"
+	findingUpgradeFailed     = "upgrade-failed"
+	findingUnitBlocked       = "unit-blocked"
+	findingSaasBroken        = "saas-broken"
+	findingRelationMissing   = "relation-not-established"
+	findingUnitWaiting       = "unit-waiting"
+	findingStaleStatus       = "stale-status"
+	findingUpgradePending    = "upgrade-pending"
+	findingUpgradeAvailable  = "upgrade-available"
+	findingOfferDisconnected = "offer-disconnected"
+)
+
+var findingScores = map[string]int{
+	findingUnitError:         90,
+	findingAgentLost:         85,
+	findingMachineDown:       80,
+	findingUpgradeFailed:     75,
+	findingUnitBlocked:       70,
+	findingSaasBroken:        60,
+	findingRelationMissing:   50,
+	findingOfferDisconnected: 45,
+	findingUnitWaiting:       40,
+	findingStaleStatus:       35,
+	findingUpgradePending:    30,
+	findingUpgradeAvailable:  10,
+}
+
+// healthFinding is an issue of a model with its score and the entity it is
+// about.
+type healthFinding struct {
+	issue
+	Score  int    `json:"score"`
+	Kind   string `json:"kind"`
+	Entity string `json:"entity"`
+}
+
+// healthReport is the result of the model-health tool and resource.
+type healthReport struct {
+	Model     string          `json:"model"`
+	CheckedAt time.Time       `json:"checked_at"`
+	Summary   string          `json:"summary"`
+	Findings  []healthFinding `json:"findings"`
+}
+
+// statusTimeFormat is the format of the since fields with --utc.
+const statusTimeFormat = "2006-01-02 15:04:05Z"
+
+// statusInfo is a status of `juju status --format json`.
+type statusInfo struct {
+	Current string `json:"current"`
+	Message string `json:"message"`
+	Since   string `json:"since"`
+}
+
+func (s statusInfo) since() (time.Time, bool) {
+	t, err := time.Parse(statusTimeFormat, s.Since)
+	return t, err == nil
+}
+
+type modelStatusUnit struct {
+	WorkloadStatus statusInfo                 `json:"workload-status"`
+	JujuStatus     statusInfo                 `json:"juju-status"`
+	UpgradingFrom  string                     `json:"upgrading-from"`
+	Machine        string                     `json:"machine"`
+	Subordinates   map[string]modelStatusUnit `json:"subordinates"`
+}
+
+type modelStatusMachine struct {
+	JujuStatus    statusInfo                    `json:"juju-status"`
+	MachineStatus statusInfo                    `json:"machine-status"`
+	Containers    map[string]modelStatusMachine `json:"containers"`
+}
+
+type modelStatusApplication struct {
+	Charm         string                     `json:"charm"`
+	CharmName     string                     `json:"charm-name"`
+	CharmOrigin   string                     `json:"charm-origin"`
+	CharmRev      int                        `json:"charm-rev"`
+	CharmChannel  string                     `json:"charm-channel"`
+	CanUpgradeTo  string                     `json:"can-upgrade-to"`
+	Base          statusBase                 `json:"base"`
+	Scale         int                        `json:"scale"`
+	Status        statusInfo                 `json:"application-status"`
+	Units         map[string]modelStatusUnit `json:"units"`
+	SubordinateTo []string                   `json:"subordinate-to"`
+	Relations     map[string][]struct {
+		RelatedApplication string `json:"related-application"`
+		Interface          string `json:"interface"`
+	} `json:"relations"`
+}
+
+// statusBase is the base of an application, such as ubuntu 22.04.
+type statusBase struct {
+	Name    string `json:"name"`
+	Channel string `json:"channel"`
+}
+
+func (b statusBase) String() string {
+	if b.Name == "" {
+		return ""
+	}
+	return b.Name + "@" + b.Channel
+}
+
+// statusEndpoint is an endpoint of a SAAS application or an offer.
+type statusEndpoint struct {
+	Interface string `json:"interface"`
+	Role      string `json:"role"`
+}
+
+type modelStatusSaas struct {
+	URL       string                    `json:"url"`
+	Status    statusInfo                `json:"application-status"`
+	Endpoints map[string]statusEndpoint `json:"endpoints"`
+	Relations map[string][]string       `json:"relations"`
+}
+
+type modelStatusOffer struct {
+	Application          string                    `json:"application"`
+	Endpoints            map[string]statusEndpoint `json:"endpoints"`
+	TotalConnectedCount  int                       `json:"total-connected-count"`
+	ActiveConnectedCount int                       `json:"active-connected-count"`
+}
+
+// modelStatus is the part of `juju status --format json` the health
+// analysis and the status diff look at.
+type modelStatus struct {
+	Model struct {
+		Name string `json:"name"`
+	} `json:"model"`
+	Machines     map[string]modelStatusMachine     `json:"machines"`
+	Applications map[string]modelStatusApplication `json:"applications"`
+	Saas         map[string]modelStatusSaas        `json:"application-endpoints"`
+	Offers       map[string]modelStatusOffer       `json:"offers"`
+}
+
+// registerHealth adds the model-health tool and the juju://health resource
+// template.
+func (a *adapter) registerHealth() {
+	a.builtins.addTool(mcp.NewTool(ModelHealthToolName,
+		mcp.WithDescription("Analyze the status of a model and return a prioritized list of findings: units in error, blocked or waiting, "+
+			"lost agents, machines down, pending or failed charm upgrades, relations not established, stale statuses and "+
+			"cross-model offers or consumed applications with broken connections. Each finding has a severity score; the highest come first."),
+		mcp.WithString("model",
+			mcp.Description("Model to analyze, the current model if empty"),
+		),
+		mcp.WithString("stale_after",
+			mcp.Description("How long a unit may stay in a transient status, such as maintenance or executing, before it is reported as stale"),
+			mcp.DefaultString(defaultStaleAfter.String()),
+		),
+		mcp.WithReadOnlyHintAnnotation(true),
+	), a.handleModelHealth)
+
+	a.builtins.addTemplate(healthTemplateName, mcp.NewResourceTemplate(
+		healthURI+"/{model}",
+		"Juju Model Health",
+		mcp.WithTemplateDescription("Prioritized health findings of a model, from the model-health tool"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), a.handleHealthResource)
+}
+
+func (a *adapter) handleModelHealth(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	staleAfter, err := time.ParseDuration(req.GetString("stale_after", defaultStaleAfter.String()))
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid stale_after: %v", err)), nil
+	}
+	report, err := a.modelHealth(ctx, req.GetString("model", ""), staleAfter)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	encoded, err := json.MarshalIndent(report, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(encoded)), nil
+}
+
+func (a *adapter) handleHealthResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	model, err := url.PathUnescape(strings.TrimPrefix(req.Params.URI, healthURI+"/"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid model in %s: %w", req.Params.URI, err)
+	}
+	report, err := a.modelHealth(ctx, model, defaultStaleAfter)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResourceContents(req.Params.URI, report)
+}
+
+func (a *adapter) modelHealth(ctx context.Context, model string, staleAfter time.Duration) (*healthReport, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		FixedFlags:  withModel(map[string]string{"format": "json", "utc": "true"}, model),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var status modelStatus
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status: %w", err)
+	}
+	if model == "" {
+		model = status.Model.Name
+	}
+	now := time.Now().UTC()
+	findings := analyzeModelHealth(status, now, staleAfter)
+	issues := make([]issue, len(findings))
+	for i, f := range findings {
+		issues[i] = f.issue
+	}
+	return &healthReport{
+		Model:     model,
+		CheckedAt: now,
+		Summary:   summarizeIssues("model "+model, issues),
+		Findings:  findings,
+	}, nil
+}
+
+// analyzeModelHealth returns the findings of a model status, the highest
+// scores first.
+func analyzeModelHealth(status modelStatus, now time.Time, staleAfter time.Duration) []healthFinding {
+	findings := []healthFinding{}
+	add := func(kind, entity, format string, args ...any) {
+		score := findingScores[kind]
+		findings = append(findings, healthFinding{
+			issue: issue{
+				Severity: scoreSeverity(score),
+				Source:   "status",
+				Message:  strings.TrimSpace(fmt.Sprintf(format, args...)),
+			},
+			Score:  score,
+			Kind:   kind,
+			Entity: entity,
+		})
+	}
+	stale := func(s statusInfo) (time.Duration, bool) {
+		since, ok := s.since()
+		if !ok {
+			return 0, false
+		}
+		age := now.Sub(since)
+		return age.Round(time.Minute), age > staleAfter
+	}
+
+	var checkUnit func(name string, unit modelStatusUnit)
+	checkUnit = func(name string, unit modelStatusUnit) {
+		workload, agent := unit.WorkloadStatus, unit.JujuStatus
+		switch {
+		case agent.Current == "lost":
+			add(findingAgentLost, name, "agent of %s is lost: %s", name, agent.Message)
+		case workload.Current == "error" && strings.Contains(workload.Message, "upgrade-charm"):
+			add(findingUpgradeFailed, name, "charm upgrade of %s failed: %s", name, workload.Message)
+		case workload.Current == "error" || agent.Current == "error":
+			message := workload.Message
+			if workload.Current != "error" {
+				message = agent.Message
+			}
+			add(findingUnitError, name, "%s is in error: %s", name, message)
+		case workload.Current == "blocked":
+			add(findingUnitBlocked, name, "%s is blocked: %s", name, workload.Message)
+		case workload.Current == "waiting":
+			add(findingUnitWaiting, name, "%s is waiting: %s", name, workload.Message)
+		}
+		if strings.Contains(workload.Message, "relation") && (workload.Current == "blocked" || workload.Current == "waiting") {
+			add(findingRelationMissing, name, "%s reports a relation problem: %s", name, workload.Message)
+		}
+		switch workload.Current {
+		case "maintenance", "waiting":
+			if age, ok := stale(workload); ok {
+				add(findingStaleStatus, name, "%s has been %s for %s: %s", name, workload.Current, age, workload.Message)
+			}
+		}
+		switch agent.Current {
+		case "allocating", "executing":
+			if age, ok := stale(agent); ok {
+				add(findingStaleStatus, name, "agent of %s has been %s for %s: %s", name, agent.Current, age, agent.Message)
+			}
+		}
+		if unit.UpgradingFrom != "" {
+			add(findingUpgradePending, name, "%s is upgrading from %s", name, unit.UpgradingFrom)
+		}
+		for _, sub := range sortedKeys(unit.Subordinates) {
+			checkUnit(sub, unit.Subordinates[sub])
+		}
+	}
+
+	for _, name := range sortedKeys(status.Applications) {
+		app := status.Applications[name]
+		for _, unit := range sortedKeys(app.Units) {
+			checkUnit(unit, app.Units[unit])
+		}
+		if app.CanUpgradeTo != "" {
+			add(findingUpgradeAvailable, name, "%s can be upgraded from revision %d to %s", name, app.CharmRev, app.CanUpgradeTo)
+		}
+		for _, endpoint := range sortedKeys(app.Relations) {
+			for _, related := range app.Relations[endpoint] {
+				other := related.RelatedApplication
+				if other == "" || other == name {
+					continue
+				}
+				_, isApp := status.Applications[other]
+				_, isSaas := status.Saas[other]
+				if !isApp && !isSaas {
+					add(findingRelationMissing, name, "relation %s:%s - %s is not established: %s is not in the model", name, endpoint, other, other)
+				}
+			}
+		}
+	}
+
+	var checkMachine func(id string, machine modelStatusMachine)
+	checkMachine = func(id string, machine modelStatusMachine) {
+		agent, instance := machine.JujuStatus, machine.MachineStatus
+		switch {
+		case agent.Current == "down":
+			add(findingMachineDown, "machine-"+id, "machine %s is down: %s", id, agent.Message)
+		case instance.Current == "error" || instance.Current == "provisioning error":
+			add(findingMachineDown, "machine-"+id, "machine %s failed to provision: %s", id, instance.Message)
+		case agent.Current == "pending":
+			if age, ok := stale(agent); ok {
+				add(findingStaleStatus, "machine-"+id, "machine %s has been pending for %s: %s", id, age, instance.Message)
+			}
+		}
+		for _, container := range sortedKeys(machine.Containers) {
+			checkMachine(container, machine.Containers[container])
+		}
+	}
+	for _, id := range sortedKeys(status.Machines) {
+		checkMachine(id, status.Machines[id])
+	}
+
+	for _, name := range sortedKeys(status.Saas) {
+		saas := status.Saas[name]
+		switch saas.Status.Current {
+		case "", "active", "unknown":
+		default:
+			add(findingSaasBroken, name, "consumed offer %s (%s) is %s: %s", name, saas.URL, saas.Status.Current, saas.Status.Message)
+		}
+	}
+	for _, name := range sortedKeys(status.Offers) {
+		offer := status.Offers[name]
+		if inactive := offer.TotalConnectedCount - offer.ActiveConnectedCount; inactive > 0 {
+			add(findingOfferDisconnected, name, "offer %s has %d of %d connections inactive", name, inactive, offer.TotalConnectedCount)
+		}
+	}
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		return findings[i].Score > findings[j].Score
+	})
+	return findings
+}
+
+// scoreSeverity maps a finding score to a severity.
+func scoreSeverity(score int) string {
+	switch {
+	case score >= 70:
+		return SeverityError
+	case score >= 30:
+		return SeverityWarning
+	default:
+		return SeverityInfo
+	}
+}
@@ -0,0 +1,114 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const unhealthyModelStatus = `{
+	"model": {"name": "prod"},
+	"machines": {
+		"0": {"juju-status": {"current": "started"}, "machine-status": {"current": "running"},
+			"containers": {"0/lxd/0": {"juju-status": {"current": "down", "message": "agent is not communicating"}}}},
+		"1": {"juju-status": {"current": "pending", "since": "2026-10-19 08:00:00Z"},
+			"machine-status": {"current": "allocating", "message": "waiting for an instance"}}
+	},
+	"applications": {
+		"mysql": {
+			"charm-rev": 151, "can-upgrade-to": "ch:amd64/mysql-160",
+			"units": {
+				"mysql/0": {"workload-status": {"current": "error", "message": "hook failed: \"install\""}, "juju-status": {"current": "idle"},
+					"subordinates": {"telegraf/0": {"workload-status": {"current": "active"}, "juju-status": {"current": "lost", "message": "agent lost"}}}},
+				"mysql/1": {"workload-status": {"current": "maintenance", "message": "installing", "since": "2026-10-19 09:30:00Z"},
+					"juju-status": {"current": "executing"}, "upgrading-from": "ch:amd64/mysql-150"}
+			},
+			"relations": {"db": [{"related-application": "wordpress"}], "monitoring": [{"related-application": "prometheus"}]}
+		},
+		"wordpress": {
+			"units": {"wordpress/0": {"workload-status": {"current": "blocked", "message": "missing relation: cache"}, "juju-status": {"current": "idle"}}},
+			"relations": {"db": [{"related-application": "mysql"}]}
+		}
+	},
+	"application-endpoints": {"keystone": {"url": "admin/iam.keystone", "application-status": {"current": "error", "message": "offer removed"}}},
+	"offers": {"mysql-db": {"total-connected-count": 3, "active-connected-count": 1}}
+}`
+
+func TestAnalyzeModelHealth(t *testing.T) {
+	// Arrange
+	var status modelStatus
+	require.NoError(t, json.Unmarshal([]byte(unhealthyModelStatus), &status))
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	// Act
+	findings := analyzeModelHealth(status, now, time.Hour)
+
+	// Assert
+	type summary struct{ kind, entity string }
+	var got []summary
+	for _, f := range findings {
+		got = append(got, summary{f.Kind, f.Entity})
+	}
+	assert.Equal(t, []summary{
+		{findingUnitError, "mysql/0"},
+		{findingAgentLost, "telegraf/0"},
+		{findingMachineDown, "machine-0/lxd/0"},
+		{findingUnitBlocked, "wordpress/0"},
+		{findingSaasBroken, "keystone"},
+		{findingRelationMissing, "mysql"},
+		{findingRelationMissing, "wordpress/0"},
+		{findingOfferDisconnected, "mysql-db"},
+		{findingStaleStatus, "machine-1"},
+		{findingUpgradePending, "mysql/1"},
+		{findingUpgradeAvailable, "mysql"},
+	}, got)
+	assert.Equal(t, issue{Severity: SeverityError, Source: "status", Message: `mysql/0 is in error: hook failed: "install"`}, findings[0].issue)
+	assert.Equal(t, 90, findings[0].Score)
+	assert.Equal(t, "relation mysql:monitoring - prometheus is not established: prometheus is not in the model", findings[5].Message)
+	assert.Equal(t, "machine 1 has been pending for 2h0m0s: waiting for an instance", findings[8].Message)
+	assert.Equal(t, SeverityInfo, findings[10].Severity)
+}
+
+func TestAnalyzeModelHealth_StaleUnits(t *testing.T) {
+	var status modelStatus
+	require.NoError(t, json.Unmarshal([]byte(unhealthyModelStatus), &status))
+	now := time.Date(2026, 10, 19, 10, 0, 0, 0, time.UTC)
+
+	findings := analyzeModelHealth(status, now, 15*time.Minute)
+
+	assert.Contains(t, findings, healthFinding{
+		issue:  issue{Severity: SeverityWarning, Source: "status", Message: "mysql/1 has been maintenance for 30m0s: installing"},
+		Score:  findingScores[findingStaleStatus],
+		Kind:   findingStaleStatus,
+		Entity: "mysql/1",
+	})
+}
+
+func TestModelHealthResource(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string { return unhealthyModelStatus })
+	factory.flags = []string{"utc"}
+	_, handler, err := a.GetResourceTemplate(healthTemplateName)
+	require.NoError(t, err)
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "juju://health/admin%2Fprod"
+
+	// Act
+	contents, err := handler(context.Background(), req)
+
+	// Assert
+	require.NoError(t, err)
+	var report healthReport
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &report))
+	assert.Equal(t, "admin/prod", report.Model)
+	require.NotEmpty(t, report.Findings)
+	assert.Equal(t, "mysql/0", report.Findings[0].Entity)
+	assert.True(t, strings.HasPrefix(report.Summary, "model admin/prod: 4 errors, "), report.Summary)
+	assert.Equal(t, map[string]string{"format": "json", "utc": "true", "model": "admin/prod"}, factory.calls[0].flags)
+}
@@ -0,0 +1,247 @@
+package jujuadapter
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	defaultSSHTimeout = time.Minute
+	// sshGrace is how long ssh may outlive the remote timeout, to connect
+	// and to report the exit status, before the call gives up on it.
+	sshGrace = 30 * time.Second
+	// remoteTimeoutStatus is the exit status of timeout(1) when it stops
+	// the command.
+	remoteTimeoutStatus = "error code 124"
+)
+
+// interactiveCommands are the commands that only work on a terminal, with
+// what to use instead. Their tools explain it rather than run them.
+var interactiveCommands = map[JujuCommandID]string{
+	CmdDebugHooks: "debug-hooks opens an interactive tmux session on the unit to run hooks by hand, which needs a terminal " +
+		"that MCP cannot provide. Use ssh with a command to inspect the unit, exec or run to run commands on units, " +
+		"resolved to retry a failed hook and debug-log to follow the hook output.",
+	CmdDebugCode: "debug-code opens an interactive tmux session on the unit with the charm's debugger, which needs a terminal " +
+		"that MCP cannot provide. Use ssh with a command to inspect the unit, exec or run to run commands on units, " +
+		"resolved to retry a failed hook and debug-log to follow the hook output.",
+}
+
+// registerInteractive replaces the tools of the commands that prompt or need
+// a terminal: ssh runs a single command, register and login take their
+// answers as arguments, and the terminal only commands explain what to use
+// instead.
+func (a *adapter) registerInteractive() {
+	for id, explanation := range interactiveCommands {
+		a.builtins.addTool(mcp.NewTool(string(id),
+			mcp.WithDescription("Not available through MCP. "+explanation),
+		), func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError(explanation), nil
+		})
+	}
+
+	a.builtins.addTool(mcp.NewTool(string(CmdSsh),
+		mcp.WithDescription("Run a command on a machine or unit over SSH and return its output. The session is not interactive: "+
+			"there is no terminal and no input, and the command is stopped once the timeout passes, on the remote side too. "+
+			"Interactive sessions are not available through MCP."),
+		mcp.WithString("target",
+			mcp.Required(),
+			mcp.Description("Unit, machine or leader to connect to, such as mysql/0, 0 or mysql/leader, optionally as user@target"),
+		),
+		mcp.WithString("command",
+			mcp.Required(),
+			mcp.Description("Shell command to run on the target, such as \"df -h\""),
+		),
+		mcp.WithString("model",
+			mcp.Description("Model of the target, the current model if empty"),
+		),
+		mcp.WithString("container",
+			mcp.Description("Container of the unit to run the command in, on Kubernetes models"),
+		),
+		mcp.WithBoolean("proxy",
+			mcp.Description("Proxy through the API server"),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("How long the command may run"),
+			mcp.DefaultString(defaultSSHTimeout.String()),
+		),
+	), a.handleSSH)
+
+	a.builtins.addTool(mcp.NewTool(string(CmdRegister),
+		mcp.WithDescription("Register a controller from the registration string given by \"juju add-user\". "+
+			"The password and controller name juju register prompts for are taken from the arguments instead."),
+		mcp.WithString("registration",
+			mcp.Required(),
+			mcp.Description("Registration string, or the host name of a public controller"),
+		),
+		mcp.WithString("controller_name",
+			mcp.Required(),
+			mcp.Description("Name to give the controller locally"),
+		),
+		mcp.WithString("password",
+			mcp.Description("New password of the user, required unless registering a public controller"),
+		),
+		mcp.WithBoolean("replace",
+			mcp.Description("Replace an existing controller of the same name"),
+		),
+	), a.handleRegister)
+
+	a.builtins.addTool(mcp.NewTool(string(CmdLogin),
+		mcp.WithDescription("Log in to a controller as a local user with a password. Logging in without a password, "+
+			"which prompts or opens a browser, is not available through MCP."),
+		mcp.WithString("user",
+			mcp.Required(),
+			mcp.Description("Local user to log in as"),
+		),
+		mcp.WithString("password",
+			mcp.Required(),
+			mcp.Description("Password of the user"),
+		),
+		mcp.WithString("controller",
+			mcp.Description("Controller to log in to, the current controller if empty"),
+		),
+		mcp.WithString("host",
+			mcp.Description("Host name of a controller that is not known yet"),
+		),
+		mcp.WithBoolean("trust",
+			mcp.Description("Trust the controller CA certificate automatically"),
+		),
+	), a.handleLogin)
+}
+
+func (a *adapter) handleSSH(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	target, err := req.RequireString("target")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	command, err := req.RequireString("command")
+	if err != nil || strings.TrimSpace(command) == "" {
+		return mcp.NewToolResultError("a command to run is required, interactive sessions are not available through MCP"), nil
+	}
+	timeout, err := time.ParseDuration(req.GetString("timeout", defaultSSHTimeout.String()))
+	if err != nil || timeout < time.Second {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid timeout %q, expected a duration of at least 1s", req.GetString("timeout", ""))), nil
+	}
+	model := req.GetString("model", "")
+	if msg, blocked := a.checkBlocked(ctx, CmdSsh, model); blocked {
+		return mcp.NewToolResultError(msg), nil
+	}
+
+	flags := withModel(map[string]string{"pty": "false"}, model)
+	if container := req.GetString("container", ""); container != "" {
+		flags["container"] = container
+	}
+	if req.GetBool("proxy", false) {
+		flags["proxy"] = "true"
+	}
+	config := CommandExecutionConfig{
+		CommandName: string(CmdSsh),
+		Arguments:   []string{target, remoteCommand(command, timeout)},
+		FixedFlags:  flags,
+	}
+
+	type result struct {
+		output string
+		err    error
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout+sshGrace)
+	defer cancel()
+	done := make(chan result, 1)
+	go func() {
+		output, err := a.executeCommand(ctx, config)
+		done <- result{output, err}
+	}()
+
+	select {
+	case r := <-done:
+		switch {
+		case r.err != nil && strings.Contains(r.err.Error(), remoteTimeoutStatus):
+			return mcp.NewToolResultError(fmt.Sprintf("the command on %s did not finish within %s and was stopped", target, timeout)), nil
+		case r.err != nil:
+			return mcp.NewToolResultError(r.err.Error()), nil
+		}
+		return mcp.NewToolResultText(r.output), nil
+	case <-ctx.Done():
+		return mcp.NewToolResultError(fmt.Sprintf("ssh to %s did not return within %s, gave up waiting for it", target, timeout+sshGrace)), nil
+	}
+}
+
+// remoteCommand wraps a command in timeout(1), so that it stops on the
+// remote side when the timeout passes even if the connection hangs.
+func remoteCommand(command string, timeout time.Duration) string {
+	seconds := int((timeout + time.Second - 1) / time.Second)
+	return fmt.Sprintf("timeout --kill-after=5 %d sh -c %s", seconds, shellQuote(command))
+}
+
+// shellQuote quotes s for a POSIX shell.
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+func (a *adapter) handleRegister(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	registration, err := req.RequireString("registration")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	controller, err := req.RequireString("controller_name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	// Answer the prompts in the order register asks them: the new password
+	// twice, unless the controller is public, then the controller name.
+	var answers []string
+	if password := req.GetString("password", ""); password != "" {
+		answers = append(answers, password, password)
+	}
+	answers = append(answers, controller)
+
+	flags := map[string]string{}
+	if req.GetBool("replace", false) {
+		flags["replace"] = "true"
+	}
+	output, err := a.executeCommand(withStdin(ctx, strings.Join(answers, "\n")+"\n"), CommandExecutionConfig{
+		CommandName: string(CmdRegister),
+		Arguments:   []string{registration},
+		FixedFlags:  flags,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(output), nil
+}
+
+func (a *adapter) handleLogin(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	user, err := req.RequireString("user")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	password, err := req.RequireString("password")
+	if err != nil || password == "" {
+		return mcp.NewToolResultError("a password is required, logging in without one is not available through MCP"), nil
+	}
+
+	flags := map[string]string{"user": user, "no-prompt": "true"}
+	if controller := req.GetString("controller", ""); controller != "" {
+		flags["controller"] = controller
+	}
+	if req.GetBool("trust", false) {
+		flags["trust"] = "true"
+	}
+	var args []string
+	if host := req.GetString("host", ""); host != "" {
+		args = append(args, host)
+	}
+	output, err := a.executeCommand(withStdin(ctx, password+"\n"), CommandExecutionConfig{
+		CommandName: string(CmdLogin),
+		Arguments:   args,
+		FixedFlags:  flags,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultText(output), nil
+}
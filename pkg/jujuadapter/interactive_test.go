@@ -0,0 +1,96 @@
+package jujuadapter
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSSH_RunsTheCommandWithATimeout(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string { return "Filesystem Size Used\n/dev/sda1 20G 4G" })
+
+	// Act
+	result := callTool(t, a, string(CmdSsh), map[string]any{"target": "mysql/0", "command": "df -h '/var/lib'", "model": "prod", "timeout": "90s"})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	assert.Equal(t, "Filesystem Size Used\n/dev/sda1 20G 4G", resultText(result))
+	require.Len(t, factory.calls, 1)
+	assert.Equal(t, []string{"mysql/0", `timeout --kill-after=5 90 sh -c 'df -h '\''/var/lib'\'''`}, factory.calls[0].args)
+	assert.Equal(t, "prod", factory.calls[0].flags["model"])
+}
+
+func TestSSH_RequiresACommand(t *testing.T) {
+	a, factory := newFakeAdapter(nil)
+
+	result := callTool(t, a, string(CmdSsh), map[string]any{"target": "mysql/0", "command": " "})
+
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), "interactive sessions are not available")
+	assert.Empty(t, factory.calls)
+}
+
+func TestSSH_ReportsRemoteTimeouts(t *testing.T) {
+	a, factory := newFakeAdapter(nil)
+	factory.fail = func(call fakeCall) error { return errors.New("subprocess encountered error code 124") }
+
+	result := callTool(t, a, string(CmdSsh), map[string]any{"target": "0", "command": "sleep 600", "timeout": "2s"})
+
+	assert.True(t, result.IsError)
+	assert.Equal(t, "the command on 0 did not finish within 2s and was stopped", resultText(result))
+}
+
+func TestInteractiveCommands_ExplainTheAlternatives(t *testing.T) {
+	a, factory := newFakeAdapter(nil)
+
+	tool, _, err := a.GetTool(string(CmdDebugHooks))
+	require.NoError(t, err)
+	result := callTool(t, a, string(CmdDebugHooks), map[string]any{"args": []any{"mysql/0"}})
+
+	assert.Contains(t, tool.Description, "Not available through MCP")
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), "resolved to retry a failed hook")
+	assert.Empty(t, factory.calls)
+	assert.Equal(t, 1, countOf(a.availableToolNames(), string(CmdDebugHooks)))
+}
+
+func TestRegisterAndLogin_AnswerThePrompts(t *testing.T) {
+	var inputs []string
+	a, factory := newFakeAdapter(nil)
+	factory.stdin = func(call fakeCall, stdin io.Reader) {
+		input, _ := io.ReadAll(stdin)
+		inputs = append(inputs, call.name+": "+string(input))
+	}
+
+	register := callTool(t, a, string(CmdRegister), map[string]any{"registration": "MFATA3", "controller_name": "prod", "password": "s3cret"})
+	public := callTool(t, a, string(CmdRegister), map[string]any{"registration": "jaas.ai", "controller_name": "jaas"})
+	login := callTool(t, a, string(CmdLogin), map[string]any{"user": "admin", "password": "s3cret"})
+	noPassword := callTool(t, a, string(CmdLogin), map[string]any{"user": "admin"})
+
+	assert.False(t, register.IsError)
+	assert.False(t, public.IsError)
+	assert.False(t, login.IsError)
+	assert.True(t, noPassword.IsError)
+	assert.Equal(t, []string{"register: s3cret\ns3cret\nprod\n", "register: jaas\n", "login: s3cret\n"}, inputs)
+}
+
+func TestCommandsDoNotReadTheServerStdin(t *testing.T) {
+	input, err := io.ReadAll(stdin(context.Background()))
+	require.NoError(t, err)
+	assert.Empty(t, input)
+}
+
+func countOf(values []string, value string) int {
+	n := 0
+	for _, v := range values {
+		if v == value {
+			n++
+		}
+	}
+	return n
+}
@@ -0,0 +1,609 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+// journalSize is how many changes the journal keeps; older entries are
+// dropped first.
+const journalSize = 500
+
+const (
+	RevertChangeToolName = "revert-change"
+	JournalResourceName  = "journal"
+	journalTemplateName  = "journal-entry"
+	journalURI           = "juju://journal"
+)
+
+var (
+	errJournalEntryNotFound = errors.New("journal entry not found")
+	errAlreadyReverted      = errors.New("change has already been reverted")
+)
+
+// revertStep is a command that undoes a change, or part of it.
+type revertStep struct {
+	Command   string            `json:"command"`
+	Arguments []string          `json:"arguments,omitempty"`
+	Flags     map[string]string `json:"flags,omitempty"`
+}
+
+// String renders the step as the equivalent juju command line.
+func (s revertStep) String() string {
+	parts := []string{"juju", s.Command}
+	for _, arg := range s.Arguments {
+		if arg == "" || strings.ContainsAny(arg, " \t\"'") {
+			arg = strconv.Quote(arg)
+		}
+		parts = append(parts, arg)
+	}
+	for _, name := range sortedKeys(s.Flags) {
+		parts = append(parts, fmt.Sprintf("--%s=%s", name, s.Flags[name]))
+	}
+	return strings.Join(parts, " ")
+}
+
+func (s revertStep) executionConfig() CommandExecutionConfig {
+	return CommandExecutionConfig{
+		CommandName: s.Command,
+		FixedFlags:  s.Flags,
+		Arguments:   s.Arguments,
+	}
+}
+
+// journalEntry is a mutating command together with the state it replaced and
+// the commands that restore that state.
+type journalEntry struct {
+	ID         string       `json:"id"`
+	Time       time.Time    `json:"time"`
+	Command    string       `json:"command"`
+	Arguments  []string     `json:"arguments,omitempty"`
+	Model      string       `json:"model,omitempty"`
+	Before     any          `json:"before,omitempty"`
+	Revert     []revertStep `json:"revert,omitempty"`
+	Hint       string       `json:"hint,omitempty"`
+	RevertedAt *time.Time   `json:"reverted_at,omitempty"`
+}
+
+// changeJournal keeps the most recent changes in memory.
+type changeJournal struct {
+	mu      sync.Mutex
+	entries []*journalEntry
+	nextID  int
+	now     func() time.Time
+}
+
+func newChangeJournal() *changeJournal {
+	return &changeJournal{now: time.Now}
+}
+
+func (j *changeJournal) add(entry *journalEntry) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.nextID++
+	entry.ID = strconv.Itoa(j.nextID)
+	entry.Time = j.now().UTC()
+	j.entries = append(j.entries, entry)
+	if len(j.entries) > journalSize {
+		j.entries = j.entries[len(j.entries)-journalSize:]
+	}
+}
+
+func (j *changeJournal) find(id string) *journalEntry {
+	for _, entry := range j.entries {
+		if entry.ID == id {
+			return entry
+		}
+	}
+	return nil
+}
+
+// get returns a copy of the entry.
+func (j *changeJournal) get(id string) (journalEntry, bool) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry := j.find(id)
+	if entry == nil {
+		return journalEntry{}, false
+	}
+	return *entry, true
+}
+
+// list returns copies of the entries, newest first.
+func (j *changeJournal) list() []journalEntry {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entries := make([]journalEntry, 0, len(j.entries))
+	for i := len(j.entries) - 1; i >= 0; i-- {
+		entries = append(entries, *j.entries[i])
+	}
+	return entries
+}
+
+// claimRevert marks the entry as reverted so concurrent reverts of the same
+// change cannot both run.
+func (j *changeJournal) claimRevert(id string) (journalEntry, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	entry := j.find(id)
+	if entry == nil {
+		return journalEntry{}, errJournalEntryNotFound
+	}
+	if entry.RevertedAt != nil {
+		return journalEntry{}, errAlreadyReverted
+	}
+	now := j.now().UTC()
+	entry.RevertedAt = &now
+	return *entry, nil
+}
+
+// releaseRevert undoes claimRevert after a revert failed.
+func (j *changeJournal) releaseRevert(id string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	if entry := j.find(id); entry != nil {
+		entry.RevertedAt = nil
+	}
+}
+
+// settingValue is the previous value of a configuration key. Keys that were
+// not explicitly set are reset on revert rather than pinned to their default.
+type settingValue struct {
+	Value any  `json:"value,omitempty"`
+	Set   bool `json:"set"`
+}
+
+// captureChange records what a mutating command is about to change. It
+// returns nil for commands, or calls, the journal does not track.
+func (a *adapter) captureChange(ctx context.Context, config CommandExecutionConfig) *journalEntry {
+	flags := mergedFlags(config)
+	model := flags["model"]
+	if model == "" {
+		model = flags["m"]
+	}
+
+	var entry *journalEntry
+	var err error
+	switch JujuCommandID(config.CommandName) {
+	case CmdConfig:
+		entry, err = a.captureConfig(ctx, config.Arguments, flags, model)
+	case CmdModelConfig:
+		entry, err = a.captureModelConfig(ctx, config.Arguments, flags, model)
+	case CmdSetConstraints:
+		entry, err = a.captureConstraints(ctx, config.Arguments, model)
+	case CmdScaleApplication:
+		entry, err = a.captureScale(ctx, config.Arguments, model)
+	case CmdExpose:
+		entry, err = a.captureExpose(ctx, config.Arguments, model)
+	}
+	if entry == nil {
+		return nil
+	}
+	if err != nil {
+		entry.Revert = nil
+		entry.Hint = fmt.Sprintf("unable to capture the previous state: %v", err)
+	}
+	entry.Command = config.CommandName
+	entry.Arguments = config.Arguments
+	entry.Model = model
+	return entry
+}
+
+func (a *adapter) captureConfig(ctx context.Context, args []string, flags map[string]string, model string) (*journalEntry, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+	if flags["file"] != "" {
+		return &journalEntry{Hint: "settings loaded from a file are not tracked; compare with the juju://config resource to revert by hand"}, nil
+	}
+	keys := changedKeys(args[1:], flags["reset"])
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	app := args[0]
+	entry := &journalEntry{}
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdConfig),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+		Arguments:   []string{app},
+	})
+	if err != nil {
+		return entry, err
+	}
+	var current struct {
+		ApplicationConfig map[string]applicationSetting `json:"application-config"`
+		Settings          map[string]applicationSetting `json:"settings"`
+	}
+	if err := json.Unmarshal([]byte(output), &current); err != nil {
+		return entry, fmt.Errorf("failed to parse application config: %w", err)
+	}
+
+	before := make(map[string]settingValue, len(keys))
+	for _, key := range keys {
+		setting, ok := current.Settings[key]
+		if !ok {
+			setting = current.ApplicationConfig[key]
+		}
+		before[key] = settingValue{Value: setting.Value, Set: setting.Source == "user"}
+	}
+	entry.Before = before
+	entry.Revert = settingsRevert(string(CmdConfig), []string{app}, before, model)
+	return entry, nil
+}
+
+type applicationSetting struct {
+	Value  any    `json:"value"`
+	Source string `json:"source"`
+}
+
+func (a *adapter) captureModelConfig(ctx context.Context, args []string, flags map[string]string, model string) (*journalEntry, error) {
+	if flags["file"] != "" {
+		return &journalEntry{Hint: "settings loaded from a file are not tracked; compare with `juju model-config` to revert by hand"}, nil
+	}
+	keys := changedKeys(args, flags["reset"])
+	if len(keys) == 0 {
+		return nil, nil
+	}
+
+	entry := &journalEntry{}
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdModelConfig),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+	})
+	if err != nil {
+		return entry, err
+	}
+	var current map[string]struct {
+		Value  any    `json:"Value"`
+		Source string `json:"Source"`
+	}
+	if err := json.Unmarshal([]byte(output), &current); err != nil {
+		return entry, fmt.Errorf("failed to parse model config: %w", err)
+	}
+
+	before := make(map[string]settingValue, len(keys))
+	for _, key := range keys {
+		setting := current[key]
+		before[key] = settingValue{Value: setting.Value, Set: setting.Source == "model"}
+	}
+	entry.Before = before
+	entry.Revert = settingsRevert(string(CmdModelConfig), nil, before, model)
+	return entry, nil
+}
+
+func (a *adapter) captureConstraints(ctx context.Context, args []string, model string) (*journalEntry, error) {
+	if len(args) < 2 {
+		return nil, nil
+	}
+
+	app := args[0]
+	entry := &journalEntry{}
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdGetConstraints),
+		FixedFlags:  withModel(nil, model),
+		Arguments:   []string{app},
+	})
+	if err != nil {
+		return entry, err
+	}
+	previous := strings.Fields(strings.TrimSpace(output))
+	entry.Before = strings.Join(previous, " ")
+	entry.Revert = []revertStep{{
+		Command:   string(CmdSetConstraints),
+		Arguments: append([]string{app}, constraintsRevert(previous, args[1:])...),
+		Flags:     withModel(nil, model),
+	}}
+	return entry, nil
+}
+
+func (a *adapter) captureScale(ctx context.Context, args []string, model string) (*journalEntry, error) {
+	if len(args) < 2 {
+		return nil, nil
+	}
+
+	app := args[0]
+	entry := &journalEntry{}
+	status, err := a.applicationStatus(ctx, app, model)
+	if err != nil {
+		return entry, err
+	}
+	scale := status.Scale
+	if scale == 0 {
+		scale = len(status.Units)
+	}
+	entry.Before = map[string]int{"scale": scale}
+	entry.Revert = []revertStep{{
+		Command:   string(CmdScaleApplication),
+		Arguments: []string{app, strconv.Itoa(scale)},
+		Flags:     withModel(nil, model),
+	}}
+	return entry, nil
+}
+
+func (a *adapter) captureExpose(ctx context.Context, args []string, model string) (*journalEntry, error) {
+	if len(args) == 0 {
+		return nil, nil
+	}
+
+	app := args[0]
+	entry := &journalEntry{}
+	status, err := a.applicationStatus(ctx, app, model)
+	if err != nil {
+		return entry, err
+	}
+	entry.Before = map[string]bool{"exposed": status.Exposed}
+	if status.Exposed {
+		// Exposing again only widens the exposed endpoints, and the previous
+		// endpoint settings are not part of the status output.
+		entry.Hint = fmt.Sprintf("%s was already exposed; use unexpose --endpoints to narrow the exposed endpoints by hand", app)
+		return entry, nil
+	}
+	entry.Revert = []revertStep{{
+		Command:   string(CmdUnexpose),
+		Arguments: []string{app},
+		Flags:     withModel(nil, model),
+	}}
+	return entry, nil
+}
+
+type applicationStatus struct {
+	Exposed bool                       `json:"exposed"`
+	Scale   int                        `json:"scale"`
+	Units   map[string]json.RawMessage `json:"units"`
+}
+
+func (a *adapter) applicationStatus(ctx context.Context, app string, model string) (applicationStatus, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+		Arguments:   []string{app},
+	})
+	if err != nil {
+		return applicationStatus{}, err
+	}
+	var status struct {
+		Applications map[string]applicationStatus `json:"applications"`
+	}
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return applicationStatus{}, fmt.Errorf("failed to parse status: %w", err)
+	}
+	current, ok := status.Applications[app]
+	if !ok {
+		return applicationStatus{}, fmt.Errorf("application %q not found in status", app)
+	}
+	return current, nil
+}
+
+// changedKeys returns the keys a config style command sets or resets.
+func changedKeys(args []string, reset string) []string {
+	seen := make(map[string]bool)
+	for _, arg := range args {
+		if key, _, ok := strings.Cut(arg, "="); ok && key != "" {
+			seen[key] = true
+		}
+	}
+	for _, key := range strings.Split(reset, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			seen[key] = true
+		}
+	}
+	return sortedKeys(seen)
+}
+
+// settingsRevert restores explicitly set keys to their previous values and
+// resets the others.
+func settingsRevert(command string, target []string, before map[string]settingValue, model string) []revertStep {
+	var set, reset []string
+	for _, key := range sortedKeys(before) {
+		value := before[key]
+		if value.Set && value.Value != nil {
+			set = append(set, key+"="+formatSettingValue(value.Value))
+		} else {
+			reset = append(reset, key)
+		}
+	}
+
+	var steps []revertStep
+	if len(set) > 0 {
+		steps = append(steps, revertStep{
+			Command:   command,
+			Arguments: append(append([]string{}, target...), set...),
+			Flags:     withModel(nil, model),
+		})
+	}
+	if len(reset) > 0 {
+		steps = append(steps, revertStep{
+			Command:   command,
+			Arguments: append([]string{}, target...),
+			Flags:     withModel(map[string]string{"reset": strings.Join(reset, ",")}, model),
+		})
+	}
+	return steps
+}
+
+// constraintsRevert returns the constraints that restore the previous ones.
+// Constraints that were not set before are cleared with an empty value.
+func constraintsRevert(previous []string, applied []string) []string {
+	restore := append([]string{}, previous...)
+	had := make(map[string]bool)
+	for _, constraint := range previous {
+		key, _, _ := strings.Cut(constraint, "=")
+		had[key] = true
+	}
+	for _, constraint := range applied {
+		key, _, _ := strings.Cut(constraint, "=")
+		if key != "" && !had[key] {
+			restore = append(restore, key+"=")
+			had[key] = true
+		}
+	}
+	return restore
+}
+
+func formatSettingValue(value any) string {
+	switch v := value.(type) {
+	case string:
+		return v
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(v)
+	default:
+		encoded, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(encoded)
+	}
+}
+
+// mergedFlags returns the fixed and requested flags of a call as strings.
+func mergedFlags(config CommandExecutionConfig) map[string]string {
+	flags := make(map[string]string, len(config.FixedFlags)+len(config.FlagValues))
+	for name, value := range config.FlagValues {
+		flags[name] = formatFlagValue(value)
+	}
+	for name, value := range config.FixedFlags {
+		flags[name] = value
+	}
+	return flags
+}
+
+func withModel(flags map[string]string, model string) map[string]string {
+	if model == "" {
+		return flags
+	}
+	if flags == nil {
+		flags = make(map[string]string)
+	}
+	flags["model"] = model
+	return flags
+}
+
+func (a *adapter) journalNote(entry *journalEntry) string {
+	if len(entry.Revert) == 0 {
+		return fmt.Sprintf("Recorded as change %s in %s. It cannot be reverted automatically: %s", entry.ID, journalURI, entry.Hint)
+	}
+	return fmt.Sprintf("Recorded as change %s in %s. Undo it with the %s tool.", entry.ID, journalURI, a.toolName(RevertChangeToolName))
+}
+
+// registerJournal adds the revert-change tool and the journal resources.
+func (a *adapter) registerJournal() {
+	a.builtins.addTool(mcp.NewTool(RevertChangeToolName,
+		mcp.WithDescription("Revert a change recorded in the juju://journal resource by applying its inverse. "+
+			"Supported for config, model-config, set-constraints, scale-application and expose."),
+		mcp.WithString("id",
+			mcp.Required(),
+			mcp.Description("The journal entry id of the change to revert"),
+		),
+		mcp.WithBoolean("dry_run",
+			mcp.Description("Only show the commands that would revert the change"),
+			mcp.DefaultBool(false),
+		),
+	), a.handleRevertChange)
+
+	a.builtins.addResource(JournalResourceName, mcp.NewResource(
+		journalURI,
+		JournalResourceName,
+		mcp.WithResourceDescription("Recent changes made through this server, newest first, with the state they replaced and how to revert them"),
+		mcp.WithMIMEType("application/json"),
+	), a.handleJournalResource)
+
+	a.builtins.addTemplate(journalTemplateName, mcp.NewResourceTemplate(
+		journalURI+"/{id}",
+		"Juju Change Journal Entry",
+		mcp.WithTemplateDescription("A single change from the journal"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), a.handleJournalEntryResource)
+}
+
+func (a *adapter) handleJournalResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	return jsonResourceContents(req.Params.URI, a.journal.list())
+}
+
+func (a *adapter) handleJournalEntryResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	id := strings.TrimPrefix(req.Params.URI, journalURI+"/")
+	entry, ok := a.journal.get(id)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", errJournalEntryNotFound, id)
+	}
+	return jsonResourceContents(req.Params.URI, entry)
+}
+
+func jsonResourceContents(uri string, value any) ([]mcp.ResourceContents, error) {
+	encoded, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return []mcp.ResourceContents{
+		mcp.TextResourceContents{
+			URI:      uri,
+			MIMEType: "application/json",
+			Text:     string(encoded),
+		},
+	}, nil
+}
+
+func (a *adapter) handleRevertChange(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	id, err := req.RequireString("id")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	entry, ok := a.journal.get(id)
+	if !ok {
+		return mcp.NewToolResultError(fmt.Sprintf("%v: %s", errJournalEntryNotFound, id)), nil
+	}
+	if len(entry.Revert) == 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("change %s cannot be reverted automatically: %s", id, entry.Hint)), nil
+	}
+
+	steps := make([]string, len(entry.Revert))
+	for i, step := range entry.Revert {
+		steps[i] = step.String()
+	}
+	if req.GetBool("dry_run", false) {
+		return mcp.NewToolResultText(fmt.Sprintf("Change %s would be reverted with:\n%s", id, strings.Join(steps, "\n"))), nil
+	}
+
+	// Refuse up front a revert that a disabled command would stop half way.
+	for _, step := range entry.Revert {
+		config := step.executionConfig()
+		if msg, blocked := a.checkBlocked(ctx, JujuCommandID(config.CommandName), stepModel(config)); blocked {
+			return mcp.NewToolResultError(fmt.Sprintf("unable to revert change %s: %s", id, msg)), nil
+		}
+	}
+
+	if _, err := a.journal.claimRevert(id); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("unable to revert change %s: %v", id, err)), nil
+	}
+	var outputs []string
+	for i, step := range entry.Revert {
+		// Each step goes through the policies of its command, such as
+		// approvals and rate limits.
+		output, err := a.runStep(ctx, step.executionConfig())
+		if err != nil {
+			// Every step restores absolute values, so reverting again after
+			// a partial failure is safe.
+			a.journal.releaseRevert(id)
+			return mcp.NewToolResultError(fmt.Sprintf("reverting change %s failed at `%s` after %d of %d steps: %v", id, steps[i], i, len(steps), err)), nil
+		}
+		if output = strings.TrimSpace(output); output != "" {
+			outputs = append(outputs, output)
+		}
+	}
+
+	result := fmt.Sprintf("Reverted change %s with:\n%s", id, strings.Join(steps, "\n"))
+	if len(outputs) > 0 {
+		result += "\n\n" + strings.Join(outputs, "\n")
+	}
+	return mcp.NewToolResultText(result), nil
+}
@@ -0,0 +1,190 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/juju/juju/cmd/juju/block"
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestJournal_ConfigRevert(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "config" && call.flags["format"] == "json" {
+			return `{"application":"mysql","settings":{
+				"max-connections":{"value":100,"source":"user"},
+				"tuning-level":{"value":"safest","source":"default"}}}`
+		}
+		return ""
+	})
+	factory.flags = []string{"reset"}
+
+	// Act
+	result := callTool(t, a, "config", map[string]any{
+		"args":  []any{"mysql", "max-connections=500", "tuning-level=fast"},
+		"model": "prod",
+	})
+	entries := a.journal.list()
+
+	// Assert
+	require.False(t, result.IsError)
+	assert.Contains(t, resultText(result), "Recorded as change 1")
+	require.Len(t, entries, 1)
+	assert.Equal(t, "prod", entries[0].Model)
+	assert.Equal(t, []revertStep{
+		{Command: "config", Arguments: []string{"mysql", "max-connections=100"}, Flags: map[string]string{"model": "prod"}},
+		{Command: "config", Arguments: []string{"mysql"}, Flags: map[string]string{"model": "prod", "reset": "tuning-level"}},
+	}, entries[0].Revert)
+
+	// Act
+	factory.calls = nil
+	result = callTool(t, a, RevertChangeToolName, map[string]any{"id": "1"})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	var applied []fakeCall
+	for _, call := range factory.calls {
+		if call.flags["format"] == "" {
+			applied = append(applied, call)
+		}
+	}
+	require.Len(t, applied, 2)
+	assert.Equal(t, []string{"mysql", "max-connections=100"}, applied[0].args)
+	assert.Equal(t, "tuning-level", applied[1].flags["reset"])
+	entry, ok := a.journal.get("1")
+	require.True(t, ok)
+	assert.NotNil(t, entry.RevertedAt)
+
+	// Reverting twice is refused.
+	result = callTool(t, a, RevertChangeToolName, map[string]any{"id": "1"})
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), errAlreadyReverted.Error())
+}
+
+func TestJournal_RevertChecksBlocksAndPolicies(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "config" && call.flags["format"] == "json" {
+			return `{"application":"mysql","settings":{"max-connections":{"value":100,"source":"user"}}}`
+		}
+		return ""
+	})
+	callTool(t, a, "config", map[string]any{"args": []any{"mysql", "max-connections=500"}, "model": "prod"})
+	a.blocks = newBlockCache(func(ctx context.Context, model string) ([]block.BlockInfo, error) {
+		return []block.BlockInfo{{Commands: BlockAll, Message: "change freeze"}}, nil
+	})
+	factory.calls = nil
+
+	// Act
+	blocked := callTool(t, a, RevertChangeToolName, map[string]any{"id": "1"})
+	blockedCalls := len(factory.calls)
+	a.blocks = newBlockCache(func(ctx context.Context, model string) ([]block.BlockInfo, error) { return nil, nil })
+	_, handler, err := a.GetTool(RevertChangeToolName)
+	require.NoError(t, err)
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"id": "1"}
+	denied, err := handler(WithPolicy(context.Background(), func(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError(req.Params.Name + " was not run: approval request 7 was denied by bob"), nil
+		}
+	}), req)
+	require.NoError(t, err)
+
+	// Assert
+	require.True(t, blocked.IsError)
+	assert.Contains(t, resultText(blocked), "change freeze")
+	assert.Zero(t, blockedCalls)
+	require.True(t, denied.IsError)
+	assert.Contains(t, resultText(denied), "config was not run: approval request 7 was denied by bob")
+	assert.Empty(t, factory.calls)
+	entry, _ := a.journal.get("1")
+	assert.Nil(t, entry.RevertedAt, "a refused revert can be tried again")
+}
+
+func TestJournal_ReadsAreNotRecorded(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+
+	callTool(t, a, "config", map[string]any{"args": []any{"mysql"}})
+	callTool(t, a, "model-config", map[string]any{"args": []any{"update-status-hook-interval"}})
+	callTool(t, a, "status", nil)
+
+	assert.Empty(t, a.journal.list())
+}
+
+func TestJournal_ExposeAndScale(t *testing.T) {
+	a, _ := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "status" {
+			return `{"applications":{"web":{"exposed":false,"scale":3},"db":{"exposed":true,"units":{"db/0":{}}}}}`
+		}
+		return ""
+	})
+
+	callTool(t, a, "expose", map[string]any{"args": []any{"web"}})
+	callTool(t, a, "expose", map[string]any{"args": []any{"db"}})
+	callTool(t, a, "scale-application", map[string]any{"args": []any{"web", "5"}})
+	entries := a.journal.list()
+
+	require.Len(t, entries, 3)
+	assert.Equal(t, []string{"web", "3"}, entries[0].Revert[0].Arguments)
+	assert.Empty(t, entries[1].Revert, "re-exposing an exposed application cannot be undone")
+	assert.Contains(t, entries[1].Hint, "already exposed")
+	assert.Equal(t, "unexpose", entries[2].Revert[0].Command)
+
+	result := callTool(t, a, RevertChangeToolName, map[string]any{"id": entries[1].ID})
+	assert.True(t, result.IsError)
+}
+
+func TestJournal_CaptureFailure(t *testing.T) {
+	a, _ := newFakeAdapter(func(call fakeCall) string { return "not json" })
+
+	callTool(t, a, "model-config", map[string]any{"args": []any{"logging-config=<root>=DEBUG"}})
+	entries := a.journal.list()
+
+	require.Len(t, entries, 1)
+	assert.Empty(t, entries[0].Revert)
+	assert.Contains(t, entries[0].Hint, "unable to capture the previous state")
+}
+
+func TestConstraintsRevert(t *testing.T) {
+	restore := constraintsRevert([]string{"mem=4G", "cores=2"}, []string{"mem=8G", "root-disk=20G"})
+	assert.Equal(t, []string{"mem=4G", "cores=2", "root-disk="}, restore)
+}
+
+func TestRevertStepString(t *testing.T) {
+	step := revertStep{Command: "config", Arguments: []string{"mysql", "motd=hello world"}, Flags: map[string]string{"model": "prod"}}
+	assert.Equal(t, `juju config mysql "motd=hello world" --model=prod`, step.String())
+}
+
+func TestJournalResources(t *testing.T) {
+	a, _ := newFakeAdapter(func(call fakeCall) string {
+		return `{"applications":{"web":{"exposed":false}}}`
+	})
+	callTool(t, a, "expose", map[string]any{"args": []any{"web"}})
+
+	_, handler, err := a.GetResource(JournalResourceName)
+	require.NoError(t, err)
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = journalURI
+	contents, err := handler(context.Background(), req)
+	require.NoError(t, err)
+	var entries []journalEntry
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &entries))
+	assert.Len(t, entries, 1)
+
+	_, templateHandler, err := a.GetResourceTemplate(journalTemplateName)
+	require.NoError(t, err)
+	req.Params.URI = journalURI + "/1"
+	contents, err = templateHandler(context.Background(), req)
+	require.NoError(t, err)
+	assert.True(t, strings.Contains(contents[0].(mcp.TextResourceContents).Text, `"command": "expose"`))
+
+	req.Params.URI = journalURI + "/42"
+	_, err = templateHandler(context.Background(), req)
+	assert.ErrorIs(t, err, errJournalEntryNotFound)
+}
@@ -0,0 +1,706 @@
+package jujuadapter
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/juju/names/v5"
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	LogsQueryToolName = "logs-query"
+	logsTemplateName  = "logs"
+	logsURI           = "juju://logs"
+
+	// logBufferSize is how many lines are kept per model.
+	logBufferSize = 5000
+	// logBacklog is how many past lines a stream starts with.
+	logBacklog = 500
+	// logTimeLayout is the time format of debug-log with --date --ms.
+	logTimeLayout = "2006-01-02 15:04:05.000"
+
+	defaultLogsLimit = 200
+)
+
+var (
+	// logNotifyInterval batches the resource-updated notifications, so that
+	// a burst of lines notifies once.
+	logNotifyInterval = time.Second
+	// logStartWait is how long a read waits for the first lines of a stream
+	// it started.
+	logStartWait = 3 * time.Second
+	// logStreamIdle is how long a stream keeps following the log of a model
+	// after its last read.
+	logStreamIdle = 15 * time.Minute
+	// maxLogStreams is how many models can be followed at once.
+	maxLogStreams = 10
+)
+
+// logLevels are the debug-log severities, from the lowest.
+var logLevels = []string{"TRACE", "DEBUG", "INFO", "WARNING", "ERROR", "CRITICAL"}
+
+// logLine is a debug-log record. Lines that do not start a record, such as
+// the rest of a traceback, are part of the message of the record before.
+type logLine struct {
+	Seq     uint64    `json:"seq"`
+	Time    time.Time `json:"time,omitzero"`
+	Entity  string    `json:"entity,omitempty"`
+	Level   string    `json:"level,omitempty"`
+	Module  string    `json:"module,omitempty"`
+	Message string    `json:"message"`
+}
+
+// parseLogLine parses a line written by debug-log --utc --date --ms, such as
+// "unit-mysql-0: 2025-07-01 12:00:00.000 ERROR juju.worker.uniter hook failed".
+func parseLogLine(text string) (logLine, bool) {
+	fields := strings.SplitN(text, " ", 6)
+	if len(fields) < 5 || !strings.HasSuffix(fields[0], ":") {
+		return logLine{}, false
+	}
+	t, err := time.Parse(logTimeLayout, fields[1]+" "+fields[2])
+	if err != nil || !slices.Contains(logLevels, fields[3]) {
+		return logLine{}, false
+	}
+	line := logLine{
+		Time:   t.UTC(),
+		Entity: strings.TrimSuffix(fields[0], ":"),
+		Level:  fields[3],
+		Module: fields[4],
+	}
+	if len(fields) == 6 {
+		line.Message = fields[5]
+	}
+	return line, true
+}
+
+// logFilter selects log lines. Entities are given as in debug-log: unit,
+// machine and application names, tags, or tag patterns such as unit-mysql-*.
+type logFilter struct {
+	Include []string
+	Exclude []string
+	Entity  string
+	Level   string
+	Since   time.Time
+	Until   time.Time
+	Pattern *regexp.Regexp
+}
+
+func newLogFilter(include, exclude []string, entity, level string) (logFilter, error) {
+	f := logFilter{Include: include, Exclude: exclude, Entity: entity, Level: strings.ToUpper(level)}
+	if f.Level != "" && !slices.Contains(logLevels, f.Level) {
+		return logFilter{}, fmt.Errorf("unknown level %q, expected one of: %s", level, strings.Join(logLevels, ", "))
+	}
+	return f, nil
+}
+
+// parseLogFilter reads the filter of a juju://logs URI query.
+func parseLogFilter(query url.Values) (logFilter, error) {
+	return newLogFilter(splitValues(query["include"]), splitValues(query["exclude"]), query.Get("entity"), query.Get("level"))
+}
+
+// splitValues splits comma separated values, dropping empty ones.
+func splitValues(values []string) []string {
+	var out []string
+	for _, v := range values {
+		for _, s := range strings.Split(v, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				out = append(out, s)
+			}
+		}
+	}
+	return out
+}
+
+func (f logFilter) matches(line logLine) bool {
+	if f.Level != "" && slices.Index(logLevels, line.Level) < slices.Index(logLevels, f.Level) {
+		return false
+	}
+	if f.Entity != "" && !matchEntity(f.Entity, line.Entity) {
+		return false
+	}
+	if len(f.Include) > 0 && !slices.ContainsFunc(f.Include, func(p string) bool { return matchEntity(p, line.Entity) }) {
+		return false
+	}
+	if slices.ContainsFunc(f.Exclude, func(p string) bool { return matchEntity(p, line.Entity) }) {
+		return false
+	}
+	if !f.Since.IsZero() && line.Time.Before(f.Since) {
+		return false
+	}
+	if !f.Until.IsZero() && line.Time.After(f.Until) {
+		return false
+	}
+	return f.Pattern == nil || f.Pattern.MatchString(line.Message)
+}
+
+// matchEntity reports whether the entity tag of a log line matches an entity
+// given the way debug-log --include takes it. An application matches its
+// units.
+func matchEntity(pattern, tag string) bool {
+	if strings.Contains(pattern, "*") {
+		ok, _ := path.Match(pattern, tag)
+		return ok
+	}
+	if t, err := names.ParseTag(pattern); err == nil {
+		switch t.Kind() {
+		case names.UnitTagKind, names.MachineTagKind, names.ApplicationTagKind:
+			return t.String() == tag
+		}
+	}
+	switch {
+	case names.IsValidMachine(pattern):
+		return tag == names.NewMachineTag(pattern).String()
+	case names.IsValidUnit(pattern):
+		return tag == names.NewUnitTag(pattern).String()
+	case names.IsValidApplication(pattern):
+		if tag == names.NewApplicationTag(pattern).String() {
+			return true
+		}
+		unit, err := names.ParseUnitTag(tag)
+		if err != nil {
+			return false
+		}
+		application, err := names.UnitApplication(unit.Id())
+		return err == nil && application == pattern
+	}
+	return pattern == tag
+}
+
+// logStream is the log of a model, followed by a background debug-log and
+// kept in a ring buffer. It is an io.Writer fed by the debug-log output.
+type logStream struct {
+	model string
+
+	mu      sync.Mutex
+	lines   []logLine
+	start   int
+	count   int
+	seq     uint64
+	partial string
+	// replaying is set while a restarted stream replays lines that may be
+	// buffered already, and skipping while it skips one of them.
+	replaying bool
+	skipping  bool
+	running   bool
+	err       string
+	ready     chan struct{}
+	// lastRead is when the log was last read, to stop following it once
+	// nobody reads it anymore.
+	lastRead  time.Time
+	idleAfter time.Duration
+	// notify sends a resource-updated notification for a URI to a session.
+	notify func(session, uri string) error
+	// subscribers are the juju://logs URIs read by each session since the
+	// stream started, notified when a line they select arrives.
+	subscribers map[logSubscription]logFilter
+	pending     map[logSubscription]bool
+}
+
+// logSubscription is a juju://logs URI read by a client session.
+type logSubscription struct {
+	session string
+	uri     string
+}
+
+func newLogStream(model string, size int) *logStream {
+	return &logStream{
+		model:       model,
+		lines:       make([]logLine, size),
+		subscribers: make(map[logSubscription]logFilter),
+		pending:     make(map[logSubscription]bool),
+	}
+}
+
+func (s *logStream) Write(p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	text := s.partial + string(p)
+	for {
+		i := strings.IndexByte(text, '\n')
+		if i < 0 {
+			break
+		}
+		s.addLocked(strings.TrimSuffix(text[:i], "\r"))
+		text = text[i+1:]
+	}
+	s.partial = text
+	return len(p), nil
+}
+
+func (s *logStream) addLocked(text string) {
+	line, ok := parseLogLine(text)
+	if !ok {
+		if strings.TrimSpace(text) == "" || s.skipping {
+			return
+		}
+		if s.count > 0 {
+			last := &s.lines[(s.start+s.count-1)%len(s.lines)]
+			last.Message += "\n" + text
+			s.markLocked(*last)
+			return
+		}
+		line = logLine{Message: text}
+	}
+	// A restarted stream replays its backlog, which is mostly buffered
+	// already.
+	if s.skipping = s.seenLocked(line); s.skipping {
+		return
+	}
+	s.seq++
+	line.Seq = s.seq
+	if s.count < len(s.lines) {
+		s.lines[(s.start+s.count)%len(s.lines)] = line
+		s.count++
+	} else {
+		s.lines[s.start] = line
+		s.start = (s.start + 1) % len(s.lines)
+	}
+	s.markLocked(line)
+	if s.ready != nil {
+		close(s.ready)
+		s.ready = nil
+	}
+}
+
+// seenLocked reports whether a line replayed by a restarted stream is
+// buffered already. Lines are not strictly ordered across entities, so the
+// check only lasts until the replay reaches lines newer than the buffer.
+func (s *logStream) seenLocked(line logLine) bool {
+	if !s.replaying || line.Time.IsZero() {
+		return false
+	}
+	last := s.lines[(s.start+s.count-1)%len(s.lines)]
+	if line.Time.After(last.Time) {
+		s.replaying = false
+		return false
+	}
+	if line.Time.Before(last.Time) {
+		return true
+	}
+	for i := s.count - 1; i >= 0; i-- {
+		buffered := s.lines[(s.start+i)%len(s.lines)]
+		if !buffered.Time.Equal(line.Time) {
+			break
+		}
+		first, _, _ := strings.Cut(buffered.Message, "\n")
+		if buffered.Entity == line.Entity && buffered.Module == line.Module && first == line.Message {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *logStream) markLocked(line logLine) {
+	for sub, filter := range s.subscribers {
+		if filter.matches(line) {
+			s.pending[sub] = true
+		}
+	}
+}
+
+// subscribe registers the URI read by a session, to notify the session
+// about new lines it selects.
+func (s *logStream) subscribe(session, uri string, filter logFilter, notify func(session, uri string) error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.subscribers[logSubscription{session: session, uri: uri}] = filter
+	if s.notify == nil {
+		s.notify = notify
+	}
+}
+
+// flush sends the pending notifications. Sessions that are gone are
+// unsubscribed.
+func (s *logStream) flush() {
+	s.mu.Lock()
+	notify := s.notify
+	subs := make([]logSubscription, 0, len(s.pending))
+	for sub := range s.pending {
+		subs = append(subs, sub)
+	}
+	clear(s.pending)
+	s.mu.Unlock()
+	if notify == nil {
+		return
+	}
+	slices.SortFunc(subs, func(a, b logSubscription) int {
+		return cmp.Or(strings.Compare(a.session, b.session), strings.Compare(a.uri, b.uri))
+	})
+	for _, sub := range subs {
+		if err := notify(sub.session, sub.uri); errors.Is(err, mcpserver.ErrSessionNotFound) {
+			s.mu.Lock()
+			delete(s.subscribers, sub)
+			s.mu.Unlock()
+		}
+	}
+}
+
+// touch records a read of the log.
+func (s *logStream) touch() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.lastRead = time.Now()
+}
+
+// idle reports whether the log was not read for logStreamIdle.
+func (s *logStream) idle() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return time.Since(s.lastRead) > s.idleAfter
+}
+
+// notifyLoop sends the notifications of a running stream, and stops the
+// stream once it is idle.
+func (s *logStream) notifyLoop(interval time.Duration, done <-chan struct{}, stop func()) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-done:
+			s.flush()
+			return
+		case <-ticker.C:
+			s.flush()
+			if s.idle() {
+				stop()
+			}
+		}
+	}
+}
+
+// begin marks the stream running and returns a channel closed once the first
+// line arrives or the stream stops.
+func (s *logStream) begin() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.running = true
+	s.replaying = s.count > 0
+	s.err = ""
+	s.lastRead = time.Now()
+	s.idleAfter = logStreamIdle
+	s.ready = make(chan struct{})
+	return s.ready
+}
+
+func (s *logStream) isRunning() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.running
+}
+
+func (s *logStream) end(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.partial != "" {
+		s.addLocked(s.partial)
+		s.partial = ""
+	}
+	s.running = false
+	// The sessions that read the log since it started read it again to
+	// restart it.
+	clear(s.subscribers)
+	clear(s.pending)
+	switch {
+	case err != nil:
+		s.err = err.Error()
+	case time.Since(s.lastRead) > s.idleAfter:
+		s.err = fmt.Sprintf("debug-log stopped after %s without reads", s.idleAfter)
+	default:
+		s.err = "debug-log stopped"
+	}
+	if s.ready != nil {
+		close(s.ready)
+		s.ready = nil
+	}
+}
+
+// query returns the newest lines matching a filter, at most limit of them,
+// with the number of matching lines.
+func (s *logStream) query(filter logFilter, limit int) logsResult {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	result := logsResult{
+		Model:     s.model,
+		Streaming: s.running,
+		Error:     s.err,
+		Buffered:  s.count,
+		Capacity:  len(s.lines),
+		Lines:     []logLine{},
+	}
+	for i := 0; i < s.count; i++ {
+		line := s.lines[(s.start+i)%len(s.lines)]
+		if filter.matches(line) {
+			result.Lines = append(result.Lines, line)
+		}
+	}
+	result.Matched = len(result.Lines)
+	if limit > 0 && len(result.Lines) > limit {
+		result.Lines = result.Lines[len(result.Lines)-limit:]
+	}
+	return result
+}
+
+// logsResult is the content of a juju://logs resource and the result of the
+// logs-query tool.
+type logsResult struct {
+	Model     string    `json:"model"`
+	Streaming bool      `json:"streaming"`
+	Error     string    `json:"error,omitempty"`
+	Buffered  int       `json:"buffered"`
+	Capacity  int       `json:"capacity"`
+	Matched   int       `json:"matched"`
+	Lines     []logLine `json:"lines"`
+}
+
+// logStreams holds the log stream of every model read so far.
+type logStreams struct {
+	mu      sync.Mutex
+	streams map[string]*logStream
+}
+
+func newLogStreams() *logStreams {
+	return &logStreams{streams: make(map[string]*logStream)}
+}
+
+// running returns the stream of a model if it is following the log.
+func (l *logStreams) running(model string) (*logStream, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.streams[model]
+	if !ok || !s.isRunning() {
+		return nil, false
+	}
+	return s, true
+}
+
+// begin marks the stream of a model running, unless maxLogStreams streams
+// are running already. It returns a nil channel if the stream was running
+// already.
+func (l *logStreams) begin(model string) (*logStream, <-chan struct{}, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	s, ok := l.streams[model]
+	if ok && s.isRunning() {
+		return s, nil, nil
+	}
+	running := 0
+	for _, other := range l.streams {
+		if other.isRunning() {
+			running++
+		}
+	}
+	if running >= maxLogStreams {
+		return nil, nil, fmt.Errorf("already following the log of %d models, the most allowed; a log is no longer followed %s after its last read",
+			running, logStreamIdle)
+	}
+	if !ok {
+		s = newLogStream(model, logBufferSize)
+		l.streams[model] = s
+	}
+	return s, s.begin(), nil
+}
+
+// followLogs returns the stream of a model, starting its debug-log if it is
+// not running. A stream that just started is given a moment to fill in its
+// backlog. Streams are capped by maxLogStreams rather than by the debug-log
+// policies, which would let a few idle streams hold every debug-log slot, and
+// stop once the log is no longer read.
+func (a *adapter) followLogs(ctx context.Context, model string) (*logStream, error) {
+	if s, ok := a.logs.running(model); ok {
+		s.touch()
+		return s, nil
+	}
+	// debug-log --retry waits for a model that does not exist forever.
+	if _, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdShowModel),
+		Arguments:   []string{model},
+		FixedFlags:  map[string]string{"format": "json"},
+	}); err != nil {
+		return nil, fmt.Errorf("unable to follow the log of model %q: %w", model, err)
+	}
+	s, ready, err := a.logs.begin(model)
+	if err != nil || ready == nil {
+		return s, err
+	}
+
+	ctx, stop := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go s.notifyLoop(logNotifyInterval, done, stop)
+	go func() {
+		defer close(done)
+		defer stop()
+		_, err := a.executeCommand(withStdout(ctx, s), CommandExecutionConfig{
+			CommandName: string(CmdDebugLog),
+			FixedFlags: withModel(map[string]string{
+				"tail":  "true",
+				"lines": strconv.Itoa(logBacklog),
+				"utc":   "true",
+				"date":  "true",
+				"ms":    "true",
+				"retry": "true",
+			}, model),
+		})
+		if err != nil {
+			log.Warn().Err(err).Str("model", model).Msg("debug-log stream stopped")
+		}
+		s.end(err)
+	}()
+
+	select {
+	case <-ready:
+	case <-time.After(logStartWait):
+	}
+	return s, nil
+}
+
+// resourceNotifier returns the function notifying a client session that a
+// resource was updated.
+func resourceNotifier(ctx context.Context) func(session, uri string) error {
+	server := mcpserver.ServerFromContext(ctx)
+	if server == nil {
+		return nil
+	}
+	return func(session, uri string) error {
+		return server.SendNotificationToSpecificClient(session, mcp.MethodNotificationResourceUpdated, map[string]any{"uri": uri})
+	}
+}
+
+// registerLogs adds the juju://logs resource and the logs-query tool.
+func (a *adapter) registerLogs() {
+	a.builtins.addTemplate(logsTemplateName, mcp.NewResourceTemplate(
+		logsURI+"/{model}{?include,exclude,level,entity}",
+		"Juju Model Logs",
+		mcp.WithTemplateDescription(fmt.Sprintf("The latest lines of the debug log of a model, at most %d, parsed into time, entity, level, module and message. "+
+			"Reading it starts following the log in the background, into a buffer of the last %d lines, and the session that read it is sent "+
+			"resource updated notifications when new lines match. The log is no longer followed %s after its last read. include and exclude take comma separated units, machines or "+
+			"applications, level is the lowest severity and entity selects a single entity. Use logs-query to search the buffer.",
+			defaultLogsLimit, logBufferSize, logStreamIdle)),
+		mcp.WithTemplateMIMEType("application/json"),
+	), a.handleLogsResource)
+
+	a.builtins.addTool(mcp.NewTool(LogsQueryToolName,
+		mcp.WithDescription("Search the buffered debug log of a model by time range, regular expression, entity and level. "+
+			"The log is followed in the background from the first query or juju://logs read of the model, starting with its "+
+			fmt.Sprintf("last %d lines, and the newest %d lines are kept. It is no longer followed %s after its last read, and at most %d models are followed at once.",
+				logBacklog, logBufferSize, logStreamIdle, maxLogStreams)),
+		mcp.WithString("model",
+			mcp.Required(),
+			mcp.Description("Model whose log to search"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Oldest line to return, as an RFC 3339 time or a duration before now such as 15m"),
+		),
+		mcp.WithString("until",
+			mcp.Description("Newest line to return, as an RFC 3339 time or a duration before now such as 5m"),
+		),
+		mcp.WithString("pattern",
+			mcp.Description("Regular expression the message must match, such as \"hook failed|Traceback\"; (?i) ignores case"),
+		),
+		mcp.WithString("level",
+			mcp.Description("Lowest severity to return"),
+			mcp.Enum(logLevels...),
+		),
+		mcp.WithString("entity",
+			mcp.Description("Single unit, machine or application to return the lines of, such as mysql/0"),
+		),
+		mcp.WithArray("include",
+			mcp.Description("Units, machines or applications to return the lines of"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("exclude",
+			mcp.Description("Units, machines or applications to leave out"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("limit",
+			mcp.Description("Maximum number of lines, the newest are kept"),
+			mcp.DefaultNumber(defaultLogsLimit),
+		),
+	), a.handleLogsQuery)
+}
+
+func (a *adapter) handleLogsResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	u, err := url.Parse(req.Params.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid logs URI %q: %w", req.Params.URI, err)
+	}
+	model, err := url.PathUnescape(strings.TrimPrefix(u.Path, "/"))
+	if err != nil || model == "" {
+		return nil, fmt.Errorf("invalid logs URI %q: a model is required", req.Params.URI)
+	}
+	filter, err := parseLogFilter(u.Query())
+	if err != nil {
+		return nil, err
+	}
+
+	s, err := a.followLogs(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	if session := mcpserver.ClientSessionFromContext(ctx); session != nil {
+		s.subscribe(session.SessionID(), req.Params.URI, filter, resourceNotifier(ctx))
+	}
+	return jsonResourceContents(req.Params.URI, s.query(filter, defaultLogsLimit))
+}
+
+func (a *adapter) handleLogsQuery(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	model, err := req.RequireString("model")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	filter, err := newLogFilter(req.GetStringSlice("include", nil), req.GetStringSlice("exclude", nil),
+		req.GetString("entity", ""), req.GetString("level", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	now := time.Now()
+	if since := req.GetString("since", ""); since != "" {
+		if filter.Since, err = parseRelativeTime(since, now); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid since: %v", err)), nil
+		}
+	}
+	if until := req.GetString("until", ""); until != "" {
+		if filter.Until, err = parseRelativeTime(until, now); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid until: %v", err)), nil
+		}
+	}
+	if pattern := req.GetString("pattern", ""); pattern != "" {
+		if filter.Pattern, err = regexp.Compile(pattern); err != nil {
+			return mcp.NewToolResultError(fmt.Sprintf("invalid pattern: %v", err)), nil
+		}
+	}
+	limit := req.GetInt("limit", defaultLogsLimit)
+	if limit <= 0 {
+		return mcp.NewToolResultError("limit must be positive"), nil
+	}
+
+	s, err := a.followLogs(ctx, model)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonToolResult(s.query(filter, limit))
+}
+
+// parseRelativeTime parses an RFC 3339 time, or a duration before now.
+func parseRelativeTime(value string, now time.Time) (time.Time, error) {
+	if d, err := time.ParseDuration(value); err == nil {
+		return now.Add(-d).UTC(), nil
+	}
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("%q is neither an RFC 3339 time nor a duration such as 15m", value)
+	}
+	return t.UTC(), nil
+}
@@ -0,0 +1,220 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const debugLogOutput = `machine-0: 2025-07-01 12:00:00.000 INFO juju.worker.machiner machine started
+unit-mysql-0: 2025-07-01 12:00:01.000 ERROR unit.mysql/0.juju-log hook failed: Traceback (most recent call last):
+  File "./src/charm.py", line 12, in <module>
+unit-mysql-router-0: 2025-07-01 12:00:02.000 WARNING unit.mysql-router/0.juju-log waiting for database
+unit-mysql-1: 2025-07-01 12:00:03.500 DEBUG unit.mysql/1.juju-log config-changed
+`
+
+func TestParseLogLine(t *testing.T) {
+	line, ok := parseLogLine("unit-mysql-0: 2025-07-01 12:00:01.250 ERROR unit.mysql/0.juju-log hook failed: install")
+
+	require.True(t, ok)
+	assert.Equal(t, logLine{
+		Time:    time.Date(2025, 7, 1, 12, 0, 1, 250_000_000, time.UTC),
+		Entity:  "unit-mysql-0",
+		Level:   "ERROR",
+		Module:  "unit.mysql/0.juju-log",
+		Message: "hook failed: install",
+	}, line)
+
+	_, ok = parseLogLine(`  File "./src/charm.py", line 12, in <module>`)
+	assert.False(t, ok)
+}
+
+func TestMatchEntity(t *testing.T) {
+	tests := []struct {
+		pattern string
+		tag     string
+		want    bool
+	}{
+		{"mysql/0", "unit-mysql-0", true},
+		{"mysql/0", "unit-mysql-1", false},
+		{"mysql", "unit-mysql-1", true},
+		{"mysql", "unit-mysql-router-0", false},
+		{"mysql", "application-mysql", true},
+		{"0", "machine-0", true},
+		{"0/lxd/1", "machine-0-lxd-1", true},
+		{"unit-mysql-0", "unit-mysql-0", true},
+		{"unit-mysql-*", "unit-mysql-router-0", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.pattern+" "+tt.tag, func(t *testing.T) {
+			assert.Equal(t, tt.want, matchEntity(tt.pattern, tt.tag))
+		})
+	}
+}
+
+func TestLogStream_RingBufferAndReplay(t *testing.T) {
+	s := newLogStream("prod", 3)
+	s.begin()
+	_, err := s.Write([]byte(debugLogOutput))
+	require.NoError(t, err)
+	s.end(nil)
+
+	// The restarted stream replays the lines it has already.
+	s.begin()
+	_, err = s.Write([]byte(debugLogOutput + "unit-mysql-0: 2025-07-01 12:00:04.000 INFO unit.mysql/0.juju-log started\n"))
+	require.NoError(t, err)
+
+	result := s.query(logFilter{}, 0)
+	assert.True(t, result.Streaming)
+	assert.Equal(t, 3, result.Buffered)
+	require.Len(t, result.Lines, 3)
+	assert.Equal(t, []uint64{3, 4, 5}, []uint64{result.Lines[0].Seq, result.Lines[1].Seq, result.Lines[2].Seq})
+	assert.Equal(t, "waiting for database", result.Lines[0].Message)
+	assert.Equal(t, "started", result.Lines[2].Message)
+}
+
+func TestLogStream_NotifiesMatchingSubscribers(t *testing.T) {
+	s := newLogStream("prod", logBufferSize)
+	var notified []string
+	notify := func(session, uri string) error {
+		if session == "gone" {
+			return mcpserver.ErrSessionNotFound
+		}
+		notified = append(notified, session+" "+uri)
+		return nil
+	}
+	failures, _ := parseLogFilter(map[string][]string{"level": {"error"}})
+	router, _ := parseLogFilter(map[string][]string{"include": {"mysql-router"}})
+	machines, _ := parseLogFilter(map[string][]string{"entity": {"1"}})
+	s.subscribe("alice", "juju://logs/prod?level=error", failures, notify)
+	s.subscribe("bob", "juju://logs/prod?include=mysql-router", router, nil)
+	s.subscribe("bob", "juju://logs/prod?entity=1", machines, nil)
+	s.subscribe("gone", "juju://logs/prod?level=error", failures, nil)
+
+	_, err := s.Write([]byte(debugLogOutput))
+	require.NoError(t, err)
+	s.flush()
+	s.flush()
+
+	assert.Equal(t, []string{"alice juju://logs/prod?level=error", "bob juju://logs/prod?include=mysql-router"}, notified)
+	assert.Len(t, s.subscribers, 3)
+}
+
+func TestLogs_ResourceAndQuery(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string { return debugLogOutput })
+	factory.flags = []string{"utc"}
+	_, handler, err := a.GetResourceTemplate(logsTemplateName)
+	require.NoError(t, err)
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "juju://logs/prod?include=mysql,0&level=INFO"
+
+	// Act
+	contents, err := handler(context.Background(), req)
+	require.NoError(t, err)
+	query := callTool(t, a, LogsQueryToolName, map[string]any{
+		"model":   "prod",
+		"since":   "2025-07-01T12:00:01Z",
+		"pattern": "(?i)traceback|database",
+	})
+	invalid := callTool(t, a, LogsQueryToolName, map[string]any{"model": "prod", "pattern": "("})
+
+	// Assert
+	assert.Equal(t, fakeCall{name: "show-model", args: []string{"prod"}, flags: map[string]string{"format": "json"}}, factory.calls[0])
+	assert.Equal(t, "debug-log", factory.calls[1].name)
+	assert.Equal(t, map[string]string{"model": "prod", "utc": "true"}, factory.calls[1].flags)
+
+	var resource logsResult
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &resource))
+	assert.Equal(t, 4, resource.Buffered)
+	require.Len(t, resource.Lines, 2)
+	assert.Equal(t, "machine-0", resource.Lines[0].Entity)
+	assert.Equal(t, "unit-mysql-0", resource.Lines[1].Entity)
+	assert.True(t, strings.HasSuffix(resource.Lines[1].Message, `line 12, in <module>`))
+
+	require.False(t, query.IsError, resultText(query))
+	var got logsResult
+	require.NoError(t, json.Unmarshal([]byte(resultText(query)), &got))
+	assert.Equal(t, 2, got.Matched)
+	assert.Equal(t, "unit-mysql-router-0", got.Lines[1].Entity)
+
+	assert.True(t, invalid.IsError)
+	assert.Contains(t, resultText(invalid), "invalid pattern")
+}
+
+func TestLogs_UnknownModel(t *testing.T) {
+	a, factory := newFakeAdapter(func(call fakeCall) string { return debugLogOutput })
+	factory.fail = func(call fakeCall) error {
+		if call.name == string(CmdShowModel) {
+			return errors.New(`model "prdo" not found`)
+		}
+		return nil
+	}
+
+	result := callTool(t, a, LogsQueryToolName, map[string]any{"model": "prdo"})
+
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), `unable to follow the log of model "prdo"`)
+	assert.Len(t, factory.calls, 1)
+	assert.Empty(t, a.logs.streams)
+}
+
+func TestLogs_StreamLimitAndIdle(t *testing.T) {
+	// Arrange
+	idle, interval, max := logStreamIdle, logNotifyInterval, maxLogStreams
+	logStreamIdle, logNotifyInterval, maxLogStreams = 50*time.Millisecond, 5*time.Millisecond, 1
+	t.Cleanup(func() { logStreamIdle, logNotifyInterval, maxLogStreams = idle, interval, max })
+	a, factory := newFakeAdapter(func(call fakeCall) string { return debugLogOutput })
+	factory.follow = true
+
+	// Act
+	prod := callTool(t, a, LogsQueryToolName, map[string]any{"model": "prod"})
+	dev := callTool(t, a, LogsQueryToolName, map[string]any{"model": "dev"})
+
+	// Assert
+	require.False(t, prod.IsError, resultText(prod))
+	assert.True(t, dev.IsError)
+	assert.Contains(t, resultText(dev), "already following the log of 1 models")
+	assert.Eventually(t, func() bool {
+		_, running := a.logs.running("prod")
+		return !running
+	}, time.Second, 5*time.Millisecond)
+	result := a.logs.streams["prod"].query(logFilter{}, 0)
+	assert.Equal(t, "debug-log stopped after 50ms without reads", result.Error)
+
+	dev = callTool(t, a, LogsQueryToolName, map[string]any{"model": "dev"})
+	assert.False(t, dev.IsError, resultText(dev))
+	assert.Eventually(t, func() bool {
+		_, running := a.logs.running("dev")
+		return !running
+	}, time.Second, 5*time.Millisecond)
+}
+
+func TestLogs_StreamsDoNotHoldDebugLogSlots(t *testing.T) {
+	a, factory := newFakeAdapter(func(call fakeCall) string { return debugLogOutput })
+	ctx := WithPolicy(context.Background(), func(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			return mcp.NewToolResultError("rate limited by concurrency:debug-log"), nil
+		}
+	})
+	_, handler, err := a.GetTool(LogsQueryToolName)
+	require.NoError(t, err)
+	req := mcp.CallToolRequest{}
+	req.Params.Arguments = map[string]any{"model": "prod"}
+
+	result, err := handler(ctx, req)
+
+	require.NoError(t, err)
+	var got logsResult
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &got))
+	assert.Equal(t, 4, got.Buffered)
+	assert.Equal(t, "debug-log", factory.calls[1].name)
+}
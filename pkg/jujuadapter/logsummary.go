@@ -0,0 +1,295 @@
+package jujuadapter
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/names/v5"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	LogSummaryToolName = "log-summary"
+
+	defaultSummaryLines    = 1000
+	maxSummaryLines        = 20000
+	defaultSummaryClusters = 10
+)
+
+// logNormalizers replace the parts of a message that change between
+// occurrences of the same problem, in order: the more specific first.
+var logNormalizers = []struct {
+	pattern     *regexp.Regexp
+	replacement string
+	// keep, when set, leaves the matches it returns true for unchanged.
+	keep func(match string) bool
+}{
+	{regexp.MustCompile(`\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:?\d{2})?`), "<time>", nil},
+	{regexp.MustCompile(`\b\d{2}:\d{2}:\d{2}(\.\d+)?\b`), "<time>", nil},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{8}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{4}-[0-9a-fA-F]{12}\b`), "<uuid>", nil},
+	{regexp.MustCompile(`\b\d{1,3}(\.\d{1,3}){3}(:\d+)?\b`), "<ip>", nil},
+	{regexp.MustCompile(`\b0x[0-9a-fA-F]+\b`), "<hex>", nil},
+	{regexp.MustCompile(`\b[0-9a-fA-F]{6,}\b`), "<hex>", func(match string) bool {
+		// Hashes and IDs mix digits and letters, words and numbers do not.
+		return !strings.ContainsAny(match, "0123456789") || !strings.ContainsAny(strings.ToLower(match), "abcdef")
+	}},
+	{regexp.MustCompile(`\b[a-z][a-z0-9]*(-[a-z0-9]*[a-z][a-z0-9]*)*/\d+\b`), "<unit>", nil},
+	{regexp.MustCompile(`\bunit-[a-z][a-z0-9-]*-\d+\b`), "<unit>", nil},
+	{regexp.MustCompile(`\bmachine-\d+(-[a-z]+-\d+)*\b`), "<machine>", nil},
+	{regexp.MustCompile(`\b\d+\b`), "<n>", nil},
+}
+
+// normalizeLogMessage turns a message into the template shared by its
+// repetitions, such as "hook failed: <unit> exited with <n>". A multi-line
+// message, such as a traceback, is known by its first and last lines.
+func normalizeLogMessage(message string) string {
+	lines := nonEmptyLines(message)
+	if len(lines) == 0 {
+		return ""
+	}
+	text := strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		text += " ... " + strings.TrimSpace(lines[len(lines)-1])
+	}
+	for _, n := range logNormalizers {
+		text = n.pattern.ReplaceAllStringFunc(text, func(match string) string {
+			if n.keep != nil && n.keep(match) {
+				return match
+			}
+			return n.replacement
+		})
+	}
+	return text
+}
+
+// parseLogLines parses the output of debug-log --utc --date --ms. Lines that
+// do not start a record are part of the message of the record before.
+func parseLogLines(output string) []logLine {
+	var lines []logLine
+	for _, text := range strings.Split(output, "\n") {
+		text = strings.TrimRight(text, "\r")
+		line, ok := parseLogLine(text)
+		switch {
+		case ok:
+			lines = append(lines, line)
+		case strings.TrimSpace(text) == "":
+		case len(lines) > 0:
+			lines[len(lines)-1].Message += "\n" + text
+		default:
+			lines = append(lines, logLine{Message: text})
+		}
+	}
+	return lines
+}
+
+// logCluster is a group of log records sharing a template.
+type logCluster struct {
+	Level     string    `json:"level"`
+	Template  string    `json:"template"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"first_seen,omitzero"`
+	LastSeen  time.Time `json:"last_seen,omitzero"`
+	Units     []string  `json:"units,omitempty"`
+	Machines  []string  `json:"machines,omitempty"`
+	Sample    string    `json:"sample"`
+}
+
+// logSummary is the result of the log-summary tool.
+type logSummary struct {
+	Model    string         `json:"model,omitempty"`
+	Entity   string         `json:"entity,omitempty"`
+	Records  int            `json:"records"`
+	From     time.Time      `json:"from,omitzero"`
+	To       time.Time      `json:"to,omitzero"`
+	Levels   map[string]int `json:"levels"`
+	Clusters []logCluster   `json:"clusters"`
+	// Omitted is the number of warning and error clusters not returned.
+	Omitted int    `json:"omitted_clusters,omitempty"`
+	Summary string `json:"summary"`
+}
+
+// summarizeLogs clusters the warning and error records by level and template,
+// and returns the top clusters: errors first, then the most frequent.
+func summarizeLogs(lines []logLine, top int) logSummary {
+	summary := logSummary{Levels: map[string]int{}, Clusters: []logCluster{}}
+	clusters := map[string]*logCluster{}
+	units := map[string]map[string]bool{}
+	machines := map[string]map[string]bool{}
+	for _, line := range lines {
+		summary.Records++
+		if line.Level != "" {
+			summary.Levels[line.Level]++
+		}
+		if !line.Time.IsZero() {
+			if summary.From.IsZero() || line.Time.Before(summary.From) {
+				summary.From = line.Time
+			}
+			if line.Time.After(summary.To) {
+				summary.To = line.Time
+			}
+		}
+		if slices.Index(logLevels, line.Level) < slices.Index(logLevels, "WARNING") {
+			continue
+		}
+
+		template := normalizeLogMessage(line.Message)
+		key := line.Level + "\x00" + template
+		c, ok := clusters[key]
+		if !ok {
+			c = &logCluster{Level: line.Level, Template: template, FirstSeen: line.Time, Sample: logSample(line)}
+			clusters[key] = c
+			units[key] = map[string]bool{}
+			machines[key] = map[string]bool{}
+		}
+		c.Count++
+		if !line.Time.IsZero() && (c.FirstSeen.IsZero() || line.Time.Before(c.FirstSeen)) {
+			c.FirstSeen = line.Time
+		}
+		if line.Time.After(c.LastSeen) {
+			c.LastSeen = line.Time
+		}
+		if tag, err := names.ParseTag(line.Entity); err == nil {
+			switch tag.Kind() {
+			case names.UnitTagKind:
+				units[key][tag.Id()] = true
+			case names.MachineTagKind:
+				machines[key][tag.Id()] = true
+			}
+		}
+	}
+
+	all := make([]logCluster, 0, len(clusters))
+	for key, c := range clusters {
+		c.Units = sortedKeys(units[key])
+		c.Machines = sortedKeys(machines[key])
+		all = append(all, *c)
+	}
+	sort.Slice(all, func(i, j int) bool {
+		li, lj := slices.Index(logLevels, all[i].Level), slices.Index(logLevels, all[j].Level)
+		if li != lj {
+			return li > lj
+		}
+		if all[i].Count != all[j].Count {
+			return all[i].Count > all[j].Count
+		}
+		return all[i].Template < all[j].Template
+	})
+	if len(all) > top {
+		summary.Omitted = len(all) - top
+		all = all[:top]
+	}
+	summary.Clusters = all
+	summary.Summary = describeLogSummary(summary, clusters)
+	return summary
+}
+
+// logSample renders a record the way debug-log prints it, with the first
+// and last lines of a multi-line message.
+func logSample(line logLine) string {
+	lines := nonEmptyLines(line.Message)
+	message := ""
+	if len(lines) > 0 {
+		message = lines[0]
+		if len(lines) > 1 {
+			message += "\n...\n" + lines[len(lines)-1]
+		}
+	}
+	if line.Time.IsZero() {
+		return message
+	}
+	return fmt.Sprintf("%s: %s %s %s %s", line.Entity, line.Time.Format(logTimeLayout), line.Level, line.Module, message)
+}
+
+func describeLogSummary(summary logSummary, clusters map[string]*logCluster) string {
+	counts := map[string]int{}
+	for _, c := range clusters {
+		if c.Level == "WARNING" {
+			counts["warning"]++
+		} else {
+			counts["error"]++
+		}
+	}
+	errors := summary.Levels["ERROR"] + summary.Levels["CRITICAL"]
+	warnings := summary.Levels["WARNING"]
+	if errors+warnings == 0 {
+		return fmt.Sprintf("No warnings or errors in %s.", plural(summary.Records, "log record"))
+	}
+	text := fmt.Sprintf("%s in %s, %s in %s, out of %s.",
+		plural(errors, "error"), plural(counts["error"], "cluster"),
+		plural(warnings, "warning"), plural(counts["warning"], "cluster"),
+		plural(summary.Records, "log record"))
+	if len(summary.Clusters) > 0 {
+		c := summary.Clusters[0]
+		text += fmt.Sprintf(" Top: %s %q (%dx)", c.Level, c.Template, c.Count)
+		if len(c.Units) > 0 {
+			text += " on " + strings.Join(c.Units, ", ")
+		}
+		text += "."
+	}
+	return text
+}
+
+func (a *adapter) registerLogSummary() {
+	a.builtins.addTool(mcp.NewTool(LogSummaryToolName,
+		mcp.WithDescription("Summarize the recent debug log of a model, or of one unit, machine or application, as clusters of "+
+			"repeated warnings and errors. Messages are normalized into templates, with times, IDs, addresses, hex values, "+
+			"unit names and numbers replaced, and each cluster gives its count, first and last seen times, affected units and "+
+			"a sample line. Use it before reading raw debug-log output."),
+		mcp.WithString("model",
+			mcp.Description("Model whose log to summarize, the current model if empty"),
+		),
+		mcp.WithString("entity",
+			mcp.Description("Unit, machine or application to summarize the log of, such as mysql/0, 0 or mysql"),
+		),
+		mcp.WithNumber("lines",
+			mcp.Description(fmt.Sprintf("Number of recent log records to analyze, at most %d", maxSummaryLines)),
+			mcp.DefaultNumber(defaultSummaryLines),
+		),
+		mcp.WithNumber("top",
+			mcp.Description("Maximum number of clusters to return"),
+			mcp.DefaultNumber(defaultSummaryClusters),
+		),
+	), a.handleLogSummary)
+}
+
+func (a *adapter) handleLogSummary(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	lines := req.GetInt("lines", defaultSummaryLines)
+	if lines <= 0 || lines > maxSummaryLines {
+		return mcp.NewToolResultError(fmt.Sprintf("lines must be between 1 and %d", maxSummaryLines)), nil
+	}
+	top := req.GetInt("top", defaultSummaryClusters)
+	if top <= 0 {
+		return mcp.NewToolResultError("top must be positive"), nil
+	}
+	model := req.GetString("model", "")
+	entity := req.GetString("entity", "")
+
+	flags := withModel(map[string]string{
+		"no-tail": "true",
+		"limit":   strconv.Itoa(lines),
+		"utc":     "true",
+		"date":    "true",
+		"ms":      "true",
+	}, model)
+	if entity != "" {
+		flags["include"] = entity
+	}
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdDebugLog),
+		FixedFlags:  flags,
+	})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	summary := summarizeLogs(parseLogLines(output), top)
+	summary.Model = model
+	summary.Entity = entity
+	return jsonToolResult(summary)
+}
@@ -0,0 +1,150 @@
+package jujuadapter
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNormalizeLogMessage(t *testing.T) {
+	tests := []struct {
+		message string
+		want    string
+	}{
+		{
+			message: `hook "db-relation-changed" (via hook dispatching script: dispatch) failed: exit status 1`,
+			want:    `hook "db-relation-changed" (via hook dispatching script: dispatch) failed: exit status <n>`,
+		},
+		{
+			message: "cannot reach 10.0.0.12:3306 from mysql-router/2 at 2025-07-01T12:00:00Z",
+			want:    "cannot reach <ip> from <unit> at <time>",
+		},
+		{
+			message: "operation 7f3c2a9e1b for model 4f1d3c2e-5a6b-4c7d-8e9f-0a1b2c3d4e5f (0xc000123abc) decided",
+			want:    "operation <hex> for model <uuid> (<hex>) decided",
+		},
+		{
+			message: "Uncaught exception while in charm code:\nTraceback (most recent call last):\n  File \"./src/charm.py\", line 40\nKeyError: 'password'",
+			want:    "Uncaught exception while in charm code: ... KeyError: 'password'",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			assert.Equal(t, tt.want, normalizeLogMessage(tt.message))
+		})
+	}
+}
+
+const summaryLogOutput = `unit-mysql-0: 2025-07-01 12:00:00.000 ERROR unit.mysql/0.juju-log Uncaught exception while in charm code:
+Traceback (most recent call last):
+KeyError: 'password'
+unit-mysql-0: 2025-07-01 12:00:01.000 INFO juju.worker.uniter resolving hook
+unit-mysql-1: 2025-07-01 12:05:00.000 ERROR unit.mysql/1.juju-log Uncaught exception while in charm code:
+Traceback (most recent call last):
+KeyError: 'password'
+unit-mysql-router-0: 2025-07-01 12:01:00.000 WARNING unit.mysql-router/0.juju-log cannot reach 10.0.0.12:3306
+unit-mysql-router-0: 2025-07-01 12:02:00.000 WARNING unit.mysql-router/0.juju-log cannot reach 10.0.0.13:3306
+unit-mysql-router-0: 2025-07-01 12:03:00.000 WARNING unit.mysql-router/0.juju-log cannot reach 10.0.0.14:3306
+machine-0: 2025-07-01 12:04:00.000 ERROR juju.worker.machiner machine 0 is not provisioned
+`
+
+func TestSummarizeLogs(t *testing.T) {
+	tests := []struct {
+		name      string
+		output    string
+		top       int
+		records   int
+		levels    map[string]int
+		templates []string
+		omitted   int
+		summary   string
+	}{
+		{
+			name:      "clusters",
+			output:    summaryLogOutput,
+			top:       2,
+			records:   7,
+			levels:    map[string]int{"ERROR": 3, "INFO": 1, "WARNING": 3},
+			templates: []string{"Uncaught exception while in charm code: ... KeyError: 'password'", "machine <n> is not provisioned"},
+			omitted:   1,
+			summary: `3 errors in 2 clusters, 3 warnings in 1 cluster, out of 7 log records. Top: ERROR "Uncaught exception while in charm code: ... ` +
+				`KeyError: 'password'" (2x) on mysql/0, mysql/1.`,
+		},
+		{
+			name:    "all clusters",
+			output:  summaryLogOutput,
+			top:     10,
+			records: 7,
+			levels:  map[string]int{"ERROR": 3, "INFO": 1, "WARNING": 3},
+			templates: []string{
+				"Uncaught exception while in charm code: ... KeyError: 'password'", "machine <n> is not provisioned", "cannot reach <ip>",
+			},
+			summary: `3 errors in 2 clusters, 3 warnings in 1 cluster, out of 7 log records. Top: ERROR "Uncaught exception while in charm code: ... ` +
+				`KeyError: 'password'" (2x) on mysql/0, mysql/1.`,
+		},
+		{
+			name:    "no problems",
+			output:  "unit-mysql-0: 2025-07-01 12:00:01.000 INFO juju.worker.uniter resolving hook\n",
+			top:     10,
+			records: 1,
+			levels:  map[string]int{"INFO": 1},
+			summary: "No warnings or errors in 1 log record.",
+		},
+		{
+			name:    "empty",
+			top:     10,
+			levels:  map[string]int{},
+			summary: "No warnings or errors in 0 log records.",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := summarizeLogs(parseLogLines(tt.output), tt.top)
+
+			assert.Equal(t, tt.records, got.Records)
+			assert.Equal(t, tt.levels, got.Levels)
+			assert.Equal(t, tt.omitted, got.Omitted)
+			var templates []string
+			for _, c := range got.Clusters {
+				templates = append(templates, c.Template)
+			}
+			assert.Equal(t, tt.templates, templates)
+			assert.Equal(t, tt.summary, got.Summary)
+		})
+	}
+}
+
+func TestSummarizeLogs_Cluster(t *testing.T) {
+	got := summarizeLogs(parseLogLines(summaryLogOutput), 2)
+
+	require.Len(t, got.Clusters, 2)
+	traceback := got.Clusters[0]
+	assert.Equal(t, "ERROR", traceback.Level)
+	assert.Equal(t, 2, traceback.Count)
+	assert.Equal(t, []string{"mysql/0", "mysql/1"}, traceback.Units)
+	assert.Equal(t, time.Date(2025, 7, 1, 12, 0, 0, 0, time.UTC), traceback.FirstSeen)
+	assert.Equal(t, time.Date(2025, 7, 1, 12, 5, 0, 0, time.UTC), traceback.LastSeen)
+	assert.Equal(t, "unit-mysql-0: 2025-07-01 12:00:00.000 ERROR unit.mysql/0.juju-log Uncaught exception while in charm code:\n...\nKeyError: 'password'", traceback.Sample)
+	assert.Equal(t, []string{"0"}, got.Clusters[1].Machines)
+}
+
+func TestLogSummary(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string { return summaryLogOutput })
+	factory.flags = []string{"include", "limit", "utc"}
+
+	// Act
+	result := callTool(t, a, LogSummaryToolName, map[string]any{"model": "prod", "entity": "mysql/0", "lines": 500, "top": 2})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	assert.Equal(t, map[string]string{"model": "prod", "include": "mysql/0", "limit": "500", "utc": "true"}, factory.calls[0].flags)
+	var got logSummary
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &got))
+	assert.Equal(t, "prod", got.Model)
+	assert.Equal(t, "mysql/0", got.Entity)
+	assert.Len(t, got.Clusters, 2)
+}
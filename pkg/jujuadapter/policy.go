@@ -0,0 +1,96 @@
+package jujuadapter
+
+import (
+	"context"
+	"errors"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+)
+
+type policyContextKey struct{}
+
+// WithPolicy returns a context in which the commands run by tools made of
+// several commands, such as deploy-and-wait or revert-change, go through the
+// policy as if each command had been called directly. The application sets
+// it to its audit, rate limit and approval policies.
+func WithPolicy(ctx context.Context, policy mcpserver.ToolHandlerMiddleware) context.Context {
+	return context.WithValue(ctx, policyContextKey{}, policy)
+}
+
+// runStep runs a command of a tool made of several commands. The command is
+// refused if it is disabled on its model, then goes through the policy of
+// the context, if any, under its command name.
+func (a *adapter) runStep(ctx context.Context, config CommandExecutionConfig) (string, error) {
+	var output string
+	var err error
+	ran := false
+	handler := func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+		if msg, blocked := a.checkBlocked(ctx, JujuCommandID(config.CommandName), stepModel(config)); blocked {
+			return mcp.NewToolResultError(msg), nil
+		}
+		ran = true
+		output, err = a.executeCommand(ctx, config)
+		if err != nil {
+			return mcp.NewToolResultError(err.Error()), nil
+		}
+		return mcp.NewToolResultText(output), nil
+	}
+	policy, ok := ctx.Value(policyContextKey{}).(mcpserver.ToolHandlerMiddleware)
+	if ok {
+		handler = policy(handler)
+	}
+
+	req := mcp.CallToolRequest{}
+	req.Params.Name = config.CommandName
+	req.Params.Arguments = stepArguments(config)
+	result, callErr := handler(ctx, req)
+	switch {
+	case ran:
+		return output, err
+	case callErr != nil:
+		return "", callErr
+	case result != nil && result.IsError:
+		return "", errors.New(toolResultText(result))
+	}
+	return "", errors.New("the command was not run")
+}
+
+// stepArguments are the tool arguments equivalent to a command, as seen by
+// the policies and the audit trail.
+func stepArguments(config CommandExecutionConfig) map[string]any {
+	arguments := make(map[string]any, len(config.FixedFlags)+len(config.FlagValues)+1)
+	if len(config.Arguments) > 0 {
+		args := make([]any, len(config.Arguments))
+		for i, arg := range config.Arguments {
+			args[i] = arg
+		}
+		arguments["args"] = args
+	}
+	for name, value := range config.FixedFlags {
+		arguments[name] = value
+	}
+	for name, value := range config.FlagValues {
+		arguments[name] = value
+	}
+	return arguments
+}
+
+// stepModel returns the model a command runs against, "" meaning the current
+// model.
+func stepModel(config CommandExecutionConfig) string {
+	if model := config.FixedFlags["model"]; model != "" {
+		return model
+	}
+	return targetModel(config.FlagValues)
+}
+
+// toolResultText returns the text of a tool result.
+func toolResultText(result *mcp.CallToolResult) string {
+	for _, content := range result.Content {
+		if text, ok := content.(mcp.TextContent); ok {
+			return text.Text
+		}
+	}
+	return ""
+}
@@ -0,0 +1,48 @@
+package jujuadapter
+
+import (
+	"context"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeployAndWaitPolicy(t *testing.T) {
+	// Arrange
+	fastPolling(t)
+	a, factory := newFakeAdapter(deployingStatus())
+	var seen []mcp.CallToolRequest
+	ctx := WithPolicy(context.Background(), func(next mcpserver.ToolHandlerFunc) mcpserver.ToolHandlerFunc {
+		return func(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+			seen = append(seen, req)
+			if req.Params.Name == string(CmdExpose) {
+				return mcp.NewToolResultError("expose was not run: approval request 42 was denied by bob"), nil
+			}
+			return next(ctx, req)
+		}
+	})
+	_, handler, err := a.GetTool(DeployAndWaitToolName)
+	require.NoError(t, err)
+	req := mcp.CallToolRequest{}
+	req.Params.Name = DeployAndWaitToolName
+	req.Params.Arguments = map[string]any{"charm": "mysql", "model": "prod", "num_units": float64(2), "expose": true}
+
+	// Act
+	result, err := handler(ctx, req)
+
+	// Assert
+	require.NoError(t, err)
+	report := decodeDeployReport(t, resultText(result))
+	assert.Equal(t, deployPartial, report.Outcome)
+	require.Len(t, seen, 2)
+	assert.Equal(t, string(CmdDeploy), seen[0].Params.Name)
+	assert.Equal(t, map[string]any{"args": []any{"mysql"}, "model": "prod", "channel": "", "constraints": "", "base": "", "n": 2},
+		seen[0].GetArguments())
+	assert.Equal(t, "expose was not run: approval request 42 was denied by bob", report.Steps[1].Error)
+	for _, call := range factory.calls {
+		assert.NotEqual(t, string(CmdExpose), call.name)
+	}
+}
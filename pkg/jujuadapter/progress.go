@@ -0,0 +1,48 @@
+package jujuadapter
+
+import (
+	"context"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	mcpserver "github.com/mark3labs/mcp-go/server"
+	"github.com/rs/zerolog/log"
+)
+
+// progressReporter sends progress notifications for a long running tool
+// call. Clients that did not ask for progress, by leaving out the progress
+// token, get none.
+type progressReporter struct {
+	ctx      context.Context
+	token    mcp.ProgressToken
+	progress float64
+	messages []string
+}
+
+func newProgressReporter(ctx context.Context, req mcp.CallToolRequest) *progressReporter {
+	p := &progressReporter{ctx: ctx}
+	if req.Params.Meta != nil {
+		p.token = req.Params.Meta.ProgressToken
+	}
+	return p
+}
+
+// report records a step and notifies the client about it.
+func (p *progressReporter) report(message string) {
+	p.progress++
+	p.messages = append(p.messages, message)
+	if p.token == nil {
+		return
+	}
+	server := mcpserver.ServerFromContext(p.ctx)
+	if server == nil {
+		return
+	}
+	err := server.SendNotificationToClient(p.ctx, "notifications/progress", map[string]any{
+		"progressToken": p.token,
+		"progress":      p.progress,
+		"message":       message,
+	})
+	if err != nil {
+		log.Debug().Err(err).Msg("Failed to send progress notification")
+	}
+}
@@ -2,43 +2,42 @@ package jujuadapter
 
 import (
 	"fmt"
-	"strconv"
-	"strings"
+
+	"github.com/yosida95/uritemplate/v3"
 )
 
-// parseURIParameters extracts parameters from a URI based on the resource template configuration
+// parseURIParameters extracts the variables of a URI, such as the
+// application of "juju://config/mysql", using the resource template
+// configuration. Variables mapped to arguments or flags that are absent from
+// the URI are left out.
 func parseURIParameters(uri string, config ResourceTemplateConfig) (map[string]string, error) {
-	params := make(map[string]string)
-
-	// Remove protocol and split into parts
-	uriPath := strings.TrimPrefix(uri, "juju://")
-	parts := strings.Split(uriPath, "/")
+	template, err := uritemplate.New(config.URITemplate)
+	if err != nil {
+		return nil, fmt.Errorf("invalid URI template '%s': %w", config.URITemplate, err)
+	}
+	values := template.Match(uri)
+	if values == nil {
+		return nil, fmt.Errorf("URI '%s' does not match template '%s'", uri, config.URITemplate)
+	}
 
-	// Use the resource template configuration to map URI parts to parameters
-	// The config tells us exactly which URI parts map to which parameters
-	for paramName, argIndexStr := range config.URIToArgs {
-		argIndex, err := strconv.Atoi(argIndexStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid argument index '%s' for parameter '%s'", argIndexStr, paramName)
+	params := make(map[string]string)
+	for _, name := range template.Varnames() {
+		value := values.Get(name)
+		if !value.Valid() {
+			continue
 		}
-		
-		// Adjust index to account for the base path (e.g., "config" is index 0)
-		uriIndex := argIndex + 1 // +1 because index 0 is "config"
-		
-		if uriIndex < len(parts) && parts[uriIndex] != "" {
-			params[paramName] = parts[uriIndex]
+		// Exploded variables such as {/config_name*} use their first value,
+		// as the commands take a single one.
+		param := value.String()
+		if value.T == uritemplate.ValueTypeList {
+			param = ""
+			if list := value.List(); len(list) > 0 {
+				param = list[0]
+			}
+		}
+		if param != "" {
+			params[name] = param
 		}
-		// If the parameter is not present, that's OK for optional parameters
-	}
-
-	// Also handle any URI-to-flag mappings
-	for paramName, flagName := range config.URIToFlags {
-		// For flags, we would extract from URI parts as well
-		// This is extensible for future templates that map URI parts to flags
-		// For now, this is just a placeholder for future functionality
-		_ = paramName
-		_ = flagName
 	}
-
 	return params, nil
-}
\ No newline at end of file
+}
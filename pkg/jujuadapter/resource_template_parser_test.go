@@ -0,0 +1,47 @@
+package jujuadapter
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseURIParameters(t *testing.T) {
+	configs := (&commandFactory{}).GetResourceTemplateConfigs()
+	tests := []struct {
+		template string
+		uri      string
+		expected map[string]string
+	}{
+		{
+			template: "juju-config-template",
+			uri:      "juju://config/mysql/max-connections?model=prod",
+			expected: map[string]string{"application": "mysql", "config_name": "max-connections", "model": "prod"},
+		},
+		{
+			template: "juju-status-template",
+			uri:      "juju://status/admin%2Fprod",
+			expected: map[string]string{"model": "admin/prod"},
+		},
+		{
+			template: "juju-debug-log-template",
+			uri:      "juju://debug-log/prod/mysql%2F0",
+			expected: map[string]string{"model": "prod", "entity": "mysql/0"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.uri, func(t *testing.T) {
+			params, err := parseURIParameters(tt.uri, configs[tt.template])
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, params)
+		})
+	}
+}
+
+func TestParseURIParameters_RejectsOtherURIs(t *testing.T) {
+	_, err := parseURIParameters("juju://model/prod", (&commandFactory{}).GetResourceTemplateConfigs()["juju-status-template"])
+
+	assert.ErrorContains(t, err, "does not match template")
+}
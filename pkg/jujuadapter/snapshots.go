@@ -0,0 +1,640 @@
+package jujuadapter
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/rs/zerolog/log"
+)
+
+const (
+	StatusSnapshotToolName = "status-snapshot"
+	StatusDiffToolName     = "status-diff"
+	statusDiffTemplateName = "status-diff"
+	statusDiffURI          = "juju://status-diff"
+
+	// maxStatusSnapshots is how many snapshots are kept per model.
+	maxStatusSnapshots = 500
+	// snapshotFileLayout names the snapshot files by the time they were
+	// taken, so that they sort in order.
+	snapshotFileLayout = "20060102T150405.000000000Z"
+)
+
+// snapshotTimeout bounds the status call of a periodic snapshot.
+var snapshotTimeout = time.Minute
+
+// statusSnapshot is the output of `juju status --format json` for a model at
+// a point in time.
+type statusSnapshot struct {
+	Model  string          `json:"model"`
+	Taken  time.Time       `json:"taken"`
+	Status json.RawMessage `json:"status"`
+}
+
+// snapshotStore keeps the status snapshots of every model, oldest first. With
+// a directory, they are also written there and survive a restart.
+type snapshotStore struct {
+	mu        sync.Mutex
+	dir       string
+	max       int
+	snapshots map[string][]statusSnapshot
+	now       func() time.Time
+}
+
+// newSnapshotStore creates a store, loading the snapshots found in dir. An
+// empty dir keeps the snapshots in memory only.
+func newSnapshotStore(dir string, max int) (*snapshotStore, error) {
+	s := &snapshotStore{
+		dir:       dir,
+		max:       max,
+		snapshots: make(map[string][]statusSnapshot),
+		now:       time.Now,
+	}
+	if dir == "" {
+		return s, nil
+	}
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create the status snapshots directory: %w", err)
+	}
+	models, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("unable to read the status snapshots directory: %w", err)
+	}
+	for _, entry := range models {
+		if !entry.IsDir() {
+			continue
+		}
+		files, err := filepath.Glob(filepath.Join(dir, entry.Name(), "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		for _, file := range files {
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return nil, fmt.Errorf("unable to read status snapshot: %w", err)
+			}
+			var snapshot statusSnapshot
+			if err := json.Unmarshal(data, &snapshot); err != nil {
+				log.Warn().Err(err).Str("file", file).Msg("Skipping an invalid status snapshot")
+				continue
+			}
+			s.snapshots[snapshot.Model] = append(s.snapshots[snapshot.Model], snapshot)
+		}
+	}
+	for model := range s.snapshots {
+		sort.Slice(s.snapshots[model], func(i, j int) bool {
+			return s.snapshots[model][i].Taken.Before(s.snapshots[model][j].Taken)
+		})
+		s.trimLocked(model)
+	}
+	return s, nil
+}
+
+func (s *snapshotStore) path(snapshot statusSnapshot) string {
+	return filepath.Join(s.dir, url.PathEscape(snapshot.Model), snapshot.Taken.UTC().Format(snapshotFileLayout)+".json")
+}
+
+// add stores a snapshot, dropping the oldest of its model over the limit.
+func (s *snapshotStore) add(snapshot statusSnapshot) error {
+	if s.dir != "" {
+		data, err := json.Marshal(snapshot)
+		if err != nil {
+			return err
+		}
+		file := s.path(snapshot)
+		if err := os.MkdirAll(filepath.Dir(file), 0o700); err != nil {
+			return fmt.Errorf("unable to store status snapshot: %w", err)
+		}
+		if err := os.WriteFile(file, data, 0o600); err != nil {
+			return fmt.Errorf("unable to store status snapshot: %w", err)
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshots := append(s.snapshots[snapshot.Model], snapshot)
+	sort.SliceStable(snapshots, func(i, j int) bool { return snapshots[i].Taken.Before(snapshots[j].Taken) })
+	s.snapshots[snapshot.Model] = snapshots
+	s.trimLocked(snapshot.Model)
+	return nil
+}
+
+func (s *snapshotStore) trimLocked(model string) {
+	snapshots := s.snapshots[model]
+	if len(snapshots) <= s.max {
+		return
+	}
+	drop := snapshots[:len(snapshots)-s.max]
+	s.snapshots[model] = slices.Clone(snapshots[len(snapshots)-s.max:])
+	if s.dir == "" {
+		return
+	}
+	for _, snapshot := range drop {
+		if err := os.Remove(s.path(snapshot)); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Warn().Err(err).Str("model", model).Msg("Failed to delete an old status snapshot")
+		}
+	}
+}
+
+// before returns the latest snapshot of a model taken before t, or at t when
+// inclusive is set.
+func (s *snapshotStore) before(model string, t time.Time, inclusive bool) (statusSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	snapshots := s.snapshots[model]
+	for i := len(snapshots) - 1; i >= 0; i-- {
+		if snapshots[i].Taken.Before(t) || (inclusive && snapshots[i].Taken.Equal(t)) {
+			return snapshots[i], true
+		}
+	}
+	return statusSnapshot{}, false
+}
+
+func (s *snapshotStore) oldest(model string) (statusSnapshot, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if snapshots := s.snapshots[model]; len(snapshots) > 0 {
+		return snapshots[0], true
+	}
+	return statusSnapshot{}, false
+}
+
+func (s *snapshotStore) count(model string) int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.snapshots[model])
+}
+
+// models returns the models with snapshots.
+func (s *snapshotStore) models() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return sortedKeys(s.snapshots)
+}
+
+// takeSnapshot stores the current status of a model.
+func (a *adapter) takeSnapshot(ctx context.Context, model string) (statusSnapshot, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		FixedFlags:  withModel(map[string]string{"format": "json", "utc": "true"}, model),
+	})
+	if err != nil {
+		return statusSnapshot{}, err
+	}
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, []byte(output)); err != nil {
+		return statusSnapshot{}, fmt.Errorf("failed to parse status: %w", err)
+	}
+	snapshot := statusSnapshot{Model: model, Taken: a.snapshots.now().UTC(), Status: compact.Bytes()}
+	if err := a.snapshots.add(snapshot); err != nil {
+		return statusSnapshot{}, err
+	}
+	return snapshot, nil
+}
+
+// Start starts the periodic status snapshots, if an interval is set. They
+// stop once ctx is done.
+func (a *adapter) Start(ctx context.Context) {
+	if a.snapshotInterval > 0 {
+		go a.snapshotPeriodically(ctx, a.snapshotInterval)
+	}
+}
+
+// snapshotPeriodically snapshots every model with snapshots, that is those
+// snapshotted or diffed once, at each interval until ctx is done.
+func (a *adapter) snapshotPeriodically(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		for _, model := range a.snapshots.models() {
+			if err := a.snapshotWithTimeout(ctx, model); err != nil {
+				log.Warn().Err(err).Str("model", model).Msg("Failed to take a status snapshot")
+			}
+		}
+	}
+}
+
+// snapshotWithTimeout takes a periodic snapshot, giving up on a model whose
+// controller does not answer within snapshotTimeout.
+func (a *adapter) snapshotWithTimeout(ctx context.Context, model string) error {
+	ctx, cancel := context.WithTimeout(ctx, snapshotTimeout)
+	defer cancel()
+	_, err := a.takeSnapshot(ctx, model)
+	return err
+}
+
+// entityChanges lists the entities that appeared and disappeared.
+type entityChanges struct {
+	Added   []string `json:"added,omitempty"`
+	Removed []string `json:"removed,omitempty"`
+}
+
+// statusChange is a status transition of an entity. Kind is application,
+// workload or agent for units, or machine.
+type statusChange struct {
+	Entity  string `json:"entity"`
+	Kind    string `json:"kind"`
+	From    string `json:"from"`
+	To      string `json:"to"`
+	Message string `json:"message,omitempty"`
+}
+
+type charmChange struct {
+	Application  string `json:"application"`
+	FromCharm    string `json:"from_charm,omitempty"`
+	ToCharm      string `json:"to_charm,omitempty"`
+	FromRevision int    `json:"from_revision"`
+	ToRevision   int    `json:"to_revision"`
+	FromChannel  string `json:"from_channel,omitempty"`
+	ToChannel    string `json:"to_channel,omitempty"`
+}
+
+type scaleChange struct {
+	Application string `json:"application"`
+	From        int    `json:"from"`
+	To          int    `json:"to"`
+}
+
+// statusDiff is what changed in a model between two snapshots.
+type statusDiff struct {
+	Model         string         `json:"model"`
+	From          time.Time      `json:"from"`
+	To            time.Time      `json:"to"`
+	Note          string         `json:"note,omitempty"`
+	Summary       string         `json:"summary"`
+	Applications  entityChanges  `json:"applications,omitzero"`
+	Units         entityChanges  `json:"units,omitzero"`
+	Machines      entityChanges  `json:"machines,omitzero"`
+	Relations     entityChanges  `json:"relations,omitzero"`
+	StatusChanges []statusChange `json:"status_changes,omitempty"`
+	CharmChanges  []charmChange  `json:"charm_changes,omitempty"`
+	ScaleChanges  []scaleChange  `json:"scale_changes,omitempty"`
+}
+
+// diffStatus compares two statuses of a model.
+func diffStatus(from, to modelStatus) statusDiff {
+	var diff statusDiff
+	diff.Applications = diffKeys(from.Applications, to.Applications)
+	for _, name := range sortedKeys(to.Applications) {
+		before, ok := from.Applications[name]
+		if !ok {
+			continue
+		}
+		after := to.Applications[name]
+		diff.StatusChanges = appendStatusChange(diff.StatusChanges, name, "application", before.Status, after.Status)
+		if before.Charm != after.Charm || before.CharmRev != after.CharmRev || before.CharmChannel != after.CharmChannel {
+			change := charmChange{
+				Application:  name,
+				FromRevision: before.CharmRev,
+				ToRevision:   after.CharmRev,
+				FromChannel:  before.CharmChannel,
+				ToChannel:    after.CharmChannel,
+			}
+			if before.Charm != after.Charm {
+				change.FromCharm, change.ToCharm = before.Charm, after.Charm
+			}
+			diff.CharmChanges = append(diff.CharmChanges, change)
+		}
+		if b, a := applicationScale(before), applicationScale(after); b != a {
+			diff.ScaleChanges = append(diff.ScaleChanges, scaleChange{Application: name, From: b, To: a})
+		}
+	}
+
+	fromUnits, toUnits := flattenUnits(from), flattenUnits(to)
+	diff.Units = diffKeys(fromUnits, toUnits)
+	for _, name := range sortedKeys(toUnits) {
+		if before, ok := fromUnits[name]; ok {
+			after := toUnits[name]
+			diff.StatusChanges = appendStatusChange(diff.StatusChanges, name, "workload", before.WorkloadStatus, after.WorkloadStatus)
+			diff.StatusChanges = appendStatusChange(diff.StatusChanges, name, "agent", before.JujuStatus, after.JujuStatus)
+		}
+	}
+
+	fromMachines, toMachines := flattenMachines(from.Machines), flattenMachines(to.Machines)
+	diff.Machines = diffKeys(fromMachines, toMachines)
+	for _, id := range sortedKeys(toMachines) {
+		if before, ok := fromMachines[id]; ok {
+			diff.StatusChanges = appendStatusChange(diff.StatusChanges, id, "machine", before.JujuStatus, toMachines[id].JujuStatus)
+		}
+	}
+
+	diff.Relations = diffKeys(relationSet(from), relationSet(to))
+	return diff
+}
+
+func diffKeys[V any](from, to map[string]V) entityChanges {
+	var changes entityChanges
+	for _, key := range sortedKeys(to) {
+		if _, ok := from[key]; !ok {
+			changes.Added = append(changes.Added, key)
+		}
+	}
+	for _, key := range sortedKeys(from) {
+		if _, ok := to[key]; !ok {
+			changes.Removed = append(changes.Removed, key)
+		}
+	}
+	return changes
+}
+
+func appendStatusChange(changes []statusChange, entity, kind string, before, after statusInfo) []statusChange {
+	if before.Current == after.Current {
+		return changes
+	}
+	return append(changes, statusChange{Entity: entity, Kind: kind, From: before.Current, To: after.Current, Message: after.Message})
+}
+
+// applicationScale is the number of units of an application, or its scale
+// on Kubernetes.
+func applicationScale(app modelStatusApplication) int {
+	if app.Scale > 0 {
+		return app.Scale
+	}
+	return len(app.Units)
+}
+
+// flattenUnits returns the units of a model, subordinates included.
+func flattenUnits(status modelStatus) map[string]modelStatusUnit {
+	units := make(map[string]modelStatusUnit)
+	var add func(map[string]modelStatusUnit)
+	add = func(m map[string]modelStatusUnit) {
+		for name, unit := range m {
+			units[name] = unit
+			add(unit.Subordinates)
+		}
+	}
+	for _, app := range status.Applications {
+		add(app.Units)
+	}
+	return units
+}
+
+// flattenMachines returns the machines of a model, containers included.
+func flattenMachines(machines map[string]modelStatusMachine) map[string]modelStatusMachine {
+	all := make(map[string]modelStatusMachine)
+	for id, machine := range machines {
+		all[id] = machine
+		for cid, container := range flattenMachines(machine.Containers) {
+			all[cid] = container
+		}
+	}
+	return all
+}
+
+// relationPair is a relation between two endpoints, such as mysql:database
+// and wordpress:db, in name order. A peer relation has the same endpoint on
+// both sides.
+type relationPair struct {
+	Ends      [2]string
+	Interface string
+}
+
+func (r relationPair) peer() bool {
+	return r.Ends[0] == r.Ends[1]
+}
+
+// relationPairs returns the relations of a model from the relations of its
+// applications. Both sides of a relation list it, and their endpoints are
+// paired by interface. A side that is not in the model, such as an offer, is
+// given by its application only.
+func relationPairs(status modelStatus) []relationPair {
+	type end struct {
+		application, endpoint, related, iface string
+	}
+	var ends []end
+	for _, app := range sortedKeys(status.Applications) {
+		relations := status.Applications[app].Relations
+		for _, endpoint := range sortedKeys(relations) {
+			for _, r := range relations[endpoint] {
+				ends = append(ends, end{app, endpoint, r.RelatedApplication, r.Interface})
+			}
+		}
+	}
+	seen := make(map[[2]string]bool)
+	var pairs []relationPair
+	for _, e := range ends {
+		other := e.related
+		if e.related == e.application {
+			other = e.application + ":" + e.endpoint
+		}
+		for _, o := range ends {
+			if e.related != e.application && o.application == e.related && o.related == e.application && o.iface == e.iface {
+				other = o.application + ":" + o.endpoint
+				break
+			}
+		}
+		pair := relationPair{Ends: [2]string{e.application + ":" + e.endpoint, other}, Interface: e.iface}
+		if pair.Ends[1] < pair.Ends[0] {
+			pair.Ends[0], pair.Ends[1] = pair.Ends[1], pair.Ends[0]
+		}
+		if !seen[pair.Ends] {
+			seen[pair.Ends] = true
+			pairs = append(pairs, pair)
+		}
+	}
+	return pairs
+}
+
+// relationSet returns the relations of a model, such as
+// "mysql:database wordpress:db", or "mysql:database-peers" for a peer
+// relation.
+func relationSet(status modelStatus) map[string]bool {
+	set := make(map[string]bool)
+	for _, pair := range relationPairs(status) {
+		if pair.peer() {
+			set[pair.Ends[0]] = true
+		} else {
+			set[pair.Ends[0]+" "+pair.Ends[1]] = true
+		}
+	}
+	return set
+}
+
+func describeStatusDiff(diff statusDiff) string {
+	var parts []string
+	count := func(c entityChanges, kind string) {
+		if n := len(c.Added); n > 0 {
+			parts = append(parts, plural(n, kind)+" added")
+		}
+		if n := len(c.Removed); n > 0 {
+			parts = append(parts, plural(n, kind)+" removed")
+		}
+	}
+	count(diff.Applications, "application")
+	count(diff.Units, "unit")
+	count(diff.Machines, "machine")
+	count(diff.Relations, "relation")
+	if n := len(diff.StatusChanges); n > 0 {
+		parts = append(parts, plural(n, "status change"))
+	}
+	if n := len(diff.CharmChanges); n > 0 {
+		parts = append(parts, plural(n, "charm change"))
+	}
+	if n := len(diff.ScaleChanges); n > 0 {
+		parts = append(parts, plural(n, "scaling change"))
+	}
+	since := diff.From.Format(time.RFC3339)
+	if len(parts) == 0 {
+		return fmt.Sprintf("No changes in model %s since %s.", diff.Model, since)
+	}
+	return fmt.Sprintf("Since %s in model %s: %s.", since, diff.Model, strings.Join(parts, ", "))
+}
+
+// registerStatusDiff adds the status-snapshot and status-diff tools and the
+// juju://status-diff resource template.
+func (a *adapter) registerStatusDiff() {
+	a.builtins.addTool(mcp.NewTool(StatusSnapshotToolName,
+		mcp.WithDescription("Store a snapshot of the status of a model, to compare later with status-diff. "+
+			"Models with a snapshot are also snapshotted periodically when the server is configured to."),
+		mcp.WithString("model",
+			mcp.Required(),
+			mcp.Description("Model to snapshot"),
+		),
+	), a.handleStatusSnapshot)
+
+	a.builtins.addTool(mcp.NewTool(StatusDiffToolName,
+		mcp.WithDescription("Report what changed in a model between two status snapshots: added and removed applications, "+
+			"units, machines and relations, status transitions, charm revision and channel changes and scaling. "+
+			"By default the current status is compared with the previous snapshot."),
+		mcp.WithString("model",
+			mcp.Required(),
+			mcp.Description("Model to compare"),
+		),
+		mcp.WithString("since",
+			mcp.Description("Compare with the last snapshot taken at or before this time, as an RFC 3339 time or a duration "+
+				"before now such as 8h, instead of the previous snapshot"),
+		),
+		mcp.WithString("until",
+			mcp.Description("Compare up to the last snapshot taken at or before this time, instead of the current status"),
+		),
+	), a.handleStatusDiff)
+
+	a.builtins.addTemplate(statusDiffTemplateName, mcp.NewResourceTemplate(
+		statusDiffURI+"/{model}{?since}",
+		"Juju Status Diff",
+		mcp.WithTemplateDescription("What changed in a model since a time, as an RFC 3339 time or a duration such as 8h, "+
+			"or since the previous status snapshot, compared with its current status"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), a.handleStatusDiffResource)
+}
+
+func (a *adapter) handleStatusSnapshot(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	model, err := req.RequireString("model")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	snapshot, err := a.takeSnapshot(ctx, model)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	oldest, _ := a.snapshots.oldest(model)
+	return jsonToolResult(map[string]any{
+		"model":     model,
+		"taken":     snapshot.Taken,
+		"snapshots": a.snapshots.count(model),
+		"oldest":    oldest.Taken,
+	})
+}
+
+func (a *adapter) handleStatusDiff(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	model, err := req.RequireString("model")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	diff, err := a.statusDiff(ctx, model, req.GetString("since", ""), req.GetString("until", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return jsonToolResult(diff)
+}
+
+func (a *adapter) handleStatusDiffResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	u, err := url.Parse(req.Params.URI)
+	if err != nil {
+		return nil, fmt.Errorf("invalid status diff URI %q: %w", req.Params.URI, err)
+	}
+	model, err := url.PathUnescape(strings.TrimPrefix(u.Path, "/"))
+	if err != nil || model == "" {
+		return nil, fmt.Errorf("invalid status diff URI %q: a model is required", req.Params.URI)
+	}
+	diff, err := a.statusDiff(ctx, model, u.Query().Get("since"), "")
+	if err != nil {
+		return nil, err
+	}
+	return jsonResourceContents(req.Params.URI, diff)
+}
+
+// statusDiff compares the snapshot of a model at since, or the previous one,
+// with the snapshot at until, or a new one.
+func (a *adapter) statusDiff(ctx context.Context, model, since, until string) (*statusDiff, error) {
+	now := a.snapshots.now()
+	var to statusSnapshot
+	if until != "" {
+		t, err := parseRelativeTime(until, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid until: %w", err)
+		}
+		var ok bool
+		if to, ok = a.snapshots.before(model, t, true); !ok {
+			return nil, fmt.Errorf("no snapshot of model %s was taken before %s", model, t.Format(time.RFC3339))
+		}
+	} else {
+		var err error
+		if to, err = a.takeSnapshot(ctx, model); err != nil {
+			return nil, err
+		}
+	}
+
+	var note string
+	from, ok := a.snapshots.before(model, to.Taken, false)
+	if since != "" {
+		t, err := parseRelativeTime(since, now)
+		if err != nil {
+			return nil, fmt.Errorf("invalid since: %w", err)
+		}
+		if from, ok = a.snapshots.before(model, t, true); !ok {
+			if from, ok = a.snapshots.oldest(model); ok {
+				note = fmt.Sprintf("No snapshot was taken before %s, compared with the oldest one.", t.Format(time.RFC3339))
+			}
+		}
+	}
+	if !ok || !from.Taken.Before(to.Taken) {
+		if until != "" {
+			return nil, fmt.Errorf("no snapshot of model %s was taken before %s", model, to.Taken.Format(time.RFC3339))
+		}
+		return nil, fmt.Errorf("no earlier snapshot of model %s to compare with. A snapshot was taken now: call status-diff "+
+			"again later, or take snapshots with status-snapshot", model)
+	}
+
+	var before, after modelStatus
+	if err := json.Unmarshal(from.Status, &before); err != nil {
+		return nil, fmt.Errorf("failed to parse the snapshot of %s: %w", from.Taken.Format(time.RFC3339), err)
+	}
+	if err := json.Unmarshal(to.Status, &after); err != nil {
+		return nil, fmt.Errorf("failed to parse the snapshot of %s: %w", to.Taken.Format(time.RFC3339), err)
+	}
+	diff := diffStatus(before, after)
+	diff.Model = model
+	diff.From = from.Taken
+	diff.To = to.Taken
+	diff.Note = note
+	diff.Summary = describeStatusDiff(diff)
+	return &diff, nil
+}
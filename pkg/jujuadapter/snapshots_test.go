@@ -0,0 +1,156 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const morningStatus = `{
+	"model": {"name": "prod"},
+	"machines": {"0": {"juju-status": {"current": "started"}}, "1": {"juju-status": {"current": "started"},
+		"containers": {"1/lxd/0": {"juju-status": {"current": "started"}}}}},
+	"applications": {
+		"mysql": {"charm": "mysql", "charm-rev": 151, "charm-channel": "8.0/stable", "application-status": {"current": "active"},
+			"units": {"mysql/0": {"workload-status": {"current": "active"}, "juju-status": {"current": "idle"}, "machine": "0"}},
+			"relations": {"database": [{"related-application": "wordpress", "interface": "mysql"}],
+				"database-peers": [{"related-application": "mysql", "interface": "mysql_peers"}]}},
+		"wordpress": {"charm": "wordpress", "charm-rev": 20, "application-status": {"current": "active"},
+			"units": {"wordpress/0": {"workload-status": {"current": "active"}, "juju-status": {"current": "idle"},
+				"subordinates": {"telegraf/0": {"workload-status": {"current": "active"}, "juju-status": {"current": "idle"}}}}},
+			"relations": {"db": [{"related-application": "mysql", "interface": "mysql"}]}},
+		"telegraf": {"charm": "telegraf", "charm-rev": 75, "application-status": {"current": "active"}}
+	}
+}`
+
+const eveningStatus = `{
+	"model": {"name": "prod"},
+	"machines": {"0": {"juju-status": {"current": "down"}}, "2": {"juju-status": {"current": "pending"}}},
+	"applications": {
+		"mysql": {"charm": "mysql", "charm-rev": 160, "charm-channel": "8.0/candidate", "application-status": {"current": "blocked", "message": "waiting for peers"},
+			"units": {"mysql/0": {"workload-status": {"current": "blocked", "message": "waiting for peers"}, "juju-status": {"current": "idle"}, "machine": "0"},
+				"mysql/1": {"workload-status": {"current": "waiting"}, "juju-status": {"current": "allocating"}, "machine": "2"}},
+			"relations": {"database-peers": [{"related-application": "mysql", "interface": "mysql_peers"}],
+				"cos-agent": [{"related-application": "grafana-agent", "interface": "cos_agent"}]}},
+		"grafana-agent": {"charm": "grafana-agent", "charm-rev": 100, "application-status": {"current": "active"},
+			"relations": {"cos-agent": [{"related-application": "mysql", "interface": "cos_agent"}]}}
+	}
+}`
+
+func TestDiffStatus(t *testing.T) {
+	var before, after modelStatus
+	require.NoError(t, json.Unmarshal([]byte(morningStatus), &before))
+	require.NoError(t, json.Unmarshal([]byte(eveningStatus), &after))
+
+	diff := diffStatus(before, after)
+
+	assert.Equal(t, entityChanges{Added: []string{"grafana-agent"}, Removed: []string{"telegraf", "wordpress"}}, diff.Applications)
+	assert.Equal(t, entityChanges{Added: []string{"mysql/1"}, Removed: []string{"telegraf/0", "wordpress/0"}}, diff.Units)
+	assert.Equal(t, entityChanges{Added: []string{"2"}, Removed: []string{"1", "1/lxd/0"}}, diff.Machines)
+	assert.Equal(t, entityChanges{
+		Added:   []string{"grafana-agent:cos-agent mysql:cos-agent"},
+		Removed: []string{"mysql:database wordpress:db"},
+	}, diff.Relations)
+	assert.Equal(t, []statusChange{
+		{Entity: "mysql", Kind: "application", From: "active", To: "blocked", Message: "waiting for peers"},
+		{Entity: "mysql/0", Kind: "workload", From: "active", To: "blocked", Message: "waiting for peers"},
+		{Entity: "0", Kind: "machine", From: "started", To: "down"},
+	}, diff.StatusChanges)
+	assert.Equal(t, []charmChange{{Application: "mysql", FromRevision: 151, ToRevision: 160, FromChannel: "8.0/stable", ToChannel: "8.0/candidate"}}, diff.CharmChanges)
+	assert.Equal(t, []scaleChange{{Application: "mysql", From: 1, To: 2}}, diff.ScaleChanges)
+}
+
+func TestStatusDiff(t *testing.T) {
+	// Arrange
+	statuses := []string{morningStatus, eveningStatus, eveningStatus}
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		status := statuses[0]
+		statuses = statuses[1:]
+		return status
+	})
+	factory.flags = []string{"utc"}
+	now := time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC)
+	a.snapshots.now = func() time.Time { return now }
+
+	// Act
+	first := callTool(t, a, StatusDiffToolName, map[string]any{"model": "prod"})
+	now = now.Add(10 * time.Hour)
+	diff := callTool(t, a, StatusDiffToolName, map[string]any{"model": "prod", "since": "1h"})
+	now = now.Add(time.Hour)
+	unchanged := callTool(t, a, StatusDiffToolName, map[string]any{"model": "prod"})
+
+	// Assert
+	assert.True(t, first.IsError)
+	assert.Contains(t, resultText(first), "no earlier snapshot of model prod to compare with")
+	assert.Equal(t, map[string]string{"format": "json", "model": "prod", "utc": "true"}, factory.calls[0].flags)
+
+	require.False(t, diff.IsError, resultText(diff))
+	var got statusDiff
+	require.NoError(t, json.Unmarshal([]byte(resultText(diff)), &got))
+	assert.Equal(t, time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC), got.From)
+	assert.Equal(t, time.Date(2025, 7, 1, 18, 0, 0, 0, time.UTC), got.To)
+	assert.Empty(t, got.Note)
+	assert.Equal(t, "Since 2025-07-01T08:00:00Z in model prod: 1 application added, 2 applications removed, 1 unit added, "+
+		"2 units removed, 1 machine added, 2 machines removed, 1 relation added, 1 relation removed, 3 status changes, "+
+		"1 charm change, 1 scaling change.", got.Summary)
+
+	require.False(t, unchanged.IsError, resultText(unchanged))
+	require.NoError(t, json.Unmarshal([]byte(resultText(unchanged)), &got))
+	assert.Equal(t, "No changes in model prod since 2025-07-01T18:00:00Z.", got.Summary)
+	assert.Equal(t, 3, a.snapshots.count("prod"))
+}
+
+func TestSnapshotStore_PersistsAndTrims(t *testing.T) {
+	dir := t.TempDir()
+	store, err := newSnapshotStore(dir, 2)
+	require.NoError(t, err)
+	taken := time.Date(2025, 7, 1, 8, 0, 0, 0, time.UTC)
+	for i := range 3 {
+		require.NoError(t, store.add(statusSnapshot{Model: "admin/prod", Taken: taken.Add(time.Duration(i) * time.Hour), Status: json.RawMessage(`{}`)}))
+	}
+
+	reloaded, err := newSnapshotStore(dir, 2)
+	require.NoError(t, err)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*", "*.json"))
+	require.NoError(t, err)
+	assert.Len(t, files, 2)
+	assert.Equal(t, []string{"admin/prod"}, reloaded.models())
+	oldest, ok := reloaded.oldest("admin/prod")
+	require.True(t, ok)
+	assert.Equal(t, taken.Add(time.Hour), oldest.Taken)
+	_, err = os.Stat(filepath.Join(dir, "admin%2Fprod", "20250701T080000.000000000Z.json"))
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestStatusSnapshots_StartAndStop(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string { return morningStatus })
+	factory.flags = []string{"utc"}
+	a.snapshotInterval = 10 * time.Millisecond
+	_, err := a.takeSnapshot(context.Background(), "prod")
+	require.NoError(t, err)
+	calls := func() int {
+		factory.mu.Lock()
+		defer factory.mu.Unlock()
+		return len(factory.calls)
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+
+	// Act
+	a.Start(ctx)
+	require.Eventually(t, func() bool { return calls() >= 3 }, time.Second, 5*time.Millisecond)
+	cancel()
+	time.Sleep(20 * time.Millisecond)
+	stopped := calls()
+	time.Sleep(50 * time.Millisecond)
+
+	// Assert
+	assert.Equal(t, stopped, calls(), "no snapshot is taken once the context is done")
+}
@@ -0,0 +1,175 @@
+package jujuadapter
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"slices"
+	"strings"
+
+	"github.com/jneo8/mcp-juju/config"
+)
+
+// toolGroups names the command categories of command_defs.go so a whole
+// category can be enabled at once.
+var toolGroups = map[string][]JujuCommandID{
+	"reporting":              {CmdVersion, CmdStatus, CmdStatusHistory, CmdSwitch},
+	"creation":               {CmdBootstrap, CmdAddRelation},
+	"cross-model-relations":  {CmdOffer, CmdRemoveOffer, CmdShowOfferedEndpoint, CmdListEndpoints, CmdFindEndpoints, CmdConsume, CmdSuspendRelation, CmdResumeRelation},
+	"firewall":               {CmdSetFirewallRule, CmdListFirewallRules},
+	"destruction":            {CmdRemoveRelation, CmdRemoveApplication, CmdRemoveUnit, CmdRemoveSaas},
+	"debugging":              {CmdExec, CmdScp, CmdSsh, CmdResolved, CmdDebugLog, CmdDebugHooks, CmdDebugCode},
+	"configuration":          {CmdGetConstraints, CmdSetConstraints, CmdSyncAgentBinary, CmdUpgradeModel, CmdUpgradeController, CmdRefresh, CmdBind},
+	"charm-tools":            {CmdHelpHooks, CmdHelpActions},
+	"backups":                {CmdCreateBackup, CmdDownloadBackup},
+	"ssh-keys":               {CmdAddSshKey, CmdRemoveSshKey, CmdImportSshKey, CmdSshKeys},
+	"users":                  {CmdAddUser, CmdChangePassword, CmdShowUser, CmdUsers, CmdEnableUser, CmdDisableUser, CmdLogin, CmdLogout, CmdRemoveUser, CmdWhoami},
+	"machines":               {CmdAddMachine, CmdRemoveMachine, CmdMachines, CmdShowMachine, CmdUpgradeMachine},
+	"models":                 {CmdModelConfig, CmdModelDefaults, CmdRetryProvisioning, CmdDestroyModel, CmdGrant, CmdRevoke, CmdShowModel, CmdModelCredential, CmdMigrate, CmdExportBundle},
+	"actions":                {CmdActions, CmdShowAction, CmdCancelAction, CmdRun, CmdOperations, CmdShowOperation, CmdShowTask},
+	"high-availability":      {CmdEnableHa},
+	"applications":           {CmdAddUnit, CmdConfig, CmdDeploy, CmdExpose, CmdUnexpose, CmdDiffBundle, CmdShowApplication, CmdShowUnit},
+	"protection":             {CmdDisableCommand, CmdDisabledCommands, CmdEnableCommand},
+	"storage":                {CmdAddStorage, CmdStorage, CmdCreateStoragePool, CmdStoragePools, CmdRemoveStoragePool, CmdUpdateStoragePool, CmdShowStorage, CmdRemoveStorage, CmdDetachStorage, CmdAttachStorage, CmdImportFilesystem},
+	"spaces":                 {CmdAddSpace, CmdSpaces, CmdMoveToSpace, CmdReloadSpaces, CmdShowSpace, CmdRemoveSpace, CmdRenameSpace},
+	"subnets":                {CmdSubnets},
+	"controllers":            {CmdAddModel, CmdDestroyController, CmdModels, CmdKillController, CmdControllers, CmdRegister, CmdUnregister, CmdEnableDestroyController, CmdShowController, CmdControllerConfig},
+	"clouds":                 {CmdUpdateCloud, CmdUpdatePublicClouds, CmdClouds, CmdRegions, CmdShowCloud, CmdAddCloud, CmdRemoveCloud, CmdCredentials, CmdDetectCredentials, CmdSetDefaultRegion, CmdSetDefaultCredential, CmdAddCredential, CmdRemoveCredential, CmdUpdateCredential, CmdShowCredential, CmdGrantCloud, CmdRevokeCloud},
+	"caas":                   {CmdAddK8s, CmdUpdateK8s, CmdRemoveK8s, CmdScaleApplication},
+	"application-credential": {CmdTrust},
+	"dashboard":              {CmdDashboard},
+	"resources":              {CmdAttachResource, CmdResources, CmdCharmResources},
+	"charmhub":               {CmdInfo, CmdFind, CmdDownload},
+	"secrets":                {CmdSecrets, CmdShowSecret, CmdAddSecret, CmdUpdateSecret, CmdRemoveSecret, CmdGrantSecret, CmdRevokeSecret},
+	"secret-backends":        {CmdSecretBackends, CmdAddSecretBackend, CmdUpdateSecretBackend, CmdRemoveSecretBackend, CmdShowSecretBackend},
+	"payloads":               {CmdWaitFor},
+}
+
+// ToolGroupNames returns the names accepted by --tool-groups.
+func ToolGroupNames() []string {
+	return sortedKeys(toolGroups)
+}
+
+// ToolSelection describes which tools to register: the tools named or
+// matched by a glob pattern in Names, plus the members of Groups, minus
+// those matched by Exclude. Without names or groups every tool is selected.
+type ToolSelection struct {
+	Names   []string
+	Groups  []string
+	Exclude []string
+}
+
+// NewToolSelection returns the tools the configuration asks for.
+func NewToolSelection(c config.Config) ToolSelection {
+	return ToolSelection{Names: c.ToolNames, Groups: c.ToolGroups, Exclude: c.ExcludeTools}
+}
+
+// ValidateToolSelection checks that every name, group and pattern of the
+// selection refers to an existing tool, so mistakes are reported when the
+// configuration is loaded rather than when the tools are registered.
+func ValidateToolSelection(selection ToolSelection) error {
+	a := &adapter{}
+	a.registerBuiltins()
+	_, err := selectTools(selection, a.availableToolNames())
+	return err
+}
+
+// availableToolNames returns every Juju command followed by the built-in
+// tools.
+func (a *adapter) availableToolNames() []string {
+	ids := GetAllCommandIDs()
+	names := make([]string, len(ids))
+	for i, id := range ids {
+		names[i] = string(id)
+	}
+	// A built-in tool named after a command, such as ssh, replaces it.
+	for _, name := range a.builtins.toolNames() {
+		if !slices.Contains(names, name) {
+			names = append(names, name)
+		}
+	}
+	return names
+}
+
+// selectTools resolves the selection against the available tools, keeping
+// their order. The errors name the offending setting, as in "tool-names[1]".
+func selectTools(selection ToolSelection, available []string) ([]string, error) {
+	var errs config.ValidationErrors
+	selected := make(map[string]bool)
+	addError := func(field string, i int, err error) {
+		errs = append(errs, &config.FieldError{Field: fmt.Sprintf("%s[%d]", field, i), Err: err})
+	}
+
+	for i, name := range selection.Names {
+		matched, err := matchTools(name, available)
+		if err != nil {
+			addError("tool-names", i, err)
+		}
+		for _, tool := range matched {
+			selected[tool] = true
+		}
+	}
+	for i, group := range selection.Groups {
+		members, ok := toolGroups[group]
+		if !ok {
+			addError("tool-groups", i, fmt.Errorf("unknown tool group %q, available groups: %s", group, strings.Join(ToolGroupNames(), ", ")))
+			continue
+		}
+		for _, id := range members {
+			selected[string(id)] = true
+		}
+	}
+	if len(selection.Names) == 0 && len(selection.Groups) == 0 {
+		for _, tool := range available {
+			selected[tool] = true
+		}
+	}
+	for i, pattern := range selection.Exclude {
+		matched, err := matchTools(pattern, available)
+		if err != nil {
+			addError("exclude-tools", i, err)
+		}
+		for _, tool := range matched {
+			delete(selected, tool)
+		}
+	}
+	if len(errs) > 0 {
+		return nil, errs
+	}
+
+	names := make([]string, 0, len(selected))
+	for _, tool := range available {
+		if selected[tool] {
+			names = append(names, tool)
+		}
+	}
+	if len(names) == 0 {
+		return nil, config.ValidationErrors{{Field: "exclude-tools", Err: errors.New("the tool selection leaves no tools to register")}}
+	}
+	return names, nil
+}
+
+// matchTools returns the available tools matching a tool name or glob
+// pattern such as "show-*". Names and patterns that match nothing are
+// rejected, as they are almost always typos.
+func matchTools(pattern string, available []string) ([]string, error) {
+	if !strings.ContainsAny(pattern, `*?[\`) {
+		if !slices.Contains(available, pattern) {
+			return nil, fmt.Errorf("unknown tool %q", pattern)
+		}
+		return []string{pattern}, nil
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, fmt.Errorf("invalid tool pattern %q: %w", pattern, err)
+	}
+	var matched []string
+	for _, tool := range available {
+		if ok, _ := path.Match(pattern, tool); ok {
+			matched = append(matched, tool)
+		}
+	}
+	if len(matched) == 0 {
+		return nil, fmt.Errorf("tool pattern %q matches no tool", pattern)
+	}
+	return matched, nil
+}
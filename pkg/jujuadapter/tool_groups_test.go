@@ -0,0 +1,107 @@
+package jujuadapter
+
+import (
+	"errors"
+	"slices"
+	"testing"
+
+	"github.com/jneo8/mcp-juju/config"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestToolGroupsCoverEveryCommand(t *testing.T) {
+	groupOf := make(map[JujuCommandID]string)
+	for group, ids := range toolGroups {
+		for _, id := range ids {
+			other, ok := groupOf[id]
+			assert.False(t, ok, "%s is in both %s and %s", id, other, group)
+			groupOf[id] = group
+		}
+	}
+	for _, id := range GetAllCommandIDs() {
+		assert.Contains(t, groupOf, id)
+	}
+	assert.Len(t, groupOf, len(GetAllCommandIDs()))
+}
+
+func TestSelectTools(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+	available := a.availableToolNames()
+
+	tests := []struct {
+		name      string
+		selection ToolSelection
+		expected  []string
+	}{
+		{
+			name:      "names and patterns",
+			selection: ToolSelection{Names: []string{"status", "show-m*"}},
+			expected:  []string{"status", "show-machine", "show-model"},
+		},
+		{
+			name:      "groups with exclusions",
+			selection: ToolSelection{Groups: []string{"destruction", "reporting"}, Exclude: []string{"remove-*", "switch"}},
+			expected:  []string{"version", "status", "status-history"},
+		},
+		{
+			name:      "everything but exclusions",
+			selection: ToolSelection{Exclude: []string{"destroy-*", "kill-*"}},
+			expected:  withoutTools(available, "destroy-model", "destroy-controller", "kill-controller"),
+		},
+		{
+			name:      "built-in tools",
+			selection: ToolSelection{Names: []string{RevertChangeToolName, "config"}},
+			expected:  []string{"config", RevertChangeToolName},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			names, err := selectTools(tt.selection, available)
+
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, names)
+		})
+	}
+}
+
+func TestSelectTools_RejectsUnknownNames(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+
+	_, err := selectTools(ToolSelection{
+		Names:   []string{"status", "stauts", "shwo-*", "show-[a"},
+		Groups:  []string{"reporting", "debuging"},
+		Exclude: []string{"destroy-*", "kil-*"},
+	}, a.availableToolNames())
+
+	var errs config.ValidationErrors
+	require.True(t, errors.As(err, &errs))
+	require.Len(t, errs, 5)
+	assert.Equal(t, `tool-names[1]: unknown tool "stauts"`, errs[0].Error())
+	assert.Equal(t, `tool-names[2]: tool pattern "shwo-*" matches no tool`, errs[1].Error())
+	assert.Equal(t, `tool-names[3]: invalid tool pattern "show-[a": syntax error in pattern`, errs[2].Error())
+	assert.Contains(t, errs[3].Error(), `tool-groups[1]: unknown tool group "debuging", available groups: actions,`)
+	assert.Equal(t, `exclude-tools[1]: tool pattern "kil-*" matches no tool`, errs[4].Error())
+}
+
+func TestSelectTools_RejectsEmptySelection(t *testing.T) {
+	_, err := selectTools(ToolSelection{Groups: []string{"subnets"}, Exclude: []string{"subnets"}}, []string{"subnets", "status"})
+
+	assert.EqualError(t, err, "exclude-tools: the tool selection leaves no tools to register")
+}
+
+func TestSetToolSelection_KeepsToolsOnError(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+	require.NoError(t, a.SetToolSelection(ToolSelection{Groups: []string{"secrets"}}))
+
+	err := a.SetToolSelection(ToolSelection{Names: []string{"secret"}})
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"secrets", "show-secret", "add-secret", "update-secret", "remove-secret", "grant-secret", "revoke-secret"}, a.ToolNames())
+}
+
+func withoutTools(names []string, exclude ...string) []string {
+	return slices.DeleteFunc(slices.Clone(names), func(name string) bool {
+		return slices.Contains(exclude, name)
+	})
+}
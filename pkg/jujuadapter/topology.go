@@ -0,0 +1,512 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	RelationGraphToolName = "relation-graph"
+	topologyTemplateName  = "topology"
+	topologyURI           = "juju://topology"
+
+	nodeApplication = "application"
+	nodeSaas        = "saas"
+	nodeOffer       = "offer"
+
+	graphJSON    = "json"
+	graphDOT     = "dot"
+	graphMermaid = "mermaid"
+)
+
+// topologyNode is an application, a SAAS application consumed from another
+// model, or an offer of an application to other models.
+type topologyNode struct {
+	ID          string   `json:"id"`
+	Kind        string   `json:"kind"`
+	Charm       string   `json:"charm,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Units       int      `json:"units,omitempty"`
+	Subordinate bool     `json:"subordinate,omitempty"`
+	URL         string   `json:"url,omitempty"`
+	Application string   `json:"application,omitempty"`
+	Endpoints   []string `json:"endpoints,omitempty"`
+}
+
+// topologyEdge is a relation, from the provider to the requirer, or the
+// link from an application to its offer. Established is false for relations
+// that are not joined, such as joining, broken or suspended ones.
+type topologyEdge struct {
+	From         string `json:"from"`
+	FromEndpoint string `json:"from_endpoint,omitempty"`
+	To           string `json:"to"`
+	ToEndpoint   string `json:"to_endpoint,omitempty"`
+	Interface    string `json:"interface,omitempty"`
+	Type         string `json:"type"`
+	CrossModel   bool   `json:"cross_model,omitempty"`
+	Status       string `json:"status,omitempty"`
+	Message      string `json:"message,omitempty"`
+	Established  bool   `json:"established"`
+}
+
+// topology is the relation graph of a model.
+type topology struct {
+	Model   string         `json:"model"`
+	Summary string         `json:"summary"`
+	Note    string         `json:"note,omitempty"`
+	Nodes   []topologyNode `json:"nodes"`
+	Edges   []topologyEdge `json:"edges"`
+}
+
+// relationRow is a row of the relations table of `juju status --relations`.
+// Status is empty for joined relations, which the table leaves blank.
+type relationRow struct {
+	Provider  string
+	Requirer  string
+	Interface string
+	Type      string
+	Status    string
+	Message   string
+}
+
+// parseRelationsTable reads the "Integration provider" section of the
+// tabular status. The columns are found from the header, as the cells are
+// padded to line up with it.
+func parseRelationsTable(output string) []relationRow {
+	lines := strings.Split(output, "\n")
+	for i, header := range lines {
+		if !strings.HasPrefix(header, "Integration provider") {
+			continue
+		}
+		columns := []int{0}
+		for _, name := range []string{"Requirer", "Interface", "Type", "Message"} {
+			column := strings.Index(header, name)
+			if column < 0 {
+				return nil
+			}
+			columns = append(columns, column)
+		}
+		var rows []relationRow
+		for _, line := range lines[i+1:] {
+			if strings.TrimSpace(line) == "" {
+				break
+			}
+			cell := func(k int) string {
+				start, end := columns[k], len(line)
+				if k+1 < len(columns) {
+					end = min(columns[k+1], len(line))
+				}
+				if start >= end {
+					return ""
+				}
+				return strings.TrimSpace(line[start:end])
+			}
+			row := relationRow{Provider: cell(0), Requirer: cell(1), Interface: cell(2), Type: cell(3)}
+			status, message, _ := strings.Cut(cell(4), " ")
+			row.Status, row.Message = status, strings.TrimSpace(message)
+			rows = append(rows, row)
+		}
+		return rows
+	}
+	return nil
+}
+
+// buildTopology builds the graph of a model from its status, and from the
+// relations table when there is one, which has the state of the relations.
+func buildTopology(status modelStatus, rows []relationRow, haveRows bool) topology {
+	graph := topology{Nodes: []topologyNode{}, Edges: []topologyEdge{}}
+	endpoints := make(map[string]map[string]bool)
+	addEndpoint := func(ref string) {
+		node, endpoint, ok := strings.Cut(ref, ":")
+		if !ok {
+			return
+		}
+		if endpoints[node] == nil {
+			endpoints[node] = make(map[string]bool)
+		}
+		endpoints[node][endpoint] = true
+	}
+
+	for _, name := range sortedKeys(status.Applications) {
+		app := status.Applications[name]
+		graph.Nodes = append(graph.Nodes, topologyNode{
+			ID:          name,
+			Kind:        nodeApplication,
+			Charm:       app.Charm,
+			Status:      app.Status.Current,
+			Units:       applicationScale(app),
+			Subordinate: len(app.SubordinateTo) > 0,
+		})
+		for endpoint := range app.Relations {
+			addEndpoint(name + ":" + endpoint)
+		}
+	}
+	for _, name := range sortedKeys(status.Saas) {
+		saas := status.Saas[name]
+		graph.Nodes = append(graph.Nodes, topologyNode{ID: name, Kind: nodeSaas, Status: saas.Status.Current, URL: saas.URL})
+		for endpoint := range saas.Endpoints {
+			addEndpoint(name + ":" + endpoint)
+		}
+	}
+	for _, name := range sortedKeys(status.Offers) {
+		offer := status.Offers[name]
+		id := "offer:" + name
+		graph.Nodes = append(graph.Nodes, topologyNode{ID: id, Kind: nodeOffer, Application: offer.Application, Endpoints: sortedKeys(offer.Endpoints)})
+		graph.Edges = append(graph.Edges, topologyEdge{
+			From:        offer.Application,
+			To:          id,
+			Type:        nodeOffer,
+			Status:      fmt.Sprintf("%d active of %d connections", offer.ActiveConnectedCount, offer.TotalConnectedCount),
+			Established: true,
+		})
+	}
+
+	saas := func(ref string) bool {
+		node, _, _ := strings.Cut(ref, ":")
+		_, ok := status.Saas[node]
+		return ok
+	}
+	if haveRows {
+		for _, row := range rows {
+			edge := topologyEdge{Interface: row.Interface, Type: row.Type, Status: row.Status, Message: row.Message}
+			edge.From, edge.FromEndpoint, _ = strings.Cut(row.Provider, ":")
+			edge.To, edge.ToEndpoint, _ = strings.Cut(row.Requirer, ":")
+			if edge.Status == "" {
+				edge.Status = "joined"
+			}
+			edge.Established = edge.Status == "joined"
+			edge.CrossModel = saas(row.Provider) || saas(row.Requirer)
+			addEndpoint(row.Provider)
+			addEndpoint(row.Requirer)
+			graph.Edges = append(graph.Edges, edge)
+		}
+	} else {
+		// Without the relations table the direction and state of the
+		// relations are unknown.
+		for _, pair := range relationPairs(status) {
+			edge := topologyEdge{Interface: pair.Interface, Type: "regular", Status: "unknown", Established: true}
+			edge.From, edge.FromEndpoint, _ = strings.Cut(pair.Ends[0], ":")
+			edge.To, edge.ToEndpoint, _ = strings.Cut(pair.Ends[1], ":")
+			if pair.peer() {
+				edge.Type = "peer"
+			}
+			edge.CrossModel = saas(pair.Ends[0]) || saas(pair.Ends[1])
+			graph.Edges = append(graph.Edges, edge)
+		}
+		graph.Note = "The state of the relations is unknown, the relations table of juju status was not available."
+	}
+
+	for i := range graph.Nodes {
+		if graph.Nodes[i].Kind != nodeOffer {
+			graph.Nodes[i].Endpoints = sortedKeys(endpoints[graph.Nodes[i].ID])
+		}
+	}
+	sort.SliceStable(graph.Edges, func(i, j int) bool {
+		a, b := graph.Edges[i], graph.Edges[j]
+		if a.From != b.From {
+			return a.From < b.From
+		}
+		if a.FromEndpoint != b.FromEndpoint {
+			return a.FromEndpoint < b.FromEndpoint
+		}
+		return a.To < b.To
+	})
+	return graph
+}
+
+// focus keeps the relations of an application and the nodes they link.
+func (g topology) focus(application string) topology {
+	keep := map[string]bool{application: true}
+	var edges []topologyEdge
+	for _, e := range g.Edges {
+		if e.From == application || e.To == application {
+			edges = append(edges, e)
+			keep[e.From], keep[e.To] = true, true
+		}
+	}
+	focused := g
+	focused.Nodes = []topologyNode{}
+	focused.Edges = append([]topologyEdge{}, edges...)
+	for _, n := range g.Nodes {
+		if keep[n.ID] {
+			focused.Nodes = append(focused.Nodes, n)
+		}
+	}
+	return focused
+}
+
+func describeTopology(g topology) string {
+	counts := map[string]int{}
+	for _, n := range g.Nodes {
+		counts[n.Kind]++
+	}
+	relations := 0
+	var broken []string
+	for _, e := range g.Edges {
+		if e.Type == nodeOffer {
+			continue
+		}
+		relations++
+		if !e.Established {
+			broken = append(broken, fmt.Sprintf("%s - %s (%s)", edgeEnd(e.From, e.FromEndpoint), edgeEnd(e.To, e.ToEndpoint), e.Status))
+		}
+	}
+	text := fmt.Sprintf("Model %s: %s, %d SAAS, %s, %s.", g.Model, plural(counts[nodeApplication], "application"),
+		counts[nodeSaas], plural(counts[nodeOffer], "offer"), plural(relations, "relation"))
+	if len(broken) > 0 {
+		text += fmt.Sprintf(" %d not established: %s.", len(broken), strings.Join(broken, ", "))
+	}
+	return text
+}
+
+func edgeEnd(node, endpoint string) string {
+	if endpoint == "" {
+		return node
+	}
+	return node + ":" + endpoint
+}
+
+func edgeLabel(e topologyEdge) string {
+	var label string
+	switch {
+	case e.Type == nodeOffer:
+		label = e.Status
+	case e.FromEndpoint != "" || e.ToEndpoint != "":
+		label = e.FromEndpoint + " - " + e.ToEndpoint
+		if e.Interface != "" {
+			label += " (" + e.Interface + ")"
+		}
+	default:
+		label = e.Interface
+	}
+	if !e.Established {
+		label += " [" + e.Status + "]"
+	}
+	return label
+}
+
+func nodeLabel(n topologyNode) []string {
+	switch n.Kind {
+	case nodeSaas:
+		return []string{n.ID + " (SAAS)", n.URL}
+	case nodeOffer:
+		return []string{"offer " + strings.TrimPrefix(n.ID, "offer:"), strings.Join(n.Endpoints, ", ")}
+	}
+	lines := []string{n.ID}
+	if n.Charm != "" && n.Charm != n.ID {
+		lines = append(lines, n.Charm)
+	}
+	if n.Status != "" {
+		lines = append(lines, fmt.Sprintf("%s, %s", n.Status, plural(n.Units, "unit")))
+	}
+	return lines
+}
+
+// drawn reports whether an edge is drawn: peer relations are left out of the
+// drawings unless they are not established.
+func drawn(e topologyEdge) bool {
+	return e.Type != "peer" || !e.Established
+}
+
+// dot renders the graph in Graphviz DOT. Relations that are not established
+// are red and dashed.
+func (g topology) dot() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "digraph %s {\n  rankdir=LR;\n  node [shape=box];\n", dotQuote(g.Model))
+	for _, n := range g.Nodes {
+		attrs := []string{"label=" + dotQuote(strings.Join(slices.DeleteFunc(nodeLabel(n), isEmpty), "\n"))}
+		switch n.Kind {
+		case nodeSaas:
+			attrs = append(attrs, "shape=ellipse", "style=dashed")
+		case nodeOffer:
+			attrs = append(attrs, "shape=note")
+		}
+		if n.Status == "blocked" || n.Status == "error" {
+			attrs = append(attrs, "color=red")
+		}
+		fmt.Fprintf(&b, "  %s [%s];\n", dotQuote(n.ID), strings.Join(attrs, ", "))
+	}
+	for _, e := range g.Edges {
+		if !drawn(e) {
+			continue
+		}
+		attrs := []string{"label=" + dotQuote(edgeLabel(e))}
+		switch {
+		case !e.Established:
+			attrs = append(attrs, "color=red", "fontcolor=red", "style=dashed")
+		case e.Type == nodeOffer:
+			attrs = append(attrs, "style=dotted", "arrowhead=none")
+		}
+		fmt.Fprintf(&b, "  %s -> %s [%s];\n", dotQuote(e.From), dotQuote(e.To), strings.Join(attrs, ", "))
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
+
+// mermaid renders the graph as a Mermaid flowchart. Relations that are not
+// established are dashed and red.
+func (g topology) mermaid() string {
+	var b strings.Builder
+	b.WriteString("graph LR\n")
+	ids := make(map[string]string, len(g.Nodes))
+	for i, n := range g.Nodes {
+		id := fmt.Sprintf("n%d", i)
+		ids[n.ID] = id
+		label := mermaidQuote(strings.Join(slices.DeleteFunc(nodeLabel(n), isEmpty), "<br/>"))
+		switch n.Kind {
+		case nodeSaas:
+			fmt.Fprintf(&b, "  %s([%s])\n", id, label)
+		case nodeOffer:
+			fmt.Fprintf(&b, "  %s[/%s/]\n", id, label)
+		default:
+			fmt.Fprintf(&b, "  %s[%s]\n", id, label)
+		}
+	}
+	var unestablished []int
+	link := 0
+	for _, e := range g.Edges {
+		if !drawn(e) {
+			continue
+		}
+		from, to := nodeID(ids, &b, e.From), nodeID(ids, &b, e.To)
+		arrow := "-->"
+		if !e.Established || e.Type == nodeOffer {
+			arrow = "-.->"
+		}
+		fmt.Fprintf(&b, "  %s %s|%s| %s\n", from, arrow, mermaidQuote(edgeLabel(e)), to)
+		if !e.Established {
+			unestablished = append(unestablished, link)
+		}
+		link++
+	}
+	for _, i := range unestablished {
+		fmt.Fprintf(&b, "  linkStyle %d stroke:red,color:red\n", i)
+	}
+	return b.String()
+}
+
+// nodeID returns the Mermaid ID of a node, declaring the nodes that only
+// appear in relations, such as the remote side of a relation.
+func nodeID(ids map[string]string, b *strings.Builder, node string) string {
+	if id, ok := ids[node]; ok {
+		return id
+	}
+	id := fmt.Sprintf("n%d", len(ids))
+	ids[node] = id
+	fmt.Fprintf(b, "  %s[%s]\n", id, mermaidQuote(node))
+	return id
+}
+
+func isEmpty(s string) bool {
+	return s == ""
+}
+
+func dotQuote(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+func mermaidQuote(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, "#quot;") + `"`
+}
+
+// registerTopology adds the relation-graph tool and the juju://topology
+// resource template.
+func (a *adapter) registerTopology() {
+	a.builtins.addTool(mcp.NewTool(RelationGraphToolName,
+		mcp.WithDescription("Build the relation graph of a model from its status: applications, SAAS applications consumed "+
+			"from other models and offers as nodes, relations with their endpoints and interfaces as edges, from provider to "+
+			"requirer. Relations that are not established, such as joining, broken or suspended ones, are highlighted. "+
+			"Returns JSON nodes and edges, Graphviz DOT or a Mermaid flowchart."),
+		mcp.WithString("model",
+			mcp.Description("Model to graph, the current model if empty"),
+		),
+		mcp.WithString("format",
+			mcp.Description("Output format"),
+			mcp.Enum(graphJSON, graphDOT, graphMermaid),
+			mcp.DefaultString(graphJSON),
+		),
+		mcp.WithString("application",
+			mcp.Description("Only graph the relations of this application"),
+		),
+	), a.handleRelationGraph)
+
+	a.builtins.addTemplate(topologyTemplateName, mcp.NewResourceTemplate(
+		topologyURI+"/{model}",
+		"Juju Model Topology",
+		mcp.WithTemplateDescription("The relation graph of a model as JSON nodes and edges, with the relations that are not established marked"),
+		mcp.WithTemplateMIMEType("application/json"),
+	), a.handleTopologyResource)
+}
+
+func (a *adapter) handleRelationGraph(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	format := req.GetString("format", graphJSON)
+	if !slices.Contains([]string{graphJSON, graphDOT, graphMermaid}, format) {
+		return mcp.NewToolResultError(fmt.Sprintf("unknown format %q, expected json, dot or mermaid", format)), nil
+	}
+	graph, err := a.topology(ctx, req.GetString("model", ""))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	if application := req.GetString("application", ""); application != "" {
+		if !slices.ContainsFunc(graph.Nodes, func(n topologyNode) bool { return n.ID == application }) {
+			return mcp.NewToolResultError(fmt.Sprintf("application %q not found in model %s", application, graph.Model)), nil
+		}
+		graph = graph.focus(application)
+		graph.Summary = describeTopology(graph)
+	}
+
+	switch format {
+	case graphDOT:
+		return mcp.NewToolResultText(graph.dot()), nil
+	case graphMermaid:
+		return mcp.NewToolResultText(graph.mermaid()), nil
+	}
+	return jsonToolResult(graph)
+}
+
+func (a *adapter) handleTopologyResource(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+	model, err := url.PathUnescape(strings.TrimPrefix(req.Params.URI, topologyURI+"/"))
+	if err != nil || model == "" {
+		return nil, fmt.Errorf("invalid topology URI %q: a model is required", req.Params.URI)
+	}
+	graph, err := a.topology(ctx, model)
+	if err != nil {
+		return nil, err
+	}
+	return jsonResourceContents(req.Params.URI, graph)
+}
+
+// topology reads the status of a model, and its relations table for the state
+// of the relations, and builds its graph.
+func (a *adapter) topology(ctx context.Context, model string) (topology, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+	})
+	if err != nil {
+		return topology{}, err
+	}
+	var status modelStatus
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return topology{}, fmt.Errorf("failed to parse status: %w", err)
+	}
+	table, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		FixedFlags:  withModel(map[string]string{"format": "tabular", "relations": "true", "no-color": "true"}, model),
+	})
+
+	graph := buildTopology(status, parseRelationsTable(table), err == nil)
+	graph.Model = model
+	if model == "" {
+		graph.Model = status.Model.Name
+	}
+	graph.Summary = describeTopology(graph)
+	return graph, nil
+}
@@ -0,0 +1,198 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const topologyStatus = `{
+	"model": {"name": "prod"},
+	"applications": {
+		"mysql": {"charm": "mysql", "application-status": {"current": "active"},
+			"units": {"mysql/0": {"workload-status": {"current": "active"}}},
+			"relations": {"database": [{"related-application": "wordpress", "interface": "mysql"}],
+				"database-peers": [{"related-application": "mysql", "interface": "mysql_peers"}]}},
+		"wordpress": {"charm": "wordpress", "application-status": {"current": "blocked"},
+			"units": {"wordpress/0": {"workload-status": {"current": "blocked"}}},
+			"relations": {"db": [{"related-application": "mysql", "interface": "mysql"}],
+				"logs": [{"related-application": "loki", "interface": "loki_push_api"}]}}
+	},
+	"application-endpoints": {
+		"loki": {"url": "cos:admin/cos.loki", "application-status": {"current": "active"},
+			"endpoints": {"logging": {"interface": "loki_push_api", "role": "provider"}}}
+	},
+	"offers": {
+		"mysql-db": {"application": "mysql", "endpoints": {"database": {"interface": "mysql", "role": "provider"}},
+			"total-connected-count": 2, "active-connected-count": 1}
+	}
+}`
+
+const topologyTable = `Model  Controller  Cloud/Region  Version  SLA          Timestamp
+prod   lxd         lxd/default   3.6.8    unsupported  10:00:00Z
+
+App        Version  Status   Scale  Charm      Channel  Rev  Exposed  Message
+mysql               active       1  mysql               151  no
+wordpress           blocked      1  wordpress            20  no
+
+Integration provider  Requirer              Interface      Type     Message
+loki:logging          wordpress:logs        loki_push_api  regular  suspended  remote offer removed
+mysql:database        wordpress:db          mysql          regular  
+mysql:database-peers  mysql:database-peers  mysql_peers    peer     
+`
+
+func topologyOutput(call fakeCall) string {
+	if call.flags["format"] == "json" {
+		return topologyStatus
+	}
+	return topologyTable
+}
+
+func TestParseRelationsTable(t *testing.T) {
+	rows := parseRelationsTable(topologyTable)
+
+	assert.Equal(t, []relationRow{
+		{Provider: "loki:logging", Requirer: "wordpress:logs", Interface: "loki_push_api", Type: "regular", Status: "suspended", Message: "remote offer removed"},
+		{Provider: "mysql:database", Requirer: "wordpress:db", Interface: "mysql", Type: "regular"},
+		{Provider: "mysql:database-peers", Requirer: "mysql:database-peers", Interface: "mysql_peers", Type: "peer"},
+	}, rows)
+	assert.Nil(t, parseRelationsTable("App  Version  Status\nmysql  8.0  active\n"))
+}
+
+func TestRelationGraph(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(topologyOutput)
+
+	// Act
+	result := callTool(t, a, RelationGraphToolName, map[string]any{"model": "prod"})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	var graph topology
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &graph))
+	assert.Equal(t, "prod", graph.Model)
+	assert.Equal(t, []topologyNode{
+		{ID: "mysql", Kind: nodeApplication, Charm: "mysql", Status: "active", Units: 1, Endpoints: []string{"database", "database-peers"}},
+		{ID: "wordpress", Kind: nodeApplication, Charm: "wordpress", Status: "blocked", Units: 1, Endpoints: []string{"db", "logs"}},
+		{ID: "loki", Kind: nodeSaas, Status: "active", URL: "cos:admin/cos.loki", Endpoints: []string{"logging"}},
+		{ID: "offer:mysql-db", Kind: nodeOffer, Application: "mysql", Endpoints: []string{"database"}},
+	}, graph.Nodes)
+	assert.Equal(t, topologyEdge{
+		From: "loki", FromEndpoint: "logging", To: "wordpress", ToEndpoint: "logs", Interface: "loki_push_api",
+		Type: "regular", CrossModel: true, Status: "suspended", Message: "remote offer removed",
+	}, graph.Edges[0])
+	assert.Len(t, graph.Edges, 4)
+	assert.Equal(t, "Model prod: 2 applications, 1 SAAS, 1 offer, 3 relations. 1 not established: "+
+		"loki:logging - wordpress:logs (suspended).", graph.Summary)
+	assert.Empty(t, graph.Note)
+	require.Len(t, factory.calls, 2)
+	assert.Equal(t, map[string]string{"format": "tabular", "model": "prod"}, factory.calls[1].flags)
+}
+
+func TestTopologyFormats(t *testing.T) {
+	var status modelStatus
+	require.NoError(t, json.Unmarshal([]byte(topologyStatus), &status))
+	graph := buildTopology(status, parseRelationsTable(topologyTable), true)
+	graph.Model = "prod"
+
+	tests := []struct {
+		name     string
+		render   func(topology) string
+		prefix   string
+		contains []string
+		excludes []string
+	}{
+		{
+			name:   "dot",
+			render: topology.dot,
+			prefix: "digraph \"prod\" {\n",
+			contains: []string{
+				`"loki" [label="loki (SAAS)\ncos:admin/cos.loki", shape=ellipse, style=dashed];`,
+				`"loki" -> "wordpress" [label="logging - logs (loki_push_api) [suspended]", color=red, fontcolor=red, style=dashed];`,
+				`"mysql" -> "wordpress" [label="database - db (mysql)"];`,
+			},
+			excludes: []string{"mysql_peers"},
+		},
+		{
+			name:   "mermaid",
+			render: topology.mermaid,
+			prefix: "graph LR\n",
+			contains: []string{
+				`n2(["loki (SAAS)<br/>cos:admin/cos.loki"])`,
+				`n2 -.->|"logging - logs (loki_push_api) [suspended]"| n1`,
+				"linkStyle 0 stroke:red,color:red",
+			},
+		},
+		{
+			name:     "focused mermaid",
+			render:   func(g topology) string { return g.focus("loki").mermaid() },
+			prefix:   "graph LR\n",
+			contains: []string{`n1(["loki (SAAS)<br/>cos:admin/cos.loki"])`, `n1 -.->|"logging - logs (loki_push_api) [suspended]"| n0`},
+			excludes: []string{"mysql"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := tt.render(graph)
+
+			assert.True(t, strings.HasPrefix(got, tt.prefix), got)
+			for _, want := range tt.contains {
+				assert.Contains(t, got, want)
+			}
+			for _, unwanted := range tt.excludes {
+				assert.NotContains(t, got, unwanted)
+			}
+		})
+	}
+}
+
+func TestRelationGraphOptions(t *testing.T) {
+	a, _ := newFakeAdapter(topologyOutput)
+
+	focused := callTool(t, a, RelationGraphToolName, map[string]any{"model": "prod", "format": "mermaid", "application": "loki"})
+	unknown := callTool(t, a, RelationGraphToolName, map[string]any{"model": "prod", "application": "redis"})
+	format := callTool(t, a, RelationGraphToolName, map[string]any{"model": "prod", "format": "svg"})
+
+	assert.True(t, strings.HasPrefix(resultText(focused), "graph LR\n"), resultText(focused))
+	assert.Equal(t, `application "redis" not found in model prod`, resultText(unknown))
+	assert.Equal(t, `unknown format "svg", expected json, dot or mermaid`, resultText(format))
+}
+
+func TestTopologyResourceWithoutRelationsTable(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(topologyOutput)
+	factory.fail = func(call fakeCall) error {
+		if call.flags["format"] == "tabular" {
+			return errors.New("tabular status failed")
+		}
+		return nil
+	}
+	_, handler, err := a.GetResourceTemplate(topologyTemplateName)
+	require.NoError(t, err)
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "juju://topology/admin%2Fprod"
+
+	// Act
+	contents, err := handler(context.Background(), req)
+
+	// Assert
+	require.NoError(t, err)
+	var graph topology
+	require.NoError(t, json.Unmarshal([]byte(contents[0].(mcp.TextResourceContents).Text), &graph))
+	assert.Equal(t, "admin/prod", graph.Model)
+	assert.NotEmpty(t, graph.Note)
+	assert.Contains(t, graph.Edges, topologyEdge{
+		From: "mysql", FromEndpoint: "database", To: "wordpress", ToEndpoint: "db", Interface: "mysql",
+		Type: "regular", Status: "unknown", Established: true,
+	})
+	assert.Contains(t, graph.Edges, topologyEdge{
+		From: "mysql", FromEndpoint: "database-peers", To: "mysql", ToEndpoint: "database-peers", Interface: "mysql_peers",
+		Type: "peer", Status: "unknown", Established: true,
+	})
+}
@@ -0,0 +1,518 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/juju/charm/v12"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	UpgradePlanToolName = "upgrade-plan"
+
+	defaultUpgradeWait = 30 * time.Minute
+)
+
+// Actions of the applications of an upgrade plan.
+const (
+	upgradeRefresh = "refresh"
+	upgradeCurrent = "up-to-date"
+	upgradeBlocked = "blocked"
+	upgradeSkipped = "skipped"
+)
+
+// Severities of the issues of an upgrade plan.
+const (
+	upgradeBlocker = "blocker"
+	upgradeWarning = "warning"
+)
+
+// charmInfo is the part of `juju info --format json` the upgrade plan needs.
+type charmInfo struct {
+	Name     string                                  `json:"name"`
+	Tracks   []string                                `json:"tracks"`
+	Channels map[string]map[string][]charmhubRelease `json:"channels"`
+	Charm    *struct {
+		Relations map[string]map[string]string `json:"relations"`
+	} `json:"charm"`
+}
+
+type charmhubRelease struct {
+	Track    string       `json:"track"`
+	Risk     string       `json:"risk"`
+	Version  string       `json:"version"`
+	Revision int          `json:"revision"`
+	Bases    []statusBase `json:"bases"`
+}
+
+// endpoints returns the endpoints the charm provides, or requires, by name,
+// with their interface.
+func (info *charmInfo) endpoints(role string) map[string]string {
+	if info == nil || info.Charm == nil {
+		return nil
+	}
+	return info.Charm.Relations[role]
+}
+
+// supports reports whether a release can run on a base.
+func (r charmhubRelease) supports(base statusBase) bool {
+	for _, b := range r.Bases {
+		channel, _, _ := strings.Cut(b.Channel, "/")
+		if b.Name == base.Name && channel == base.Channel {
+			return true
+		}
+	}
+	return false
+}
+
+// toolCall is a call of a tool the plan is carried out with.
+type toolCall struct {
+	Tool      string         `json:"tool"`
+	Arguments map[string]any `json:"arguments"`
+}
+
+// upgradeApplication is the analysis of an application.
+type upgradeApplication struct {
+	Name           string   `json:"name"`
+	Charm          string   `json:"charm"`
+	Channel        string   `json:"channel,omitempty"`
+	Revision       int      `json:"revision"`
+	Base           string   `json:"base,omitempty"`
+	TargetChannel  string   `json:"target_channel,omitempty"`
+	TargetRevision int      `json:"target_revision,omitempty"`
+	TargetVersion  string   `json:"target_version,omitempty"`
+	Tracks         []string `json:"tracks,omitempty"`
+	After          []string `json:"after,omitempty"`
+	Action         string   `json:"action"`
+	Reason         string   `json:"reason,omitempty"`
+}
+
+// upgradeIssue is something to resolve, or to review, before refreshing.
+type upgradeIssue struct {
+	Application string `json:"application"`
+	Severity    string `json:"severity"`
+	Message     string `json:"message"`
+}
+
+// upgradeStep refreshes one application, then waits for it to settle before
+// the next step.
+type upgradeStep struct {
+	Step        int      `json:"step"`
+	Application string   `json:"application"`
+	From        string   `json:"from"`
+	To          string   `json:"to"`
+	After       []string `json:"after,omitempty"`
+	Command     string   `json:"command"`
+	Refresh     toolCall `json:"refresh"`
+	Wait        toolCall `json:"wait"`
+}
+
+// upgradePlan is the result of upgrade-plan.
+type upgradePlan struct {
+	Model        string               `json:"model,omitempty"`
+	Summary      string               `json:"summary"`
+	Applications []upgradeApplication `json:"applications"`
+	Issues       []upgradeIssue       `json:"issues"`
+	Steps        []upgradeStep        `json:"steps"`
+}
+
+// normalizeChannel returns a channel as track/risk, with the latest track
+// when it has none, such as latest/stable for stable.
+func normalizeChannel(channel string) (string, error) {
+	ch, err := charm.ParseChannelNormalize(channel)
+	if err != nil {
+		return "", err
+	}
+	if ch.Track == "" {
+		ch.Track = "latest"
+	}
+	return ch.Track + "/" + string(ch.Risk), nil
+}
+
+// upgradeOrder orders applications so that the ones providing a relation,
+// such as a database, come before the ones requiring it, such as its
+// clients. The providing side is read from the charm metadata of either
+// side. It returns the applications each one comes after, and a warning
+// when the relations form a cycle.
+func upgradeOrder(status modelStatus, infos map[string]*charmInfo) ([]string, map[string][]string, []string) {
+	after := make(map[string]map[string]bool)
+	depend := func(requirer, provider string) {
+		if after[requirer] == nil {
+			after[requirer] = make(map[string]bool)
+		}
+		after[requirer][provider] = true
+	}
+	for _, name := range sortedKeys(status.Applications) {
+		app := status.Applications[name]
+		info := infos[name]
+		for endpoint, related := range app.Relations {
+			for _, r := range related {
+				other := r.RelatedApplication
+				if other == name {
+					continue
+				}
+				if _, ok := status.Applications[other]; !ok {
+					continue
+				}
+				switch {
+				case info.endpoints("provides")[endpoint] != "":
+					depend(other, name)
+				case info.endpoints("requires")[endpoint] != "":
+					depend(name, other)
+				case slices.Contains(mapValues(infos[other].endpoints("provides")), r.Interface):
+					depend(name, other)
+				case slices.Contains(mapValues(infos[other].endpoints("requires")), r.Interface):
+					depend(other, name)
+				}
+			}
+		}
+	}
+
+	var order, warnings []string
+	remaining := make(map[string]bool, len(status.Applications))
+	for name := range status.Applications {
+		remaining[name] = true
+	}
+	for len(remaining) > 0 {
+		var ready []string
+		for _, name := range sortedKeys(remaining) {
+			blocked := false
+			for provider := range after[name] {
+				if remaining[provider] {
+					blocked = true
+					break
+				}
+			}
+			if !blocked {
+				ready = append(ready, name)
+			}
+		}
+		if len(ready) == 0 {
+			// Break the cycle at the first application.
+			cycle := sortedKeys(remaining)
+			warnings = append(warnings, fmt.Sprintf("the relations of %s form a cycle, their order is arbitrary", strings.Join(cycle, ", ")))
+			ready = cycle[:1]
+		}
+		for _, name := range ready {
+			order = append(order, name)
+			delete(remaining, name)
+		}
+	}
+
+	providers := make(map[string][]string, len(after))
+	for name, set := range after {
+		providers[name] = sortedKeys(set)
+	}
+	return order, providers, warnings
+}
+
+func mapValues(m map[string]string) []string {
+	values := make([]string, 0, len(m))
+	for _, v := range m {
+		values = append(values, v)
+	}
+	return values
+}
+
+// planApplication decides how to refresh an application from the releases
+// of its charm.
+func planApplication(name string, app modelStatusApplication, info *charmInfo, target string) (upgradeApplication, []upgradeIssue) {
+	plan := upgradeApplication{
+		Name:     name,
+		Charm:    app.CharmName,
+		Channel:  app.CharmChannel,
+		Revision: app.CharmRev,
+		Base:     app.Base.String(),
+	}
+	if plan.Charm == "" {
+		plan.Charm = charmName(app.Charm)
+	}
+	issue := func(severity, format string, args ...any) []upgradeIssue {
+		return []upgradeIssue{{Application: name, Severity: severity, Message: fmt.Sprintf(format, args...)}}
+	}
+	if app.CharmOrigin != "" && app.CharmOrigin != "charmhub" {
+		plan.Action, plan.Reason = upgradeSkipped, fmt.Sprintf("%s charm, refresh it with --path", app.CharmOrigin)
+		return plan, nil
+	}
+	if info == nil {
+		plan.Action, plan.Reason = upgradeSkipped, "charm information is not available"
+		return plan, issue(upgradeWarning, "unable to read the releases of charm %s", plan.Charm)
+	}
+	plan.Tracks = info.Tracks
+
+	if target == "" {
+		target = app.CharmChannel
+	}
+	channel, err := normalizeChannel(target)
+	if err != nil {
+		plan.Action, plan.Reason = upgradeBlocked, fmt.Sprintf("invalid channel %q", target)
+		return plan, issue(upgradeBlocker, "channel %q is not valid: %v", target, err)
+	}
+	plan.TargetChannel = channel
+	track, risk, _ := strings.Cut(channel, "/")
+
+	var issues []upgradeIssue
+	if current, err := normalizeChannel(app.CharmChannel); err == nil && current != channel {
+		currentTrack, _, _ := strings.Cut(current, "/")
+		if currentTrack != track {
+			issues = append(issues, issue(upgradeWarning, "switching track from %s to %s, check the upgrade notes of %s",
+				currentTrack, track, plan.Charm)...)
+		}
+	}
+
+	releases := info.Channels[track][risk]
+	if len(releases) == 0 {
+		plan.Action, plan.Reason = upgradeBlocked, fmt.Sprintf("channel %s is not published", channel)
+		return plan, append(issues, issue(upgradeBlocker, "charm %s has no release in %s, available tracks: %s",
+			plan.Charm, channel, strings.Join(info.Tracks, ", "))...)
+	}
+	var best *charmhubRelease
+	var bases []string
+	for i, release := range releases {
+		for _, b := range release.Bases {
+			bases = append(bases, b.String())
+		}
+		if app.Base.Name != "" && !release.supports(app.Base) {
+			continue
+		}
+		if best == nil || release.Revision > best.Revision {
+			best = &releases[i]
+		}
+	}
+	if best == nil {
+		plan.Action, plan.Reason = upgradeBlocked, fmt.Sprintf("no release in %s supports %s", channel, plan.Base)
+		return plan, append(issues, issue(upgradeBlocker, "%s in %s does not support base %s, it supports %s; upgrade the base first",
+			plan.Charm, channel, plan.Base, strings.Join(sortedUnique(bases), ", "))...)
+	}
+	plan.TargetRevision, plan.TargetVersion = best.Revision, best.Version
+
+	switch {
+	case best.Revision > app.CharmRev:
+		plan.Action = upgradeRefresh
+	case best.Revision < app.CharmRev:
+		plan.Action, plan.Reason = upgradeCurrent, fmt.Sprintf("revision %d is newer than %d, the latest of %s", app.CharmRev, best.Revision, channel)
+		issues = append(issues, issue(upgradeWarning, "deployed revision %d is newer than the latest release of %s, revision %d",
+			app.CharmRev, channel, best.Revision)...)
+	case channel != mustNormalize(app.CharmChannel):
+		plan.Action, plan.Reason = upgradeRefresh, "same revision, switching channel"
+	default:
+		plan.Action, plan.Reason = upgradeCurrent, fmt.Sprintf("revision %d is the latest of %s", app.CharmRev, channel)
+	}
+	return plan, issues
+}
+
+func mustNormalize(channel string) string {
+	normalized, err := normalizeChannel(channel)
+	if err != nil {
+		return channel
+	}
+	return normalized
+}
+
+func describeUpgradePlan(p upgradePlan) string {
+	counts := map[string]int{}
+	for _, app := range p.Applications {
+		counts[app.Action]++
+	}
+	text := fmt.Sprintf("%s to refresh, %d up to date, %d blocked, %d skipped.", plural(counts[upgradeRefresh], "application"),
+		counts[upgradeCurrent], counts[upgradeBlocked], counts[upgradeSkipped])
+	if len(p.Steps) > 0 {
+		var order []string
+		for _, step := range p.Steps {
+			order = append(order, step.Application)
+		}
+		text += " Order: " + strings.Join(order, ", ") + "."
+	}
+	blockers := 0
+	for _, issue := range p.Issues {
+		if issue.Severity == upgradeBlocker {
+			blockers++
+		}
+	}
+	if blockers > 0 {
+		text += fmt.Sprintf(" %s to resolve first.", plural(blockers, "blocker"))
+	}
+	return text
+}
+
+// registerUpgradePlan adds the upgrade-plan tool.
+func (a *adapter) registerUpgradePlan() {
+	a.builtins.addTool(mcp.NewTool(UpgradePlanToolName,
+		mcp.WithDescription("Plan the refresh of the charms of a model. Lists the charm, channel, revision and base of every "+
+			"application from status, reads the releases of each charm from Charmhub with info, and flags channels that are "+
+			"not published, bases the new revisions do not support and track switches. Applications providing relations, "+
+			"such as databases, are refreshed before their clients. Returns an ordered plan whose steps each give a refresh "+
+			"call and a wait-for-state gate to run before the next step. Nothing is changed."),
+		mcp.WithString("model",
+			mcp.Description("Model to plan the upgrade of, the current model if empty"),
+		),
+		mcp.WithArray("applications",
+			mcp.Description("Only plan the refresh of these applications"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithObject("channels",
+			mcp.Description("Channels to move applications to, such as {\"mysql\": \"8.4/stable\"}; the current channel otherwise"),
+		),
+		mcp.WithString("wait_timeout",
+			mcp.Description("Timeout of the wait gate after each refresh"),
+			mcp.DefaultString(defaultUpgradeWait.String()),
+		),
+	), a.handleUpgradePlan)
+}
+
+func (a *adapter) handleUpgradePlan(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	model := req.GetString("model", "")
+	selected := req.GetStringSlice("applications", nil)
+	waitTimeout := req.GetString("wait_timeout", defaultUpgradeWait.String())
+	if _, err := time.ParseDuration(waitTimeout); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid wait_timeout: %v", err)), nil
+	}
+	channels := make(map[string]string)
+	if raw, ok := req.GetArguments()["channels"]; ok && raw != nil {
+		values, ok := raw.(map[string]any)
+		if !ok {
+			return mcp.NewToolResultError("channels must be an object"), nil
+		}
+		for app, channel := range values {
+			channels[app] = fmt.Sprint(channel)
+		}
+	}
+
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+	})
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	var status modelStatus
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("failed to parse status: %v", err)), nil
+	}
+	for _, name := range append(slices.Clone(selected), sortedKeys(channels)...) {
+		if _, ok := status.Applications[name]; !ok {
+			return mcp.NewToolResultError(fmt.Sprintf("application %q not found in the model", name)), nil
+		}
+	}
+
+	// Read the releases of every charm once, local charms have none. infos
+	// is keyed by application, which may be named differently from its
+	// charm or share it with other applications.
+	infos := make(map[string]*charmInfo)
+	byCharm := make(map[string]*charmInfo)
+	for _, name := range sortedKeys(status.Applications) {
+		app := status.Applications[name]
+		if app.CharmOrigin != "" && app.CharmOrigin != "charmhub" {
+			continue
+		}
+		charm := app.CharmName
+		if charm == "" {
+			charm = charmName(app.Charm)
+		}
+		info, ok := byCharm[charm]
+		if !ok {
+			info, _ = a.charmInfo(ctx, charm)
+			byCharm[charm] = info
+		}
+		infos[name] = info
+	}
+
+	plan := upgradePlan{Model: model, Applications: []upgradeApplication{}, Issues: []upgradeIssue{}, Steps: []upgradeStep{}}
+	order, providers, warnings := upgradeOrder(status, infos)
+	for _, warning := range warnings {
+		plan.Issues = append(plan.Issues, upgradeIssue{Severity: upgradeWarning, Message: warning})
+	}
+	actions := make(map[string]string)
+	for _, name := range order {
+		if len(selected) > 0 && !slices.Contains(selected, name) {
+			continue
+		}
+		app := status.Applications[name]
+		planned, issues := planApplication(name, app, infos[name], channels[name])
+		planned.After = providers[name]
+		actions[name] = planned.Action
+		plan.Applications = append(plan.Applications, planned)
+		plan.Issues = append(plan.Issues, issues...)
+		if planned.Action != upgradeRefresh {
+			continue
+		}
+
+		var after []string
+		for _, provider := range providers[name] {
+			switch actions[provider] {
+			case upgradeRefresh:
+				after = append(after, provider)
+			case upgradeBlocked:
+				plan.Issues = append(plan.Issues, upgradeIssue{Application: name, Severity: upgradeWarning,
+					Message: fmt.Sprintf("%s is refreshed while %s, which it relates to, is blocked", name, provider)})
+			}
+		}
+		plan.Steps = append(plan.Steps, a.upgradeStep(len(plan.Steps)+1, planned, app, after, model, waitTimeout))
+	}
+	plan.Summary = describeUpgradePlan(plan)
+	return jsonToolResult(plan)
+}
+
+// upgradeStep builds the refresh of an application and the gate that waits
+// for it to settle.
+func (a *adapter) upgradeStep(n int, planned upgradeApplication, app modelStatusApplication, after []string, model, waitTimeout string) upgradeStep {
+	flags := withModel(map[string]string{
+		"channel":  planned.TargetChannel,
+		"revision": strconv.Itoa(planned.TargetRevision),
+	}, model)
+	refresh := map[string]any{
+		"args":     []string{planned.Name},
+		"channel":  planned.TargetChannel,
+		"revision": planned.TargetRevision,
+	}
+	wait := map[string]any{
+		"entity_type":     entityApplication,
+		"name":            planned.Name,
+		"workload_status": []string{"active"},
+		"agent_status":    []string{"idle"},
+		"timeout":         waitTimeout,
+	}
+	// An application that was blocked or waiting before is expected to be
+	// so again.
+	if current := app.Status.Current; current == "blocked" || current == "waiting" {
+		wait["workload_status"] = []string{"active", current}
+	}
+	if model != "" {
+		refresh["model"] = model
+		wait["model"] = model
+	}
+
+	return upgradeStep{
+		Step:        n,
+		Application: planned.Name,
+		From:        fmt.Sprintf("%s r%d", planned.Channel, planned.Revision),
+		To:          fmt.Sprintf("%s r%d", planned.TargetChannel, planned.TargetRevision),
+		After:       after,
+		Command:     revertStep{Command: string(CmdRefresh), Arguments: []string{planned.Name}, Flags: flags}.String(),
+		Refresh:     toolCall{Tool: a.toolName(string(CmdRefresh)), Arguments: refresh},
+		Wait:        toolCall{Tool: a.toolName(WaitForStateToolName), Arguments: wait},
+	}
+}
+
+// charmInfo reads the releases and the relations of a charm from Charmhub.
+func (a *adapter) charmInfo(ctx context.Context, name string) (*charmInfo, error) {
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdInfo),
+		FixedFlags:  map[string]string{"format": "json"},
+		Arguments:   []string{name},
+	})
+	if err != nil {
+		return nil, err
+	}
+	var info charmInfo
+	if err := json.Unmarshal([]byte(output), &info); err != nil {
+		return nil, fmt.Errorf("failed to parse the information of charm %s: %w", name, err)
+	}
+	return &info, nil
+}
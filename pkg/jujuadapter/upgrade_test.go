@@ -0,0 +1,236 @@
+package jujuadapter
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const upgradeStatus = `{"model": {"name": "prod"}, "applications": {
+	"mysql": {"charm": "mysql", "charm-name": "mysql", "charm-origin": "charmhub", "charm-rev": 196, "charm-channel": "8.0/stable",
+		"base": {"name": "ubuntu", "channel": "22.04"}, "application-status": {"current": "active"},
+		"relations": {"database": [{"related-application": "wordpress", "interface": "mysql_client"}],
+			"cos-agent": [{"related-application": "grafana-agent", "interface": "cos_agent"}]}},
+	"wordpress": {"charm": "wordpress", "charm-name": "wordpress", "charm-origin": "charmhub", "charm-rev": 10, "charm-channel": "stable",
+		"base": {"name": "ubuntu", "channel": "20.04"}, "application-status": {"current": "active"},
+		"relations": {"db": [{"related-application": "mysql", "interface": "mysql_client"}]}},
+	"grafana-agent": {"charm": "grafana-agent", "charm-name": "grafana-agent", "charm-origin": "charmhub", "charm-rev": 52, "charm-channel": "latest/stable",
+		"base": {"name": "ubuntu", "channel": "22.04"}, "application-status": {"current": "blocked"}, "subordinate-to": ["mysql"],
+		"relations": {"cos-agent": [{"related-application": "mysql", "interface": "cos_agent"}]}},
+	"tool": {"charm": "local:tool-0", "charm-name": "tool", "charm-origin": "local", "charm-rev": 0,
+		"base": {"name": "ubuntu", "channel": "22.04"}, "application-status": {"current": "active"}}
+}}`
+
+var upgradeInfo = map[string]string{
+	"mysql": `{"name": "mysql", "tracks": ["8.0", "8.4"], "channels": {
+		"8.0": {"stable": [
+			{"track": "8.0", "risk": "stable", "version": "8.0.39", "revision": 240, "bases": [{"name": "ubuntu", "channel": "22.04"}]},
+			{"track": "8.0", "risk": "stable", "version": "8.0.39", "revision": 241, "bases": [{"name": "ubuntu", "channel": "22.04"}]}]},
+		"8.4": {"stable": [
+			{"track": "8.4", "risk": "stable", "version": "8.4.2", "revision": 300, "bases": [{"name": "ubuntu", "channel": "24.04/stable"}]}]}},
+		"charm": {"relations": {"provides": {"database": "mysql_client"}, "requires": {}}}}`,
+	"wordpress": `{"name": "wordpress", "tracks": ["latest"], "channels": {
+		"latest": {"stable": [
+			{"track": "latest", "risk": "stable", "version": "6.5", "revision": 12, "bases": [{"name": "ubuntu", "channel": "22.04"}]}]}},
+		"charm": {"relations": {"requires": {"db": "mysql_client"}}}}`,
+	"grafana-agent": `{"name": "grafana-agent", "tracks": ["latest"], "channels": {
+		"latest": {"stable": [
+			{"track": "latest", "risk": "stable", "revision": 60, "bases": [{"name": "ubuntu", "channel": "22.04"}, {"name": "ubuntu", "channel": "24.04"}]}]}},
+		"charm": {"subordinate": true, "relations": {"provides": {}, "requires": {"cos-agent": "cos_agent"}}}}`,
+}
+
+func upgradeOutput(call fakeCall) string {
+	switch JujuCommandID(call.name) {
+	case CmdStatus:
+		return upgradeStatus
+	case CmdInfo:
+		return upgradeInfo[call.args[0]]
+	}
+	return ""
+}
+
+func TestUpgradePlan(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(upgradeOutput)
+
+	// Act
+	result := callTool(t, a, UpgradePlanToolName, map[string]any{"model": "prod", "wait_timeout": "20m"})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	var plan upgradePlan
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &plan))
+	actions := map[string]string{}
+	for _, app := range plan.Applications {
+		actions[app.Name] = app.Action
+	}
+	assert.Equal(t, map[string]string{
+		"mysql": upgradeRefresh, "grafana-agent": upgradeRefresh, "wordpress": upgradeBlocked, "tool": upgradeSkipped,
+	}, actions)
+	require.Len(t, plan.Steps, 2)
+	assert.Equal(t, "mysql", plan.Steps[0].Application)
+	assert.Equal(t, "8.0/stable r196", plan.Steps[0].From)
+	assert.Equal(t, "8.0/stable r241", plan.Steps[0].To)
+	assert.Equal(t, "juju refresh mysql --channel=8.0/stable --model=prod --revision=241", plan.Steps[0].Command)
+	assert.Equal(t, toolCall{Tool: "refresh", Arguments: map[string]any{
+		"args": []any{"mysql"}, "channel": "8.0/stable", "revision": float64(241), "model": "prod",
+	}}, plan.Steps[0].Refresh)
+	assert.Equal(t, toolCall{Tool: WaitForStateToolName, Arguments: map[string]any{
+		"entity_type": "application", "name": "mysql", "model": "prod", "timeout": "20m",
+		"workload_status": []any{"active"}, "agent_status": []any{"idle"},
+	}}, plan.Steps[0].Wait)
+	assert.Equal(t, "grafana-agent", plan.Steps[1].Application)
+	assert.Equal(t, []string{"mysql"}, plan.Steps[1].After)
+	assert.Equal(t, []any{"active", "blocked"}, plan.Steps[1].Wait.Arguments["workload_status"])
+
+	assert.Contains(t, plan.Issues, upgradeIssue{Application: "wordpress", Severity: upgradeBlocker,
+		Message: "wordpress in latest/stable does not support base ubuntu@20.04, it supports ubuntu@22.04; upgrade the base first"})
+	assert.Equal(t, "2 applications to refresh, 0 up to date, 1 blocked, 1 skipped. Order: mysql, grafana-agent. 1 blocker to resolve first.", plan.Summary)
+
+	infoCalls := 0
+	for _, call := range factory.calls {
+		if call.name == string(CmdInfo) {
+			infoCalls++
+		}
+	}
+	assert.Equal(t, 3, infoCalls)
+
+	unknown := callTool(t, a, UpgradePlanToolName, map[string]any{"applications": []any{"postgresql"}})
+	assert.Equal(t, `application "postgresql" not found in the model`, resultText(unknown))
+}
+
+func TestPlanApplication(t *testing.T) {
+	var status modelStatus
+	require.NoError(t, json.Unmarshal([]byte(upgradeStatus), &status))
+	infos := map[string]*charmInfo{}
+	for name, output := range upgradeInfo {
+		info := &charmInfo{}
+		require.NoError(t, json.Unmarshal([]byte(output), info))
+		infos[name] = info
+	}
+	withRevision := func(app modelStatusApplication, revision int) modelStatusApplication {
+		app.CharmRev = revision
+		return app
+	}
+
+	tests := []struct {
+		name     string
+		app      string
+		status   modelStatusApplication
+		noInfo   bool
+		target   string
+		action   string
+		revision int
+		issues   []string
+	}{
+		{name: "newer revision", app: "mysql", action: upgradeRefresh, revision: 241},
+		{
+			name: "other track without the base", app: "mysql", target: "8.4/stable", action: upgradeBlocked,
+			issues: []string{
+				"warning: switching track from 8.0 to 8.4, check the upgrade notes of mysql",
+				"blocker: mysql in 8.4/stable does not support base ubuntu@22.04, it supports ubuntu@24.04/stable; upgrade the base first",
+			},
+		},
+		{
+			name: "unsupported base", app: "wordpress", action: upgradeBlocked,
+			issues: []string{"blocker: wordpress in latest/stable does not support base ubuntu@20.04, it supports ubuntu@22.04; upgrade the base first"},
+		},
+		{
+			name: "unpublished channel", app: "mysql", target: "8.0/edge", action: upgradeBlocked,
+			issues: []string{"blocker: charm mysql has no release in 8.0/edge, available tracks: 8.0, 8.4"},
+		},
+		{name: "local charm", app: "tool", action: upgradeSkipped},
+		{
+			name: "no charm information", app: "mysql", noInfo: true, action: upgradeSkipped,
+			issues: []string{"warning: unable to read the releases of charm mysql"},
+		},
+		{name: "latest revision", app: "mysql", status: withRevision(status.Applications["mysql"], 241), action: upgradeCurrent, revision: 241},
+		{
+			name: "revision newer than the channel", app: "mysql", status: withRevision(status.Applications["mysql"], 250), action: upgradeCurrent, revision: 241,
+			issues: []string{"warning: deployed revision 250 is newer than the latest release of 8.0/stable, revision 241"},
+		},
+		{name: "track without risk", app: "mysql", status: withRevision(status.Applications["mysql"], 241), target: "8.0", action: upgradeCurrent, revision: 241},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			app := status.Applications[tt.app]
+			if tt.status.Charm != "" {
+				app = tt.status
+			}
+			info := infos[app.CharmName]
+			if tt.noInfo {
+				info = nil
+			}
+
+			plan, issues := planApplication(tt.app, app, info, tt.target)
+
+			assert.Equal(t, tt.action, plan.Action, plan.Reason)
+			assert.Equal(t, tt.revision, plan.TargetRevision)
+			var got []string
+			for _, issue := range issues {
+				got = append(got, issue.Severity+": "+issue.Message)
+			}
+			assert.Equal(t, tt.issues, got)
+		})
+	}
+}
+
+func TestUpgradePlanApplicationsNamedDifferentlyFromCharms(t *testing.T) {
+	// Arrange
+	const status = `{"model": {"name": "prod"}, "applications": {
+		"db": {"charm": "mysql", "charm-name": "mysql", "charm-origin": "charmhub", "charm-rev": 196, "charm-channel": "8.0/stable",
+			"base": {"name": "ubuntu", "channel": "22.04"}, "application-status": {"current": "active"},
+			"relations": {"database": [{"related-application": "blog", "interface": "mysql_client"}]}},
+		"db-replica": {"charm": "mysql", "charm-name": "mysql", "charm-origin": "charmhub", "charm-rev": 196, "charm-channel": "8.0/stable",
+			"base": {"name": "ubuntu", "channel": "22.04"}, "application-status": {"current": "active"}},
+		"blog": {"charm": "wordpress", "charm-name": "wordpress", "charm-origin": "charmhub", "charm-rev": 10, "charm-channel": "stable",
+			"base": {"name": "ubuntu", "channel": "22.04"}, "application-status": {"current": "active"},
+			"relations": {"db": [{"related-application": "db", "interface": "mysql_client"}]}}
+	}}`
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		if call.name == string(CmdInfo) {
+			return upgradeInfo[call.args[0]]
+		}
+		return status
+	})
+
+	// Act
+	result := callTool(t, a, UpgradePlanToolName, map[string]any{"model": "prod"})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	var plan upgradePlan
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &plan))
+	actions := map[string]string{}
+	for _, app := range plan.Applications {
+		actions[app.Name] = app.Action
+	}
+	assert.Equal(t, map[string]string{"db": upgradeRefresh, "db-replica": upgradeRefresh, "blog": upgradeRefresh}, actions)
+	require.Len(t, plan.Steps, 3)
+	assert.Equal(t, []string{"db", "db-replica", "blog"},
+		[]string{plan.Steps[0].Application, plan.Steps[1].Application, plan.Steps[2].Application})
+	assert.Equal(t, []string{"db"}, plan.Steps[2].After)
+	assert.Len(t, factory.calls, 3)
+}
+
+func TestUpgradeOrderCycle(t *testing.T) {
+	var status modelStatus
+	require.NoError(t, json.Unmarshal([]byte(`{"applications": {
+		"a": {"relations": {"x": [{"related-application": "b", "interface": "x"}]}},
+		"b": {"relations": {"y": [{"related-application": "a", "interface": "y"}]}},
+		"c": {}
+	}}`), &status))
+	provides := func(endpoint string) *charmInfo {
+		info := &charmInfo{}
+		require.NoError(t, json.Unmarshal([]byte(`{"charm": {"relations": {"provides": {"`+endpoint+`": "`+endpoint+`"}}}}`), info))
+		return info
+	}
+
+	order, providers, warnings := upgradeOrder(status, map[string]*charmInfo{"a": provides("x"), "b": provides("y")})
+
+	assert.Equal(t, []string{"c", "a", "b"}, order)
+	assert.Equal(t, map[string][]string{"a": {"b"}, "b": {"a"}}, providers)
+	assert.Equal(t, []string{"the relations of a, b form a cycle, their order is arbitrary"}, warnings)
+}
@@ -0,0 +1,389 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/juju/juju/cmd/juju/waitfor/query"
+	"github.com/juju/names/v5"
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	WaitForStateToolName = "wait-for-state"
+
+	defaultWaitTimeout = 10 * time.Minute
+	// waitGrace is how long a wait-for call may outlive its own timeout
+	// before it is cancelled.
+	waitGrace = 30 * time.Second
+)
+
+// Outcomes of wait-for-state.
+const (
+	waitReached  = "reached"
+	waitTimedOut = "timed_out"
+)
+
+// Entity types wait-for-state can wait for.
+const (
+	entityApplication = "application"
+	entityUnit        = "unit"
+	entityMachine     = "machine"
+	entityModel       = "model"
+)
+
+// Status values accepted by the wait-for-state condition fields.
+var (
+	workloadStatuses  = []string{"active", "blocked", "maintenance", "waiting", "error", "unknown", "terminated"}
+	unitAgentStatuses = []string{"allocating", "executing", "idle", "error", "failed", "lost", "rebooting"}
+	machineStatuses   = []string{"pending", "started", "stopped", "error", "down"}
+	modelStatuses     = []string{"available", "busy", "error", "suspended"}
+)
+
+// waitCondition is the typed form of a wait-for query.
+type waitCondition struct {
+	EntityType     string
+	Name           string
+	Status         []string
+	WorkloadStatus []string
+	AgentStatus    []string
+	UnitCount      int // -1 when not set
+}
+
+// waitResult is the result of wait-for-state.
+type waitResult struct {
+	EntityType string          `json:"entity_type"`
+	Name       string          `json:"name"`
+	Model      string          `json:"model,omitempty"`
+	Query      string          `json:"query"`
+	Outcome    string          `json:"outcome"`
+	Waited     string          `json:"waited"`
+	Output     string          `json:"output,omitempty"`
+	LastState  json.RawMessage `json:"last_state,omitempty"`
+	StateError string          `json:"state_error,omitempty"`
+}
+
+// registerWaitFor adds the wait-for-state tool.
+func (a *adapter) registerWaitFor() {
+	a.builtins.addTool(mcp.NewTool(WaitForStateToolName,
+		mcp.WithDescription("Wait until an application, unit, machine or model reaches the given state, without writing a wait-for query. "+
+			"The fields are checked and compiled into a wait-for query; all of them must hold. Returns whether the state was reached "+
+			"or the timeout passed, the compiled query and the last observed state of the entity."),
+		mcp.WithString("entity_type",
+			mcp.Required(),
+			mcp.Description("Type of the entity to wait for"),
+			mcp.Enum(entityApplication, entityUnit, entityMachine, entityModel),
+		),
+		mcp.WithString("name",
+			mcp.Required(),
+			mcp.Description("Application name, unit name such as mysql/0, machine id or model name"),
+		),
+		mcp.WithString("model",
+			mcp.Description("Model of the entity, the current model if empty"),
+		),
+		mcp.WithArray("status",
+			mcp.Description("Accepted statuses of the entity itself: the application status, the workload status of a unit, "+
+				"the agent status of a machine or the model status"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("workload_status",
+			mcp.Description("Accepted workload statuses of the unit, or of every unit of the application or model"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithArray("agent_status",
+			mcp.Description("Accepted agent statuses of the unit or machine, or of every unit of the application or model"),
+			mcp.WithStringItems(),
+		),
+		mcp.WithNumber("unit_count",
+			mcp.Description("Exact number of units the application or model must have"),
+		),
+		mcp.WithString("timeout",
+			mcp.Description("How long to wait"),
+			mcp.DefaultString(defaultWaitTimeout.String()),
+		),
+	), a.handleWaitForState)
+}
+
+func (a *adapter) handleWaitForState(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	entityType, err := req.RequireString("entity_type")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	name, err := req.RequireString("name")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	timeout, err := time.ParseDuration(req.GetString("timeout", defaultWaitTimeout.String()))
+	if err != nil || timeout <= 0 {
+		return mcp.NewToolResultError(fmt.Sprintf("invalid timeout %q", req.GetString("timeout", ""))), nil
+	}
+	condition := waitCondition{
+		EntityType:     entityType,
+		Name:           name,
+		Status:         req.GetStringSlice("status", nil),
+		WorkloadStatus: req.GetStringSlice("workload_status", nil),
+		AgentStatus:    req.GetStringSlice("agent_status", nil),
+		UnitCount:      req.GetInt("unit_count", -1),
+	}
+	q, err := condition.compile()
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	model := req.GetString("model", "")
+
+	args := []string{entityType, name, "--query=" + q, "--timeout=" + timeout.String()}
+	if model != "" && entityType != entityModel {
+		args = append(args, "--model="+model)
+	}
+	waitCtx, cancel := context.WithTimeout(ctx, timeout+waitGrace)
+	defer cancel()
+	started := time.Now()
+	output, err := a.executeCommand(waitCtx, CommandExecutionConfig{CommandName: string(CmdWaitFor), Arguments: args})
+	result := waitResult{
+		EntityType: entityType,
+		Name:       name,
+		Model:      model,
+		Query:      q,
+		Outcome:    waitReached,
+		Waited:     time.Since(started).Round(time.Second).String(),
+		Output:     strings.TrimSpace(output),
+	}
+	switch {
+	case err == nil:
+	case ctx.Err() != nil:
+		// The client cancelled the call, the condition was not given the
+		// time to be reached.
+		return mcp.NewToolResultError(fmt.Sprintf("waiting for %s %s was cancelled: %v", entityType, name, context.Cause(ctx))), nil
+	case strings.Contains(err.Error(), "timed out waiting for") || waitCtx.Err() != nil:
+		result.Outcome = waitTimedOut
+	default:
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	state, err := a.entityState(ctx, entityType, name, model)
+	if err != nil {
+		result.StateError = err.Error()
+	}
+	result.LastState = state
+
+	encoded, err := json.MarshalIndent(result, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(encoded)), nil
+}
+
+// compile checks the condition and returns the wait-for query for it.
+func (c waitCondition) compile() (string, error) {
+	switch c.EntityType {
+	case entityApplication:
+		if !names.IsValidApplication(c.Name) {
+			return "", fmt.Errorf("%q is not a valid application name", c.Name)
+		}
+	case entityUnit:
+		if !names.IsValidUnit(c.Name) {
+			return "", fmt.Errorf("%q is not a valid unit name", c.Name)
+		}
+	case entityMachine:
+		if !names.IsValidMachine(c.Name) {
+			return "", fmt.Errorf("%q is not a valid machine id", c.Name)
+		}
+	case entityModel:
+		if _, model := splitModelName(c.Name); !names.IsValidModelName(model) {
+			return "", fmt.Errorf("%q is not a valid model name", c.Name)
+		}
+	default:
+		return "", fmt.Errorf("unknown entity type %q, expected application, unit, machine or model", c.EntityType)
+	}
+
+	hasUnits := c.EntityType == entityApplication || c.EntityType == entityModel
+	if c.UnitCount >= 0 && !hasUnits {
+		return "", fmt.Errorf("unit_count only applies to applications and models")
+	}
+	if len(c.WorkloadStatus) > 0 && c.EntityType == entityMachine {
+		return "", fmt.Errorf("machines have no workload status")
+	}
+
+	var statuses []string
+	switch c.EntityType {
+	case entityApplication, entityUnit:
+		statuses = workloadStatuses
+	case entityMachine:
+		statuses = machineStatuses
+	case entityModel:
+		statuses = modelStatuses
+	}
+	agentStatuses := unitAgentStatuses
+	if c.EntityType == entityMachine {
+		agentStatuses = machineStatuses
+	}
+	for _, check := range []struct {
+		field         string
+		values, valid []string
+	}{
+		{"status", c.Status, statuses},
+		{"workload_status", c.WorkloadStatus, workloadStatuses},
+		{"agent_status", c.AgentStatus, agentStatuses},
+	} {
+		for _, value := range check.values {
+			if !slices.Contains(check.valid, value) {
+				return "", fmt.Errorf("%s %q is not valid for entity type %s, expected one of: %s", check.field, value, c.EntityType, strings.Join(check.valid, ", "))
+			}
+		}
+	}
+
+	// A unit has no status of its own, its status is the workload status.
+	statusIdent, agentIdent := "status", "agent-status"
+	if c.EntityType == entityUnit {
+		statusIdent = "workload-status"
+	}
+	clauses := []string{`life=="alive"`}
+	if len(c.Status) > 0 {
+		clauses = append(clauses, anyOf("", statusIdent, c.Status))
+	}
+	if hasUnits {
+		if c.UnitCount >= 0 {
+			clauses = append(clauses, fmt.Sprintf("len(units)==%d", c.UnitCount))
+		}
+		if len(c.WorkloadStatus) > 0 {
+			clauses = append(clauses, "forEach(units, unit => "+anyOf("unit.", "workload-status", c.WorkloadStatus)+")")
+		}
+		if len(c.AgentStatus) > 0 {
+			clauses = append(clauses, "forEach(units, unit => "+anyOf("unit.", agentIdent, c.AgentStatus)+")")
+		}
+	} else {
+		if len(c.WorkloadStatus) > 0 {
+			clauses = append(clauses, anyOf("", "workload-status", c.WorkloadStatus))
+		}
+		if len(c.AgentStatus) > 0 {
+			clauses = append(clauses, anyOf("", agentIdent, c.AgentStatus))
+		}
+	}
+
+	q := strings.Join(clauses, " && ")
+	if _, err := query.Parse(q); err != nil {
+		return "", fmt.Errorf("invalid wait-for query %s: %w", q, err)
+	}
+	return q, nil
+}
+
+// anyOf returns a query clause that holds when the identifier has one of
+// the values.
+func anyOf(prefix string, ident string, values []string) string {
+	terms := make([]string, len(values))
+	for i, value := range values {
+		terms[i] = fmt.Sprintf("%s%s==%q", prefix, ident, value)
+	}
+	if len(terms) == 1 {
+		return terms[0]
+	}
+	return "(" + strings.Join(terms, " || ") + ")"
+}
+
+// splitModelName splits a [controller:]model name.
+func splitModelName(name string) (string, string) {
+	if controller, model, ok := strings.Cut(name, ":"); ok {
+		return controller, model
+	}
+	return "", name
+}
+
+// entityState returns the status of an entity as reported by juju status.
+func (a *adapter) entityState(ctx context.Context, entityType string, name string, model string) (json.RawMessage, error) {
+	var args []string
+	switch entityType {
+	case entityModel:
+		model = name
+	case entityUnit, entityApplication:
+		args = []string{name}
+	}
+	output, err := a.executeCommand(ctx, CommandExecutionConfig{
+		CommandName: string(CmdStatus),
+		Arguments:   args,
+		FixedFlags:  withModel(map[string]string{"format": "json"}, model),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var status map[string]json.RawMessage
+	if err := json.Unmarshal([]byte(output), &status); err != nil {
+		return nil, fmt.Errorf("failed to parse status: %w", err)
+	}
+	var entities map[string]json.RawMessage
+	if entityType != entityModel {
+		key := "applications"
+		if entityType == entityMachine {
+			key = "machines"
+		}
+		if err := json.Unmarshal(status[key], &entities); err != nil && status[key] != nil {
+			return nil, fmt.Errorf("failed to parse status %s: %w", key, err)
+		}
+	}
+
+	var state json.RawMessage
+	switch entityType {
+	case entityModel:
+		state = status["model"]
+	case entityApplication:
+		state = entities[name]
+	case entityMachine:
+		state = findMachine(entities, name)
+	case entityUnit:
+		state = findUnit(entities, name)
+	}
+	if state == nil {
+		return nil, fmt.Errorf("%s %s not found in the status", entityType, name)
+	}
+	return state, nil
+}
+
+// findMachine looks a machine or container up in the machines of the
+// status.
+func findMachine(machines map[string]json.RawMessage, id string) json.RawMessage {
+	if machine, ok := machines[id]; ok {
+		return machine
+	}
+	for _, machine := range machines {
+		var nested struct {
+			Containers map[string]json.RawMessage `json:"containers"`
+		}
+		if json.Unmarshal(machine, &nested) == nil && len(nested.Containers) > 0 {
+			if found := findMachine(nested.Containers, id); found != nil {
+				return found
+			}
+		}
+	}
+	return nil
+}
+
+// findUnit looks a principal or subordinate unit up in the applications of
+// the status.
+func findUnit(applications map[string]json.RawMessage, name string) json.RawMessage {
+	for _, app := range applications {
+		var parsed struct {
+			Units map[string]json.RawMessage `json:"units"`
+		}
+		if json.Unmarshal(app, &parsed) != nil {
+			continue
+		}
+		if unit, ok := parsed.Units[name]; ok {
+			return unit
+		}
+		for _, unit := range parsed.Units {
+			var principal struct {
+				Subordinates map[string]json.RawMessage `json:"subordinates"`
+			}
+			if json.Unmarshal(unit, &principal) == nil {
+				if sub, ok := principal.Subordinates[name]; ok {
+					return sub
+				}
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,164 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWaitCondition_Compile(t *testing.T) {
+	tests := []struct {
+		name      string
+		condition waitCondition
+		query     string
+		err       string
+	}{
+		{
+			name:      "application status and unit count",
+			condition: waitCondition{EntityType: "application", Name: "mysql", Status: []string{"active"}, UnitCount: 3},
+			query:     `life=="alive" && status=="active" && len(units)==3`,
+		},
+		{
+			name:      "every unit of an application",
+			condition: waitCondition{EntityType: "application", Name: "mysql", WorkloadStatus: []string{"active", "blocked"}, AgentStatus: []string{"idle"}, UnitCount: -1},
+			query:     `life=="alive" && forEach(units, unit => (unit.workload-status=="active" || unit.workload-status=="blocked")) && forEach(units, unit => unit.agent-status=="idle")`,
+		},
+		{
+			name:      "unit status is its workload status",
+			condition: waitCondition{EntityType: "unit", Name: "mysql/0", Status: []string{"active"}, AgentStatus: []string{"idle"}, UnitCount: -1},
+			query:     `life=="alive" && workload-status=="active" && agent-status=="idle"`,
+		},
+		{
+			name:      "machine",
+			condition: waitCondition{EntityType: "machine", Name: "0/lxd/1", Status: []string{"started"}, UnitCount: -1},
+			query:     `life=="alive" && status=="started"`,
+		},
+		{
+			name:      "model",
+			condition: waitCondition{EntityType: "model", Name: "admin:prod", Status: []string{"available"}, UnitCount: 0},
+			query:     `life=="alive" && status=="available" && len(units)==0`,
+		},
+		{
+			name:      "invalid name",
+			condition: waitCondition{EntityType: "unit", Name: "mysql", UnitCount: -1},
+			err:       `"mysql" is not a valid unit name`,
+		},
+		{
+			name:      "invalid status",
+			condition: waitCondition{EntityType: "machine", Name: "0", Status: []string{"active"}, UnitCount: -1},
+			err:       `status "active" is not valid for entity type machine, expected one of: pending, started, stopped, error, down`,
+		},
+		{
+			name:      "unit count of a unit",
+			condition: waitCondition{EntityType: "unit", Name: "mysql/0", UnitCount: 1},
+			err:       "unit_count only applies to applications and models",
+		},
+		{
+			name:      "workload status of a machine",
+			condition: waitCondition{EntityType: "machine", Name: "0", WorkloadStatus: []string{"active"}, UnitCount: -1},
+			err:       "machines have no workload status",
+		},
+		{
+			name:      "unknown entity type",
+			condition: waitCondition{EntityType: "controller", Name: "lxd", UnitCount: -1},
+			err:       `unknown entity type "controller", expected application, unit, machine or model`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			q, err := tt.condition.compile()
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.query, q)
+		})
+	}
+}
+
+const waitForStatus = `{
+	"model": {"name": "prod", "type": "iaas"},
+	"machines": {"0": {"juju-status": {"current": "started"}, "containers": {"0/lxd/0": {"juju-status": {"current": "pending"}}}}},
+	"applications": {"mysql": {"application-status": {"current": "waiting"}, "units": {
+		"mysql/0": {"workload-status": {"current": "waiting"}, "juju-status": {"current": "executing"},
+			"subordinates": {"telegraf/0": {"workload-status": {"current": "active"}}}}}}}
+}`
+
+func TestWaitForState(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(func(call fakeCall) string {
+		if call.name == "wait-for" {
+			return "properties:\n  life: alive\n  workload-status: active"
+		}
+		return waitForStatus
+	})
+
+	// Act
+	result := callTool(t, a, WaitForStateToolName, map[string]any{
+		"entity_type":     "unit",
+		"name":            "mysql/0",
+		"model":           "prod",
+		"workload_status": []any{"active"},
+		"timeout":         "5m",
+	})
+
+	// Assert
+	require.False(t, result.IsError, resultText(result))
+	var got waitResult
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &got))
+	assert.Equal(t, waitReached, got.Outcome)
+	assert.Equal(t, `life=="alive" && workload-status=="active"`, got.Query)
+	assert.Contains(t, got.Output, "workload-status: active")
+	assert.JSONEq(t, `{"workload-status": {"current": "waiting"}, "juju-status": {"current": "executing"},
+		"subordinates": {"telegraf/0": {"workload-status": {"current": "active"}}}}`, string(got.LastState))
+	assert.Equal(t, []string{"unit", "mysql/0", `--query=life=="alive" && workload-status=="active"`, "--timeout=5m0s", "--model=prod"}, factory.calls[0].args)
+	assert.Equal(t, map[string]string{"format": "json", "model": "prod"}, factory.calls[1].flags)
+}
+
+func TestWaitForState_TimesOut(t *testing.T) {
+	a, factory := newFakeAdapter(func(call fakeCall) string { return waitForStatus })
+	factory.fail = func(call fakeCall) error {
+		if call.name == "wait-for" {
+			return errors.New(`timed out waiting for "0/lxd/0" to reach goal state`)
+		}
+		return nil
+	}
+
+	result := callTool(t, a, WaitForStateToolName, map[string]any{"entity_type": "machine", "name": "0/lxd/0", "status": []any{"started"}})
+
+	require.False(t, result.IsError, resultText(result))
+	var got waitResult
+	require.NoError(t, json.Unmarshal([]byte(resultText(result)), &got))
+	assert.Equal(t, waitTimedOut, got.Outcome)
+	assert.JSONEq(t, `{"juju-status": {"current": "pending"}}`, string(got.LastState))
+	assert.Empty(t, got.StateError)
+}
+
+func TestWaitForState_Cancelled(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	a, factory := newFakeAdapter(func(call fakeCall) string { return waitForStatus })
+	factory.fail = func(call fakeCall) error {
+		cancel()
+		return context.Canceled
+	}
+
+	result := callToolContext(t, ctx, a, WaitForStateToolName, map[string]any{"entity_type": "machine", "name": "0/lxd/0", "status": []any{"started"}})
+
+	assert.True(t, result.IsError)
+	assert.Equal(t, "waiting for machine 0/lxd/0 was cancelled: context canceled", resultText(result))
+}
+
+func TestWaitForState_RejectsInvalidConditions(t *testing.T) {
+	a, factory := newFakeAdapter(nil)
+
+	result := callTool(t, a, WaitForStateToolName, map[string]any{"entity_type": "application", "name": "mysql", "agent_status": []any{"started"}})
+
+	assert.True(t, result.IsError)
+	assert.Contains(t, resultText(result), `agent_status "started" is not valid for entity type application`)
+	assert.Empty(t, factory.calls)
+}
@@ -0,0 +1,614 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"mime"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/mark3labs/mcp-go/mcp"
+)
+
+const (
+	WorkspaceUploadToolName   = "workspace-upload"
+	WorkspaceListToolName     = "workspace-list"
+	WorkspaceDownloadToolName = "workspace-download"
+	workspaceTemplateName     = "workspace-file"
+	workspaceURI              = "juju://workspace/"
+)
+
+var errNoWorkspace = errors.New("the workspace is disabled, start the server with --workspace-dir to transfer files")
+
+// workspace is the directory clients upload files to and download the
+// files produced by commands from. When it is set, the local paths given to
+// commands are resolved inside it and may not leave it.
+type workspace struct {
+	root string
+}
+
+// newWorkspace creates the workspace directory if needed.
+func newWorkspace(dir string) (*workspace, error) {
+	root, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, fmt.Errorf("invalid workspace directory: %w", err)
+	}
+	if err := os.MkdirAll(root, 0o700); err != nil {
+		return nil, fmt.Errorf("unable to create the workspace directory: %w", err)
+	}
+	// Compare paths against the real location of the workspace.
+	if root, err = filepath.EvalSymlinks(root); err != nil {
+		return nil, fmt.Errorf("invalid workspace directory: %w", err)
+	}
+	return &workspace{root: root}, nil
+}
+
+// resolve returns the absolute path of a workspace path. Relative paths are
+// relative to the workspace; absolute paths and symbolic links must stay
+// inside it.
+func (w *workspace) resolve(name string) (string, error) {
+	if name == "" {
+		return "", errors.New("empty path")
+	}
+	full := filepath.Clean(name)
+	if !filepath.IsAbs(full) {
+		full = filepath.Join(w.root, full)
+	}
+	if !w.contains(full) {
+		return "", fmt.Errorf("path %q is outside the workspace", name)
+	}
+	// Follow the symbolic links of the part of the path that exists.
+	existing := full
+	for {
+		real, err := filepath.EvalSymlinks(existing)
+		if err == nil {
+			if !w.contains(real) {
+				return "", fmt.Errorf("path %q is outside the workspace", name)
+			}
+			break
+		}
+		if existing == w.root {
+			break
+		}
+		existing = filepath.Dir(existing)
+	}
+	return full, nil
+}
+
+func (w *workspace) contains(full string) bool {
+	rel, err := filepath.Rel(w.root, full)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}
+
+// rel returns the workspace path of an absolute path inside it.
+func (w *workspace) rel(full string) string {
+	rel, err := filepath.Rel(w.root, full)
+	if err != nil {
+		return full
+	}
+	return filepath.ToSlash(rel)
+}
+
+// commandPaths confines the local paths of the commands that read or write
+// files to the workspace.
+var commandPaths = map[JujuCommandID]func(w *workspace, config *CommandExecutionConfig) error{
+	CmdScp: func(w *workspace, config *CommandExecutionConfig) error {
+		// Remote paths are written as target:path.
+		for i, arg := range config.Arguments {
+			if strings.HasPrefix(arg, "-") || strings.Contains(arg, ":") {
+				continue
+			}
+			if err := w.confineArgument(config, i); err != nil {
+				return err
+			}
+		}
+		return nil
+	},
+	CmdDeploy: func(w *workspace, config *CommandExecutionConfig) error {
+		if len(config.Arguments) > 0 && isLocalCharm(config.Arguments[0]) {
+			if err := w.confineArgument(config, 0); err != nil {
+				return err
+			}
+		}
+		if err := w.confineFlag(config, "overlay", nil); err != nil {
+			return err
+		}
+		if err := w.confineFlag(config, "resource", resourcePath); err != nil {
+			return err
+		}
+		return w.confineFlag(config, "config", configPath)
+	},
+	CmdRefresh: func(w *workspace, config *CommandExecutionConfig) error {
+		if err := w.confineFlag(config, "path", nil); err != nil {
+			return err
+		}
+		if err := w.confineFlag(config, "resource", resourcePath); err != nil {
+			return err
+		}
+		return w.confineFlag(config, "config", configPath)
+	},
+	CmdDiffBundle: func(w *workspace, config *CommandExecutionConfig) error {
+		if len(config.Arguments) > 0 && isLocalCharm(config.Arguments[0]) {
+			if err := w.confineArgument(config, 0); err != nil {
+				return err
+			}
+		}
+		return w.confineFlag(config, "overlay", nil)
+	},
+	CmdAttachResource: func(w *workspace, config *CommandExecutionConfig) error {
+		for i, arg := range config.Arguments {
+			name, file, ok := strings.Cut(arg, "=")
+			if !ok || isRevision(file) {
+				continue
+			}
+			resolved, err := w.resolve(file)
+			if err != nil {
+				return err
+			}
+			config.Arguments[i] = name + "=" + resolved
+		}
+		return nil
+	},
+	CmdAddCloud: func(w *workspace, config *CommandExecutionConfig) error {
+		// The cloud definition may also be given after the cloud name.
+		if len(config.Arguments) > 1 {
+			if err := w.confineArgument(config, 1); err != nil {
+				return err
+			}
+		}
+		return confineFileFlag(w, config)
+	},
+	CmdAddSecret:    confineSecretFiles,
+	CmdUpdateSecret: confineSecretFiles,
+	CmdAddModel: func(w *workspace, config *CommandExecutionConfig) error {
+		return w.confineFlag(config, "config", configPath)
+	},
+	CmdBootstrap: func(w *workspace, config *CommandExecutionConfig) error {
+		if err := w.confineFlag(config, "config", configPath); err != nil {
+			return err
+		}
+		return w.confineFlag(config, "model-default", configPath)
+	},
+	CmdAddSecretBackend:    confineNamedFlag("config"),
+	CmdUpdateSecretBackend: confineNamedFlag("config"),
+	CmdRun:                 confineNamedFlag("params"),
+	CmdSyncAgentBinary:     confineNamedFlag("source"),
+	CmdExportBundle:        confineNamedFlag("filename"),
+	// The positional arguments of the configuration commands are keys and
+	// key=value pairs, files are only read with --file.
+	CmdConfig:           confineNamedFlag("file"),
+	CmdModelConfig:      confineNamedFlag("file"),
+	CmdModelDefaults:    confineNamedFlag("file"),
+	CmdControllerConfig: confineNamedFlag("file"),
+	CmdAddCredential:    confineFileFlag,
+	CmdUpdateCredential: confineFileFlag,
+	CmdUpdateCloud:      confineFileFlag,
+	CmdUpdateK8s:        confineFileFlag,
+	CmdDownloadBackup:   confineOutput,
+	CmdCreateBackup:     confineOutput,
+	CmdDownload:         confineOutput,
+}
+
+// confineNamedFlag confines a flag whose value is always a path.
+func confineNamedFlag(name string) func(w *workspace, config *CommandExecutionConfig) error {
+	return func(w *workspace, config *CommandExecutionConfig) error {
+		return w.confineFlag(config, name, nil)
+	}
+}
+
+// confineSecretFiles confines the --file flag of a secret and the values of
+// its key#file=path arguments.
+func confineSecretFiles(w *workspace, config *CommandExecutionConfig) error {
+	for i, arg := range config.Arguments {
+		key, file, ok := strings.Cut(arg, "=")
+		if !ok || !strings.HasSuffix(key, "#file") {
+			continue
+		}
+		resolved, err := w.resolve(file)
+		if err != nil {
+			return err
+		}
+		config.Arguments[i] = key + "=" + resolved
+	}
+	return w.confineFlag(config, "file", nil)
+}
+
+// resourcePath splits a --resource value, name=path or name=revision.
+func resourcePath(value string) (string, string, bool) {
+	name, file, ok := strings.Cut(value, "=")
+	return name + "=", file, ok && !isRevision(file)
+}
+
+// configPath splits a --config value, a file or key=value pairs.
+func configPath(value string) (string, string, bool) {
+	return "", value, !strings.Contains(value, "=")
+}
+
+func confineFileFlag(w *workspace, config *CommandExecutionConfig) error {
+	for _, name := range []string{"f", "file"} {
+		if err := w.confineFlag(config, name, nil); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// confine rewrites the local paths of a command to paths inside the
+// workspace, failing if one is outside it.
+func (w *workspace) confine(config *CommandExecutionConfig) error {
+	confine, ok := commandPaths[JujuCommandID(config.CommandName)]
+	if !ok {
+		return nil
+	}
+	// Work on copies, the caller may reuse its configuration.
+	config.Arguments = append([]string(nil), config.Arguments...)
+	fixed := make(map[string]string, len(config.FixedFlags))
+	for k, v := range config.FixedFlags {
+		fixed[k] = v
+	}
+	config.FixedFlags = fixed
+	values := make(map[string]interface{}, len(config.FlagValues))
+	for k, v := range config.FlagValues {
+		values[k] = v
+	}
+	config.FlagValues = values
+	return confine(w, config)
+}
+
+func (w *workspace) confineArgument(config *CommandExecutionConfig, i int) error {
+	resolved, err := w.resolve(config.Arguments[i])
+	if err != nil {
+		return err
+	}
+	config.Arguments[i] = resolved
+	return nil
+}
+
+// confineFlag resolves the paths of a flag, which may be repeated. split
+// returns the part of a value before the path, the path, and whether the
+// value is a path at all; without it the whole value is a path.
+func (w *workspace) confineFlag(config *CommandExecutionConfig, name string, split func(string) (string, string, bool)) error {
+	resolve := func(value string) (string, error) {
+		prefix, file, ok := "", value, true
+		if split != nil {
+			prefix, file, ok = split(value)
+		}
+		if !ok || value == "" {
+			return value, nil
+		}
+		resolved, err := w.resolve(file)
+		if err != nil {
+			return "", err
+		}
+		return prefix + resolved, nil
+	}
+
+	if value, ok := config.FixedFlags[name]; ok {
+		resolved, err := resolve(value)
+		if err != nil {
+			return err
+		}
+		config.FixedFlags[name] = resolved
+	}
+	switch value := config.FlagValues[name].(type) {
+	case string:
+		resolved, err := resolve(value)
+		if err != nil {
+			return err
+		}
+		config.FlagValues[name] = resolved
+	case []string:
+		resolved := make([]string, len(value))
+		for i, v := range value {
+			r, err := resolve(v)
+			if err != nil {
+				return err
+			}
+			resolved[i] = r
+		}
+		config.FlagValues[name] = resolved
+	}
+	return nil
+}
+
+// confineOutput resolves the file a command writes, which defaults to the
+// workspace (see defaultOutput).
+func confineOutput(w *workspace, config *CommandExecutionConfig) error {
+	return w.confineFlag(config, artifactOutputs[JujuCommandID(config.CommandName)], nil)
+}
+
+// isLocalCharm reports whether a deploy argument is a charm or bundle file
+// rather than a Charmhub name.
+func isLocalCharm(arg string) bool {
+	if strings.HasPrefix(arg, ".") || strings.HasPrefix(arg, "/") || strings.HasPrefix(arg, "~") {
+		return true
+	}
+	switch filepath.Ext(arg) {
+	case ".charm", ".yaml", ".yml", ".zip":
+		return true
+	}
+	return false
+}
+
+func isRevision(value string) bool {
+	if value == "" {
+		return false
+	}
+	for _, r := range value {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// workspaceFile describes a file of the workspace.
+type workspaceFile struct {
+	Path     string    `json:"path"`
+	URI      string    `json:"uri"`
+	Size     int64     `json:"size"`
+	MIMEType string    `json:"mime_type"`
+	Modified time.Time `json:"modified"`
+}
+
+func (w *workspace) describe(full string, info fs.FileInfo) workspaceFile {
+	rel := w.rel(full)
+	return workspaceFile{
+		Path:     rel,
+		URI:      workspaceURI + rel,
+		Size:     info.Size(),
+		MIMEType: mimeType(rel),
+		Modified: info.ModTime().UTC(),
+	}
+}
+
+// mimeType guesses the MIME type of a file from its name.
+func mimeType(name string) string {
+	switch {
+	case strings.HasSuffix(name, ".charm"), strings.HasSuffix(name, ".zip"):
+		return "application/zip"
+	case strings.HasSuffix(name, ".tar.gz"), strings.HasSuffix(name, ".tgz"):
+		return "application/gzip"
+	case strings.HasSuffix(name, ".yaml"), strings.HasSuffix(name, ".yml"):
+		return "application/yaml"
+	}
+	if t := mime.TypeByExtension(filepath.Ext(name)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+// registerWorkspace adds the tools and the resource template to transfer
+// files through the workspace.
+func (a *adapter) registerWorkspace() {
+	a.builtins.addTool(mcp.NewTool(WorkspaceUploadToolName,
+		mcp.WithDescription("Upload a file to the workspace, for commands that read local files such as deploy of a local charm "+
+			"or bundle, attach-resource, scp, add-cloud, add-credential, add-secret, model-config --file and bootstrap --config. Give the content as base64, as text, "+
+			"or as an embedded resource. Relative paths given to those commands are resolved in the workspace."),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Path of the file in the workspace, such as charms/mysql.charm"),
+		),
+		mcp.WithString("content",
+			mcp.Description("Base64 encoded content"),
+		),
+		mcp.WithString("text",
+			mcp.Description("Text content, such as a bundle"),
+		),
+		mcp.WithObject("resource",
+			mcp.Description("Embedded resource holding the content, with a text or base64 blob field"),
+		),
+		mcp.WithBoolean("overwrite",
+			mcp.Description("Replace the file if it exists"),
+		),
+	), a.handleWorkspaceUpload)
+
+	a.builtins.addTool(mcp.NewTool(WorkspaceListToolName,
+		mcp.WithDescription("List the files of the workspace, the uploaded ones and those produced by commands such as "+
+			"download, create-backup, download-backup and scp, with their size and resource URI."),
+		mcp.WithString("dir",
+			mcp.Description("Directory of the workspace to list, the whole workspace if empty"),
+		),
+	), a.handleWorkspaceList)
+
+	a.builtins.addTool(mcp.NewTool(WorkspaceDownloadToolName,
+		mcp.WithDescription("Download a file of the workspace as an embedded blob resource. "+
+			"The file can also be read as the resource juju://workspace/{path}."),
+		mcp.WithString("path",
+			mcp.Required(),
+			mcp.Description("Path of the file in the workspace"),
+		),
+	), a.handleWorkspaceDownload)
+
+	a.builtins.addTemplate(workspaceTemplateName, mcp.NewResourceTemplate(
+		workspaceURI+"{+path}",
+		workspaceTemplateName,
+		mcp.WithTemplateDescription("A file of the workspace, as a base64 blob"),
+	), func(ctx context.Context, req mcp.ReadResourceRequest) ([]mcp.ResourceContents, error) {
+		if a.workspace == nil {
+			return nil, errNoWorkspace
+		}
+		contents, err := a.workspace.read(strings.TrimPrefix(req.Params.URI, workspaceURI))
+		if err != nil {
+			return nil, err
+		}
+		return []mcp.ResourceContents{contents}, nil
+	})
+}
+
+func (a *adapter) handleWorkspaceUpload(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if a.workspace == nil {
+		return mcp.NewToolResultError(errNoWorkspace.Error()), nil
+	}
+	name, err := req.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	data, err := uploadContent(req)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	full, err := a.workspace.resolve(name)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(full), 0o700); err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("unable to create the directory: %v", err)), nil
+	}
+	flags := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+	if !req.GetBool("overwrite", false) {
+		flags |= os.O_EXCL
+	}
+	f, err := os.OpenFile(full, flags, 0o600)
+	if errors.Is(err, fs.ErrExist) {
+		return mcp.NewToolResultError(fmt.Sprintf("%s already exists, set overwrite to replace it", name)), nil
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("unable to write %s: %v", name, err)), nil
+	}
+	_, err = f.Write(data)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("unable to write %s: %v", name, err)), nil
+	}
+
+	info, err := os.Stat(full)
+	if err != nil {
+		return nil, err
+	}
+	return jsonToolResult(struct {
+		workspaceFile
+		LocalPath string `json:"local_path"`
+	}{a.workspace.describe(full, info), full})
+}
+
+// uploadContent returns the content of an upload from whichever argument
+// carries it.
+func uploadContent(req mcp.CallToolRequest) ([]byte, error) {
+	arguments := req.GetArguments()
+	var given []string
+	for _, name := range []string{"content", "text", "resource"} {
+		if value, ok := arguments[name]; ok && value != nil && value != "" {
+			given = append(given, name)
+		}
+	}
+	if len(given) != 1 {
+		return nil, errors.New("exactly one of content, text or resource is required")
+	}
+
+	switch given[0] {
+	case "content":
+		data, err := base64.StdEncoding.DecodeString(req.GetString("content", ""))
+		if err != nil {
+			return nil, fmt.Errorf("content is not valid base64: %w", err)
+		}
+		return data, nil
+	case "text":
+		return []byte(req.GetString("text", "")), nil
+	}
+
+	resource, ok := arguments["resource"].(map[string]any)
+	if !ok {
+		return nil, errors.New("resource must be an object")
+	}
+	// Accept an embedded resource as well as its contents.
+	if inner, ok := resource["resource"].(map[string]any); ok {
+		resource = inner
+	}
+	contents, err := mcp.ParseResourceContents(resource)
+	if err != nil {
+		return nil, fmt.Errorf("invalid resource: %w", err)
+	}
+	switch c := contents.(type) {
+	case mcp.TextResourceContents:
+		return []byte(c.Text), nil
+	case mcp.BlobResourceContents:
+		data, err := base64.StdEncoding.DecodeString(c.Blob)
+		if err != nil {
+			return nil, fmt.Errorf("resource blob is not valid base64: %w", err)
+		}
+		return data, nil
+	}
+	return nil, errors.New("resource has neither text nor blob")
+}
+
+func (a *adapter) handleWorkspaceList(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if a.workspace == nil {
+		return mcp.NewToolResultError(errNoWorkspace.Error()), nil
+	}
+	dir, err := a.workspace.resolve(req.GetString("dir", "."))
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+
+	files := []workspaceFile{}
+	err = filepath.WalkDir(dir, func(full string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		files = append(files, a.workspace.describe(full, info))
+		return nil
+	})
+	if err != nil {
+		return mcp.NewToolResultError(fmt.Sprintf("unable to list the workspace: %v", err)), nil
+	}
+	return jsonToolResult(files)
+}
+
+func (a *adapter) handleWorkspaceDownload(ctx context.Context, req mcp.CallToolRequest) (*mcp.CallToolResult, error) {
+	if a.workspace == nil {
+		return mcp.NewToolResultError(errNoWorkspace.Error()), nil
+	}
+	name, err := req.RequireString("path")
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	contents, err := a.workspace.read(name)
+	if err != nil {
+		return mcp.NewToolResultError(err.Error()), nil
+	}
+	return mcp.NewToolResultResource(fmt.Sprintf("%s (%s)", contents.URI, contents.MIMEType), contents), nil
+}
+
+// read returns the content of a workspace file as a blob.
+func (w *workspace) read(name string) (mcp.BlobResourceContents, error) {
+	full, err := w.resolve(name)
+	if err != nil {
+		return mcp.BlobResourceContents{}, err
+	}
+	data, err := os.ReadFile(full)
+	if err != nil {
+		return mcp.BlobResourceContents{}, fmt.Errorf("unable to read %s: %w", name, err)
+	}
+	rel := w.rel(full)
+	return mcp.BlobResourceContents{
+		URI:      workspaceURI + rel,
+		MIMEType: mimeType(rel),
+		Blob:     base64.StdEncoding.EncodeToString(data),
+	}, nil
+}
+
+// jsonToolResult returns v as indented JSON text.
+func jsonToolResult(v any) (*mcp.CallToolResult, error) {
+	encoded, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return mcp.NewToolResultText(string(encoded)), nil
+}
@@ -0,0 +1,264 @@
+package jujuadapter
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/mark3labs/mcp-go/mcp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func newTestWorkspace(t *testing.T) *workspace {
+	w, err := newWorkspace(filepath.Join(t.TempDir(), "workspace"))
+	require.NoError(t, err)
+	return w
+}
+
+func TestWorkspace_Resolve(t *testing.T) {
+	w := newTestWorkspace(t)
+	outside := t.TempDir()
+	require.NoError(t, os.Symlink(outside, filepath.Join(w.root, "escape")))
+
+	tests := []struct {
+		name string
+		path string
+		want string
+		err  string
+	}{
+		{name: "relative", path: "charms/mysql.charm", want: filepath.Join(w.root, "charms/mysql.charm")},
+		{name: "dot slash", path: "./bundle.yaml", want: filepath.Join(w.root, "bundle.yaml")},
+		{name: "absolute inside", path: filepath.Join(w.root, "a.txt"), want: filepath.Join(w.root, "a.txt")},
+		{name: "parent", path: "../secrets", err: `path "../secrets" is outside the workspace`},
+		{name: "absolute outside", path: "/etc/passwd", err: `path "/etc/passwd" is outside the workspace`},
+		{name: "symbolic link", path: "escape/id_rsa", err: `path "escape/id_rsa" is outside the workspace`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := w.resolve(tt.path)
+			if tt.err != "" {
+				assert.EqualError(t, err, tt.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tt.want, got)
+		})
+	}
+}
+
+func TestWorkspace_ConfinesCommandPaths(t *testing.T) {
+	// Arrange
+	a, factory := newFakeAdapter(nil)
+	factory.repeatable = []string{"config"}
+	a.workspace = newTestWorkspace(t)
+	root := a.workspace.root
+
+	// Act
+	_, deployErr := a.executeCommand(context.Background(), CommandExecutionConfig{
+		CommandName: string(CmdDeploy),
+		Arguments:   []string{"./mysql.charm"},
+		FlagValues:  map[string]any{"config": []string{"profile=testing", "config.yaml"}},
+	})
+	_, scpErr := a.executeCommand(context.Background(), CommandExecutionConfig{
+		CommandName: string(CmdScp),
+		Arguments:   []string{"mysql/0:/var/log/syslog", "logs/"},
+	})
+	_, charmhubErr := a.executeCommand(context.Background(), CommandExecutionConfig{
+		CommandName: string(CmdDeploy),
+		Arguments:   []string{"mysql"},
+	})
+	_, outsideErr := a.executeCommand(context.Background(), CommandExecutionConfig{
+		CommandName: string(CmdAddCredential),
+		Arguments:   []string{"aws"},
+		FixedFlags:  map[string]string{"file": "/root/.aws/credentials"},
+	})
+
+	// Assert
+	require.NoError(t, deployErr)
+	require.NoError(t, scpErr)
+	require.NoError(t, charmhubErr)
+	assert.EqualError(t, outsideErr, `command 'add-credential': path "/root/.aws/credentials" is outside the workspace`)
+	require.Len(t, factory.calls, 3)
+	assert.Equal(t, []string{filepath.Join(root, "mysql.charm")}, factory.calls[0].args)
+	assert.Equal(t, "profile=testing,"+filepath.Join(root, "config.yaml"), factory.calls[0].flags["config"])
+	assert.Equal(t, []string{"mysql/0:/var/log/syslog", filepath.Join(root, "logs")}, factory.calls[1].args)
+	assert.Equal(t, []string{"mysql"}, factory.calls[2].args)
+}
+
+func TestWorkspace_CommandPaths(t *testing.T) {
+	w := newTestWorkspace(t)
+	in := func(name string) string { return filepath.Join(w.root, name) }
+
+	tests := []struct {
+		command   JujuCommandID
+		args      []string
+		flags     map[string]any
+		wantArgs  []string
+		wantFlags map[string]any
+	}{
+		{command: CmdScp, args: []string{"mysql/0:/var/log/syslog", "logs/"}, wantArgs: []string{"mysql/0:/var/log/syslog", in("logs")}},
+		{
+			command:   CmdDeploy,
+			args:      []string{"./bundle.yaml"},
+			flags:     map[string]any{"overlay": []string{"overlay.yaml"}, "resource": []string{"image=image.tar", "data=3"}},
+			wantArgs:  []string{in("bundle.yaml")},
+			wantFlags: map[string]any{"overlay": []string{in("overlay.yaml")}, "resource": []string{"image=" + in("image.tar"), "data=3"}},
+		},
+		{
+			command:   CmdRefresh,
+			args:      []string{"mysql"},
+			flags:     map[string]any{"path": "mysql.charm", "config": []string{"config.yaml", "profile=testing"}},
+			wantArgs:  []string{"mysql"},
+			wantFlags: map[string]any{"path": in("mysql.charm"), "config": []string{in("config.yaml"), "profile=testing"}},
+		},
+		{
+			command:   CmdDiffBundle,
+			args:      []string{"bundle.yaml"},
+			flags:     map[string]any{"overlay": []string{"overlay.yaml"}},
+			wantArgs:  []string{in("bundle.yaml")},
+			wantFlags: map[string]any{"overlay": []string{in("overlay.yaml")}},
+		},
+		{command: CmdDiffBundle, args: []string{"mysql-bundle"}, wantArgs: []string{"mysql-bundle"}},
+		{command: CmdAttachResource, args: []string{"mysql", "image=image.tar"}, wantArgs: []string{"mysql", "image=" + in("image.tar")}},
+		{command: CmdAddCloud, args: []string{"maas", "clouds.yaml"}, wantArgs: []string{"maas", in("clouds.yaml")}},
+		{command: CmdAddCloud, args: []string{"maas"}, flags: map[string]any{"f": "clouds.yaml"}, wantArgs: []string{"maas"}, wantFlags: map[string]any{"f": in("clouds.yaml")}},
+		{command: CmdUpdateCloud, args: []string{"maas"}, flags: map[string]any{"f": "clouds.yaml"}, wantArgs: []string{"maas"}, wantFlags: map[string]any{"f": in("clouds.yaml")}},
+		{command: CmdUpdateK8s, args: []string{"k8s"}, flags: map[string]any{"f": "k8s.yaml"}, wantArgs: []string{"k8s"}, wantFlags: map[string]any{"f": in("k8s.yaml")}},
+		{command: CmdAddCredential, args: []string{"aws"}, flags: map[string]any{"file": "creds.yaml"}, wantArgs: []string{"aws"}, wantFlags: map[string]any{"file": in("creds.yaml")}},
+		{command: CmdUpdateCredential, args: []string{"aws"}, flags: map[string]any{"f": "creds.yaml"}, wantArgs: []string{"aws"}, wantFlags: map[string]any{"f": in("creds.yaml")}},
+		{
+			command:  CmdAddSecret,
+			args:     []string{"db-pass", "cert#file=tls.crt", "password=s3cret"},
+			wantArgs: []string{"db-pass", "cert#file=" + in("tls.crt"), "password=s3cret"},
+		},
+		{command: CmdUpdateSecret, args: []string{"db-pass"}, flags: map[string]any{"file": "secret.yaml"}, wantArgs: []string{"db-pass"}, wantFlags: map[string]any{"file": in("secret.yaml")}},
+		{command: CmdAddSecretBackend, args: []string{"vault", "vault"}, flags: map[string]any{"config": "vault.yaml"}, wantArgs: []string{"vault", "vault"}, wantFlags: map[string]any{"config": in("vault.yaml")}},
+		{command: CmdUpdateSecretBackend, args: []string{"vault"}, flags: map[string]any{"config": "vault.yaml"}, wantArgs: []string{"vault"}, wantFlags: map[string]any{"config": in("vault.yaml")}},
+		{
+			command:   CmdAddModel,
+			args:      []string{"dev"},
+			flags:     map[string]any{"config": []string{"model.yaml", "logging-config=<root>=DEBUG"}},
+			wantArgs:  []string{"dev"},
+			wantFlags: map[string]any{"config": []string{in("model.yaml"), "logging-config=<root>=DEBUG"}},
+		},
+		{
+			command:   CmdBootstrap,
+			args:      []string{"lxd"},
+			flags:     map[string]any{"config": []string{"controller.yaml"}, "model-default": []string{"defaults.yaml"}},
+			wantArgs:  []string{"lxd"},
+			wantFlags: map[string]any{"config": []string{in("controller.yaml")}, "model-default": []string{in("defaults.yaml")}},
+		},
+		{command: CmdConfig, args: []string{"mysql"}, flags: map[string]any{"file": "mysql.yaml"}, wantArgs: []string{"mysql"}, wantFlags: map[string]any{"file": in("mysql.yaml")}},
+		{command: CmdModelConfig, args: []string{"logging-config"}, flags: map[string]any{"file": "model.yaml"}, wantArgs: []string{"logging-config"}, wantFlags: map[string]any{"file": in("model.yaml")}},
+		{command: CmdModelDefaults, flags: map[string]any{"file": "defaults.yaml"}, wantFlags: map[string]any{"file": in("defaults.yaml")}},
+		{command: CmdControllerConfig, flags: map[string]any{"file": "controller.yaml"}, wantFlags: map[string]any{"file": in("controller.yaml")}},
+		{command: CmdRun, args: []string{"mysql/0", "backup"}, flags: map[string]any{"params": "params.yaml"}, wantArgs: []string{"mysql/0", "backup"}, wantFlags: map[string]any{"params": in("params.yaml")}},
+		{command: CmdSyncAgentBinary, flags: map[string]any{"source": "agents"}, wantFlags: map[string]any{"source": in("agents")}},
+		{command: CmdExportBundle, flags: map[string]any{"filename": "bundle.yaml"}, wantFlags: map[string]any{"filename": in("bundle.yaml")}},
+		{command: CmdCreateBackup, flags: map[string]any{"filename": "backup.tar.gz"}, wantFlags: map[string]any{"filename": in("backup.tar.gz")}},
+		{command: CmdDownloadBackup, args: []string{"backup"}, flags: map[string]any{"filename": "backup.tar.gz"}, wantArgs: []string{"backup"}, wantFlags: map[string]any{"filename": in("backup.tar.gz")}},
+		{command: CmdDownload, args: []string{"mysql"}, flags: map[string]any{"filepath": "mysql.charm"}, wantArgs: []string{"mysql"}, wantFlags: map[string]any{"filepath": in("mysql.charm")}},
+	}
+	covered := map[JujuCommandID]bool{}
+	for _, tt := range tests {
+		covered[tt.command] = true
+		t.Run(string(tt.command), func(t *testing.T) {
+			config := CommandExecutionConfig{CommandName: string(tt.command), Arguments: tt.args, FlagValues: tt.flags}
+
+			require.NoError(t, w.confine(&config))
+
+			assert.Equal(t, tt.wantArgs, config.Arguments)
+			for name, want := range tt.wantFlags {
+				assert.Equal(t, want, config.FlagValues[name], name)
+			}
+		})
+	}
+	for command := range commandPaths {
+		assert.True(t, covered[command], "%s is not tested", command)
+	}
+}
+
+func TestWorkspace_CommandPathsOutside(t *testing.T) {
+	w := newTestWorkspace(t)
+
+	for _, config := range []CommandExecutionConfig{
+		{CommandName: string(CmdExportBundle), FlagValues: map[string]any{"filename": "/tmp/bundle.yaml"}},
+		{CommandName: string(CmdAddSecret), Arguments: []string{"key", "id#file=../.ssh/id_rsa"}},
+		{CommandName: string(CmdModelConfig), FlagValues: map[string]any{"file": "/etc/juju.yaml"}},
+		{CommandName: string(CmdBootstrap), Arguments: []string{"lxd"}, FlagValues: map[string]any{"config": []string{"/etc/juju.yaml"}}},
+	} {
+		assert.ErrorContains(t, w.confine(&config), "is outside the workspace", config.CommandName)
+	}
+}
+
+func TestWorkspace_UploadListDownload(t *testing.T) {
+	// Arrange
+	a, _ := newFakeAdapter(nil)
+	a.workspace = newTestWorkspace(t)
+	charm := []byte("PK\x03\x04charm")
+
+	// Act
+	upload := callTool(t, a, WorkspaceUploadToolName, map[string]any{
+		"path":    "charms/mysql.charm",
+		"content": base64.StdEncoding.EncodeToString(charm),
+	})
+	embedded := callTool(t, a, WorkspaceUploadToolName, map[string]any{
+		"path":     "bundle.yaml",
+		"resource": map[string]any{"type": "resource", "resource": map[string]any{"uri": "file:///bundle.yaml", "text": "applications: {}\n"}},
+	})
+	again := callTool(t, a, WorkspaceUploadToolName, map[string]any{"path": "bundle.yaml", "text": "x"})
+	list := callTool(t, a, WorkspaceListToolName, nil)
+	download := callTool(t, a, WorkspaceDownloadToolName, map[string]any{"path": "charms/mysql.charm"})
+
+	// Assert
+	require.False(t, upload.IsError, resultText(upload))
+	require.False(t, embedded.IsError, resultText(embedded))
+	assert.True(t, again.IsError)
+	assert.Equal(t, "bundle.yaml already exists, set overwrite to replace it", resultText(again))
+
+	var files []workspaceFile
+	require.NoError(t, json.Unmarshal([]byte(resultText(list)), &files))
+	require.Len(t, files, 2)
+	assert.Equal(t, "bundle.yaml", files[0].Path)
+	assert.Equal(t, "application/yaml", files[0].MIMEType)
+	assert.Equal(t, "charms/mysql.charm", files[1].Path)
+	assert.Equal(t, "juju://workspace/charms/mysql.charm", files[1].URI)
+	assert.Equal(t, int64(len(charm)), files[1].Size)
+
+	require.False(t, download.IsError)
+	blob := download.Content[1].(mcp.EmbeddedResource).Resource.(mcp.BlobResourceContents)
+	assert.Equal(t, "application/zip", blob.MIMEType)
+	assert.Equal(t, base64.StdEncoding.EncodeToString(charm), blob.Blob)
+}
+
+func TestWorkspace_Resource(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+	a.workspace = newTestWorkspace(t)
+	require.NoError(t, os.WriteFile(filepath.Join(a.workspace.root, "notes.txt"), []byte("hello"), 0o600))
+	_, handler, err := a.GetResourceTemplate(workspaceTemplateName)
+	require.NoError(t, err)
+
+	req := mcp.ReadResourceRequest{}
+	req.Params.URI = "juju://workspace/notes.txt"
+	contents, err := handler(context.Background(), req)
+	require.NoError(t, err)
+	blob := contents[0].(mcp.BlobResourceContents)
+	assert.Equal(t, base64.StdEncoding.EncodeToString([]byte("hello")), blob.Blob)
+
+	req.Params.URI = "juju://workspace/../../etc/passwd"
+	_, err = handler(context.Background(), req)
+	assert.ErrorContains(t, err, "is outside the workspace")
+}
+
+func TestWorkspace_Disabled(t *testing.T) {
+	a, _ := newFakeAdapter(nil)
+
+	result := callTool(t, a, WorkspaceListToolName, nil)
+
+	assert.True(t, result.IsError)
+	assert.Equal(t, errNoWorkspace.Error(), resultText(result))
+}